@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/merdernoty/anime-service/internal/application/services"
-	"github.com/merdernoty/anime-service/internal/domain/models"
 	"github.com/merdernoty/anime-service/internal/infrastructure/api"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
 	"github.com/merdernoty/anime-service/internal/infrastructure/config"
 	"github.com/merdernoty/anime-service/internal/infrastructure/database"
 	"github.com/merdernoty/anime-service/internal/infrastructure/log"
+	"github.com/merdernoty/anime-service/internal/infrastructure/mail"
 	"github.com/merdernoty/anime-service/internal/infrastructure/repositories"
 	httpServer "github.com/merdernoty/anime-service/internal/interfaces/http"
 	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
@@ -49,9 +52,13 @@ func main() {
 		Level:   cfg.Logger.Level,
 		Nocolor: cfg.App.Environment != "development",
 	})
-	
 
 	log.SetStandartLogger(logger)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, logger, os.Args[2:]))
+	}
+
 	// Подключение к базе данных
 	db, err := database.NewConnector(cfg.Database)
 	if err != nil {
@@ -63,66 +70,300 @@ func main() {
 			logger.Error("Error closing database connection", map[string]interface{}{"error": err.Error()})
 		}
 	}()
-    
-	// Автомиграция моделей
-	if err := database.AutoMigrate(db, 
-        &models.User{},
-        &models.UserAnime{},
-    ); err != nil {
-        logger.Error("Failed to auto-migrate database schema", map[string]interface{}{"error": err.Error()})
-        os.Exit(1)
-    }
+
+	// Применение миграций схемы; при нескольких репликах мигрирует только одна
+	if cfg.Database.AutoMigrate {
+		if err := migrateUp(db, logger); err != nil {
+			logger.Error("Failed to migrate database schema", map[string]interface{}{"error": err.Error()})
+			os.Exit(1)
+		}
+	}
 
 	if cfg.App.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	userRepo := repositories.NewUserRepository(db)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	passwordResetRepo := repositories.NewPasswordResetTokenRepository(db)
+	mfaRepo := repositories.NewMFARepository(db)
 	sqlDB, err := db.DB()
 	if err != nil {
 		logger.Error("Failed to get *sql.DB from *gorm.DB", map[string]interface{}{"error": err.Error()})
 		os.Exit(1)
 	}
 	userAnimeRepo := repositories.NewUserAnimeRepository(sqlDB, logger)
+	episodeWatchRepo := repositories.NewEpisodeWatchRepository(sqlDB, userAnimeRepo, logger)
+	animeRepo := repositories.NewAnimeRepository(sqlDB, logger)
+	importJobRepo := repositories.NewImportJobRepository(sqlDB, logger)
+
+	responseCache, err := cache.New(cache.Config{
+		Driver:            cfg.Cache.Driver,
+		Host:              cfg.Cache.Host,
+		Port:              cfg.Cache.Port,
+		Password:          cfg.Cache.Password,
+		DB:                cfg.Cache.DB,
+		MaxEntries:        cfg.Cache.MaxEntries,
+		DefaultExpiration: cfg.Cache.DefaultExpiration,
+	})
+	if err != nil {
+		logger.Error("Failed to initialize cache", map[string]interface{}{
+			"driver": cfg.Cache.Driver,
+			"error":  err.Error(),
+		})
+		os.Exit(1)
+	}
+
+	// Состояние, которое нельзя терять до истечения срока, хранится отдельно от
+	// кэша ответов: тот в памяти вытесняет записи при переполнении.
+	stateStore, err := cache.NewStore(cache.Config{
+		Driver:            cfg.Store.Driver,
+		Host:              cfg.Cache.Host,
+		Port:              cfg.Cache.Port,
+		Password:          cfg.Cache.Password,
+		DB:                cfg.Store.RedisDB,
+		DefaultExpiration: cfg.Cache.DefaultExpiration,
+	})
+	if err != nil {
+		logger.Error("Failed to initialize state store", map[string]interface{}{
+			"driver": cfg.Store.Driver,
+			"error":  err.Error(),
+		})
+		os.Exit(1)
+	}
+
+	mailer, err := mail.New(mail.Config{
+		Driver:   cfg.Mail.Driver,
+		Host:     cfg.Mail.Host,
+		Port:     cfg.Mail.Port,
+		Username: cfg.Mail.Username,
+		Password: cfg.Mail.Password,
+		From:     cfg.Mail.From,
+	}, logger)
+	if err != nil {
+		logger.Error("Failed to initialize mailer", map[string]interface{}{
+			"driver": cfg.Mail.Driver,
+			"error":  err.Error(),
+		})
+		os.Exit(1)
+	}
 
-	jikanClient := api.NewJikanClient(logger)
+	jikanAPIClient := api.NewJikanClient(
+		logger,
+		api.NewRateLimiter(
+			api.RateLimit{Requests: cfg.Jikan.RequestsPerSecond, Per: time.Second},
+			api.RateLimit{Requests: cfg.Jikan.RequestsPerMinute, Per: time.Minute},
+		),
+		api.RetryPolicy{
+			MaxRetries:    cfg.Jikan.MaxRetries,
+			BaseDelay:     cfg.Jikan.RetryBaseDelay,
+			MaxDelay:      cfg.Jikan.RetryMaxDelay,
+			MaxRetryAfter: cfg.Jikan.MaxRetryAfter,
+		},
+	)
 
-	tokenMaker := auth.NewJWTTokenMaker(
-		cfg.Auth.SecretKey, 
-		cfg.Auth.TokenDuration,
+	jikanClient := api.NewCachedJikanClient(
+		jikanAPIClient,
+		responseCache,
+		api.CacheTTL{
+			Anime:           cfg.Cache.AnimeTTL,
+			Search:          cfg.Cache.SearchTTL,
+			Top:             cfg.Cache.TopTTL,
+			Seasonal:        cfg.Cache.SeasonalTTL,
+			Recommendations: cfg.Cache.RecommendationTTL,
+		},
+		logger,
+	)
+
+	var tokenMaker interface {
+		auth.TokenMaker
+		auth.JWKSProvider
+	}
+	if cfg.Auth.KeysDir != "" {
+		keys, err := auth.LoadKeySet(cfg.Auth.KeysDir, cfg.Auth.ActiveKeyID)
+		if err != nil {
+			logger.Error("Failed to load JWT signing keys", map[string]interface{}{
+				"dir":   cfg.Auth.KeysDir,
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		tokenMaker = auth.NewAsymmetricTokenMaker(keys, cfg.Auth.TokenDuration)
+		logger.Info("Signing tokens with asymmetric key", map[string]interface{}{
+			"kid": keys.Active().ID,
+			"alg": keys.Active().Method.Alg(),
+		})
+	} else {
+		tokenMaker = auth.NewJWTTokenMaker(
+			cfg.Auth.SecretKey,
+			cfg.Auth.TokenDuration,
+		)
+	}
+
+	verificationSigner, err := auth.NewHMACSigner(cfg.Auth.EmailVerificationSecret, "email-verification")
+	if err != nil {
+		logger.Error("Failed to initialize email verification signer", map[string]interface{}{"error": err.Error()})
+		os.Exit(1)
+	}
+
+	emailVerificationService := services.NewEmailVerificationService(
+		userRepo,
+		mailer,
+		verificationSigner,
+		stateStore,
+		services.EmailVerificationSettings{
+			TokenTTL:       cfg.Auth.EmailVerificationTTL,
+			VerifyURL:      cfg.Auth.EmailVerificationURL,
+			ResendCooldown: cfg.Auth.EmailVerificationResendCooldown,
+		},
+		logger,
 	)
 
 	userService := services.NewUserService(
 		userRepo,
+		emailVerificationService,
 		logger,
 	)
 
+	if cfg.Auth.BootstrapAdminEmail != "" {
+		if err := userService.BootstrapAdmin(context.Background(), cfg.Auth.BootstrapAdminEmail); err != nil {
+			logger.Warn("Failed to bootstrap admin", map[string]interface{}{
+				"email": cfg.Auth.BootstrapAdminEmail,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	sessionService := services.NewSessionService(
+		sessionRepo,
+		refreshTokenRepo,
+		stateStore,
+		logger,
+	)
+
+	totpSecrets, err := auth.NewSecretBox(cfg.Auth.MFAEncryptionKey, "totp-secret")
+	if err != nil {
+		logger.Error("Failed to initialize TOTP secret encryption", map[string]interface{}{"error": err.Error()})
+		os.Exit(1)
+	}
+
+	mfaService := services.NewMFAService(
+		userRepo,
+		mfaRepo,
+		stateStore,
+		totpSecrets,
+		cfg.App.Name,
+		logger,
+	)
+
+	loginThrottleService := services.NewLoginThrottleService(
+		userRepo,
+		stateStore,
+		services.LoginThrottleSettings{
+			MaxAccountFailures: cfg.Auth.LoginMaxAccountFailures,
+			MaxIPFailures:      cfg.Auth.LoginMaxIPFailures,
+			FailureWindow:      cfg.Auth.LoginFailureWindow,
+			LockoutDuration:    cfg.Auth.LoginLockoutDuration,
+			BaseDelay:          cfg.Auth.LoginBaseDelay,
+			MaxDelay:           cfg.Auth.LoginMaxDelay,
+		},
+		logger,
+	)
+
+	refreshTokenRotator, err := auth.NewRefreshTokenRotator(cfg.Auth.RefreshTokenSecret)
+	if err != nil {
+		logger.Error("Failed to initialize refresh token rotation", map[string]interface{}{"error": err.Error()})
+		os.Exit(1)
+	}
+
 	authService := services.NewAuthService(
-		userRepo, 
+		userRepo,
+		refreshTokenRepo,
+		refreshTokenRotator,
+		sessionService,
+		emailVerificationService,
+		mfaService,
+		loginThrottleService,
 		logger,
 		tokenMaker,
 	)
 
+	passwordResetService := services.NewPasswordResetService(
+		userRepo,
+		passwordResetRepo,
+		sessionService,
+		mailer,
+		stateStore,
+		services.PasswordResetSettings{
+			TokenTTL:    cfg.Auth.PasswordResetTTL,
+			ResetURL:    cfg.Auth.PasswordResetURL,
+			MaxPerEmail: cfg.Auth.PasswordResetMaxPerEmail,
+			MaxPerIP:    cfg.Auth.PasswordResetMaxPerIP,
+			Window:      cfg.Auth.PasswordResetWindow,
+		},
+		logger,
+	)
+
+	catalogService := services.NewAnimeCatalogService(
+		animeRepo,
+		jikanClient,
+		cfg.Catalog.StaleAfter,
+		logger,
+	)
+
+	backgroundCtx, cancelBackground := context.WithCancel(context.Background())
+	defer cancelBackground()
+	catalogService.StartRefresher(backgroundCtx, cfg.Catalog.RefreshInterval, cfg.Catalog.RefreshBatchSize)
+	authService.StartRefreshTokenCleanup(backgroundCtx)
+	passwordResetService.StartCleanup(backgroundCtx)
+
 	animeService := services.NewAnimeService(
 		jikanClient,
 		userAnimeRepo,
+		episodeWatchRepo,
+		catalogService,
 		logger,
 	)
-	
-	authMiddleware := middleware.NewAuthMiddleware(tokenMaker, userRepo)
-	authController := controllers.NewAuthController(authService)
+
+	listTransferService := services.NewListTransferService(
+		userAnimeRepo,
+		episodeWatchRepo,
+		userRepo,
+		importJobRepo,
+		services.ImportSettings{
+			SyncLimit:  cfg.Import.SyncLimit,
+			JobTTL:     cfg.Import.JobTTL,
+			JobTimeout: cfg.Import.JobTimeout,
+			MaxEntries: cfg.Import.MaxEntries,
+			MaxXMLSize: int64(cfg.Import.MaxXMLSize),
+		},
+		logger,
+	)
+
+	authMiddleware := middleware.NewAuthMiddleware(tokenMaker, userRepo, sessionService, cfg.Auth.RequireVerifiedEmail)
+	authController := controllers.NewAuthController(authService, passwordResetService, emailVerificationService)
 	animeController := controllers.NewAnimeController(*animeService, logger)
-	userController := controllers.NewUserController(userService, logger)
+	userController := controllers.NewUserController(userService, loginThrottleService, logger)
+	healthController := controllers.NewHealthController(jikanAPIClient)
+	listTransferController := controllers.NewListTransferController(listTransferService, int64(cfg.Import.MaxUploadSize), logger)
+	sessionController := controllers.NewSessionController(sessionService)
+	jwksController := controllers.NewJWKSController(tokenMaker)
+	mfaController := controllers.NewMFAController(mfaService)
 
 	router := gin.Default()
-	
-	
+
 	routes.SetupRoutes(
-		router, 
+		router,
 		routes.NewService(
 			authController,
 			animeController,
 			userController,
+			healthController,
+			listTransferController,
+			sessionController,
+			jwksController,
+			mfaController,
 		),
 		authMiddleware,
 	)
@@ -132,6 +373,11 @@ func main() {
 		authController,
 		animeController,
 		userController,
+		healthController,
+		listTransferController,
+		sessionController,
+		jwksController,
+		mfaController,
 		*authMiddleware,
 	)
 
@@ -144,4 +390,4 @@ func main() {
 		logger.Error("Server error", map[string]interface{}{"error": err.Error()})
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
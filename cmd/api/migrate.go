@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/merdernoty/anime-service/internal/infrastructure/config"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/migrations"
+	"gorm.io/gorm"
+	"logur.dev/logur"
+)
+
+// migrationsDir — каталог исходников миграций, куда "migrate create" пишет новые файлы.
+const migrationsDir = "internal/infrastructure/database/migrations"
+
+const migrateUsage = `Usage: %s migrate <command>
+
+Commands:
+  up             apply all pending migrations
+  down [N]       revert the last N migrations (default 1)
+  status         list migrations and when they were applied
+  create <name>  create an empty up/down migration pair in ` + migrationsDir + `
+`
+
+// runMigrate выполняет подкоманду migrate и возвращает код завершения процесса.
+func runMigrate(cfg *config.Config, logger logur.LoggerFacade, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, migrateUsage, os.Args[0])
+		return 2
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, migrateUsage, os.Args[0])
+			return 2
+		}
+		upPath, downPath, err := database.CreateMigration(migrationsDir, args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating migration: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Created %s\nCreated %s\n", upPath, downPath)
+		return 0
+	}
+
+	steps := 1
+	switch args[0] {
+	case "up", "status":
+	case "down":
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "Invalid number of steps: %s\n", args[1])
+				return 2
+			}
+			steps = n
+		}
+	default:
+		fmt.Fprintf(os.Stderr, migrateUsage, os.Args[0])
+		return 2
+	}
+
+	db, err := database.NewConnector(cfg.Database)
+	if err != nil {
+		logger.Error("Failed to connect to database", map[string]interface{}{"error": err.Error()})
+		return 1
+	}
+	defer database.CloseDB(db)
+
+	migrator, err := newMigrator(db, logger)
+	if err != nil {
+		logger.Error("Failed to load migrations", map[string]interface{}{"error": err.Error()})
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			logger.Error("Failed to apply migrations", map[string]interface{}{"error": err.Error()})
+			return 1
+		}
+		fmt.Printf("Applied %d migration(s)\n", len(applied))
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			logger.Error("Failed to revert migrations", map[string]interface{}{"error": err.Error()})
+			return 1
+		}
+		fmt.Printf("Reverted %d migration(s)\n", len(reverted))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			logger.Error("Failed to read migration status", map[string]interface{}{"error": err.Error()})
+			return 1
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d  %-40s  %s\n", status.Version, status.Name, appliedAt)
+		}
+	}
+	return 0
+}
+
+// migrateUp применяет непримененные миграции при старте сервиса.
+func migrateUp(db *gorm.DB, logger logur.LoggerFacade) error {
+	migrator, err := newMigrator(db, logger)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(context.Background())
+	return err
+}
+
+func newMigrator(db *gorm.DB, logger logur.LoggerFacade) (*database.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return database.NewMigrator(sqlDB, migrations.FS, logger)
+}
@@ -24,9 +24,29 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Возвращает JWKS с открытыми ключами, которыми другие сервисы проверяют access-токены. При подписи HS256 набор пуст",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Открытые ключи подписи токенов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.JSONWebKeySet"
+                        }
+                    }
+                }
+            }
+        },
         "/anime/search": {
             "get": {
-                "description": "Выполняет поиск аниме по заданному запросу с пагинацией",
+                "description": "Выполняет поиск аниме по заданному запросу и фильтрам с пагинацией",
                 "consumes": [
                     "application/json"
                 ],
@@ -42,8 +62,124 @@ const docTemplate = `{
                         "type": "string",
                         "description": "Поисковый запрос",
                         "name": "query",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "tv",
+                            "movie",
+                            "ova",
+                            "special",
+                            "ona",
+                            "music",
+                            "cm",
+                            "pv",
+                            "tv_special"
+                        ],
+                        "type": "string",
+                        "description": "Тип аниме",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "airing",
+                            "complete",
+                            "upcoming"
+                        ],
+                        "type": "string",
+                        "description": "Статус выхода",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "g",
+                            "pg",
+                            "pg13",
+                            "r17",
+                            "r",
+                            "rx"
+                        ],
+                        "type": "string",
+                        "description": "Возрастной рейтинг",
+                        "name": "rating",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID жанров через запятую",
+                        "name": "genres",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID исключаемых жанров через запятую",
+                        "name": "genres_exclude",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 10,
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Минимальная оценка",
+                        "name": "min_score",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 10,
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Максимальная оценка",
+                        "name": "max_score",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата начала выхода (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата окончания выхода (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "mal_id",
+                            "title",
+                            "start_date",
+                            "end_date",
+                            "episodes",
+                            "score",
+                            "scored_by",
+                            "rank",
+                            "popularity",
+                            "members",
+                            "favorites"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Направление сортировки",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Исключить контент для взрослых",
+                        "name": "sfw",
+                        "in": "query"
                     },
                     {
                         "minimum": 1,
@@ -70,6 +206,15 @@ const docTemplate = `{
                             "$ref": "#/definitions/dtos.AnimeListResponse"
                         }
                     },
+                    "400": {
+                        "description": "Неверные параметры поиска",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
@@ -335,7 +480,7 @@ const docTemplate = `{
         },
         "/auth/login": {
             "post": {
-                "description": "Выполняет вход в систему и возвращает токены",
+                "description": "Выполняет вход по email или никнейму (без учета регистра) и возвращает токены. Если у пользователя включена 2FA, вместо токенов возвращается mfa_token для /auth/login/mfa",
                 "consumes": [
                     "application/json"
                 ],
@@ -364,6 +509,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/dtos.TokenResponseDTO"
                         }
                     },
+                    "202": {
+                        "description": "Требуется код 2FA",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.MFAChallengeDTO"
+                        }
+                    },
                     "400": {
                         "description": "Ошибка валидации",
                         "schema": {
@@ -376,6 +527,64 @@ const docTemplate = `{
                             "$ref": "#/definitions/dtos.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Слишком много неудачных попыток, см. заголовок Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login/mfa": {
+            "post": {
+                "description": "Завершает вход по mfa_token из /auth/login и коду из приложения-аутентификатора или коду восстановления",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Второй шаг входа с 2FA",
+                "parameters": [
+                    {
+                        "description": "Токен второго шага и код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.MFALoginDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.TokenResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный код или токен второго шага",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
@@ -387,7 +596,7 @@ const docTemplate = `{
         },
         "/auth/logout": {
             "post": {
-                "description": "Удаляет refresh-токен и очищает куки",
+                "description": "Удаляет с сервера refresh-токен текущей сессии и очищает куки",
                 "consumes": [
                     "application/json"
                 ],
@@ -411,25 +620,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/refresh": {
+        "/auth/logout-all": {
             "post": {
-                "description": "Обновляет access-токен, используя refresh-токен из куки",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет все refresh-токены пользователя, завершая его сессии на всех устройствах",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Auth"
                 ],
-                "summary": "Обновление JWT-токена",
+                "summary": "Выход со всех устройств",
                 "responses": {
                     "200": {
-                        "description": "Возвращает новый access-токен",
+                        "description": "Успешный выход",
                         "schema": {
-                            "$ref": "#/definitions/dtos.TokenResponseDTO"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "401": {
-                        "description": "Refresh-токен недействителен или отсутствует",
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
                         "schema": {
                             "$ref": "#/definitions/dtos.ErrorResponse"
                         }
@@ -437,9 +660,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/register": {
+        "/auth/mfa/totp/confirm": {
             "post": {
-                "description": "Создает нового пользователя в системе",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Включает 2FA после проверки кода из приложения и возвращает одноразовые коды восстановления. Коды показываются только один раз",
                 "consumes": [
                     "application/json"
                 ],
@@ -449,33 +677,39 @@ const docTemplate = `{
                 "tags": [
                     "Auth"
                 ],
-                "summary": "Регистрация нового пользователя",
+                "summary": "Подтверждение подключения 2FA",
                 "parameters": [
                     {
-                        "description": "Данные для регистрации",
+                        "description": "Код из приложения",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dtos.CreateUserDTO"
+                            "$ref": "#/definitions/dtos.MFACodeDTO"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Успешная регистрация",
+                    "200": {
+                        "description": "2FA включена",
                         "schema": {
-                            "$ref": "#/definitions/dtos.UserResponseDTO"
+                            "$ref": "#/definitions/dtos.RecoveryCodesResponse"
                         }
                     },
                     "400": {
-                        "description": "Ошибка валидации",
+                        "description": "Ошибка валидации или подключение не начато",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный код",
                         "schema": {
                             "$ref": "#/definitions/dtos.ErrorResponse"
                         }
                     },
                     "409": {
-                        "description": "Пользователь уже существует",
+                        "description": "2FA уже включена",
                         "schema": {
                             "$ref": "#/definitions/dtos.ErrorResponse"
                         }
@@ -489,14 +723,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/user/profile": {
-            "get": {
+        "/auth/mfa/totp/disable": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Получить профиль пользователя по ID",
+                "description": "Отключает 2FA. Требуется действующий код из приложения; коды восстановления удаляются",
                 "consumes": [
                     "application/json"
                 ],
@@ -504,30 +738,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Profile"
+                    "Auth"
+                ],
+                "summary": "Отключение 2FA",
+                "parameters": [
+                    {
+                        "description": "Код из приложения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.MFACodeDTO"
+                        }
+                    }
                 ],
-                "summary": "Получить профиль пользователя",
                 "responses": {
                     "200": {
-                        "description": "Успешное получение профиля",
+                        "description": "2FA отключена",
                         "schema": {
-                            "$ref": "#/definitions/dtos.UserResponseDTO"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Ошибка валидации",
+                        "description": "Ошибка валидации или 2FA не включена",
                         "schema": {
                             "$ref": "#/definitions/dtos.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Пользователь не авторизован",
-                        "schema": {
-                            "$ref": "#/definitions/dtos.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Пользователь не найден",
+                        "description": "Неверный код",
                         "schema": {
                             "$ref": "#/definitions/dtos.ErrorResponse"
                         }
@@ -539,49 +781,38 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/auth/mfa/totp/enroll": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Обновить профиль пользователя по ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Генерирует секрет TOTP и возвращает otpauth URI и QR-код (PNG в base64) для приложения-аутентификатора. 2FA включается после подтверждения кодом",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Profile"
-                ],
-                "summary": "Обновить профиль пользователя",
-                "parameters": [
-                    {
-                        "description": "Данные для обновления профиля",
-                        "name": "request",
-                        "in": "body",
-                        "schema": {
-                            "$ref": "#/definitions/dtos.UpdateUserDTO"
-                        }
-                    }
+                    "Auth"
                 ],
+                "summary": "Подключение 2FA",
                 "responses": {
                     "200": {
-                        "description": "Успешное обновление профиля",
+                        "description": "Данные для подключения",
                         "schema": {
-                            "$ref": "#/definitions/dtos.UserResponseDTO"
+                            "$ref": "#/definitions/dtos.TOTPEnrollmentResponse"
                         }
                     },
-                    "400": {
-                        "description": "Ошибка валидации",
+                    "401": {
+                        "description": "Пользователь не авторизован",
                         "schema": {
                             "$ref": "#/definitions/dtos.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Пользователь не найден",
+                    "409": {
+                        "description": "2FA уже включена",
                         "schema": {
                             "$ref": "#/definitions/dtos.ErrorResponse"
                         }
@@ -595,9 +826,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{user_id}/anime": {
-            "get": {
-                "description": "Возвращает список аниме пользователя с возможностью фильтрации по статусу",
+        "/auth/password/forgot": {
+            "post": {
+                "description": "Отправляет на email ссылку с одноразовым токеном сброса пароля. Ответ одинаков независимо от того, зарегистрирован ли email. Число запросов на один email и с одного IP ограничено",
                 "consumes": [
                     "application/json"
                 ],
@@ -605,37 +836,1400 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "Auth"
                 ],
-                "summary": "Получить список аниме пользователя",
+                "summary": "Запрос на сброс пароля",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID пользователя",
-                        "name": "user_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Статус аниме (watched, plan_to_watch, watching, waiting)",
-                        "name": "status",
-                        "in": "query"
-                    },
-                    {
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Номер страницы",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Email аккаунта",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ForgotPasswordDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Запрос принят",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много запросов сброса пароля",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password/reset": {
+            "post": {
+                "description": "Задает новый пароль по токену из письма. Токен одноразовый; все сессии пользователя завершаются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Сброс пароля",
+                "parameters": [
+                    {
+                        "description": "Токен и новый пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ResetPasswordDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пароль изменен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации или недействительный токен",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Обновляет access-токен, используя refresh-токен из куки",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Обновление JWT-токена",
+                "responses": {
+                    "200": {
+                        "description": "Возвращает новый access-токен",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.TokenResponseDTO"
+                        }
+                    },
+                    "401": {
+                        "description": "Refresh-токен недействителен или отсутствует",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Создает нового пользователя в системе",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Регистрация нового пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для регистрации",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.CreateUserDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Успешная регистрация",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь уже существует",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify-email": {
+            "post": {
+                "description": "Подтверждает email по подписанному токену из письма. Токен привязан к адресу и перестает действовать после его смены",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Подтверждение email",
+                "parameters": [
+                    {
+                        "description": "Токен из письма",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.VerifyEmailDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email подтвержден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации или недействительный токен",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify-email/resend": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Повторно отправляет письмо со ссылкой подтверждения email. Письма отправляются не чаще одного раза за период ограничения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Повторная отправка письма подтверждения",
+                "responses": {
+                    "202": {
+                        "description": "Письмо отправлено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email уже подтвержден",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Письмо уже отправлено недавно",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/jikan": {
+            "get": {
+                "description": "Возвращает глубину очереди ограничителя запросов и количество повторов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Статистика клиента Jikan API",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ClientStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/anime/export": {
+            "get": {
+                "description": "Потоково выгружает весь список пользователя в формате XML-экспорта MyAnimeList. При Accept-Encoding: gzip ответ сжимается",
+                "produces": [
+                    "text/xml"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Экспорт списка аниме",
+                "parameters": [
+                    {
+                        "enum": [
+                            "mal-xml"
+                        ],
+                        "type": "string",
+                        "default": "mal-xml",
+                        "description": "Формат экспорта",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл экспорта",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Неподдерживаемый формат",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/anime/import": {
+            "post": {
+                "description": "Импортирует список из XML-экспорта MyAnimeList (в том числе сжатого gzip). В пробном режиме ничего не сохраняет и возвращает различия. Большие файлы обрабатываются в фоне: ответ 202 содержит задачу, статус которой доступен по /me/anime/import/{job_id}",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Импорт списка аниме",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Файл экспорта MAL (.xml или .xml.gz)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Пробный прогон без сохранения",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "skip",
+                            "overwrite",
+                            "newest"
+                        ],
+                        "type": "string",
+                        "default": "skip",
+                        "description": "Что делать с уже добавленными аниме",
+                        "name": "policy",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Импорт выполнен",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportJob"
+                        }
+                    },
+                    "202": {
+                        "description": "Импорт запущен в фоне",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный файл или параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл слишком большой или содержит слишком много записей",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/anime/import/{job_id}": {
+            "get": {
+                "description": "Возвращает состояние фоновой задачи импорта и, после завершения, отчет по каждой записи",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Статус импорта списка",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID задачи импорта",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportJob"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/me/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает активные входы пользователя с разных устройств: время входа и последнего использования, IP и разобранный User-Agent. Текущая сессия отмечена полем current",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Активные сессии",
+                "responses": {
+                    "200": {
+                        "description": "Список сессий",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.SessionListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает сессию пользователя: ее refresh-токены удаляются, а выданные ей access-токены перестают приниматься сразу",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Завершение сессии",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сессия завершена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/profile": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить профиль пользователя по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Profile"
+                ],
+                "summary": "Получить профиль пользователя",
+                "responses": {
+                    "200": {
+                        "description": "Успешное получение профиля",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновить профиль пользователя по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Profile"
+                ],
+                "summary": "Обновить профиль пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для обновления профиля",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UpdateUserDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное обновление профиля",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка валидации",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/anime": {
+            "get": {
+                "description": "Возвращает список аниме пользователя с возможностью фильтрации по статусу и годам начала и окончания просмотра",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Получить список аниме пользователя",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "watched",
+                            "plan_to_watch",
+                            "watching",
+                            "waiting",
+                            "dropped",
+                            "on_hold",
+                            "rewatching"
+                        ],
+                        "type": "string",
+                        "description": "Статус аниме",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 50,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Количество результатов на странице",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Год начала просмотра",
+                        "name": "started_year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Год окончания просмотра",
+                        "name": "finished_year",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID пользователя или фильтр",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Данные аниме временно недоступны; повторите запрос позже",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Добавляет аниме в список пользователя с указанным статусом",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Добавить аниме в список пользователя",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Информация о добавляемом аниме",
+                        "name": "anime",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.AddAnimeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Успешное добавление",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные входные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Аниме не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/anime/bulk": {
+            "post": {
+                "description": "Выполняет до 100 операций (add, remove, set_status, set_rating, set_episodes) в одной транзакции. В режиме all_or_nothing ошибка любой операции отменяет весь пакет, в режиме best_effort фиксируются все успешные операции. Итог каждой операции — в results. Аниме, которых нет в локальном каталоге, загружаются из Jikan API не больше 10 за запрос; если их больше или Jikan API недоступен, возвращается 503, а загруженные аниме сохраняются в каталоге",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Пакетно изменить список пользователя",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Операции",
+                        "name": "bulk",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.BulkUserAnimeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изменения зафиксированы",
+                        "schema": {
+                            "$ref": "#/definitions/models.BulkResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные входные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Пакет all_or_nothing отменен; причина — в results",
+                        "schema": {
+                            "$ref": "#/definitions/models.BulkResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Данные аниме временно недоступны; повторите запрос позже",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/anime/stats": {
+            "get": {
+                "description": "Возвращает статистику пользователя по просмотру аниме",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Получить статистику пользователя по аниме",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Статистика пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.StatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/anime/{anime_id}": {
+            "get": {
+                "description": "Возвращает запись списка пользователя по MAL ID аниме. Версия записи возвращается в заголовке ETag; ее нужно передавать в If-Match при изменении или удалении записи",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Получить запись из списка пользователя",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "MAL ID аниме",
+                        "name": "anime_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные входные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Запись не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет аниме из списка пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Удалить аниме из списка пользователя",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "MAL ID аниме",
+                        "name": "anime_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Версия записи вместо If-Match",
+                        "name": "version",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное удаление",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные входные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Запись не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Запись изменилась, в ответе ее актуальное состояние",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeConflictResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передана версия записи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Применяет JSON Merge Patch к статусу, прогрессу, рейтингу, заметкам, датам и тегам записи в одной транзакции. Отсутствующее поле не меняется, null сбрасывает значение. Без явного статуса он сдвигается по прогрессу так же, как в PUT /episodes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Изменить запись в списке пользователя",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "MAL ID аниме",
+                        "name": "anime_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Изменяемые поля",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.PatchUserAnimeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Запись после изменения",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные входные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Запись не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Запись изменилась, в ответе ее актуальное состояние",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeConflictResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Недопустимый переход статуса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Не передана версия записи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/anime/{anime_id}/episodes": {
+            "put": {
+                "description": "Обновляет количество просмотренных эпизодов аниме в списке пользователя. Статус меняется автоматически: первый эпизод переводит из plan_to_watch в watching, последний эпизод завершенного тайтла — в watched, догнавший онгоинг — в waiting",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Обновить количество просмотренных эпизодов",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "maximum": 50,
+                        "type": "integer",
+                        "description": "MAL ID аниме",
+                        "name": "anime_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Новое количество просмотренных эпизодов",
+                        "name": "episodes",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UpdateEpisodesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное обновление",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UpdateEpisodesResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные входные данные или количество эпизодов вне диапазона",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Запись не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Запись изменилась, в ответе ее актуальное состояние",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeConflictResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передана версия записи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/anime/{anime_id}/episodes/history": {
+            "get": {
+                "description": "Возвращает события журнала просмотров по аниме из списка пользователя, начиная с последних",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Журнал просмотра эпизодов",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "MAL ID аниме",
+                        "name": "anime_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 500,
                         "minimum": 1,
                         "type": "integer",
-                        "default": 10,
-                        "description": "Количество результатов на странице",
+                        "default": 100,
+                        "description": "Количество событий",
                         "name": "limit",
                         "in": "query"
                     }
@@ -644,11 +2238,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dtos.UserAnimeListResponse"
+                            "$ref": "#/definitions/dtos.EpisodeWatchHistoryResponse"
                         }
                     },
                     "400": {
-                        "description": "Неверный ID пользователя",
+                        "description": "Неверные входные данные",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -666,9 +2260,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/users/{user_id}/anime/{anime_id}/episodes/watched": {
             "post": {
-                "description": "Добавляет аниме в список пользователя с указанным статусом",
+                "description": "Добавляет в журнал просмотров отметки для эпизода или диапазона эпизодов и пересчитывает прогресс и статус записи",
                 "consumes": [
                     "application/json"
                 ],
@@ -678,7 +2274,7 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Добавить аниме в список пользователя",
+                "summary": "Отметить эпизоды просмотренными",
                 "parameters": [
                     {
                         "type": "integer",
@@ -688,18 +2284,43 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Информация о добавляемом аниме",
-                        "name": "anime",
+                        "type": "integer",
+                        "description": "MAL ID аниме",
+                        "name": "anime_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи (или список ETag; слабые W/ не совпадают); без него версия не проверяется",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Диапазон эпизодов",
+                        "name": "episodes",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dtos.AddAnimeRequest"
+                            "$ref": "#/definitions/dtos.MarkEpisodesRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Успешное добавление",
+                    "200": {
+                        "description": "Прогресс после отметки",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UpdateEpisodesResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверные входные данные или эпизоды вне диапазона",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -707,17 +2328,98 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Неверные входные данные",
+                    "404": {
+                        "description": "Запись не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Запись изменилась, в ответе ее актуальное состояние",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeConflictResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Добавляет в журнал просмотров снятие отметки для эпизода или диапазона эпизодов и пересчитывает прогресс записи",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Снять отметку просмотра с эпизодов",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "MAL ID аниме",
+                        "name": "anime_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Первый эпизод",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Последний эпизод (по умолчанию равен from)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Версия записи вместо If-Match",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Прогресс после снятия отметки",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UpdateEpisodesResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
+                        }
                     },
-                    "404": {
-                        "description": "Аниме не найдено",
+                    "400": {
+                        "description": "Неверные входные данные или эпизоды вне диапазона",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -725,49 +2427,23 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "404": {
+                        "description": "Запись не найдена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/users/{user_id}/anime/stats": {
-            "get": {
-                "description": "Возвращает статистику пользователя по просмотру аниме",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "users"
-                ],
-                "summary": "Получить статистику пользователя по аниме",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID пользователя",
-                        "name": "user_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Статистика пользователя",
+                    },
+                    "412": {
+                        "description": "Запись изменилась, в ответе ее актуальное состояние",
                         "schema": {
-                            "$ref": "#/definitions/dtos.StatsResponse"
+                            "$ref": "#/definitions/dtos.UserAnimeConflictResponse"
                         }
                     },
-                    "400": {
-                        "description": "Неверный ID пользователя",
+                    "428": {
+                        "description": "Не передана версия записи",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -787,9 +2463,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{user_id}/anime/{anime_id}": {
-            "delete": {
-                "description": "Удаляет аниме из списка пользователя",
+        "/users/{user_id}/anime/{anime_id}/rating": {
+            "put": {
+                "description": "Обновляет пользовательский рейтинг аниме в списке пользователя",
                 "consumes": [
                     "application/json"
                 ],
@@ -799,7 +2475,7 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Удалить аниме из списка пользователя",
+                "summary": "Обновить рейтинг аниме",
                 "parameters": [
                     {
                         "type": "integer",
@@ -814,16 +2490,37 @@ const docTemplate = `{
                         "name": "anime_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Новый рейтинг аниме (от 0 до 10)",
+                        "name": "rating",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UpdateRatingRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное удаление",
+                        "description": "Успешное обновление",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
                         }
                     },
                     "400": {
@@ -844,6 +2541,21 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "412": {
+                        "description": "Запись изменилась, в ответе ее актуальное состояние",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeConflictResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передана версия записи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
@@ -856,9 +2568,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{user_id}/anime/{anime_id}/episodes": {
+        "/users/{user_id}/anime/{anime_id}/status": {
             "put": {
-                "description": "Обновляет количество просмотренных эпизодов аниме в списке пользователя",
+                "description": "Обновляет статус аниме в списке пользователя",
                 "consumes": [
                     "application/json"
                 ],
@@ -868,7 +2580,7 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Обновить количество просмотренных эпизодов",
+                "summary": "Обновить статус аниме в списке пользователя",
                 "parameters": [
                     {
                         "type": "integer",
@@ -885,12 +2597,18 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Новое количество просмотренных эпизодов",
-                        "name": "episodes",
+                        "type": "string",
+                        "description": "ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version. * — любая версия существующей записи",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Новый статус аниме",
+                        "name": "status",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dtos.UpdateEpisodesRequest"
+                            "$ref": "#/definitions/dtos.UpdateStatusRequest"
                         }
                     }
                 ],
@@ -902,6 +2620,12 @@ const docTemplate = `{
                             "additionalProperties": {
                                 "type": "string"
                             }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
                         }
                     },
                     "400": {
@@ -922,6 +2646,21 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "412": {
+                        "description": "Запись изменилась, в ответе ее актуальное состояние",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeConflictResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передана версия записи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
@@ -934,9 +2673,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{user_id}/anime/{anime_id}/rating": {
+        "/users/{user_id}/anime/{anime_id}/tracking": {
             "put": {
-                "description": "Обновляет пользовательский рейтинг аниме в списке пользователя",
+                "description": "Вручную заменяет даты начала и окончания просмотра, количество пересмотров и прогресс текущего пересмотра. Не переданная дата очищается",
                 "consumes": [
                     "application/json"
                 ],
@@ -946,7 +2685,7 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Обновить рейтинг аниме",
+                "summary": "Задать даты просмотра и пересмотры",
                 "parameters": [
                     {
                         "type": "integer",
@@ -963,12 +2702,18 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Новый рейтинг аниме (от 0 до 10)",
-                        "name": "rating",
+                        "type": "string",
+                        "description": "ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Даты просмотра и счетчики пересмотров",
+                        "name": "tracking",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dtos.UpdateRatingRequest"
+                            "$ref": "#/definitions/dtos.UpdateTrackingRequest"
                         }
                     }
                 ],
@@ -976,9 +2721,12 @@ const docTemplate = `{
                     "200": {
                         "description": "Успешное обновление",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "$ref": "#/definitions/dtos.UserAnimeTrackingResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
                             }
                         }
                     },
@@ -1000,6 +2748,21 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "412": {
+                        "description": "Запись изменилась, в ответе ее актуальное состояние",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.UserAnimeConflictResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передана версия записи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
@@ -1012,9 +2775,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{user_id}/anime/{anime_id}/status": {
+        "/users/{user_id}/role": {
             "put": {
-                "description": "Обновляет статус аниме в списке пользователя",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Назначает пользователю роль user, moderator или admin. Доступно только администраторам; свою роль изменить нельзя",
                 "consumes": [
                     "application/json"
                 ],
@@ -1022,9 +2790,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "Profile"
                 ],
-                "summary": "Обновить статус аниме в списке пользователя",
+                "summary": "Сменить роль пользователя",
                 "parameters": [
                     {
                         "type": "integer",
@@ -1034,64 +2802,178 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "MAL ID аниме",
-                        "name": "anime_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Новый статус аниме",
-                        "name": "status",
+                        "description": "Новая роль",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dtos.UpdateStatusRequest"
+                            "$ref": "#/definitions/dtos.UpdateUserRoleDTO"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное обновление",
+                        "description": "Роль изменена",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/dtos.UserResponseDTO"
                         }
                     },
                     "400": {
-                        "description": "Неверные входные данные",
+                        "description": "Ошибка валидации",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Запись не найдена",
+                        "description": "Пользователь не найден",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/dtos.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/unlock": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Снимает временную блокировку входа, выставленную после серии неудачных попыток, и сбрасывает счетчики. Доступно только администраторам",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Profile"
+                ],
+                "summary": "Снять блокировку входа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Блокировка снята",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    },
+                    "400": {
+                        "description": "Неверный ID пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Пользователь не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.ErrorResponse"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "api.ClientStats": {
+            "type": "object",
+            "properties": {
+                "queue_depth": {
+                    "type": "integer"
+                },
+                "rate_limited": {
+                    "type": "integer"
+                },
+                "retries": {
+                    "type": "integer"
+                }
+            }
+        },
+        "auth.JSONWebKey": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.JSONWebKeySet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.JSONWebKey"
+                    }
+                }
+            }
+        },
         "dtos.AddAnimeRequest": {
             "type": "object",
             "required": [
@@ -1104,6 +2986,14 @@ const docTemplate = `{
                     "example": 5114
                 },
                 "status": {
+                    "enum": [
+                        "watched",
+                        "plan_to_watch",
+                        "watching",
+                        "waiting",
+                        "dropped",
+                        "on_hold"
+                    ],
                     "allOf": [
                         {
                             "$ref": "#/definitions/models.WatchStatus"
@@ -1203,6 +3093,87 @@ const docTemplate = `{
                 }
             }
         },
+        "dtos.BulkUserAnimeOperation": {
+            "type": "object",
+            "required": [
+                "action",
+                "anime_mal_id"
+            ],
+            "properties": {
+                "action": {
+                    "enum": [
+                        "add",
+                        "remove",
+                        "set_status",
+                        "set_rating",
+                        "set_episodes"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.BulkAction"
+                        }
+                    ],
+                    "example": "add"
+                },
+                "anime_mal_id": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 5114
+                },
+                "episodes_watched": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 12
+                },
+                "rating": {
+                    "type": "number",
+                    "maximum": 10,
+                    "minimum": 0,
+                    "example": 9.5
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.WatchStatus"
+                        }
+                    ],
+                    "example": "plan_to_watch"
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 3
+                }
+            }
+        },
+        "dtos.BulkUserAnimeRequest": {
+            "type": "object",
+            "required": [
+                "operations"
+            ],
+            "properties": {
+                "mode": {
+                    "enum": [
+                        "all_or_nothing",
+                        "best_effort"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.BulkMode"
+                        }
+                    ],
+                    "example": "all_or_nothing"
+                },
+                "operations": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/dtos.BulkUserAnimeOperation"
+                    }
+                }
+            }
+        },
         "dtos.CreateUserDTO": {
             "type": "object",
             "required": [
@@ -1237,6 +3208,46 @@ const docTemplate = `{
                 }
             }
         },
+        "dtos.EpisodeWatchHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dtos.EpisodeWatchResponse"
+                    }
+                }
+            }
+        },
+        "dtos.EpisodeWatchResponse": {
+            "type": "object",
+            "properties": {
+                "episode": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "rating": {
+                    "type": "number",
+                    "example": 8
+                },
+                "rewatch_number": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "unwatched": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "watched_at": {
+                    "type": "string",
+                    "example": "2025-03-02T22:30:00Z"
+                }
+            }
+        },
         "dtos.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -1250,6 +3261,18 @@ const docTemplate = `{
                 }
             }
         },
+        "dtos.ForgotPasswordDTO": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                }
+            }
+        },
         "dtos.GenreObject": {
             "type": "object",
             "properties": {
@@ -1273,12 +3296,233 @@ const docTemplate = `{
                     "type": "string",
                     "example": "john.doe@example.com"
                 },
+                "login": {
+                    "type": "string",
+                    "example": "johndoe123"
+                },
                 "password": {
                     "type": "string",
                     "example": "StrongPass123!"
                 }
             }
         },
+        "dtos.MFAChallengeDTO": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "type": "integer",
+                    "example": 300
+                },
+                "mfa_required": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "mfa_token": {
+                    "type": "string",
+                    "example": "Jx8v2kq..."
+                }
+            }
+        },
+        "dtos.MFACodeDTO": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "dtos.MFALoginDTO": {
+            "type": "object",
+            "required": [
+                "code",
+                "mfa_token"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "mfa_token": {
+                    "type": "string",
+                    "example": "Jx8v2kq..."
+                }
+            }
+        },
+        "dtos.MarkEpisodesRequest": {
+            "type": "object",
+            "required": [
+                "from"
+            ],
+            "properties": {
+                "from": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                },
+                "rating": {
+                    "type": "number",
+                    "maximum": 10,
+                    "minimum": 0,
+                    "example": 8
+                },
+                "to": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 3
+                },
+                "watched_at": {
+                    "type": "string",
+                    "example": "2025-03-02T22:30:00Z"
+                }
+            }
+        },
+        "dtos.PatchUserAnimeRequest": {
+            "type": "object",
+            "properties": {
+                "episodes_watched": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "finished_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-03-02T22:30:00Z"
+                },
+                "notes": {
+                    "type": "string",
+                    "example": "Пересмотреть с друзьями"
+                },
+                "rating": {
+                    "type": "number",
+                    "example": 8.5
+                },
+                "started_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-01-10T20:00:00Z"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "watched",
+                        "plan_to_watch",
+                        "watching",
+                        "waiting",
+                        "dropped",
+                        "on_hold",
+                        "rewatching"
+                    ],
+                    "example": "watching"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "favorite",
+                        "classic"
+                    ]
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 3
+                }
+            }
+        },
+        "dtos.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ABCDE-FGHIJ"
+                    ]
+                }
+            }
+        },
+        "dtos.ResetPasswordDTO": {
+            "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "minLength": 6,
+                    "example": "NewStrongPass123!"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "kq3Yp1x..."
+                }
+            }
+        },
+        "dtos.SessionListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dtos.SessionResponse"
+                    }
+                }
+            }
+        },
+        "dtos.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "browser": {
+                    "type": "string",
+                    "example": "Firefox 125.0"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-04-28T10:30:00Z"
+                },
+                "current": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "device": {
+                    "type": "string",
+                    "example": "desktop"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f2b8c1d9e7a4b6c8d0e1f2a3b4c5d6e"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "203.0.113.10"
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "example": "2024-04-28T10:30:00Z"
+                },
+                "os": {
+                    "type": "string",
+                    "example": "Linux x86_64"
+                },
+                "user_agent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0 (X11; Linux x86_64) ..."
+                }
+            }
+        },
         "dtos.StatsResponse": {
             "type": "object",
             "properties": {
@@ -1286,25 +3530,58 @@ const docTemplate = `{
                     "type": "number",
                     "example": 8.75
                 },
+                "total_dropped": {
+                    "type": "integer",
+                    "example": 3
+                },
                 "total_episodes": {
                     "type": "integer",
                     "example": 347
                 },
+                "total_on_hold": {
+                    "type": "integer",
+                    "example": 2
+                },
                 "total_plan_to_watch": {
                     "type": "integer",
                     "example": 30
                 },
+                "total_rewatches": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "total_rewatching": {
+                    "type": "integer",
+                    "example": 1
+                },
                 "total_waiting": {
                     "type": "integer",
                     "example": 10
                 },
-                "total_watched": {
-                    "type": "integer",
-                    "example": 15
+                "total_watched": {
+                    "type": "integer",
+                    "example": 15
+                },
+                "total_watching": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "dtos.TOTPEnrollmentResponse": {
+            "type": "object",
+            "properties": {
+                "otpauth_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/anime-service:john.doe@example.com?issuer=anime-service\u0026secret=JBSWY3DPEHPK3PXP"
+                },
+                "qr_code_png": {
+                    "type": "string",
+                    "example": "iVBORw0KGgoAAAANSUhEUgAA..."
                 },
-                "total_watching": {
-                    "type": "integer",
-                    "example": 5
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXP"
                 }
             }
         },
@@ -1333,7 +3610,46 @@ const docTemplate = `{
             "properties": {
                 "episodes_watched": {
                     "type": "integer",
+                    "minimum": 0,
                     "example": 24
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 3
+                }
+            }
+        },
+        "dtos.UpdateEpisodesResponse": {
+            "type": "object",
+            "properties": {
+                "anime_mal_id": {
+                    "type": "integer",
+                    "example": 5114
+                },
+                "episodes_watched": {
+                    "type": "integer",
+                    "example": 64
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Количество просмотренных эпизодов успешно обновлено"
+                },
+                "rewatch_episodes": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.WatchStatus"
+                        }
+                    ],
+                    "example": "watched"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 4
                 }
             }
         },
@@ -1348,6 +3664,11 @@ const docTemplate = `{
                     "maximum": 10,
                     "minimum": 0,
                     "example": 9.5
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 3
                 }
             }
         },
@@ -1358,12 +3679,54 @@ const docTemplate = `{
             ],
             "properties": {
                 "status": {
+                    "enum": [
+                        "watched",
+                        "plan_to_watch",
+                        "watching",
+                        "waiting",
+                        "dropped",
+                        "on_hold",
+                        "rewatching"
+                    ],
                     "allOf": [
                         {
                             "$ref": "#/definitions/models.WatchStatus"
                         }
                     ],
                     "example": "watched"
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 3
+                }
+            }
+        },
+        "dtos.UpdateTrackingRequest": {
+            "type": "object",
+            "properties": {
+                "finished_at": {
+                    "type": "string",
+                    "example": "2025-03-02T22:30:00Z"
+                },
+                "rewatch_count": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1
+                },
+                "rewatch_episodes": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 12
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2025-01-10T20:00:00Z"
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 3
                 }
             }
         },
@@ -1400,6 +3763,39 @@ const docTemplate = `{
                 }
             }
         },
+        "dtos.UpdateUserRoleDTO": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "user",
+                        "moderator",
+                        "admin"
+                    ],
+                    "example": "moderator"
+                }
+            }
+        },
+        "dtos.UserAnimeConflictResponse": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "$ref": "#/definitions/dtos.UserAnimeResponse"
+                },
+                "details": {
+                    "type": "string",
+                    "example": "user anime version mismatch"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "precondition failed"
+                }
+            }
+        },
         "dtos.UserAnimeListResponse": {
             "type": "object",
             "properties": {
@@ -1458,6 +3854,10 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 24
                 },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2025-03-02T22:30:00Z"
+                },
                 "id": {
                     "type": "integer",
                     "example": 1
@@ -1470,6 +3870,18 @@ const docTemplate = `{
                     "type": "number",
                     "example": 9.5
                 },
+                "rewatch_count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "rewatch_episodes": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2025-01-10T20:00:00Z"
+                },
                 "status": {
                     "allOf": [
                         {
@@ -1478,9 +3890,60 @@ const docTemplate = `{
                     ],
                     "example": "watching"
                 },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "favorite",
+                        "classic"
+                    ]
+                },
                 "user_id": {
                     "type": "integer",
                     "example": 42
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "dtos.UserAnimeTrackingResponse": {
+            "type": "object",
+            "properties": {
+                "anime_mal_id": {
+                    "type": "integer",
+                    "example": 5114
+                },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2025-03-02T22:30:00Z"
+                },
+                "rewatch_count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "rewatch_episodes": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2025-01-10T20:00:00Z"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.WatchStatus"
+                        }
+                    ],
+                    "example": "watched"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 4
                 }
             }
         },
@@ -1499,6 +3962,10 @@ const docTemplate = `{
                     "type": "string",
                     "example": "john.doe@example.com"
                 },
+                "email_verified_at": {
+                    "type": "string",
+                    "example": "2024-04-28T10:30:00Z"
+                },
                 "firstname": {
                     "type": "string",
                     "example": "John"
@@ -1511,29 +3978,295 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Doe"
                 },
+                "locked_until": {
+                    "type": "string",
+                    "example": "2024-04-28T10:45:00Z"
+                },
                 "nickname": {
                     "type": "string",
                     "example": "johndoe123"
                 },
+                "role": {
+                    "type": "string",
+                    "example": "user"
+                },
                 "updated_at": {
                     "type": "string",
                     "example": "2024-04-28T10:30:00Z"
                 }
             }
         },
+        "dtos.VerifyEmailDTO": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string",
+                    "example": "eyJ1aWQiOjEs..."
+                }
+            }
+        },
+        "models.BulkAction": {
+            "type": "string",
+            "enum": [
+                "add",
+                "remove",
+                "set_status",
+                "set_rating",
+                "set_episodes"
+            ],
+            "x-enum-varnames": [
+                "BulkActionAdd",
+                "BulkActionRemove",
+                "BulkActionSetStatus",
+                "BulkActionSetRating",
+                "BulkActionSetEpisodes"
+            ]
+        },
+        "models.BulkMode": {
+            "type": "string",
+            "enum": [
+                "all_or_nothing",
+                "best_effort"
+            ],
+            "x-enum-varnames": [
+                "BulkModeAllOrNothing",
+                "BulkModeBestEffort"
+            ]
+        },
+        "models.BulkOperationResult": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/models.BulkAction"
+                },
+                "anime_mal_id": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "outcome": {
+                    "$ref": "#/definitions/models.BulkOutcome"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BulkOutcome": {
+            "type": "string",
+            "enum": [
+                "applied",
+                "failed",
+                "rolled_back",
+                "skipped"
+            ],
+            "x-enum-comments": {
+                "BulkOutcomeRolledBack": "Операция выполнилась, но транзакция откачена из-за другой",
+                "BulkOutcomeSkipped": "Операция не выполнялась, потому что пакет уже не может быть применен"
+            },
+            "x-enum-varnames": [
+                "BulkOutcomeApplied",
+                "BulkOutcomeFailed",
+                "BulkOutcomeRolledBack",
+                "BulkOutcomeSkipped"
+            ]
+        },
+        "models.BulkResult": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "integer"
+                },
+                "committed": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "$ref": "#/definitions/models.BulkMode"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BulkOperationResult"
+                    }
+                }
+            }
+        },
+        "models.FieldChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "new": {},
+                "old": {}
+            }
+        },
+        "models.ImportAction": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "skip",
+                "unchanged",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "ImportActionCreate",
+                "ImportActionUpdate",
+                "ImportActionSkip",
+                "ImportActionUnchanged",
+                "ImportActionFailed"
+            ]
+        },
+        "models.ImportEntryResult": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/models.ImportAction"
+                },
+                "anime_mal_id": {
+                    "type": "integer"
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldChange"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ImportJob": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "options": {
+                    "$ref": "#/definitions/models.ImportOptions"
+                },
+                "report": {
+                    "$ref": "#/definitions/models.ImportReport"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.ImportJobStatus"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportJobStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "running",
+                "completed",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "ImportJobPending",
+                "ImportJobRunning",
+                "ImportJobCompleted",
+                "ImportJobFailed"
+            ]
+        },
+        "models.ImportOptions": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "policy": {
+                    "$ref": "#/definitions/models.ImportPolicy"
+                }
+            }
+        },
+        "models.ImportPolicy": {
+            "type": "string",
+            "enum": [
+                "skip",
+                "overwrite",
+                "newest"
+            ],
+            "x-enum-varnames": [
+                "ImportPolicySkip",
+                "ImportPolicyOverwrite",
+                "ImportPolicyKeepNewest"
+            ]
+        },
+        "models.ImportReport": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportEntryResult"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unchanged": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.WatchStatus": {
             "type": "string",
             "enum": [
                 "watched",
                 "plan_to_watch",
                 "watching",
-                "waiting"
+                "waiting",
+                "dropped",
+                "on_hold",
+                "rewatching"
             ],
             "x-enum-varnames": [
                 "StatusWatched",
                 "StatusPlanToWatch",
                 "StatusWatching",
-                "StatusWaiting"
+                "StatusWaiting",
+                "StatusDropped",
+                "StatusOnHold",
+                "StatusRewatching"
             ]
         }
     },
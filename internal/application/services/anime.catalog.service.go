@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	domainRepositories "github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/repositories"
+	"logur.dev/logur"
+)
+
+// AnimeCatalogService отдает аниме из локального каталога, при первом обращении
+// загружая его из Jikan API, и в фоне обновляет устаревшие записи.
+type AnimeCatalogService struct {
+	animeRepo   *repositories.AnimeRepository
+	jikanClient domainRepositories.JikanClient
+	staleAfter  time.Duration
+	logger      logur.LoggerFacade
+}
+
+func NewAnimeCatalogService(animeRepo *repositories.AnimeRepository, jikanClient domainRepositories.JikanClient, staleAfter time.Duration, logger logur.LoggerFacade) *AnimeCatalogService {
+	return &AnimeCatalogService{
+		animeRepo:   animeRepo,
+		jikanClient: jikanClient,
+		staleAfter:  staleAfter,
+		logger:      logger,
+	}
+}
+
+func (s *AnimeCatalogService) GetAnime(ctx context.Context, malID int64) (*models.Anime, error) {
+	anime, err := s.animeRepo.GetByMALID(ctx, malID)
+	if err == nil {
+		return anime, nil
+	}
+	if !errors.Is(err, repositories.ErrCatalogAnimeNotFound) {
+		s.logger.Warn("Failed to read anime from catalog, falling back to Jikan API", map[string]interface{}{
+			"mal_id": malID,
+			"error":  err.Error(),
+		})
+	}
+
+	return s.fetch(ctx, malID)
+}
+
+// ErrCatalogUnavailable — данные части аниме сейчас нельзя получить ни из
+// каталога, ни из Jikan API. В отличие от отсутствующего аниме это временная
+// ошибка: запрос можно повторить позже.
+var ErrCatalogUnavailable = errors.New("anime catalog is unavailable")
+
+// maxCatalogMissesPerRequest — сколько отсутствующих в каталоге аниме GetAnimes
+// загружает из Jikan API за один вызов. Jikan API отвечает по одному аниме и с
+// ограничением частоты, поэтому большой пакет новых аниме держал бы запрос
+// десятки секунд.
+const maxCatalogMissesPerRequest = 10
+
+// GetAnimes возвращает аниме по списку идентификаторов: найденные в каталоге —
+// одним запросом, остальные — по одному из Jikan API, но не больше
+// maxCatalogMissesPerRequest. Аниме, которых нет и в Jikan API, в результате
+// отсутствуют. Если каталог недоступен, Jikan API отвечает ошибкой (таймаут,
+// 429, 5xx) или отсутствующих в каталоге аниме больше лимита, возвращается
+// ErrCatalogUnavailable; уже загруженные аниме остаются в каталоге, поэтому
+// повторный запрос продвигается дальше.
+func (s *AnimeCatalogService) GetAnimes(ctx context.Context, malIDs []int64) (map[int64]*models.Anime, error) {
+	animes, err := s.animeRepo.GetByMALIDs(ctx, malIDs)
+	if err != nil {
+		s.logger.Error("Failed to read animes from catalog", map[string]interface{}{
+			"count": len(malIDs),
+			"error": err.Error(),
+		})
+		return nil, ErrCatalogUnavailable
+	}
+
+	var misses []int64
+	for _, malID := range malIDs {
+		if _, ok := animes[malID]; !ok {
+			misses = append(misses, malID)
+		}
+	}
+
+	fetched := misses
+	if len(fetched) > maxCatalogMissesPerRequest {
+		fetched = fetched[:maxCatalogMissesPerRequest]
+	}
+	for _, malID := range fetched {
+		anime, err := s.fetch(ctx, malID)
+		if errors.Is(err, models.ErrAnimeNotFound) {
+			continue
+		}
+		if err != nil {
+			s.logger.Warn("Failed to get anime from Jikan API", map[string]interface{}{
+				"mal_id": malID,
+				"error":  err.Error(),
+			})
+			return nil, ErrCatalogUnavailable
+		}
+		animes[malID] = anime
+	}
+
+	if len(misses) > len(fetched) {
+		s.logger.Warn("Too many animes missing from catalog", map[string]interface{}{
+			"missing": len(misses),
+			"fetched": len(fetched),
+		})
+		return nil, ErrCatalogUnavailable
+	}
+
+	return animes, nil
+}
+
+func (s *AnimeCatalogService) fetch(ctx context.Context, malID int64) (*models.Anime, error) {
+	anime, err := s.jikanClient.GetAnimeByID(ctx, malID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.animeRepo.Upsert(ctx, anime); err != nil {
+		s.logger.Warn("Failed to store anime in catalog", map[string]interface{}{
+			"mal_id": malID,
+			"error":  err.Error(),
+		})
+	}
+
+	return anime, nil
+}
+
+// HydrateUserAnimeList заполняет данные аниме для записей списка, которых еще
+// нет в каталоге. Недостающие аниме загружаются одним вызовом GetAnimes, поэтому
+// на них действует тот же лимит загрузок из Jikan API; если данные сейчас
+// получить нельзя, возвращается ErrCatalogUnavailable. Записи аниме, которых нет
+// и в Jikan API, получают название "Unknown".
+func (s *AnimeCatalogService) HydrateUserAnimeList(ctx context.Context, list *models.UserAnimeList) error {
+	var misses []int64
+	for _, item := range list.Items {
+		if item.AnimeTitle == "" {
+			misses = append(misses, item.AnimeMALID)
+		}
+	}
+	if len(misses) == 0 {
+		return nil
+	}
+
+	animes, err := s.GetAnimes(ctx, misses)
+	if err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		if item.AnimeTitle != "" {
+			continue
+		}
+		if anime, ok := animes[item.AnimeMALID]; ok {
+			fillAnimeDetails(item, anime)
+		} else {
+			item.AnimeTitle = "Unknown"
+		}
+	}
+	return nil
+}
+
+// HydrateUserAnime заполняет данные аниме для записи, которой еще нет в каталоге.
+func (s *AnimeCatalogService) HydrateUserAnime(ctx context.Context, item *models.UserAnimeWithDetails) {
+	if item.AnimeTitle != "" {
+		return
+	}
+
+	anime, err := s.fetch(ctx, item.AnimeMALID)
+	if err != nil {
+		s.logger.Warn("Failed to get anime details from Jikan API", map[string]interface{}{
+			"anime_mal_id": item.AnimeMALID,
+			"error":        err.Error(),
+		})
+		item.AnimeTitle = "Unknown"
+		return
+	}
+
+	fillAnimeDetails(item, anime)
+}
+
+func fillAnimeDetails(item *models.UserAnimeWithDetails, anime *models.Anime) {
+	item.AnimeTitle = anime.Title
+	item.AnimeImage = anime.ImageURL
+	item.AnimeType = anime.Type
+	item.AnimeEpisodes = anime.Episodes
+	item.AnimeStatus = anime.Status
+	item.AnimeScore = anime.Score
+}
+
+// RefreshStale обновляет из Jikan API до batchSize записей каталога, устаревших более чем на staleAfter.
+func (s *AnimeCatalogService) RefreshStale(ctx context.Context, batchSize int) error {
+	malIDs, err := s.animeRepo.ListStaleMALIDs(ctx, time.Now().Add(-s.staleAfter), batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, malID := range malIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.fetch(ctx, malID); err != nil {
+			s.logger.Warn("Failed to refresh catalog anime", map[string]interface{}{
+				"mal_id": malID,
+				"error":  err.Error(),
+			})
+		}
+	}
+
+	if len(malIDs) > 0 {
+		s.logger.Info("Refreshed stale catalog animes", map[string]interface{}{
+			"count": len(malIDs),
+		})
+	}
+
+	return nil
+}
+
+// StartRefresher запускает фоновое обновление каталога с заданным интервалом до отмены контекста.
+func (s *AnimeCatalogService) StartRefresher(ctx context.Context, interval time.Duration, batchSize int) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.RefreshStale(ctx, batchSize); err != nil && ctx.Err() == nil {
+					s.logger.Error("Error refreshing anime catalog", map[string]interface{}{
+						"error": err.Error(),
+					})
+				}
+			}
+		}
+	}()
+}
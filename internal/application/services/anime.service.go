@@ -6,32 +6,39 @@ import (
 
 	"emperror.dev/errors"
 	"github.com/merdernoty/anime-service/internal/domain/models"
-	"github.com/merdernoty/anime-service/internal/infrastructure/api"
+	domainRepositories "github.com/merdernoty/anime-service/internal/domain/repositories"
 	"github.com/merdernoty/anime-service/internal/infrastructure/repositories"
 	"logur.dev/logur"
 )
 
 type AnimeServiceImpl struct {
-	jikanClient   *api.JikanClient
-	userAnimeRepo *repositories.UserAnimeRepository
-	logger        logur.LoggerFacade
+	jikanClient      domainRepositories.JikanClient
+	userAnimeRepo    *repositories.UserAnimeRepository
+	episodeWatchRepo *repositories.EpisodeWatchRepository
+	catalog          *AnimeCatalogService
+	logger           logur.LoggerFacade
 }
 
 var (
-	ErrAnimeNotFound = errors.New("anime not found")
-	ErrAnimeAlreadyExists = errors.New("anime already exists in user list")
-	ErrAnimeNotInUserList = errors.New("anime not found in user list")
-	ErrAnimeUpdateFailed = errors.New("failed to update anime in user list")
-	ErrAnimeDeleteFailed = errors.New("failed to delete anime from user list")
-	ErrAnimeStatsFailed = errors.New("failed to get user anime stats")
-	ErrFetchAnimeFailed = errors.New("failed to fetch anime details")
+	ErrAnimeNotFound         = errors.New("anime not found")
+	ErrAnimeAlreadyExists    = errors.New("anime already exists in user list")
+	ErrAnimeNotInUserList    = errors.New("anime not found in user list")
+	ErrAnimeUpdateFailed     = errors.New("failed to update anime in user list")
+	ErrAnimeDeleteFailed     = errors.New("failed to delete anime from user list")
+	ErrAnimeStatsFailed      = errors.New("failed to get user anime stats")
+	ErrFetchAnimeFailed      = errors.New("failed to fetch anime details")
+	ErrInvalidBulkMode       = errors.New("invalid bulk mode")
+	ErrTooManyBulkOperations = errors.New("too many bulk operations")
+	ErrBulkUpdateFailed      = errors.New("failed to apply bulk changes to user list")
 )
 
-func NewAnimeService( jikanClient *api.JikanClient, userAnimeRepo *repositories.UserAnimeRepository, logger logur.LoggerFacade) *AnimeServiceImpl {
+func NewAnimeService(jikanClient domainRepositories.JikanClient, userAnimeRepo *repositories.UserAnimeRepository, episodeWatchRepo *repositories.EpisodeWatchRepository, catalog *AnimeCatalogService, logger logur.LoggerFacade) *AnimeServiceImpl {
 	return &AnimeServiceImpl{
-		jikanClient:   jikanClient,
-		userAnimeRepo: userAnimeRepo,
-		logger:        logger,
+		jikanClient:      jikanClient,
+		userAnimeRepo:    userAnimeRepo,
+		episodeWatchRepo: episodeWatchRepo,
+		catalog:          catalog,
+		logger:           logger,
 	}
 }
 func (s *AnimeServiceImpl) GetAnimeByID(ctx context.Context, malID int64) (*models.Anime, error) {
@@ -39,7 +46,7 @@ func (s *AnimeServiceImpl) GetAnimeByID(ctx context.Context, malID int64) (*mode
 		"mal_id": malID,
 	})
 
-	anime, err := s.jikanClient.GetAnimeByID(ctx, malID)
+	anime, err := s.catalog.GetAnime(ctx, malID)
 	if err != nil {
 		s.logger.Error("Error getting anime by ID", map[string]interface{}{
 			"mal_id": malID,
@@ -51,17 +58,17 @@ func (s *AnimeServiceImpl) GetAnimeByID(ctx context.Context, malID int64) (*mode
 	return anime, nil
 }
 
-func (s *AnimeServiceImpl) SearchAnime(ctx context.Context, query string, page, limit int) ([]*models.Anime, int, error) {
+func (s *AnimeServiceImpl) SearchAnime(ctx context.Context, search models.AnimeSeatch) ([]*models.Anime, int, error) {
 	s.logger.Info("Searching anime", map[string]interface{}{
-		"query": query,
-		"page":  page,
-		"limit": limit,
+		"query": search.Query,
+		"page":  search.Page,
+		"limit": search.Limit,
 	})
 
-	animes, totalPages, err := s.jikanClient.SearchAnime(ctx, query, page, limit)
+	animes, totalPages, err := s.jikanClient.SearchAnime(ctx, search)
 	if err != nil {
 		s.logger.Error("Error searching anime", map[string]interface{}{
-			"query": query,
+			"query": search.Query,
 			"error": err.Error(),
 		})
 		return nil, 0, ErrFetchAnimeFailed
@@ -135,7 +142,7 @@ func (s *AnimeServiceImpl) GetUserAnimeList(ctx context.Context, filter models.U
 		"limit":   filter.Limit,
 	})
 
-	userAnimeList, err := s.userAnimeRepo.GetUserAnimeWithDetails(ctx, filter, s.jikanClient)
+	userAnimeList, err := s.userAnimeRepo.GetUserAnimeWithDetails(ctx, filter)
 	if err != nil {
 		s.logger.Error("Error getting user anime list", map[string]interface{}{
 			"user_id": filter.UserID,
@@ -144,9 +151,33 @@ func (s *AnimeServiceImpl) GetUserAnimeList(ctx context.Context, filter models.U
 		return nil, ErrFetchAnimeFailed
 	}
 
+	if err := s.catalog.HydrateUserAnimeList(ctx, userAnimeList); err != nil {
+		return nil, err
+	}
+
 	return userAnimeList, nil
 }
 
+// GetUserAnimeEntry возвращает запись списка пользователя вместе с данными аниме.
+func (s *AnimeServiceImpl) GetUserAnimeEntry(ctx context.Context, userID uint, animeMALID int64) (*models.UserAnimeWithDetails, error) {
+	item, err := s.userAnimeRepo.GetUserAnimeWithDetailsByAnimeMALID(ctx, userID, animeMALID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserAnimeNotFound) {
+			return nil, ErrAnimeNotInUserList
+		}
+		s.logger.Error("Error getting user anime entry", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, ErrFetchAnimeFailed
+	}
+
+	s.catalog.HydrateUserAnime(ctx, item)
+
+	return item, nil
+}
+
 func (s *AnimeServiceImpl) AddAnimeToUserList(ctx context.Context, userID uint, animeMALID int64, status models.WatchStatus) error {
 	s.logger.Info("Adding anime to user list", map[string]interface{}{
 		"user_id":      userID,
@@ -154,7 +185,7 @@ func (s *AnimeServiceImpl) AddAnimeToUserList(ctx context.Context, userID uint,
 		"status":       status,
 	})
 
-	_, err := s.jikanClient.GetAnimeByID(ctx, animeMALID)
+	_, err := s.catalog.GetAnime(ctx, animeMALID)
 	if err != nil {
 		s.logger.Error("Error getting anime by ID for adding to user list", map[string]interface{}{
 			"anime_mal_id": animeMALID,
@@ -178,91 +209,464 @@ func (s *AnimeServiceImpl) AddAnimeToUserList(ctx context.Context, userID uint,
 			"anime_mal_id": animeMALID,
 			"error":        err.Error(),
 		})
-		return ErrAnimeUpdateFailed
+		return statusChangeError(err)
 	}
 
 	return nil
 }
 
-func (s *AnimeServiceImpl) RemoveAnimeFromUserList(ctx context.Context, userID uint, animeMALID int64) error {
+// RemoveAnimeFromUserList удаляет запись из списка; при expectedVersion > 0 —
+// только если версия записи совпадает.
+func (s *AnimeServiceImpl) RemoveAnimeFromUserList(ctx context.Context, userID uint, animeMALID int64, expectedVersion int64) error {
 	s.logger.Info("Removing anime from user list", map[string]interface{}{
 		"user_id":      userID,
 		"anime_mal_id": animeMALID,
 	})
 
-	err := s.userAnimeRepo.DeleteByUserAndAnimeMALID(ctx, userID, animeMALID)
+	err := s.userAnimeRepo.DeleteByUserAndAnimeMALID(ctx, userID, animeMALID, expectedVersion)
 	if err != nil {
 		s.logger.Error("Error removing anime from user list", map[string]interface{}{
 			"user_id":      userID,
 			"anime_mal_id": animeMALID,
 			"error":        err.Error(),
 		})
-		return ErrAnimeDeleteFailed
+		switch {
+		case errors.Is(err, models.ErrUserAnimeVersionMismatch):
+			return models.ErrUserAnimeVersionMismatch
+		case errors.Is(err, models.ErrUserAnimeNotFound):
+			return ErrAnimeNotInUserList
+		default:
+			return ErrAnimeDeleteFailed
+		}
 	}
 
 	return nil
 }
 
-func (s *AnimeServiceImpl) UpdateUserAnimeStatus(ctx context.Context, userID uint, animeMALID int64, status models.WatchStatus) error {
+func (s *AnimeServiceImpl) UpdateUserAnimeStatus(ctx context.Context, userID uint, animeMALID int64, status models.WatchStatus, expectedVersion int64) (*models.UserAnime, error) {
 	s.logger.Info("Updating user anime status", map[string]interface{}{
 		"user_id":      userID,
 		"anime_mal_id": animeMALID,
 		"status":       status,
 	})
 
-	err := s.userAnimeRepo.ChangeStatus(ctx, userID, animeMALID, status)
+	userAnime, err := s.userAnimeRepo.ChangeStatus(ctx, userID, animeMALID, status, expectedVersion)
 	if err != nil {
 		s.logger.Error("Error updating user anime status", map[string]interface{}{
 			"user_id":      userID,
 			"anime_mal_id": animeMALID,
 			"error":        err.Error(),
 		})
-		return ErrAnimeUpdateFailed
+		return nil, statusChangeError(err)
 	}
 
-	return nil
+	return userAnime, nil
+}
+
+// statusChangeError отдает наружу ошибки валидации статуса и конфликт версий как есть, остальные сводит к ErrAnimeUpdateFailed.
+func statusChangeError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrInvalidWatchStatus):
+		return models.ErrInvalidWatchStatus
+	case errors.Is(err, models.ErrInvalidStatusTransition):
+		return models.ErrInvalidStatusTransition
+	case errors.Is(err, models.ErrUserAnimeVersionMismatch):
+		return models.ErrUserAnimeVersionMismatch
+	case errors.Is(err, models.ErrUserAnimeNotFound):
+		return ErrAnimeNotInUserList
+	default:
+		return ErrAnimeUpdateFailed
+	}
 }
 
-func (s *AnimeServiceImpl) UpdateUserAnimeEpisodes(ctx context.Context, userID uint, animeMALID int64, episodesWatched int) error {
+func (s *AnimeServiceImpl) UpdateUserAnimeEpisodes(ctx context.Context, userID uint, animeMALID int64, episodesWatched int, expectedVersion int64) (*models.UserAnime, error) {
 	s.logger.Info("Updating user anime episodes watched", map[string]interface{}{
 		"user_id":          userID,
 		"anime_mal_id":     animeMALID,
 		"episodes_watched": episodesWatched,
 	})
 
-	err := s.userAnimeRepo.UpdateEpisodesWatched(ctx, userID, animeMALID, episodesWatched)
+	if episodesWatched < 0 {
+		return nil, models.ErrInvalidEpisodeCount
+	}
+
+	anime, err := s.catalog.GetAnime(ctx, animeMALID)
+	if err != nil {
+		s.logger.Error("Error getting anime by ID for updating episodes watched", map[string]interface{}{
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, ErrAnimeNotFound
+	}
+
+	if episodesWatched > 0 {
+		if err := models.ValidateEpisodeRange(1, episodesWatched, anime); err != nil {
+			return nil, models.ErrInvalidEpisodeCount
+		}
+	}
+
+	userAnime, err := s.episodeWatchRepo.SetProgress(ctx, userID, animeMALID, episodesWatched, anime, expectedVersion)
 	if err != nil {
 		s.logger.Error("Error updating user anime episodes watched", map[string]interface{}{
 			"user_id":      userID,
 			"anime_mal_id": animeMALID,
 			"error":        err.Error(),
 		})
+		return nil, progressError(err)
+	}
+
+	return userAnime, nil
+}
+
+// MarkEpisodesWatched отмечает эпизоды from..to просмотренными и возвращает пересчитанную запись.
+func (s *AnimeServiceImpl) MarkEpisodesWatched(ctx context.Context, userID uint, animeMALID int64, from, to int, watchedAt time.Time, rating *float32, expectedVersion int64) (*models.UserAnime, error) {
+	s.logger.Info("Marking user anime episodes watched", map[string]interface{}{
+		"user_id":      userID,
+		"anime_mal_id": animeMALID,
+		"from":         from,
+		"to":           to,
+	})
+
+	anime, err := s.episodeRangeAnime(ctx, animeMALID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	userAnime, err := s.episodeWatchRepo.MarkWatched(ctx, userID, animeMALID, episodeRange(from, to), watchedAt, rating, anime, expectedVersion)
+	if err != nil {
+		s.logger.Error("Error marking user anime episodes watched", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, progressError(err)
+	}
+
+	return userAnime, nil
+}
+
+// UnmarkEpisodesWatched снимает отметки с эпизодов from..to и возвращает пересчитанную запись.
+func (s *AnimeServiceImpl) UnmarkEpisodesWatched(ctx context.Context, userID uint, animeMALID int64, from, to int, expectedVersion int64) (*models.UserAnime, error) {
+	s.logger.Info("Unmarking user anime episodes watched", map[string]interface{}{
+		"user_id":      userID,
+		"anime_mal_id": animeMALID,
+		"from":         from,
+		"to":           to,
+	})
+
+	anime, err := s.episodeRangeAnime(ctx, animeMALID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	userAnime, err := s.episodeWatchRepo.MarkUnwatched(ctx, userID, animeMALID, episodeRange(from, to), anime, expectedVersion)
+	if err != nil {
+		s.logger.Error("Error unmarking user anime episodes watched", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, progressError(err)
+	}
+
+	return userAnime, nil
+}
+
+func (s *AnimeServiceImpl) GetEpisodeWatchHistory(ctx context.Context, userID uint, animeMALID int64, limit int) ([]*models.EpisodeWatch, error) {
+	watches, err := s.episodeWatchRepo.ListByUserAnime(ctx, userID, animeMALID, limit)
+	if err != nil {
+		s.logger.Error("Error getting episode watch history", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, ErrAnimeUpdateFailed
+	}
+
+	return watches, nil
+}
+
+// episodeRangeAnime загружает тайтл из каталога и проверяет по нему диапазон эпизодов.
+func (s *AnimeServiceImpl) episodeRangeAnime(ctx context.Context, animeMALID int64, from, to int) (*models.Anime, error) {
+	anime, err := s.catalog.GetAnime(ctx, animeMALID)
+	if err != nil {
+		s.logger.Error("Error getting anime by ID for episode range", map[string]interface{}{
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, ErrAnimeNotFound
+	}
+
+	if err := models.ValidateEpisodeRange(from, to, anime); err != nil {
+		return nil, models.ErrInvalidEpisodeCount
+	}
+
+	return anime, nil
+}
+
+func episodeRange(from, to int) []int {
+	episodes := make([]int, 0, to-from+1)
+	for episode := from; episode <= to; episode++ {
+		episodes = append(episodes, episode)
+	}
+	return episodes
+}
+
+// progressError отдает наружу ошибки валидации прогресса и конфликт версий как есть, остальные сводит к ErrAnimeUpdateFailed.
+func progressError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrUserAnimeVersionMismatch):
+		return models.ErrUserAnimeVersionMismatch
+	case errors.Is(err, models.ErrInvalidEpisodeCount):
+		return models.ErrInvalidEpisodeCount
+	case errors.Is(err, models.ErrInvalidTrackingDates):
+		return models.ErrInvalidTrackingDates
+	case errors.Is(err, models.ErrUserAnimeNotFound):
+		return ErrAnimeNotInUserList
+	default:
 		return ErrAnimeUpdateFailed
 	}
+}
 
-	return nil
+func (s *AnimeServiceImpl) UpdateUserAnimeTracking(ctx context.Context, userID uint, animeMALID int64, tracking models.UserAnimeTracking, expectedVersion int64) (*models.UserAnime, error) {
+	s.logger.Info("Updating user anime tracking", map[string]interface{}{
+		"user_id":      userID,
+		"anime_mal_id": animeMALID,
+	})
+
+	anime, err := s.catalog.GetAnime(ctx, animeMALID)
+	if err != nil {
+		s.logger.Error("Error getting anime by ID for updating tracking", map[string]interface{}{
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, ErrAnimeNotFound
+	}
+
+	userAnime, err := s.userAnimeRepo.UpdateTracking(ctx, userID, animeMALID, tracking, anime, expectedVersion)
+	if err != nil {
+		s.logger.Error("Error updating user anime tracking", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, progressError(err)
+	}
+
+	return userAnime, nil
 }
 
-func (s *AnimeServiceImpl) UpdateUserAnimeRating(ctx context.Context, userID uint, animeMALID int64, rating float32) error {
+func (s *AnimeServiceImpl) UpdateUserAnimeRating(ctx context.Context, userID uint, animeMALID int64, rating float32, expectedVersion int64) (*models.UserAnime, error) {
 	s.logger.Info("Updating user anime rating", map[string]interface{}{
 		"user_id":      userID,
 		"anime_mal_id": animeMALID,
 		"rating":       rating,
 	})
 
-	err := s.userAnimeRepo.UpdateRating(ctx, userID, animeMALID, rating)
+	userAnime, err := s.userAnimeRepo.UpdateRating(ctx, userID, animeMALID, rating, expectedVersion)
 	if err != nil {
 		s.logger.Error("Error updating user anime rating", map[string]interface{}{
 			"user_id":      userID,
 			"anime_mal_id": animeMALID,
 			"error":        err.Error(),
 		})
-		return ErrAnimeUpdateFailed
+		return nil, progressError(err)
+	}
+
+	return userAnime, nil
+}
+
+// PatchUserAnime применяет к записи списка изменения из JSON Merge Patch в одной
+// транзакции и возвращает обновленную запись вместе с данными аниме.
+func (s *AnimeServiceImpl) PatchUserAnime(ctx context.Context, userID uint, animeMALID int64, patch models.UserAnimePatch, expectedVersion int64) (*models.UserAnimeWithDetails, error) {
+	s.logger.Info("Patching user anime", map[string]interface{}{
+		"user_id":      userID,
+		"anime_mal_id": animeMALID,
+	})
+
+	anime, err := s.catalog.GetAnime(ctx, animeMALID)
+	if err != nil {
+		s.logger.Error("Error getting anime by ID for patching user anime", map[string]interface{}{
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, ErrAnimeNotFound
+	}
+
+	if patch.EpisodesWatched.Set && patch.EpisodesWatched.Value != 0 {
+		if err := models.ValidateEpisodeRange(1, patch.EpisodesWatched.Value, anime); err != nil {
+			return nil, models.ErrInvalidEpisodeCount
+		}
+	}
+
+	userAnime, err := s.episodeWatchRepo.Patch(ctx, userID, animeMALID, patch, anime, expectedVersion)
+	if err != nil {
+		s.logger.Error("Error patching user anime", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, patchError(err)
+	}
+
+	return models.NewUserAnimeWithDetails(userAnime, anime), nil
+}
+
+// patchError отдает наружу ошибки валидации записи и конфликт версий как есть, остальные сводит к ErrAnimeUpdateFailed.
+func patchError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrInvalidWatchStatus):
+		return models.ErrInvalidWatchStatus
+	case errors.Is(err, models.ErrInvalidStatusTransition):
+		return models.ErrInvalidStatusTransition
+	case errors.Is(err, models.ErrInvalidRating):
+		return models.ErrInvalidRating
+	case errors.Is(err, models.ErrInvalidNotes):
+		return models.ErrInvalidNotes
+	case errors.Is(err, models.ErrInvalidTags):
+		return models.ErrInvalidTags
+	default:
+		return progressError(err)
+	}
+}
+
+// BulkUpdateUserAnime выполняет операции над списком пользователя в одной
+// транзакции. Аниме для add и set_episodes загружаются из каталога одним запросом.
+// В режиме all_or_nothing ошибка любой операции отменяет весь пакет, в режиме
+// best_effort фиксируются все успешные операции. Итог каждой операции — в результате.
+func (s *AnimeServiceImpl) BulkUpdateUserAnime(ctx context.Context, userID uint, mode models.BulkMode, ops []models.BulkOperation) (*models.BulkResult, error) {
+	if mode == "" {
+		mode = models.BulkModeAllOrNothing
+	}
+	if !mode.IsValid() {
+		return nil, ErrInvalidBulkMode
 	}
+	if len(ops) > models.MaxBulkOperations {
+		return nil, ErrTooManyBulkOperations
+	}
+
+	s.logger.Info("Applying bulk changes to user anime list", map[string]interface{}{
+		"user_id":    userID,
+		"mode":       mode,
+		"operations": len(ops),
+	})
 
+	animes, err := s.catalog.GetAnimes(ctx, bulkAnimeMALIDs(ops))
+	if errors.Is(err, ErrCatalogUnavailable) {
+		return nil, ErrCatalogUnavailable
+	}
+	if err != nil {
+		s.logger.Error("Error getting animes for bulk changes", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil, ErrFetchAnimeFailed
+	}
+
+	result := &models.BulkResult{Mode: mode, Results: make([]models.BulkOperationResult, 0, len(ops))}
+	results := make([]models.BulkOperationResult, len(ops))
+	var pending []models.BulkOperation
+	var pendingIndexes []int
+	for i, op := range ops {
+		results[i] = models.BulkOperationResult{Index: i, Action: op.Action, AnimeMALID: op.AnimeMALID}
+		if err := bulkOperationError(op, animes[op.AnimeMALID]); err != nil {
+			results[i].Outcome = models.BulkOutcomeFailed
+			results[i].Error = err.Error()
+			continue
+		}
+		pending = append(pending, op)
+		pendingIndexes = append(pendingIndexes, i)
+	}
+
+	atomic := mode == models.BulkModeAllOrNothing
+	if atomic && len(pending) < len(ops) {
+		pending = nil
+	}
+
+	var outcomes []repositories.BulkOutcome
+	if len(pending) > 0 {
+		outcomes, result.Committed, err = s.episodeWatchRepo.ApplyBulk(ctx, userID, pending, animes, atomic)
+		if err != nil {
+			s.logger.Error("Error applying bulk changes to user anime list", map[string]interface{}{
+				"user_id": userID,
+				"error":   err.Error(),
+			})
+			return nil, ErrBulkUpdateFailed
+		}
+	}
+
+	for i, outcome := range outcomes {
+		r := &results[pendingIndexes[i]]
+		switch {
+		case outcome.Err != nil:
+			r.Outcome = models.BulkOutcomeFailed
+			r.Error = bulkError(r.Action, outcome.Err).Error()
+		case !result.Committed:
+			r.Outcome = models.BulkOutcomeRolledBack
+		default:
+			r.Outcome = models.BulkOutcomeApplied
+			if outcome.UserAnime != nil {
+				r.Version = outcome.UserAnime.Version
+			}
+		}
+	}
+
+	for _, r := range results {
+		if r.Outcome == "" {
+			r.Outcome = models.BulkOutcomeSkipped
+		}
+		result.Add(r)
+	}
+
+	return result, nil
+}
+
+// bulkAnimeMALIDs возвращает без повторов идентификаторы аниме операций, которым нужны данные каталога.
+func bulkAnimeMALIDs(ops []models.BulkOperation) []int64 {
+	var malIDs []int64
+	seen := make(map[int64]bool, len(ops))
+	for _, op := range ops {
+		if !op.Action.NeedsAnime() || op.AnimeMALID <= 0 || seen[op.AnimeMALID] {
+			continue
+		}
+		seen[op.AnimeMALID] = true
+		malIDs = append(malIDs, op.AnimeMALID)
+	}
+	return malIDs
+}
+
+// bulkOperationError проверяет операцию до обращения к списку: ее поля и, если
+// действию нужно аниме, наличие аниме и допустимость количества эпизодов.
+func bulkOperationError(op models.BulkOperation, anime *models.Anime) error {
+	if err := op.Validate(); err != nil {
+		return bulkError(op.Action, err)
+	}
+	if !op.Action.NeedsAnime() {
+		return nil
+	}
+	if anime == nil {
+		return ErrAnimeNotFound
+	}
+	if op.Action == models.BulkActionSetEpisodes && *op.EpisodesWatched > 0 {
+		if err := models.ValidateEpisodeRange(1, *op.EpisodesWatched, anime); err != nil {
+			return models.ErrInvalidEpisodeCount
+		}
+	}
 	return nil
 }
 
+// bulkError сводит ошибку операции пакета к тем же ошибкам, что и одиночные запросы.
+func bulkError(action models.BulkAction, err error) error {
+	switch {
+	case errors.Is(err, models.ErrInvalidBulkOperation):
+		return models.ErrInvalidBulkOperation
+	case action == models.BulkActionRemove && !errors.Is(err, models.ErrUserAnimeVersionMismatch) && !errors.Is(err, models.ErrUserAnimeNotFound):
+		return ErrAnimeDeleteFailed
+	default:
+		return patchError(err)
+	}
+}
+
 func (s *AnimeServiceImpl) GetUserAnimeStats(ctx context.Context, userID uint) (*models.AnimeStats, error) {
 	s.logger.Info("Getting user anime stats", map[string]interface{}{
 		"user_id": userID,
@@ -278,4 +682,4 @@ func (s *AnimeServiceImpl) GetUserAnimeStats(ctx context.Context, userID uint) (
 	}
 
 	return stats, nil
-}
\ No newline at end of file
+}
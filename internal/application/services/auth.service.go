@@ -1,8 +1,11 @@
 package services
 
 import (
+	"context"
 	"net/http"
-	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/gin-gonic/gin"
@@ -10,6 +13,7 @@ import (
 	"github.com/merdernoty/anime-service/internal/domain/models"
 	"github.com/merdernoty/anime-service/internal/domain/repositories"
 	"github.com/merdernoty/anime-service/pkg/auth"
+	"golang.org/x/crypto/bcrypt"
 	"logur.dev/logur"
 )
 
@@ -20,7 +24,8 @@ var (
 	ErrInvalidCredentials  = errors.New("invalid credentials")
 	ErrTokenCreationFailed = errors.New("failed to create token")
 	ErrNoCookieFound       = errors.New("refresh token not found in cookies")
-	ErrUnauthorized		= errors.New("unauthorized")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, all sessions of this login were revoked")
 )
 
 const (
@@ -29,24 +34,51 @@ const (
 	CookieMaxAge           = 30 * 24 * 3600 // 30 days
 	CookieSecure           = true
 	CookieHTTPOnly         = true
-	CookieSameSiteNone     = http.SameSiteNoneMode 
+	CookieSameSiteNone     = http.SameSiteNoneMode
+	CookieDomain           = ".traefik.me"
+	RefreshTokenTTL        = CookieMaxAge * time.Second
+
+	refreshTokenCleanupInterval = time.Hour
 )
 
+// dummyPasswordHash сверяется с паролем, когда пользователь не найден, чтобы
+// время ответа не выдавало, существует ли логин.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
 type AuthServiceImpl struct {
-	repo       repositories.UserRepository
-	logger     logur.LoggerFacade
-	tokenMaker auth.TokenMaker
+	repo              repositories.UserRepository
+	refreshTokens     repositories.RefreshTokenRepository
+	rotator           *auth.RefreshTokenRotator
+	sessions          *SessionService
+	emailVerification *EmailVerificationService
+	mfa               *MFAService
+	loginThrottle     *LoginThrottleService
+	logger            logur.LoggerFacade
+	tokenMaker        auth.TokenMaker
 }
 
-func NewAuthService(repo repositories.UserRepository, logger logur.LoggerFacade, tokenMaker auth.TokenMaker) *AuthServiceImpl {
+func NewAuthService(repo repositories.UserRepository, refreshTokens repositories.RefreshTokenRepository, rotator *auth.RefreshTokenRotator, sessions *SessionService, emailVerification *EmailVerificationService, mfa *MFAService, loginThrottle *LoginThrottleService, logger logur.LoggerFacade, tokenMaker auth.TokenMaker) *AuthServiceImpl {
 	return &AuthServiceImpl{
-		repo:       repo,
-		logger:     logger,
-		tokenMaker: tokenMaker,
+		repo:              repo,
+		refreshTokens:     refreshTokens,
+		rotator:           rotator,
+		sessions:          sessions,
+		emailVerification: emailVerification,
+		mfa:               mfa,
+		loginThrottle:     loginThrottle,
+		logger:            logger,
+		tokenMaker:        tokenMaker,
 	}
 }
 
 func (s *AuthServiceImpl) Register(ctx *gin.Context, dto dtos.CreateUserDTO, w http.ResponseWriter) (dtos.TokenResponseDTO, error) {
+	if err := models.ValidateNickname(dto.NickName); err != nil {
+		return dtos.TokenResponseDTO{}, err
+	}
+
 	_, err := s.repo.GetByEmail(ctx, dto.Email)
 	if err == nil {
 		return dtos.TokenResponseDTO{}, ErrUserAlreadyExists
@@ -58,8 +90,8 @@ func (s *AuthServiceImpl) Register(ctx *gin.Context, dto dtos.CreateUserDTO, w h
 	}
 
 	user := models.User{
-		Nickname:  dto.NickName,
-		Email:     dto.Email,
+		Nickname:  models.NormalizeNickname(dto.NickName),
+		Email:     models.NormalizeEmail(dto.Email),
 		Firstname: dto.FirstName,
 		Lastname:  dto.LastName,
 		Password:  dto.Password,
@@ -81,135 +113,218 @@ func (s *AuthServiceImpl) Register(ctx *gin.Context, dto dtos.CreateUserDTO, w h
 
 	createdUser.Password = ""
 
-	accessToken, err := s.tokenMaker.CreateToken(
-		user.ID,
-		user.Nickname,
-		user.Email,
-		"access",
-		3600,
-	)
-	if err != nil {
-		return dtos.TokenResponseDTO{}, errors.Wrap(err, "failed to create token")
+	// Ошибка уже залогирована; письмо можно запросить повторно.
+	_ = s.emailVerification.SendVerification(ctx, createdUser)
+
+	return s.startSession(ctx, createdUser)
+}
+
+// Login проверяет пароль и открывает сессию. Вход возможен по email или никнейму
+// без учета регистра; неизвестный логин и неверный пароль неотличимы для клиента.
+// Если у пользователя включена 2FA, вместо токенов возвращается токен второго шага
+// для CompleteMFALogin. Неудачные попытки ограничиваются LoginThrottleService.
+func (s *AuthServiceImpl) Login(ctx *gin.Context, dto dtos.LoginDTO, w http.ResponseWriter) (dtos.TokenResponseDTO, *dtos.MFAChallengeDTO, error) {
+	ip := ctx.ClientIP()
+	if err := s.loginThrottle.CheckIP(ctx, ip); err != nil {
+		return dtos.TokenResponseDTO{}, nil, err
 	}
 
-	refreshToken, err := s.tokenMaker.CreateToken(
-		user.ID,
-		user.Nickname,
-		user.Email,
-		"refresh",
-		CookieMaxAge,
-	)
+	var account *models.User
+	user, err := s.findByLogin(ctx, dto.Identifier())
+	if err == nil {
+		account = &user
+	}
+
+	if err := s.loginThrottle.CheckAccount(ctx, dto.Identifier(), account); err != nil {
+		return dtos.TokenResponseDTO{}, nil, err
+	}
+
+	if account == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(dto.Password))
+		s.loginThrottle.RecordFailure(ctx, dto.Identifier(), ip, nil)
+		return dtos.TokenResponseDTO{}, nil, ErrInvalidCredentials
+	}
+
+	if !user.CheckPassword(dto.Password) {
+		s.loginThrottle.RecordFailure(ctx, dto.Identifier(), ip, account)
+		return dtos.TokenResponseDTO{}, nil, ErrInvalidCredentials
+	}
+	s.loginThrottle.RecordSuccess(ctx, dto.Identifier(), account)
+
+	if user.TOTPEnabled {
+		mfaToken, err := s.mfa.CreateChallenge(ctx, user.ID)
+		if err != nil {
+			return dtos.TokenResponseDTO{}, nil, err
+		}
+		return dtos.TokenResponseDTO{}, &dtos.MFAChallengeDTO{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int64(MFAChallengeTTL.Seconds()),
+		}, nil
+	}
+
+	tokens, err := s.startSession(ctx, user)
 	if err != nil {
-		return dtos.TokenResponseDTO{}, errors.Wrap(err, "failed to create refresh token")
+		return dtos.TokenResponseDTO{}, nil, err
 	}
 
-	s.setRefreshTokenCookie(ctx, refreshToken)
+	s.logger.Info("user logged in successfully", map[string]interface{}{
+		"NickName": user.Nickname,
+		"Email":    user.Email,
+	})
 
-	return dtos.TokenResponseDTO{
-		AccessToken: accessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   3600,
-	}, nil
+	return tokens, nil, nil
+}
+
+// findByLogin ищет пользователя по email, если логин содержит "@", иначе по никнейму.
+func (s *AuthServiceImpl) findByLogin(ctx context.Context, login string) (models.User, error) {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return models.User{}, ErrUserNotFound
+	}
+	if strings.Contains(login, "@") {
+		return s.repo.GetByEmail(ctx, login)
+	}
+	return s.repo.GetByNickName(ctx, login)
 }
 
-func (s *AuthServiceImpl) Login(ctx *gin.Context, dto dtos.LoginDTO, w http.ResponseWriter) (dtos.TokenResponseDTO, error) {
-	user, err := s.repo.GetByEmail(ctx, dto.Email)
+// CompleteMFALogin завершает вход с 2FA по токену второго шага и коду TOTP или коду восстановления.
+func (s *AuthServiceImpl) CompleteMFALogin(ctx *gin.Context, dto dtos.MFALoginDTO) (dtos.TokenResponseDTO, error) {
+	userID, err := s.mfa.CompleteChallenge(ctx, dto.MFAToken, dto.Code)
+	if err != nil {
+		return dtos.TokenResponseDTO{}, err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		return dtos.TokenResponseDTO{}, ErrUserNotFound
 	}
 
-	if !user.CheckPassword(dto.Password) {
-		return dtos.TokenResponseDTO{}, ErrInvalidCredentials
+	tokens, err := s.startSession(ctx, user)
+	if err != nil {
+		return dtos.TokenResponseDTO{}, err
 	}
 
-	accessToken, err := s.tokenMaker.CreateToken(
-		user.ID,
-		user.Nickname,
-		user.Email,
-		"access",
-		3600,
-	)
+	s.logger.Info("user logged in with two-factor authentication", map[string]interface{}{
+		"NickName": user.Nickname,
+		"Email":    user.Email,
+	})
+
+	return tokens, nil
+}
+
+// startSession открывает сессию устройства с новым семейством refresh-токенов и выдает пару токенов.
+func (s *AuthServiceImpl) startSession(ctx *gin.Context, user models.User) (dtos.TokenResponseDTO, error) {
+	familyID, err := auth.NewTokenFamilyID()
 	if err != nil {
 		return dtos.TokenResponseDTO{}, ErrTokenCreationFailed
 	}
 
-	refreshToken, err := s.tokenMaker.CreateToken(
-		user.ID,
-		user.Nickname,
-		user.Email,
-		"refresh",
-		CookieMaxAge,
-	)
+	refreshToken, record, err := newRefreshTokenRecord()
 	if err != nil {
 		return dtos.TokenResponseDTO{}, ErrTokenCreationFailed
 	}
+	record.UserID = user.ID
+	record.FamilyID = familyID
 
-	s.setRefreshTokenCookie(ctx, refreshToken)
+	if err := s.sessions.Start(ctx, record, ctx.ClientIP(), ctx.Request.UserAgent()); err != nil {
+		return dtos.TokenResponseDTO{}, ErrTokenCreationFailed
+	}
 
-	s.logger.Info("user logged in successfully", map[string]interface{}{
-		"NickName": user.Nickname,
-		"Email":    user.Email,
-	})
+	return s.issueTokens(ctx, user, familyID, refreshToken)
+}
+
+// issueTokens выдает access-токен сессии и кладет уже сохраненный refresh-токен в куки.
+func (s *AuthServiceImpl) issueTokens(ctx *gin.Context, user models.User, sessionID string, refreshToken string) (dtos.TokenResponseDTO, error) {
+	accessToken, payload, err := s.tokenMaker.CreateToken(user.ID, user.Nickname, user.Email, sessionID, auth.TokenTypeAccess, auth.AudienceAPI)
+	if err != nil {
+		return dtos.TokenResponseDTO{}, ErrTokenCreationFailed
+	}
+
+	s.setRefreshTokenCookie(ctx, refreshToken)
 
 	return dtos.TokenResponseDTO{
 		AccessToken: accessToken,
 		TokenType:   "Bearer",
-		ExpiresIn:   3600,
+		ExpiresIn:   int64(payload.ExpiresIn().Seconds()),
+	}, nil
+}
+
+func newRefreshTokenRecord() (string, models.RefreshToken, error) {
+	token, tokenHash, err := auth.NewRefreshToken()
+	if err != nil {
+		return "", models.RefreshToken{}, err
+	}
+	return token, models.RefreshToken{
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
 	}, nil
 }
 
+// RefreshToken меняет refresh-токен из куки на новый из того же семейства.
+// Повторное предъявление уже обмененного токена считается кражей: семейство
+// удаляется целиком, и всем его владельцам придется войти заново. Исключение —
+// первые RefreshTokenReuseGrace после ротации: преемник выводится из самого
+// токена, и повторный запрос получает того же преемника.
 func (s *AuthServiceImpl) RefreshToken(ctx *gin.Context, r *http.Request, w http.ResponseWriter) (dtos.TokenResponseDTO, error) {
 	refreshToken, err := ctx.Cookie(RefreshTokenCookieName)
 	if err != nil {
 		return dtos.TokenResponseDTO{}, ErrNoCookieFound
 	}
 
-	claims, err := s.tokenMaker.VerifyToken(refreshToken)
-	if err != nil {
-		return dtos.TokenResponseDTO{}, ErrInvalidCredentials
+	nextToken, nextHash := s.rotator.Next(refreshToken)
+	next := models.RefreshToken{
+		TokenHash: nextHash,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
 	}
-	userID, err := strconv.ParseUint(claims.UserID, 10, 32)
-	if err != nil {
-		return dtos.TokenResponseDTO{}, errors.Wrap(err, "failed to parse user ID")
+
+	current, err := s.refreshTokens.Rotate(ctx, auth.HashRefreshToken(refreshToken), next)
+	switch {
+	case errors.Is(err, models.ErrRefreshTokenReused):
+		s.logger.Warn("refresh token reuse detected, revoking token family", map[string]interface{}{
+			"user_id":   current.UserID,
+			"family_id": current.FamilyID,
+		})
+		_ = s.sessions.End(ctx, current.FamilyID)
+		s.clearRefreshTokenCookie(ctx)
+		return dtos.TokenResponseDTO{}, ErrRefreshTokenReused
+	case errors.Is(err, models.ErrRefreshTokenNotFound), errors.Is(err, models.ErrRefreshTokenExpired):
+		s.clearRefreshTokenCookie(ctx)
+		return dtos.TokenResponseDTO{}, ErrInvalidCredentials
+	case err != nil:
+		return dtos.TokenResponseDTO{}, errors.Wrap(err, "failed to rotate refresh token")
 	}
 
-	user, err := s.repo.GetByID(ctx, uint(userID))
+	user, err := s.repo.GetByID(ctx, current.UserID)
 	if err != nil {
 		return dtos.TokenResponseDTO{}, ErrUserNotFound
 	}
 
-	token, err := s.tokenMaker.CreateToken(user.ID, user.Nickname, user.Email, "access", 3600)
-	if err != nil {
+	if err := s.sessions.Touch(ctx, current.FamilyID, user.ID, ctx.ClientIP(), ctx.Request.UserAgent()); err != nil {
 		return dtos.TokenResponseDTO{}, ErrTokenCreationFailed
 	}
 
-	refreshToken, err = s.tokenMaker.CreateToken(user.ID, user.Nickname, user.Email, "refresh", CookieMaxAge)
+	tokens, err := s.issueTokens(ctx, user, current.FamilyID, nextToken)
 	if err != nil {
-		return dtos.TokenResponseDTO{}, ErrTokenCreationFailed
+		return dtos.TokenResponseDTO{}, err
 	}
-	s.setRefreshTokenCookie(ctx, refreshToken)
+
 	s.logger.Info("user refreshed token successfully", map[string]interface{}{
 		"NickName": user.Nickname,
 		"Email":    user.Email,
 	})
 
-	return dtos.TokenResponseDTO{
-		AccessToken: token,
-		TokenType:   "Bearer",
-		ExpiresIn:   3600,
-	}, nil
+	return tokens, nil
 }
 
 func (s *AuthServiceImpl) setRefreshTokenCookie(ctx *gin.Context, refreshToken string) {
-	domain := ".traefik.me"
-
 	ctx.SetSameSite(CookieSameSiteNone)
 	ctx.SetCookie(
 		RefreshTokenCookieName,
 		refreshToken,
 		CookieMaxAge,
 		CookiePath,
-		domain,
+		CookieDomain,
 		CookieSecure,
 		CookieHTTPOnly,
 	)
@@ -223,14 +338,88 @@ func (s *AuthServiceImpl) setRefreshTokenCookie(ctx *gin.Context, refreshToken s
 	}
 }
 
+// Logout завершает сессию refresh-токена из куки и очищает куки.
 func (s *AuthServiceImpl) Logout(ctx *gin.Context) {
+	if refreshToken, err := ctx.Cookie(RefreshTokenCookieName); err == nil && refreshToken != "" {
+		token, err := s.refreshTokens.GetByHash(ctx, auth.HashRefreshToken(refreshToken))
+		if err == nil {
+			err = s.sessions.End(ctx, token.FamilyID)
+		}
+		if err != nil && !errors.Is(err, models.ErrRefreshTokenNotFound) {
+			s.logger.Error("failed to revoke refresh token on logout", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	s.clearRefreshTokenCookie(ctx)
+}
+
+// LogoutAll завершает сессии пользователя на всех устройствах.
+func (s *AuthServiceImpl) LogoutAll(ctx *gin.Context, userID uint) error {
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+
+	s.clearRefreshTokenCookie(ctx)
+
+	s.logger.Info("user logged out from all sessions", map[string]interface{}{
+		"user_id": userID,
+	})
+	return nil
+}
+
+func (s *AuthServiceImpl) clearRefreshTokenCookie(ctx *gin.Context) {
+	ctx.SetSameSite(CookieSameSiteNone)
 	ctx.SetCookie(
 		RefreshTokenCookieName,
 		"",
 		-1,
 		CookiePath,
-		"",
+		CookieDomain,
 		CookieSecure,
 		CookieHTTPOnly,
 	)
 }
+
+// StartRefreshTokenCleanup периодически удаляет истекшие refresh-токены и
+// сессии, которые не обновлялись дольше срока жизни refresh-токена, до отмены контекста.
+func (s *AuthServiceImpl) StartRefreshTokenCleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(refreshTokenCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := s.refreshTokens.DeleteExpired(ctx, time.Now())
+				if err != nil && ctx.Err() == nil {
+					s.logger.Error("failed to delete expired refresh tokens", map[string]interface{}{
+						"error": err.Error(),
+					})
+					continue
+				}
+				if deleted > 0 {
+					s.logger.Info("deleted expired refresh tokens", map[string]interface{}{
+						"count": deleted,
+					})
+				}
+
+				deleted, err = s.sessions.DeleteInactive(ctx, time.Now().Add(-RefreshTokenTTL))
+				if err != nil && ctx.Err() == nil {
+					s.logger.Error("failed to delete inactive sessions", map[string]interface{}{
+						"error": err.Error(),
+					})
+					continue
+				}
+				if deleted > 0 {
+					s.logger.Info("deleted inactive sessions", map[string]interface{}{
+						"count": deleted,
+					})
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/domain/dtos"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"logur.dev/logur"
+)
+
+// loginTestUsers ищет пользователей без учета регистра, как репозиторий, и
+// запоминает, каким методом шел поиск.
+type loginTestUsers struct {
+	repositories.UserRepository
+	users  []models.User
+	lookup string
+}
+
+func (r *loginTestUsers) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	r.lookup = "email"
+	for _, user := range r.users {
+		if user.Email == models.NormalizeEmail(email) {
+			return user, nil
+		}
+	}
+	return models.User{}, models.ErrUserNotFound
+}
+
+func (r *loginTestUsers) GetByNickName(ctx context.Context, nickname string) (models.User, error) {
+	r.lookup = "nickname"
+	for _, user := range r.users {
+		if user.Nickname == models.NormalizeNickname(nickname) {
+			return user, nil
+		}
+	}
+	return models.User{}, models.ErrUserNotFound
+}
+
+func TestFindByLogin(t *testing.T) {
+	alice := models.User{Nickname: "alice_anime", Email: "alice@example.com"}
+	alice.ID = 1
+
+	tests := []struct {
+		name       string
+		login      string
+		wantLookup string
+		wantID     uint
+	}{
+		{"email", "alice@example.com", "email", 1},
+		{"email in another case", " Alice@Example.COM ", "email", 1},
+		{"nickname", "alice_anime", "nickname", 1},
+		{"nickname in another case", "ALICE_Anime", "nickname", 1},
+		{"nickname looked up as email", "alice_anime@", "email", 0},
+		{"email looked up as nickname", "alice.example.com", "nickname", 0},
+		{"unknown email", "bob@example.com", "email", 0},
+		{"empty login", "   ", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &loginTestUsers{users: []models.User{alice}}
+			service := NewAuthService(repo, nil, nil, nil, nil, nil, nil, logur.NoopLogger{}, nil)
+
+			user, err := service.findByLogin(context.Background(), tt.login)
+			if repo.lookup != tt.wantLookup {
+				t.Errorf("looked up by %q, want %q", repo.lookup, tt.wantLookup)
+			}
+			if tt.wantID == 0 {
+				if err == nil {
+					t.Errorf("found user %d, want none", user.ID)
+				}
+				return
+			}
+			if err != nil || user.ID != tt.wantID {
+				t.Errorf("findByLogin = (%d, %v), want user %d", user.ID, err, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsNicknameWithAt(t *testing.T) {
+	repo := &loginTestUsers{}
+	service := NewAuthService(repo, nil, nil, nil, nil, nil, nil, logur.NoopLogger{}, nil)
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	_, err := service.Register(ctx, dtos.CreateUserDTO{
+		NickName: "alice@example.com",
+		Email:    "bob@example.com",
+		Password: "password",
+	}, recorder)
+	if err != models.ErrInvalidNickname {
+		t.Fatalf("Register with @ in nickname: err = %v, want %v", err, models.ErrInvalidNickname)
+	}
+	if repo.lookup != "" {
+		t.Errorf("Register looked up users by %s before rejecting the nickname", repo.lookup)
+	}
+}
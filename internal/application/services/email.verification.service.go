@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"github.com/merdernoty/anime-service/internal/infrastructure/mail"
+	"github.com/merdernoty/anime-service/pkg/auth"
+	"logur.dev/logur"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("email verification token is invalid or expired")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrVerificationThrottled    = errors.New("verification email was sent recently, try again later")
+	ErrEmailVerificationFailed  = errors.New("failed to verify email")
+)
+
+const emailVerificationMailTimeout = 30 * time.Second
+
+// EmailVerificationSettings — срок жизни ссылки, адрес страницы подтверждения
+// (к нему добавляется параметр token) и минимальный интервал между письмами.
+type EmailVerificationSettings struct {
+	TokenTTL       time.Duration
+	VerifyURL      string
+	ResendCooldown time.Duration
+}
+
+// emailVerificationClaims — данные подписанной ссылки. Email входит в подпись,
+// поэтому после смены адреса старые ссылки перестают работать.
+type emailVerificationClaims struct {
+	UserID    uint   `json:"uid"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// EmailVerificationService подтверждает email пользователя по подписанной ссылке из письма.
+type EmailVerificationService struct {
+	users    repositories.UserRepository
+	mailer   mail.Mailer
+	signer   *auth.HMACSigner
+	store    cache.Cache
+	settings EmailVerificationSettings
+	logger   logur.LoggerFacade
+}
+
+func NewEmailVerificationService(users repositories.UserRepository, mailer mail.Mailer, signer *auth.HMACSigner, store cache.Cache, settings EmailVerificationSettings, logger logur.LoggerFacade) *EmailVerificationService {
+	return &EmailVerificationService{
+		users:    users,
+		mailer:   mailer,
+		signer:   signer,
+		store:    store,
+		settings: settings,
+		logger:   logger,
+	}
+}
+
+// SendVerification отправляет в фоне письмо со ссылкой подтверждения текущего
+// email пользователя — не чаще раза в ResendCooldown на пользователя, с какого бы
+// пути ни пришел запрос: регистрации, смены email или повторной отправки.
+func (s *EmailVerificationService) SendVerification(ctx context.Context, user models.User) error {
+	if err := s.claimCooldown(ctx, user.ID); err != nil {
+		return err
+	}
+
+	token, err := s.signer.Sign(emailVerificationClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(s.settings.TokenTTL).Unix(),
+	})
+	if err != nil {
+		s.logger.Error("failed to sign email verification token", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+		s.releaseCooldown(ctx, user.ID)
+		return ErrEmailVerificationFailed
+	}
+
+	message := mail.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\nПодтвердите адрес электронной почты, перейдя по ссылке:\n%s\n\nСсылка действует %s. Если вы не регистрировались, просто проигнорируйте это письмо.\n",
+			user.Nickname, s.verifyLink(token), s.settings.TokenTTL,
+		),
+	}
+	go func() {
+		mailCtx, cancel := context.WithTimeout(context.Background(), emailVerificationMailTimeout)
+		defer cancel()
+
+		if err := s.mailer.Send(mailCtx, message); err != nil {
+			s.logger.Error("failed to send verification email", map[string]interface{}{
+				"user_id": user.ID,
+				"error":   err.Error(),
+			})
+		}
+	}()
+
+	return nil
+}
+
+// Resend повторно отправляет письмо подтверждения не чаще раза в ResendCooldown.
+func (s *EmailVerificationService) Resend(ctx context.Context, userID uint) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+// Verify подтверждает email по токену из ссылки. Повторное подтверждение не считается ошибкой.
+func (s *EmailVerificationService) Verify(ctx context.Context, token string) error {
+	var claims emailVerificationClaims
+	if err := s.signer.Verify(token, &claims); err != nil {
+		return ErrInvalidVerificationToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.users.GetByID(ctx, claims.UserID)
+	if err != nil || user.Email != claims.Email {
+		return ErrInvalidVerificationToken
+	}
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	err = s.users.MarkEmailVerified(ctx, user.ID, claims.Email, time.Now())
+	if errors.Is(err, models.ErrUserNotFound) {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		s.logger.Error("failed to mark email verified", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+		return ErrEmailVerificationFailed
+	}
+
+	s.logger.Info("email verified", map[string]interface{}{
+		"user_id": user.ID,
+	})
+	return nil
+}
+
+// claimCooldown одной операцией Add занимает интервал между письмами, поэтому из
+// параллельных запросов письмо отправляет только один. Если хранилище
+// недоступно, письмо отправляется без ограничения.
+func (s *EmailVerificationService) claimCooldown(ctx context.Context, userID uint) error {
+	claimed, err := s.store.Add(ctx, emailVerificationThrottleKey(userID), []byte("1"), s.settings.ResendCooldown)
+	if err != nil {
+		s.logger.Warn("failed to store verification throttle", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil
+	}
+	if !claimed {
+		return ErrVerificationThrottled
+	}
+	return nil
+}
+
+func (s *EmailVerificationService) releaseCooldown(ctx context.Context, userID uint) {
+	if err := s.store.Delete(ctx, emailVerificationThrottleKey(userID)); err != nil {
+		s.logger.Warn("failed to release verification throttle", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+func (s *EmailVerificationService) verifyLink(token string) string {
+	link, err := url.Parse(s.settings.VerifyURL)
+	if err != nil {
+		return s.settings.VerifyURL + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+func emailVerificationThrottleKey(userID uint) string {
+	return fmt.Sprintf("verify-email:resend:%d", userID)
+}
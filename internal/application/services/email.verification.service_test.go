@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"github.com/merdernoty/anime-service/internal/infrastructure/mail"
+	"github.com/merdernoty/anime-service/pkg/auth"
+	"logur.dev/logur"
+)
+
+type verificationTestUsers struct {
+	repositories.UserRepository
+	user models.User
+}
+
+func (r *verificationTestUsers) GetByID(ctx context.Context, id uint) (models.User, error) {
+	if id != r.user.ID {
+		return models.User{}, models.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+func newVerificationTestService(t *testing.T, users repositories.UserRepository) (*EmailVerificationService, *resetTestMailer) {
+	t.Helper()
+
+	signer, err := auth.NewHMACSigner("test-secret", "email-verification")
+	if err != nil {
+		t.Fatalf("NewHMACSigner: %v", err)
+	}
+	mailer := &resetTestMailer{sent: make(chan mail.Message, 100)}
+	service := NewEmailVerificationService(users, mailer, signer, cache.NewMemoryStore(time.Minute), EmailVerificationSettings{
+		TokenTTL:       time.Hour,
+		VerifyURL:      "http://localhost/verify",
+		ResendCooldown: time.Minute,
+	}, logur.NoopLogger{})
+	return service, mailer
+}
+
+func TestResendSendsOneEmailForParallelRequests(t *testing.T) {
+	user := models.User{Nickname: "user", Email: "user@example.com"}
+	user.ID = 1
+	service, mailer := newVerificationTestService(t, &verificationTestUsers{user: user})
+
+	const requests = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sent := 0
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := service.Resend(context.Background(), 1); err == nil {
+				mu.Lock()
+				sent++
+				mu.Unlock()
+			} else if err != ErrVerificationThrottled {
+				t.Errorf("Resend: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sent != 1 {
+		t.Errorf("%d parallel resend requests were accepted, want 1", sent)
+	}
+	select {
+	case <-mailer.sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("verification email was not sent")
+	}
+}
+
+func TestSendVerificationIsThrottledPerUser(t *testing.T) {
+	service, _ := newVerificationTestService(t, &verificationTestUsers{})
+
+	first := models.User{Email: "first@example.com"}
+	first.ID = 1
+	second := models.User{Email: "second@example.com"}
+	second.ID = 2
+
+	if err := service.SendVerification(context.Background(), first); err != nil {
+		t.Fatalf("first SendVerification: %v", err)
+	}
+	first.Email = "victim@example.com"
+	if err := service.SendVerification(context.Background(), first); err != ErrVerificationThrottled {
+		t.Errorf("SendVerification after an email change: err = %v, want %v", err, ErrVerificationThrottled)
+	}
+	if err := service.SendVerification(context.Background(), second); err != nil {
+		t.Errorf("SendVerification for another user: %v", err)
+	}
+}
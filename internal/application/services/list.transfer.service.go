@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	domainRepositories "github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/mal"
+	"github.com/merdernoty/anime-service/internal/infrastructure/repositories"
+	"logur.dev/logur"
+)
+
+var (
+	ErrExportFailed        = errors.New("failed to export user anime list")
+	ErrInvalidImportFile   = errors.New("invalid import file")
+	ErrImportFailed        = errors.New("failed to import user anime list")
+	ErrImportJobNotFound   = errors.New("import job not found")
+	ErrImportFileTooLarge  = errors.New("import file is too large")
+	ErrInvalidImportPolicy = errors.New("invalid import conflict policy")
+)
+
+// importJobSaveTimeout — сколько ждать сохранения итога фоновой задачи.
+const importJobSaveTimeout = 10 * time.Second
+
+// errImportJobInterrupted — фоновая задача не завершилась за отведенное время,
+// например потому, что экземпляр API перезапустился во время импорта.
+var errImportJobInterrupted = errors.New("import job was interrupted")
+
+// ImportSettings — ограничения импорта списков.
+type ImportSettings struct {
+	SyncLimit  int           // Сколько записей импортируется в рамках запроса; большие файлы уходят в фоновую задачу
+	JobTTL     time.Duration // Сколько хранится результат фоновой задачи
+	JobTimeout time.Duration // Максимальная длительность фоновой задачи
+	MaxEntries int           // Максимальное количество записей в файле
+	MaxXMLSize int64         // Максимальный размер XML после распаковки gzip, в байтах
+}
+
+// ListTransferService переносит списки пользователей между трекерами
+// в формате XML-экспорта MyAnimeList.
+type ListTransferService struct {
+	userAnimeRepo    *repositories.UserAnimeRepository
+	episodeWatchRepo *repositories.EpisodeWatchRepository
+	userRepo         domainRepositories.UserRepository
+	jobs             *repositories.ImportJobRepository
+	settings         ImportSettings
+	logger           logur.LoggerFacade
+}
+
+func NewListTransferService(userAnimeRepo *repositories.UserAnimeRepository, episodeWatchRepo *repositories.EpisodeWatchRepository, userRepo domainRepositories.UserRepository, jobs *repositories.ImportJobRepository, settings ImportSettings, logger logur.LoggerFacade) *ListTransferService {
+	return &ListTransferService{
+		userAnimeRepo:    userAnimeRepo,
+		episodeWatchRepo: episodeWatchRepo,
+		userRepo:         userRepo,
+		jobs:             jobs,
+		settings:         settings,
+		logger:           logger,
+	}
+}
+
+// ExportMAL пишет весь список пользователя в w в формате MAL XML. Итоги и владелец
+// загружаются до первой записи в w, поэтому ошибки на этом этапе можно вернуть клиенту
+// обычным ответом; ошибки после начала потока только прерывают его.
+func (s *ListTransferService) ExportMAL(ctx context.Context, userID uint, w io.Writer) error {
+	s.logger.Info("Exporting user anime list", map[string]interface{}{
+		"user_id": userID,
+		"format":  "mal-xml",
+	})
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Error getting user for export", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return ErrUserNotFound
+	}
+
+	stats, err := s.userAnimeRepo.GetUserStats(ctx, userID)
+	if err != nil {
+		s.logger.Error("Error getting user anime stats for export", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return ErrExportFailed
+	}
+
+	writer := mal.NewWriter(w)
+	if err := writer.WriteHeader(mal.NewInfo(userID, user.Nickname, stats)); err != nil {
+		return s.exportError(userID, err)
+	}
+
+	err = s.userAnimeRepo.ForEachUserAnimeWithDetails(ctx, userID, func(item *models.UserAnimeWithDetails) error {
+		return writer.WriteEntry(mal.NewEntry(item))
+	})
+	if err != nil {
+		return s.exportError(userID, err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return s.exportError(userID, err)
+	}
+
+	return nil
+}
+
+func (s *ListTransferService) exportError(userID uint, err error) error {
+	s.logger.Error("Error writing user anime list export", map[string]interface{}{
+		"user_id": userID,
+		"error":   err.Error(),
+	})
+	return ErrExportFailed
+}
+
+// ImportMAL разбирает файл экспорта MAL и применяет его к списку пользователя.
+// Пробный прогон и файлы до SyncLimit записей обрабатываются сразу и возвращаются
+// завершенной задачей; большие файлы обрабатываются в фоне, а задача возвращается
+// в статусе pending и доступна через GetImportJob.
+func (s *ListTransferService) ImportMAL(ctx context.Context, userID uint, r io.Reader, options models.ImportOptions) (*models.ImportJob, error) {
+	if !options.Policy.IsValid() {
+		return nil, ErrInvalidImportPolicy
+	}
+
+	entries, err := readMALEntries(r, s.settings.MaxEntries, s.settings.MaxXMLSize)
+	if err != nil {
+		s.logger.Warn("Invalid import file", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		if errors.Is(err, mal.ErrFileTooLarge) || errors.Is(err, errTooManyImportEntries) {
+			return nil, ErrImportFileTooLarge
+		}
+		return nil, ErrInvalidImportFile
+	}
+
+	s.logger.Info("Importing user anime list", map[string]interface{}{
+		"user_id": userID,
+		"entries": len(entries),
+		"dry_run": options.DryRun,
+		"policy":  options.Policy,
+	})
+
+	job := &models.ImportJob{
+		ID:        newImportJobID(),
+		UserID:    userID,
+		Status:    models.ImportJobPending,
+		Options:   options,
+		CreatedAt: time.Now(),
+	}
+
+	if options.DryRun || len(entries) <= s.settings.SyncLimit {
+		report, err := s.applyImport(ctx, userID, entries, options)
+		if err != nil {
+			return nil, ErrImportFailed
+		}
+		s.finishJob(job, report, nil)
+		return job, nil
+	}
+
+	if err := s.jobs.DeleteExpired(ctx); err != nil {
+		s.logger.Warn("Error deleting expired import jobs", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if err := s.saveJob(ctx, job); err != nil {
+		return nil, ErrImportFailed
+	}
+
+	go s.runImportJob(*job, entries)
+
+	return job, nil
+}
+
+// GetImportJob возвращает задачу импорта, если она принадлежит пользователю.
+// Незавершенная задача, которая работает дольше JobTimeout, возвращается
+// как неудавшаяся: обрабатывавший ее экземпляр API уже не сохранит итог.
+func (s *ListTransferService) GetImportJob(ctx context.Context, userID uint, jobID string) (*models.ImportJob, error) {
+	job, err := s.jobs.GetByID(ctx, jobID)
+	if errors.Is(err, models.ErrImportJobNotFound) {
+		return nil, ErrImportJobNotFound
+	}
+	if err != nil {
+		return nil, ErrImportFailed
+	}
+	if job.UserID != userID {
+		return nil, ErrImportJobNotFound
+	}
+
+	if s.jobInterrupted(job) {
+		s.finishJob(job, job.Report, errImportJobInterrupted)
+		if err := s.saveJob(ctx, job); err != nil {
+			return nil, ErrImportFailed
+		}
+	}
+
+	return job, nil
+}
+
+func (s *ListTransferService) jobInterrupted(job *models.ImportJob) bool {
+	if job.Status != models.ImportJobPending && job.Status != models.ImportJobRunning {
+		return false
+	}
+	return time.Since(job.CreatedAt) > s.settings.JobTimeout+importJobSaveTimeout
+}
+
+func (s *ListTransferService) runImportJob(job models.ImportJob, entries []*mal.Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.settings.JobTimeout)
+	defer cancel()
+
+	job.Status = models.ImportJobRunning
+	if err := s.saveJob(ctx, &job); err != nil {
+		return
+	}
+
+	report, err := s.applyImport(ctx, job.UserID, entries, job.Options)
+	s.finishJob(&job, report, err)
+
+	// Итог сохраняется и после истечения JobTimeout, поэтому с отдельным контекстом.
+	saveCtx, cancelSave := context.WithTimeout(context.Background(), importJobSaveTimeout)
+	defer cancelSave()
+	if err := s.saveJob(saveCtx, &job); err != nil {
+		return
+	}
+
+	s.logger.Info("Import job finished", map[string]interface{}{
+		"job_id":  job.ID,
+		"user_id": job.UserID,
+		"status":  job.Status,
+	})
+}
+
+func (s *ListTransferService) finishJob(job *models.ImportJob, report *models.ImportReport, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Report = report
+	if err != nil {
+		job.Status = models.ImportJobFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = models.ImportJobCompleted
+}
+
+func (s *ListTransferService) saveJob(ctx context.Context, job *models.ImportJob) error {
+	return s.jobs.Save(ctx, job, time.Now().Add(s.settings.JobTTL))
+}
+
+// applyImport обрабатывает записи по одной: ошибка отдельной записи попадает в отчет
+// и не прерывает импорт. Прерывает его только отмена контекста.
+func (s *ListTransferService) applyImport(ctx context.Context, userID uint, entries []*mal.Entry, options models.ImportOptions) (*models.ImportReport, error) {
+	report := &models.ImportReport{Entries: make([]models.ImportEntryResult, 0, len(entries))}
+	seen := make(map[int64]bool, len(entries))
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		result := s.importEntry(ctx, userID, entry, options, seen)
+		if result.Action == models.ImportActionFailed {
+			s.logger.Warn("Failed to import anime entry", map[string]interface{}{
+				"user_id":      userID,
+				"anime_mal_id": result.AnimeMALID,
+				"reason":       result.Reason,
+			})
+		}
+		report.Add(result)
+	}
+
+	return report, nil
+}
+
+func (s *ListTransferService) importEntry(ctx context.Context, userID uint, entry *mal.Entry, options models.ImportOptions, seen map[int64]bool) models.ImportEntryResult {
+	result := models.ImportEntryResult{
+		AnimeMALID: entry.SeriesAnimeDBID,
+		Title:      entry.SeriesTitle.Value,
+	}
+	fail := func(reason string) models.ImportEntryResult {
+		result.Action = models.ImportActionFailed
+		result.Reason = reason
+		return result
+	}
+
+	imported, err := entry.ToUserAnime(userID)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if seen[imported.AnimeMALID] {
+		return fail("duplicate entry in import file")
+	}
+	seen[imported.AnimeMALID] = true
+
+	existing, err := s.userAnimeRepo.GetByUserAndAnimeMALID(ctx, userID, imported.AnimeMALID)
+	if err != nil && !errors.Is(err, models.ErrUserAnimeNotFound) {
+		return fail("failed to read existing entry")
+	}
+
+	// Каталог для импорта не загружается: число эпизодов берется из файла, по нему
+	// же ToUserAnime уже проверил прогресс.
+	anime := &models.Anime{MALId: imported.AnimeMALID, Episodes: entry.SeriesEpisodes}
+
+	if existing == nil {
+		result.Action = models.ImportActionCreate
+		if !options.DryRun {
+			if _, err := s.episodeWatchRepo.Import(ctx, imported, anime, 0); err != nil {
+				return fail(importWriteFailure("failed to create entry", err))
+			}
+		}
+		return result
+	}
+
+	result.Changes = models.ImportDiff(existing, imported)
+	switch {
+	case len(result.Changes) == 0:
+		result.Action = models.ImportActionUnchanged
+		return result
+	case options.Policy == models.ImportPolicySkip:
+		result.Action = models.ImportActionSkip
+		result.Reason = "entry already exists"
+		return result
+	case options.Policy == models.ImportPolicyKeepNewest && !importIsNewer(existing, entry):
+		result.Action = models.ImportActionSkip
+		result.Reason = "existing entry is newer"
+		return result
+	}
+
+	result.Action = models.ImportActionUpdate
+	if !options.DryRun {
+		if _, err := s.episodeWatchRepo.Import(ctx, imported, anime, existing.Version); err != nil {
+			return fail(importWriteFailure("failed to update entry", err))
+		}
+	}
+	return result
+}
+
+// importWriteFailure объясняет в отчете, почему запись не сохранена: запрещенный
+// переход статуса и изменение записи во время импорта — ожидаемые отказы, об
+// остальных ошибках отчет сообщает без подробностей.
+func importWriteFailure(reason string, err error) string {
+	switch {
+	case errors.Is(err, models.ErrInvalidStatusTransition):
+		return "status transition is not allowed"
+	case errors.Is(err, models.ErrUserAnimeVersionMismatch), errors.Is(err, models.ErrUserAnimeNotFound):
+		return "entry changed during import"
+	case errors.Is(err, models.ErrInvalidEpisodeCount), errors.Is(err, models.ErrInvalidTrackingDates):
+		return err.Error()
+	default:
+		return reason
+	}
+}
+
+// importIsNewer сравнивает последнюю дату просмотра из файла с последним изменением записи.
+// В экспорте MAL нет времени изменения записи, поэтому запись без дат считается более старой.
+func importIsNewer(existing *models.UserAnime, entry *mal.Entry) bool {
+	activity := entry.LastActivity()
+	return activity != nil && activity.After(existing.UpdatedAt)
+}
+
+var errTooManyImportEntries = errors.New("too many entries in import file")
+
+// readMALEntries читает записи файла, прерывая чтение, как только записей
+// становится больше maxEntries или распакованный XML превышает maxXMLSize.
+// Нулевые ограничения не действуют.
+func readMALEntries(r io.Reader, maxEntries int, maxXMLSize int64) ([]*mal.Entry, error) {
+	reader, err := mal.NewReader(r, maxXMLSize)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var entries []*mal.Entry
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if maxEntries > 0 && len(entries) >= maxEntries {
+			return nil, errors.WithDetails(errTooManyImportEntries, "max", maxEntries)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func newImportJobID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+	"github.com/merdernoty/anime-service/internal/infrastructure/mal"
+	"github.com/merdernoty/anime-service/internal/infrastructure/repositories"
+	"logur.dev/logur"
+)
+
+func newTransferTestService(t *testing.T, settings ImportSettings) (*ListTransferService, *sql.DB) {
+	t.Helper()
+
+	db := dbtest.OpenMigrated(t)
+	logger := logur.NoopLogger{}
+	jobs := repositories.NewImportJobRepository(db, logger)
+	userAnimeRepo := repositories.NewUserAnimeRepository(db, logger)
+	episodeWatchRepo := repositories.NewEpisodeWatchRepository(db, userAnimeRepo, logger)
+	return NewListTransferService(userAnimeRepo, episodeWatchRepo, nil, jobs, settings, logger), db
+}
+
+func TestGetImportJobChecksOwner(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTransferTestService(t, ImportSettings{JobTTL: time.Hour, JobTimeout: time.Minute})
+
+	job := &models.ImportJob{ID: "job", UserID: 1, Status: models.ImportJobPending, CreatedAt: time.Now()}
+	if err := service.saveJob(ctx, job); err != nil {
+		t.Fatalf("saveJob: %v", err)
+	}
+
+	if loaded, err := service.GetImportJob(ctx, 1, "job"); err != nil || loaded.Status != models.ImportJobPending {
+		t.Errorf("GetImportJob for the owner = (%+v, %v), want the pending job", loaded, err)
+	}
+	if _, err := service.GetImportJob(ctx, 2, "job"); err != ErrImportJobNotFound {
+		t.Errorf("GetImportJob for another user: err = %v, want %v", err, ErrImportJobNotFound)
+	}
+}
+
+func TestGetImportJobFailsInterruptedJob(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTransferTestService(t, ImportSettings{JobTTL: time.Hour, JobTimeout: time.Minute})
+
+	// Задача, которую обрабатывал остановленный экземпляр API.
+	job := &models.ImportJob{ID: "job", UserID: 1, Status: models.ImportJobRunning, CreatedAt: time.Now().Add(-time.Hour)}
+	if err := service.saveJob(ctx, job); err != nil {
+		t.Fatalf("saveJob: %v", err)
+	}
+
+	loaded, err := service.GetImportJob(ctx, 1, "job")
+	if err != nil {
+		t.Fatalf("GetImportJob: %v", err)
+	}
+	if loaded.Status != models.ImportJobFailed || loaded.FinishedAt == nil {
+		t.Errorf("interrupted job status = %s, want %s", loaded.Status, models.ImportJobFailed)
+	}
+}
+
+// malExport собирает файл экспорта MAL из записей anime.
+func malExport(entries ...string) *strings.Reader {
+	return strings.NewReader("<myanimelist>" + strings.Join(entries, "") + "</myanimelist>")
+}
+
+func malEntry(malID int64, episodes, watched int, status string) string {
+	return fmt.Sprintf(`<anime>
+		<series_animedb_id>%d</series_animedb_id>
+		<series_episodes>%d</series_episodes>
+		<my_watched_episodes>%d</my_watched_episodes>
+		<my_status>%s</my_status>
+	</anime>`, malID, episodes, watched, status)
+}
+
+func TestImportWritesEpisodeLogAndChecksTransitions(t *testing.T) {
+	ctx := context.Background()
+	service, db := newTransferTestService(t, ImportSettings{SyncLimit: 100, JobTTL: time.Hour, JobTimeout: time.Minute})
+	userID := dbtest.CreateUser(t, db)
+
+	loggedEpisodes := func(malID int64) int {
+		t.Helper()
+		var count int
+		err := db.QueryRow(`
+			SELECT count(*) FROM (
+				SELECT DISTINCT ON (episode) unwatched
+				FROM episode_watches
+				WHERE user_id = $1 AND anime_mal_id = $2
+				ORDER BY episode, id DESC
+			) latest
+			WHERE NOT unwatched
+		`, userID, malID).Scan(&count)
+		if err != nil {
+			t.Fatalf("count logged episodes: %v", err)
+		}
+		return count
+	}
+
+	job, err := service.ImportMAL(ctx, userID, malExport(
+		malEntry(1, 12, 5, "Watching"),
+		malEntry(2, 24, 24, "Completed"),
+	), models.ImportOptions{Policy: models.ImportPolicyOverwrite})
+	if err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+	if job.Report.Created != 2 {
+		t.Fatalf("first import created %d entries, want 2: %+v", job.Report.Created, job.Report.Entries)
+	}
+	if got := loggedEpisodes(1); got != 5 {
+		t.Errorf("anime 1 has %d logged episodes, want 5", got)
+	}
+	if got := loggedEpisodes(2); got != 24 {
+		t.Errorf("anime 2 has %d logged episodes, want 24", got)
+	}
+
+	// Откат прогресса пишется в журнал, а перевод просмотренного в планы запрещен.
+	job, err = service.ImportMAL(ctx, userID, malExport(
+		malEntry(1, 12, 3, "Watching"),
+		malEntry(2, 24, 0, "Plan to Watch"),
+	), models.ImportOptions{Policy: models.ImportPolicyOverwrite})
+	if err != nil {
+		t.Fatalf("second import: %v", err)
+	}
+	if job.Report.Updated != 1 || job.Report.Failed != 1 {
+		t.Fatalf("second import = (updated %d, failed %d), want (1, 1): %+v", job.Report.Updated, job.Report.Failed, job.Report.Entries)
+	}
+	if got := loggedEpisodes(1); got != 3 {
+		t.Errorf("anime 1 has %d logged episodes after rollback, want 3", got)
+	}
+	if got := loggedEpisodes(2); got != 24 {
+		t.Errorf("anime 2 has %d logged episodes after a rejected import, want 24", got)
+	}
+	for _, entry := range job.Report.Entries {
+		if entry.AnimeMALID == 2 && entry.Reason != "status transition is not allowed" {
+			t.Errorf("rejected entry reason = %q", entry.Reason)
+		}
+	}
+}
+
+func TestReadMALEntriesLimits(t *testing.T) {
+	export := "<myanimelist>" + malEntry(1, 12, 1, "Watching") + malEntry(2, 12, 2, "Watching") + "</myanimelist>"
+
+	// Сжатый файл в несколько килобайт, который распаковывается в мегабайты.
+	var bomb bytes.Buffer
+	gz := gzip.NewWriter(&bomb)
+	_, _ = gz.Write([]byte("<myanimelist>" + strings.Repeat(" ", 8<<20) + "</myanimelist>"))
+	_ = gz.Close()
+
+	tests := []struct {
+		name       string
+		file       []byte
+		maxEntries int
+		maxSize    int64
+		wantCount  int
+		wantErr    error
+	}{
+		{"within limits", []byte(export), 2, int64(len(export)), 2, nil},
+		{"no limits", []byte(export), 0, 0, 2, nil},
+		{"too many entries", []byte(export), 1, 0, 0, errTooManyImportEntries},
+		{"xml larger than limit", []byte(export), 0, int64(len(export)) - 1, 0, mal.ErrFileTooLarge},
+		{"gzip bomb", bomb.Bytes(), 0, 1 << 20, 0, mal.ErrFileTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := readMALEntries(bytes.NewReader(tt.file), tt.maxEntries, tt.maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(entries) != tt.wantCount {
+				t.Errorf("read %d entries, want %d", len(entries), tt.wantCount)
+			}
+		})
+	}
+}
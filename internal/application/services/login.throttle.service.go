@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"logur.dev/logur"
+)
+
+var ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+// LoginThrottledError сообщает, через сколько можно повторить попытку входа.
+// Сравнивается с ErrTooManyLoginAttempts через errors.Is.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LoginThrottleSettings — ограничения на подбор пароля. Счетчики неудачных
+// попыток живут в окне FailureWindow; после каждой неудачи следующая попытка
+// для аккаунта возможна не раньше, чем через BaseDelay * 2^(n-1), но не более
+// MaxDelay. После MaxAccountFailures аккаунт блокируется на LockoutDuration.
+type LoginThrottleSettings struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	FailureWindow      time.Duration
+	LockoutDuration    time.Duration
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+}
+
+// loginAttemptTimeout — сколько аккаунт остается занятым проверяемой попыткой
+// входа, если ее результат так и не был записан.
+const loginAttemptTimeout = 10 * time.Second
+
+// LoginThrottleService считает неудачные попытки входа по аккаунту и по IP
+// в хранилище состояния и блокирует аккаунт после серии неудач.
+type LoginThrottleService struct {
+	users    repositories.UserRepository
+	store    cache.Cache
+	settings LoginThrottleSettings
+	logger   logur.LoggerFacade
+}
+
+func NewLoginThrottleService(users repositories.UserRepository, store cache.Cache, settings LoginThrottleSettings, logger logur.LoggerFacade) *LoginThrottleService {
+	return &LoginThrottleService{
+		users:    users,
+		store:    store,
+		settings: settings,
+		logger:   logger,
+	}
+}
+
+// CheckIP отклоняет попытку входа, если с адреса было слишком много неудач.
+func (s *LoginThrottleService) CheckIP(ctx context.Context, ip string) error {
+	if s.settings.MaxIPFailures <= 0 {
+		return nil
+	}
+	data, err := s.store.Get(ctx, loginIPFailuresKey(ip))
+	if err != nil {
+		return nil
+	}
+	failures, _ := strconv.ParseInt(string(data), 10, 64)
+	if failures >= int64(s.settings.MaxIPFailures) {
+		return &LoginThrottledError{RetryAfter: s.settings.FailureWindow}
+	}
+	return nil
+}
+
+// CheckAccount отклоняет попытку входа в заблокированный аккаунт или раньше,
+// чем истекла задержка после предыдущей неудачи. Для несуществующих логинов
+// (user == nil) действуют те же ограничения, чтобы по ответу нельзя было
+// понять, существует ли аккаунт.
+//
+// Пропущенная попытка атомарно занимает аккаунт до вызова RecordFailure или
+// RecordSuccess, поэтому параллельные запросы не проверяют пароли в обход
+// задержки: пока идет одна проверка, остальные получают отказ.
+func (s *LoginThrottleService) CheckAccount(ctx context.Context, login string, user *models.User) error {
+	now := time.Now()
+	if user != nil && user.IsLocked(now) {
+		return &LoginThrottledError{RetryAfter: user.LockedUntil.Sub(now)}
+	}
+
+	key := accountKey(login, user)
+	if user == nil {
+		if until, ok := s.deadline(ctx, loginLockKey(key)); ok && now.Before(until) {
+			return &LoginThrottledError{RetryAfter: until.Sub(now)}
+		}
+	}
+
+	value := strconv.FormatInt(now.Add(loginAttemptTimeout).UnixMilli(), 10)
+	claimed, err := s.store.Add(ctx, loginDelayKey(key), []byte(value), loginAttemptTimeout)
+	if err != nil {
+		s.logger.Warn("failed to check login throttle", map[string]interface{}{
+			"account": key,
+			"error":   err.Error(),
+		})
+		return nil
+	}
+	if !claimed {
+		retryAfter := loginAttemptTimeout
+		if until, ok := s.deadline(ctx, loginDelayKey(key)); ok && now.Before(until) {
+			retryAfter = until.Sub(now)
+		}
+		return &LoginThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RecordFailure учитывает неудачную попытку входа и при превышении лимита
+// блокирует аккаунт. Задержка до следующей попытки считается по значению,
+// которое вернул атомарный счетчик неудач.
+func (s *LoginThrottleService) RecordFailure(ctx context.Context, login string, ip string, user *models.User) {
+	if _, err := s.store.Increment(ctx, loginIPFailuresKey(ip), s.settings.FailureWindow); err != nil {
+		s.logger.Warn("failed to count login failure", map[string]interface{}{
+			"ip":    ip,
+			"error": err.Error(),
+		})
+	}
+
+	key := accountKey(login, user)
+	failures, err := s.store.Increment(ctx, loginFailuresKey(key), s.settings.FailureWindow)
+	if err != nil {
+		s.logger.Warn("failed to count login failure", map[string]interface{}{
+			"account": key,
+			"error":   err.Error(),
+		})
+		s.release(ctx, key)
+		return
+	}
+
+	if s.settings.MaxAccountFailures > 0 && failures >= int64(s.settings.MaxAccountFailures) {
+		s.lock(ctx, key, ip, user, failures)
+		return
+	}
+
+	delay := s.delay(failures)
+	if delay <= 0 {
+		s.release(ctx, key)
+		return
+	}
+	s.setDeadline(ctx, loginDelayKey(key), time.Now().Add(delay), delay)
+}
+
+// RecordSuccess сбрасывает счетчик неудач аккаунта после успешного входа.
+func (s *LoginThrottleService) RecordSuccess(ctx context.Context, login string, user *models.User) {
+	s.reset(ctx, accountKey(login, user))
+}
+
+// Unlock снимает блокировку входа с пользователя и сбрасывает его счетчики.
+func (s *LoginThrottleService) Unlock(ctx context.Context, actorID uint, userID uint) error {
+	if err := s.users.SetLockedUntil(ctx, userID, nil); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return errors.Wrap(err, "failed to unlock user")
+	}
+	s.reset(ctx, userAccountKey(userID))
+
+	s.logger.Info("user unlocked", map[string]interface{}{
+		"actor_id": actorID,
+		"user_id":  userID,
+	})
+	return nil
+}
+
+func (s *LoginThrottleService) lock(ctx context.Context, key string, ip string, user *models.User, failures int64) {
+	until := time.Now().Add(s.settings.LockoutDuration)
+	// Аккаунт остается занятым до конца блокировки: попытка, загрузившая
+	// пользователя до записи locked_until, не пройдет и со старыми данными.
+	s.setDeadline(ctx, loginDelayKey(key), until, s.settings.LockoutDuration)
+	s.clear(ctx, loginFailuresKey(key))
+
+	fields := map[string]interface{}{
+		"account":      key,
+		"ip":           ip,
+		"failures":     failures,
+		"locked_until": until,
+	}
+
+	if user == nil {
+		s.setDeadline(ctx, loginLockKey(key), until, s.settings.LockoutDuration)
+		s.logger.Warn("login locked for unknown account", fields)
+		return
+	}
+
+	fields["user_id"] = user.ID
+	if err := s.users.SetLockedUntil(ctx, user.ID, &until); err != nil {
+		fields["error"] = err.Error()
+		s.logger.Error("failed to lock user", fields)
+		return
+	}
+	s.logger.Warn("user locked after failed login attempts", fields)
+}
+
+// release освобождает аккаунт после попытки, за которой не следует задержка.
+func (s *LoginThrottleService) release(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, loginDelayKey(key)); err != nil {
+		s.logger.Warn("failed to release login throttle", map[string]interface{}{
+			"account": key,
+			"error":   err.Error(),
+		})
+	}
+}
+
+func (s *LoginThrottleService) reset(ctx context.Context, key string) {
+	s.clear(ctx, loginFailuresKey(key), loginDelayKey(key), loginLockKey(key))
+}
+
+func (s *LoginThrottleService) clear(ctx context.Context, keys ...string) {
+	for _, k := range keys {
+		if err := s.store.Delete(ctx, k); err != nil {
+			s.logger.Warn("failed to reset login throttle", map[string]interface{}{
+				"key":   k,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+func (s *LoginThrottleService) delay(failures int64) time.Duration {
+	if s.settings.BaseDelay <= 0 || failures <= 0 {
+		return 0
+	}
+	delay := s.settings.BaseDelay
+	for i := int64(1); i < failures && delay < s.settings.MaxDelay; i++ {
+		delay *= 2
+	}
+	if s.settings.MaxDelay > 0 && delay > s.settings.MaxDelay {
+		delay = s.settings.MaxDelay
+	}
+	return delay
+}
+
+func (s *LoginThrottleService) deadline(ctx context.Context, key string) (time.Time, bool) {
+	data, err := s.store.Get(ctx, key)
+	if err != nil {
+		return time.Time{}, false
+	}
+	unixMilli, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(unixMilli), true
+}
+
+func (s *LoginThrottleService) setDeadline(ctx context.Context, key string, until time.Time, ttl time.Duration) {
+	value := strconv.FormatInt(until.UnixMilli(), 10)
+	if err := s.store.Set(ctx, key, []byte(value), ttl); err != nil {
+		s.logger.Warn("failed to store login throttle", map[string]interface{}{
+			"key":   key,
+			"error": err.Error(),
+		})
+	}
+}
+
+// accountKey — ключ счетчиков аккаунта. Для существующего пользователя он не
+// зависит от того, входят по email или по никнейму.
+func accountKey(login string, user *models.User) string {
+	if user != nil {
+		return userAccountKey(user.ID)
+	}
+	return "login:" + strings.ToLower(strings.TrimSpace(login))
+}
+
+func userAccountKey(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func loginFailuresKey(account string) string {
+	return "login-throttle:failures:" + account
+}
+
+func loginDelayKey(account string) string {
+	return "login-throttle:delay:" + account
+}
+
+func loginLockKey(account string) string {
+	return "login-throttle:lock:" + account
+}
+
+func loginIPFailuresKey(ip string) string {
+	return "login-throttle:ip:" + ip
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"logur.dev/logur"
+)
+
+func newThrottleTestService(settings LoginThrottleSettings) *LoginThrottleService {
+	return NewLoginThrottleService(nil, cache.NewMemoryStore(time.Minute), settings, logur.NoopLogger{})
+}
+
+func TestCheckAccountAdmitsOneParallelAttempt(t *testing.T) {
+	ctx := context.Background()
+	service := newThrottleTestService(LoginThrottleSettings{
+		MaxAccountFailures: 5,
+		FailureWindow:      time.Minute,
+		LockoutDuration:    time.Minute,
+		BaseDelay:          time.Second,
+		MaxDelay:           time.Minute,
+	})
+
+	const requests = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := service.CheckAccount(ctx, "user", nil); err == nil {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if admitted != 1 {
+		t.Fatalf("%d parallel attempts were admitted, want 1", admitted)
+	}
+}
+
+func TestRecordFailureDelaysNextAttempt(t *testing.T) {
+	ctx := context.Background()
+	service := newThrottleTestService(LoginThrottleSettings{
+		MaxAccountFailures: 5,
+		FailureWindow:      time.Minute,
+		LockoutDuration:    time.Minute,
+		BaseDelay:          time.Second,
+		MaxDelay:           time.Minute,
+	})
+
+	if err := service.CheckAccount(ctx, "user", nil); err != nil {
+		t.Fatalf("first CheckAccount: %v", err)
+	}
+	service.RecordFailure(ctx, "user", "10.0.0.1", nil)
+
+	err := service.CheckAccount(ctx, "user", nil)
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("CheckAccount right after a failure: err = %v, want %T", err, throttled)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Second {
+		t.Errorf("RetryAfter = %s, want at most %s", throttled.RetryAfter, time.Second)
+	}
+}
+
+func TestRecordFailureLocksAccountAfterLimit(t *testing.T) {
+	ctx := context.Background()
+	service := newThrottleTestService(LoginThrottleSettings{
+		MaxAccountFailures: 3,
+		FailureWindow:      time.Minute,
+		LockoutDuration:    time.Minute,
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := service.CheckAccount(ctx, "user", nil); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+		service.RecordFailure(ctx, "user", "10.0.0.1", nil)
+	}
+	if err := service.CheckAccount(ctx, "user", nil); err != nil {
+		t.Fatalf("attempt 3: %v", err)
+	}
+	service.RecordFailure(ctx, "user", "10.0.0.1", nil)
+
+	err := service.CheckAccount(ctx, "user", nil)
+	if !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Fatalf("CheckAccount after 3 failures: err = %v, want %v", err, ErrTooManyLoginAttempts)
+	}
+}
+
+func TestRecordSuccessReleasesAccount(t *testing.T) {
+	ctx := context.Background()
+	service := newThrottleTestService(LoginThrottleSettings{
+		MaxAccountFailures: 5,
+		FailureWindow:      time.Minute,
+		LockoutDuration:    time.Minute,
+		BaseDelay:          time.Second,
+	})
+	user := &models.User{}
+	user.ID = 1
+
+	if err := service.CheckAccount(ctx, "user", user); err != nil {
+		t.Fatalf("CheckAccount: %v", err)
+	}
+	service.RecordSuccess(ctx, "user", user)
+
+	if err := service.CheckAccount(ctx, "user", user); err != nil {
+		t.Errorf("CheckAccount after a successful login: %v", err)
+	}
+}
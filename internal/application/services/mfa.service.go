@@ -0,0 +1,369 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"github.com/merdernoty/anime-service/pkg/auth"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"logur.dev/logur"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled      = errors.New("two-factor authentication enrolment was not started")
+	ErrInvalidMFACode      = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAChallenge = errors.New("two-factor login challenge is invalid or expired")
+	ErrMFAFailed           = errors.New("failed to process two-factor authentication")
+)
+
+const (
+	// MFAChallengeTTL — сколько живет токен второго шага входа.
+	MFAChallengeTTL = 5 * time.Minute
+
+	mfaChallengeMaxAttempts = 5
+	totpQRCodeSize          = 256
+	recoveryCodeLength      = 10
+)
+
+// TOTPEnrollment — данные для подключения приложения-аутентификатора.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+	QRCode string // PNG в base64
+}
+
+// MFAService управляет TOTP-аутентификацией: подключением, проверкой кодов,
+// кодами восстановления и токенами второго шага входа.
+// Секреты TOTP хранятся в базе зашифрованными secrets; токены второго шага,
+// счетчики попыток и отметки использованных кодов — в store.
+type MFAService struct {
+	users   repositories.UserRepository
+	mfa     repositories.MFARepository
+	store   cache.Cache
+	secrets *auth.SecretBox
+	issuer  string
+	logger  logur.LoggerFacade
+}
+
+func NewMFAService(users repositories.UserRepository, mfa repositories.MFARepository, store cache.Cache, secrets *auth.SecretBox, issuer string, logger logur.LoggerFacade) *MFAService {
+	return &MFAService{
+		users:   users,
+		mfa:     mfa,
+		store:   store,
+		secrets: secrets,
+		issuer:  issuer,
+		logger:  logger,
+	}
+}
+
+// StartEnrollment генерирует новый секрет TOTP. 2FA включается только после
+// подтверждения кодом в ConfirmEnrollment.
+func (s *MFAService) StartEnrollment(ctx context.Context, userID uint) (TOTPEnrollment, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return TOTPEnrollment{}, ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.issuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		s.logger.Error("failed to generate TOTP secret", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return TOTPEnrollment{}, ErrMFAFailed
+	}
+
+	image, err := key.Image(totpQRCodeSize, totpQRCodeSize)
+	if err != nil {
+		return TOTPEnrollment{}, ErrMFAFailed
+	}
+	var qr bytes.Buffer
+	if err := png.Encode(&qr, image); err != nil {
+		return TOTPEnrollment{}, ErrMFAFailed
+	}
+
+	sealed, err := s.secrets.Seal(key.Secret())
+	if err != nil {
+		return TOTPEnrollment{}, ErrMFAFailed
+	}
+	if err := s.mfa.SetPendingTOTPSecret(ctx, userID, sealed); err != nil {
+		s.logger.Error("failed to store TOTP secret", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return TOTPEnrollment{}, ErrMFAFailed
+	}
+
+	return TOTPEnrollment{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: base64.StdEncoding.EncodeToString(qr.Bytes()),
+	}, nil
+}
+
+// ConfirmEnrollment включает 2FA, если код подходит к выданному секрету, и
+// возвращает коды восстановления. Коды показываются только один раз.
+func (s *MFAService) ConfirmEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if !s.validateTOTP(ctx, user, code) {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, records, err := newRecoveryCodes()
+	if err != nil {
+		return nil, ErrMFAFailed
+	}
+	if err := s.mfa.EnableTOTP(ctx, userID, records); err != nil {
+		s.logger.Error("failed to enable TOTP", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil, ErrMFAFailed
+	}
+
+	s.logger.Info("two-factor authentication enabled", map[string]interface{}{
+		"user_id": userID,
+	})
+	return codes, nil
+}
+
+// Disable выключает 2FA. Требуется действующий код из приложения, а не код восстановления.
+func (s *MFAService) Disable(ctx context.Context, userID uint, code string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if !s.validateTOTP(ctx, user, code) {
+		return ErrInvalidMFACode
+	}
+
+	if err := s.mfa.DisableTOTP(ctx, userID); err != nil {
+		s.logger.Error("failed to disable TOTP", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return ErrMFAFailed
+	}
+
+	s.logger.Info("two-factor authentication disabled", map[string]interface{}{
+		"user_id": userID,
+	})
+	return nil
+}
+
+// CreateChallenge выдает одноразовый токен второго шага входа.
+func (s *MFAService) CreateChallenge(ctx context.Context, userID uint) (string, error) {
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", ErrMFAFailed
+	}
+	if err := s.storeChallenge(ctx, tokenHash, models.MFAChallenge{UserID: userID}); err != nil {
+		return "", ErrMFAFailed
+	}
+	return token, nil
+}
+
+// CompleteChallenge проверяет код TOTP или код восстановления для токена
+// второго шага и возвращает ID пользователя. Попытка засчитывается атомарным
+// счетчиком до проверки кода, поэтому параллельные запросы не обходят лимит в
+// mfaChallengeMaxAttempts кодов; после него токен аннулируется. Токен
+// одноразовый: при верном коде он атомарно изымается, и из параллельных
+// запросов вход завершает только один.
+func (s *MFAService) CompleteChallenge(ctx context.Context, token string, code string) (uint, error) {
+	tokenHash := auth.HashOpaqueToken(token)
+	data, err := s.store.Get(ctx, mfaChallengeKey(tokenHash))
+	if err != nil {
+		return 0, ErrInvalidMFAChallenge
+	}
+	var challenge models.MFAChallenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		return 0, ErrInvalidMFAChallenge
+	}
+
+	attempts, err := s.store.Increment(ctx, mfaAttemptsKey(tokenHash), MFAChallengeTTL)
+	if err != nil {
+		s.logger.Error("failed to count MFA challenge attempt", map[string]interface{}{
+			"user_id": challenge.UserID,
+			"error":   err.Error(),
+		})
+		return 0, ErrMFAFailed
+	}
+	if attempts > mfaChallengeMaxAttempts {
+		s.deleteChallenge(ctx, tokenHash)
+		return 0, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.users.GetByID(ctx, challenge.UserID)
+	if err != nil || !user.TOTPEnabled {
+		s.deleteChallenge(ctx, tokenHash)
+		return 0, ErrInvalidMFAChallenge
+	}
+
+	if !s.validateTOTP(ctx, user, code) && !s.useRecoveryCode(ctx, user.ID, code) {
+		if attempts == mfaChallengeMaxAttempts {
+			s.deleteChallenge(ctx, tokenHash)
+			return 0, ErrInvalidMFAChallenge
+		}
+		return 0, ErrInvalidMFACode
+	}
+
+	if _, err := s.store.Take(ctx, mfaChallengeKey(tokenHash)); err != nil {
+		return 0, ErrInvalidMFAChallenge
+	}
+	s.deleteAttempts(ctx, tokenHash)
+	return user.ID, nil
+}
+
+// validateTOTP проверяет код с допуском в один шаг и не принимает один и тот
+// же код повторно, пока он действителен: отметка об использовании ставится
+// атомарно, поэтому код не проходит и в параллельных запросах.
+func (s *MFAService) validateTOTP(ctx context.Context, user models.User, code string) bool {
+	secret, err := s.secrets.Open(user.TOTPSecret)
+	if err != nil {
+		s.logger.Error("failed to decrypt TOTP secret", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+		return false
+	}
+
+	code = strings.TrimSpace(code)
+	valid, err := totp.ValidateCustom(code, secret, time.Now(), totp.ValidateOpts{
+		Period:    30,
+		Skew:      1,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil || !valid {
+		return false
+	}
+
+	key := fmt.Sprintf("mfa:totp:used:%d:%s", user.ID, code)
+	added, err := s.store.Add(ctx, key, []byte("1"), 90*time.Second)
+	if err != nil {
+		s.logger.Error("failed to store used TOTP code", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+		return false
+	}
+	return added
+}
+
+func (s *MFAService) useRecoveryCode(ctx context.Context, userID uint, code string) bool {
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return false
+	}
+
+	err := s.mfa.UseRecoveryCode(ctx, userID, auth.HashOpaqueToken(normalized))
+	if err == nil {
+		s.logger.Info("recovery code used", map[string]interface{}{
+			"user_id": userID,
+		})
+		return true
+	}
+	if !errors.Is(err, models.ErrRecoveryCodeInvalid) {
+		s.logger.Error("failed to use recovery code", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+	return false
+}
+
+func (s *MFAService) storeChallenge(ctx context.Context, tokenHash string, challenge models.MFAChallenge) error {
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Set(ctx, mfaChallengeKey(tokenHash), data, MFAChallengeTTL); err != nil {
+		s.logger.Error("failed to store MFA challenge", map[string]interface{}{
+			"user_id": challenge.UserID,
+			"error":   err.Error(),
+		})
+		return err
+	}
+	return nil
+}
+
+func (s *MFAService) deleteChallenge(ctx context.Context, tokenHash string) {
+	if err := s.store.Delete(ctx, mfaChallengeKey(tokenHash)); err != nil {
+		s.logger.Warn("failed to delete MFA challenge", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	s.deleteAttempts(ctx, tokenHash)
+}
+
+func (s *MFAService) deleteAttempts(ctx context.Context, tokenHash string) {
+	if err := s.store.Delete(ctx, mfaAttemptsKey(tokenHash)); err != nil {
+		s.logger.Warn("failed to delete MFA challenge attempts", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+func mfaChallengeKey(tokenHash string) string {
+	return "mfa:challenge:" + tokenHash
+}
+
+func mfaAttemptsKey(tokenHash string) string {
+	return "mfa:challenge:attempts:" + tokenHash
+}
+
+// newRecoveryCodes генерирует коды восстановления в виде XXXXX-XXXXX и их записи с хешами.
+func newRecoveryCodes() ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, 0, models.RecoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, models.RecoveryCodeCount)
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for i := 0; i < models.RecoveryCodeCount; i++ {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to generate recovery code")
+		}
+		code := encoding.EncodeToString(buf)[:recoveryCodeLength]
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		records = append(records, models.RecoveryCode{CodeHash: auth.HashOpaqueToken(code)})
+	}
+	return codes, records, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
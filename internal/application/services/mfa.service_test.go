@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"github.com/merdernoty/anime-service/pkg/auth"
+	"github.com/pquerna/otp/totp"
+	"logur.dev/logur"
+)
+
+type mfaTestUsers struct {
+	repositories.UserRepository
+	user models.User
+}
+
+func (r *mfaTestUsers) GetByID(ctx context.Context, id uint) (models.User, error) {
+	if id != r.user.ID {
+		return models.User{}, models.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+type mfaTestRepo struct {
+	repositories.MFARepository
+	mu      sync.Mutex
+	pending string
+}
+
+func (r *mfaTestRepo) SetPendingTOTPSecret(ctx context.Context, userID uint, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = secret
+	return nil
+}
+
+func (r *mfaTestRepo) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	return models.ErrRecoveryCodeInvalid
+}
+
+// newMFATestService возвращает сервис для пользователя с включенной 2FA и его секрет TOTP.
+func newMFATestService(t *testing.T) (*MFAService, string) {
+	t.Helper()
+
+	secrets, err := auth.NewSecretBox("test-secret", "totp-secret")
+	if err != nil {
+		t.Fatalf("NewSecretBox: %v", err)
+	}
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "test", AccountName: "user@example.com"})
+	if err != nil {
+		t.Fatalf("generate TOTP key: %v", err)
+	}
+	sealed, err := secrets.Seal(key.Secret())
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	user := models.User{TOTPSecret: sealed, TOTPEnabled: true}
+	user.ID = 1
+	users := &mfaTestUsers{user: user}
+	service := NewMFAService(users, &mfaTestRepo{}, cache.NewMemoryStore(time.Minute), secrets, "test", logur.NoopLogger{})
+	return service, key.Secret()
+}
+
+// wrongCode возвращает код, не подходящий ни к одному шагу из окна проверки.
+func wrongCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	now := time.Now()
+	valid := make(map[string]bool)
+	for _, offset := range []time.Duration{-30 * time.Second, 0, 30 * time.Second} {
+		code, err := totp.GenerateCode(secret, now.Add(offset))
+		if err != nil {
+			t.Fatalf("GenerateCode: %v", err)
+		}
+		valid[code] = true
+	}
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if !valid[code] {
+			return code
+		}
+	}
+}
+
+func TestCompleteChallengeLimitsParallelGuesses(t *testing.T) {
+	ctx := context.Background()
+	service, secret := newMFATestService(t)
+
+	token, err := service.CreateChallenge(ctx, 1)
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+
+	const guesses = 50
+	code := wrongCode(t, secret)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	checked := 0
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.CompleteChallenge(ctx, token, code); err == ErrInvalidMFACode {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if checked >= mfaChallengeMaxAttempts {
+		t.Errorf("%d parallel guesses were checked, want fewer than %d", checked, mfaChallengeMaxAttempts)
+	}
+
+	valid, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	if _, err := service.CompleteChallenge(ctx, token, valid); err != ErrInvalidMFAChallenge {
+		t.Errorf("CompleteChallenge after exhausting attempts: err = %v, want %v", err, ErrInvalidMFAChallenge)
+	}
+}
+
+func TestCompleteChallengeIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	service, secret := newMFATestService(t)
+
+	token, err := service.CreateChallenge(ctx, 1)
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+
+	const requests = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.CompleteChallenge(ctx, token, code); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d parallel requests completed the challenge, want 1", succeeded)
+	}
+}
+
+func TestStartEnrollmentStoresEncryptedSecret(t *testing.T) {
+	ctx := context.Background()
+	secrets, err := auth.NewSecretBox("test-secret", "totp-secret")
+	if err != nil {
+		t.Fatalf("NewSecretBox: %v", err)
+	}
+	repo := &mfaTestRepo{}
+	user := models.User{Email: "user@example.com"}
+	user.ID = 1
+	users := &mfaTestUsers{user: user}
+	service := NewMFAService(users, repo, cache.NewMemoryStore(time.Minute), secrets, "test", logur.NoopLogger{})
+
+	enrollment, err := service.StartEnrollment(ctx, 1)
+	if err != nil {
+		t.Fatalf("StartEnrollment: %v", err)
+	}
+	if strings.Contains(repo.pending, enrollment.Secret) {
+		t.Fatal("TOTP secret is stored in plaintext")
+	}
+	opened, err := secrets.Open(repo.pending)
+	if err != nil {
+		t.Fatalf("Open stored secret: %v", err)
+	}
+	if opened != enrollment.Secret {
+		t.Errorf("stored secret decrypts to %q, want %q", opened, enrollment.Secret)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"github.com/merdernoty/anime-service/internal/infrastructure/mail"
+	"github.com/merdernoty/anime-service/pkg/auth"
+	"logur.dev/logur"
+)
+
+var (
+	ErrInvalidResetToken      = errors.New("password reset token is invalid or expired")
+	ErrPasswordResetFailed    = errors.New("failed to reset password")
+	ErrPasswordResetThrottled = errors.New("too many password reset requests, try again later")
+)
+
+const (
+	passwordResetMailTimeout     = 30 * time.Second
+	passwordResetCleanupInterval = time.Hour
+)
+
+// PasswordResetSettings — срок жизни токена сброса и адрес страницы сброса
+// пароля, к которому в письме добавляется параметр token. MaxPerEmail и
+// MaxPerIP ограничивают число запросов за Window; 0 — без ограничения.
+type PasswordResetSettings struct {
+	TokenTTL    time.Duration
+	ResetURL    string
+	MaxPerEmail int
+	MaxPerIP    int
+	Window      time.Duration
+}
+
+// PasswordResetService восстанавливает доступ к аккаунту через одноразовый
+// токен, отправленный на почту пользователя.
+type PasswordResetService struct {
+	users       repositories.UserRepository
+	resetTokens repositories.PasswordResetTokenRepository
+	sessions    *SessionService
+	mailer      mail.Mailer
+	store       cache.Cache
+	settings    PasswordResetSettings
+	logger      logur.LoggerFacade
+}
+
+func NewPasswordResetService(users repositories.UserRepository, resetTokens repositories.PasswordResetTokenRepository, sessions *SessionService, mailer mail.Mailer, store cache.Cache, settings PasswordResetSettings, logger logur.LoggerFacade) *PasswordResetService {
+	return &PasswordResetService{
+		users:       users,
+		resetTokens: resetTokens,
+		sessions:    sessions,
+		mailer:      mailer,
+		store:       store,
+		settings:    settings,
+		logger:      logger,
+	}
+}
+
+// RequestReset принимает запрос на сброс пароля. Синхронно проверяются только
+// лимиты запросов на email и на IP, которые не зависят от того, зарегистрирован
+// ли email; поиск пользователя, выпуск токена и отправка письма выполняются в
+// фоне. Поэтому ни ответ, ни время ответа не выдают, есть ли такой аккаунт.
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string, ip string) error {
+	email = models.NormalizeEmail(email)
+	if s.exceeded(ctx, passwordResetEmailKey(email), s.settings.MaxPerEmail) ||
+		s.exceeded(ctx, passwordResetIPKey(ip), s.settings.MaxPerIP) {
+		s.logger.Warn("password reset requests throttled", map[string]interface{}{
+			"ip": ip,
+		})
+		return ErrPasswordResetThrottled
+	}
+
+	go func() {
+		resetCtx, cancel := context.WithTimeout(context.Background(), passwordResetMailTimeout)
+		defer cancel()
+		s.sendReset(resetCtx, email)
+	}()
+	return nil
+}
+
+// exceeded засчитывает запрос в счетчике key и сообщает, превышен ли лимит.
+// Если счетчик недоступен, запрос пропускается: сброс пароля важнее лимита.
+func (s *PasswordResetService) exceeded(ctx context.Context, key string, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	count, err := s.store.Increment(ctx, key, s.settings.Window)
+	if err != nil {
+		s.logger.Warn("failed to count password reset request", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return false
+	}
+	return count > int64(limit)
+}
+
+// sendReset выпускает токен сброса и отправляет письмо, если email зарегистрирован.
+func (s *PasswordResetService) sendReset(ctx context.Context, email string) {
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		s.logger.Info("password reset requested for unknown email", map[string]interface{}{
+			"email": email,
+		})
+		return
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		s.logger.Error("failed to generate password reset token", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Действителен только последний выданный токен.
+	if err := s.resetTokens.DeleteByUser(ctx, user.ID); err != nil {
+		s.logger.Error("failed to delete previous password reset tokens", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+		return
+	}
+	record := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.settings.TokenTTL),
+	}
+	if err := s.resetTokens.Create(ctx, record); err != nil {
+		s.logger.Error("failed to store password reset token", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	message := mail.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\nСсылка действует %s и может быть использована один раз. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			user.Nickname, s.resetLink(token), s.settings.TokenTTL,
+		),
+	}
+	if err := s.mailer.Send(ctx, message); err != nil {
+		s.logger.Error("failed to send password reset email", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// ResetPassword меняет пароль по токену из письма и завершает все сессии пользователя.
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token string, password string) error {
+	record, err := s.resetTokens.Consume(ctx, auth.HashOpaqueToken(token))
+	if errors.Is(err, models.ErrPasswordResetTokenInvalid) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		s.logger.Error("failed to consume password reset token", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return ErrPasswordResetFailed
+	}
+
+	user, err := s.users.GetByID(ctx, record.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	user.Password = password
+	if err := user.HashPassword(); err != nil {
+		return ErrPasswordResetFailed
+	}
+	if err := s.users.UpdatePassword(ctx, user.ID, user.Password); err != nil {
+		s.logger.Error("failed to update password", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+		return ErrPasswordResetFailed
+	}
+
+	if err := s.resetTokens.DeleteByUser(ctx, user.ID); err != nil {
+		s.logger.Warn("failed to delete password reset tokens", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+	}
+	if err := s.sessions.RevokeAll(ctx, user.ID); err != nil {
+		return ErrPasswordResetFailed
+	}
+
+	s.logger.Info("password reset", map[string]interface{}{
+		"user_id": user.ID,
+	})
+	return nil
+}
+
+func passwordResetEmailKey(email string) string {
+	return "password-reset:email:" + email
+}
+
+func passwordResetIPKey(ip string) string {
+	return "password-reset:ip:" + ip
+}
+
+func (s *PasswordResetService) resetLink(token string) string {
+	link, err := url.Parse(s.settings.ResetURL)
+	if err != nil {
+		return s.settings.ResetURL + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// StartCleanup периодически удаляет истекшие токены сброса до отмены контекста.
+func (s *PasswordResetService) StartCleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(passwordResetCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := s.resetTokens.DeleteExpired(ctx, time.Now())
+				if err != nil && ctx.Err() == nil {
+					s.logger.Error("failed to delete expired password reset tokens", map[string]interface{}{
+						"error": err.Error(),
+					})
+					continue
+				}
+				if deleted > 0 {
+					s.logger.Info("deleted expired password reset tokens", map[string]interface{}{
+						"count": deleted,
+					})
+				}
+			}
+		}
+	}()
+}
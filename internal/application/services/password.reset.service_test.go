@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"github.com/merdernoty/anime-service/internal/infrastructure/mail"
+	"github.com/merdernoty/anime-service/pkg/auth"
+	"logur.dev/logur"
+)
+
+type resetTestUsers struct {
+	repositories.UserRepository
+	user models.User
+	// passwords — хеши, сохраненные через UpdatePassword, по id пользователя.
+	passwords map[uint]string
+}
+
+func (r *resetTestUsers) GetByID(ctx context.Context, id uint) (models.User, error) {
+	if id != r.user.ID {
+		return models.User{}, models.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+func (r *resetTestUsers) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	r.passwords[id] = passwordHash
+	return nil
+}
+
+func (r *resetTestUsers) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	if email != models.NormalizeEmail(r.user.Email) {
+		return models.User{}, models.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+type resetTestTokens struct {
+	repositories.PasswordResetTokenRepository
+}
+
+func (r *resetTestTokens) DeleteByUser(ctx context.Context, userID uint) error {
+	return nil
+}
+
+func (r *resetTestTokens) Consume(ctx context.Context, tokenHash string) (models.PasswordResetToken, error) {
+	if tokenHash != auth.HashOpaqueToken("reset-token") {
+		return models.PasswordResetToken{}, models.ErrPasswordResetTokenInvalid
+	}
+	return models.PasswordResetToken{UserID: 1, TokenHash: tokenHash}, nil
+}
+
+func (r *resetTestTokens) Create(ctx context.Context, token models.PasswordResetToken) error {
+	return nil
+}
+
+type resetTestMailer struct {
+	sent chan mail.Message
+}
+
+func (m *resetTestMailer) Send(ctx context.Context, message mail.Message) error {
+	m.sent <- message
+	return nil
+}
+
+func newResetTestService(settings PasswordResetSettings) (*PasswordResetService, *resetTestMailer) {
+	service, mailer, _ := newResetTestServiceWithUsers(settings)
+	return service, mailer
+}
+
+func newResetTestServiceWithUsers(settings PasswordResetSettings) (*PasswordResetService, *resetTestMailer, *resetTestUsers) {
+	user := models.User{Nickname: "user", Email: "user@example.com", Password: "old-hash"}
+	user.ID = 1
+	users := &resetTestUsers{user: user, passwords: make(map[uint]string)}
+	mailer := &resetTestMailer{sent: make(chan mail.Message, 10)}
+	settings.TokenTTL = time.Hour
+	settings.ResetURL = "http://localhost/reset"
+	settings.Window = time.Hour
+	store := cache.NewMemoryStore(time.Minute)
+	sessions := NewSessionService(&sessionTestRepo{sessions: make(map[string]models.Session)}, &sessionTestTokens{}, store, logur.NoopLogger{})
+	service := NewPasswordResetService(users, &resetTestTokens{}, sessions, mailer, store, settings, logur.NoopLogger{})
+	return service, mailer, users
+}
+
+func TestRequestResetSendsMailInBackground(t *testing.T) {
+	service, mailer := newResetTestService(PasswordResetSettings{})
+
+	if err := service.RequestReset(context.Background(), " User@Example.com ", "10.0.0.1"); err != nil {
+		t.Fatalf("RequestReset: %v", err)
+	}
+	select {
+	case message := <-mailer.sent:
+		if message.To != "user@example.com" || !strings.Contains(message.Body, "http://localhost/reset?token=") {
+			t.Errorf("unexpected message to %q: %q", message.To, message.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("password reset email was not sent")
+	}
+
+	if err := service.RequestReset(context.Background(), "unknown@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("RequestReset for unknown email: %v", err)
+	}
+	select {
+	case message := <-mailer.sent:
+		t.Errorf("email sent for an unknown address to %q", message.To)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRequestResetLimitsPerEmail(t *testing.T) {
+	service, _ := newResetTestService(PasswordResetSettings{MaxPerEmail: 3})
+
+	for i := 0; i < 3; i++ {
+		if err := service.RequestReset(context.Background(), "user@example.com", fmt.Sprintf("10.0.0.%d", i)); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	if err := service.RequestReset(context.Background(), "USER@example.com", "10.0.0.99"); err != ErrPasswordResetThrottled {
+		t.Errorf("request over the email limit: err = %v, want %v", err, ErrPasswordResetThrottled)
+	}
+	if err := service.RequestReset(context.Background(), "unknown@example.com", "10.0.0.99"); err != nil {
+		t.Errorf("request for another email: %v", err)
+	}
+}
+
+func TestRequestResetLimitsPerIP(t *testing.T) {
+	service, _ := newResetTestService(PasswordResetSettings{MaxPerIP: 3})
+
+	for i := 0; i < 3; i++ {
+		if err := service.RequestReset(context.Background(), fmt.Sprintf("user%d@example.com", i), "10.0.0.1"); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	if err := service.RequestReset(context.Background(), "user99@example.com", "10.0.0.1"); err != ErrPasswordResetThrottled {
+		t.Errorf("request over the IP limit: err = %v, want %v", err, ErrPasswordResetThrottled)
+	}
+	if err := service.RequestReset(context.Background(), "user99@example.com", "10.0.0.2"); err != nil {
+		t.Errorf("request from another IP: %v", err)
+	}
+}
+
+func TestResetPasswordUpdatesOnlyPassword(t *testing.T) {
+	service, _, users := newResetTestServiceWithUsers(PasswordResetSettings{})
+
+	if err := service.ResetPassword(context.Background(), "wrong-token", "new-password"); err != ErrInvalidResetToken {
+		t.Errorf("ResetPassword with an unknown token: err = %v, want %v", err, ErrInvalidResetToken)
+	}
+
+	// resetTestUsers не реализует Update: сохранение всей строки вызвало бы панику.
+	if err := service.ResetPassword(context.Background(), "reset-token", "new-password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	hash, ok := users.passwords[1]
+	if !ok {
+		t.Fatal("password was not updated")
+	}
+	updated := models.User{Password: hash}
+	if !updated.CheckPassword("new-password") {
+		t.Error("stored hash does not match the new password")
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"github.com/mssola/useragent"
+	"logur.dev/logur"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionFailed   = errors.New("failed to process session")
+)
+
+const (
+	// sessionCacheTTL ограничивает, как долго middleware доверяет закешированному
+	// состоянию сессии. Отзыв через сервис сбрасывает кеш сразу.
+	sessionCacheTTL = 5 * time.Minute
+
+	// sessionRevoked — закешированное состояние отозванной сессии; для активной
+	// кешируется ID ее владельца.
+	sessionRevoked = "0"
+)
+
+// SessionService ведет учет входов пользователя с разных устройств. Сессия
+// живет, пока живо семейство ее refresh-токенов, и удаляется вместе с ним.
+// Состояние сессий кешируется в общем для всех реплик store, поэтому отзыв
+// на одной реплике сразу виден остальным.
+type SessionService struct {
+	sessions      repositories.SessionRepository
+	refreshTokens repositories.RefreshTokenRepository
+	store         cache.Cache
+	logger        logur.LoggerFacade
+}
+
+func NewSessionService(sessions repositories.SessionRepository, refreshTokens repositories.RefreshTokenRepository, store cache.Cache, logger logur.LoggerFacade) *SessionService {
+	return &SessionService{
+		sessions:      sessions,
+		refreshTokens: refreshTokens,
+		store:         store,
+		logger:        logger,
+	}
+}
+
+// Start открывает сессию с ID семейства refresh-токенов token.FamilyID и
+// сохраняет вместе с ней первый токен семейства.
+func (s *SessionService) Start(ctx context.Context, token models.RefreshToken, ip string, userAgent string) error {
+	session := newSession(token.FamilyID, token.UserID, ip, userAgent)
+	if err := s.sessions.CreateWithRefreshToken(ctx, session, token); err != nil {
+		s.logger.Error("failed to start session", map[string]interface{}{
+			"user_id": token.UserID,
+			"error":   err.Error(),
+		})
+		return ErrSessionFailed
+	}
+
+	s.setCached(ctx, session.ID, activeState(session.UserID))
+	return nil
+}
+
+// Create сохраняет сессию с указанным ID (ID семейства refresh-токенов).
+func (s *SessionService) Create(ctx context.Context, id string, userID uint, ip string, userAgent string) error {
+	session := newSession(id, userID, ip, userAgent)
+	if err := s.sessions.Create(ctx, session); err != nil {
+		s.logger.Error("failed to create session", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return ErrSessionFailed
+	}
+
+	s.setCached(ctx, id, activeState(userID))
+	return nil
+}
+
+// Touch отмечает использование сессии при обновлении токенов. Семейства
+// refresh-токенов, выданные до появления сессий, получают сессию здесь.
+func (s *SessionService) Touch(ctx context.Context, id string, userID uint, ip string, userAgent string) error {
+	session := models.Session{
+		ID:         id,
+		IP:         ip,
+		LastUsedAt: time.Now(),
+	}
+	setUserAgent(&session, userAgent)
+
+	err := s.sessions.Touch(ctx, session)
+	if errors.Is(err, models.ErrSessionNotFound) {
+		return s.Create(ctx, id, userID, ip, userAgent)
+	}
+	if err != nil {
+		s.logger.Error("failed to update session last use", map[string]interface{}{
+			"session_id": id,
+			"error":      err.Error(),
+		})
+		return ErrSessionFailed
+	}
+	return nil
+}
+
+// IsActive сообщает, что сессия пользователя существует и не отозвана.
+// Результат кешируется на sessionCacheTTL вместе с владельцем сессии.
+func (s *SessionService) IsActive(ctx context.Context, id string, userID uint) (bool, error) {
+	data, err := s.store.Get(ctx, sessionCacheKey(id))
+	if err == nil {
+		return string(data) == activeState(userID), nil
+	}
+	if !errors.Is(err, cache.ErrCacheMiss) {
+		s.logger.Warn("failed to read session from cache", map[string]interface{}{
+			"session_id": id,
+			"error":      err.Error(),
+		})
+	}
+
+	// Прочитанное из базы состояние кешируется, только если его не успел
+	// записать отзыв: иначе параллельный отзыв затерся бы устаревшим ответом.
+	session, err := s.sessions.GetByID(ctx, id)
+	if errors.Is(err, models.ErrSessionNotFound) {
+		s.addCached(ctx, id, sessionRevoked)
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to load session")
+	}
+
+	s.addCached(ctx, id, activeState(session.UserID))
+	return session.UserID == userID, nil
+}
+
+// List возвращает активные сессии пользователя, последние использованные первыми.
+func (s *SessionService) List(ctx context.Context, userID uint) ([]models.Session, error) {
+	sessions, err := s.sessions.ListByUser(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to list sessions", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil, ErrSessionFailed
+	}
+	return sessions, nil
+}
+
+// Revoke завершает сессию пользователя. Чужие сессии считаются несуществующими.
+func (s *SessionService) Revoke(ctx context.Context, userID uint, id string) error {
+	session, err := s.sessions.GetByID(ctx, id)
+	if errors.Is(err, models.ErrSessionNotFound) || (err == nil && session.UserID != userID) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		s.logger.Error("failed to load session", map[string]interface{}{
+			"session_id": id,
+			"error":      err.Error(),
+		})
+		return ErrSessionFailed
+	}
+
+	return s.End(ctx, id)
+}
+
+// End удаляет сессию вместе с ее refresh-токенами без проверки владельца.
+func (s *SessionService) End(ctx context.Context, id string) error {
+	if err := s.refreshTokens.DeleteFamily(ctx, id); err != nil {
+		s.logger.Error("failed to revoke session refresh tokens", map[string]interface{}{
+			"session_id": id,
+			"error":      err.Error(),
+		})
+		return ErrSessionFailed
+	}
+
+	if err := s.sessions.Delete(ctx, id); err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+		s.logger.Error("failed to delete session", map[string]interface{}{
+			"session_id": id,
+			"error":      err.Error(),
+		})
+		return ErrSessionFailed
+	}
+
+	s.setCached(ctx, id, sessionRevoked)
+	return nil
+}
+
+// RevokeAll завершает все сессии пользователя.
+func (s *SessionService) RevokeAll(ctx context.Context, userID uint) error {
+	if err := s.refreshTokens.DeleteByUser(ctx, userID); err != nil {
+		s.logger.Error("failed to revoke user refresh tokens", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return ErrSessionFailed
+	}
+
+	ids, err := s.sessions.DeleteByUser(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to delete user sessions", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return ErrSessionFailed
+	}
+
+	for _, id := range ids {
+		s.setCached(ctx, id, sessionRevoked)
+	}
+	return nil
+}
+
+// DeleteInactive удаляет сессии, не использовавшиеся с момента before.
+func (s *SessionService) DeleteInactive(ctx context.Context, before time.Time) (int64, error) {
+	return s.sessions.DeleteInactive(ctx, before)
+}
+
+func (s *SessionService) setCached(ctx context.Context, id string, state string) {
+	if err := s.store.Set(ctx, sessionCacheKey(id), []byte(state), sessionCacheTTL); err != nil {
+		s.logger.Warn("failed to cache session state", map[string]interface{}{
+			"session_id": id,
+			"error":      err.Error(),
+		})
+	}
+}
+
+func (s *SessionService) addCached(ctx context.Context, id string, state string) {
+	if _, err := s.store.Add(ctx, sessionCacheKey(id), []byte(state), sessionCacheTTL); err != nil {
+		s.logger.Warn("failed to cache session state", map[string]interface{}{
+			"session_id": id,
+			"error":      err.Error(),
+		})
+	}
+}
+
+func activeState(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+func newSession(id string, userID uint, ip string, userAgent string) models.Session {
+	now := time.Now()
+	session := models.Session{
+		ID:         id,
+		UserID:     userID,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	setUserAgent(&session, userAgent)
+	return session
+}
+
+func sessionCacheKey(id string) string {
+	return "session:" + id
+}
+
+// setUserAgent сохраняет заголовок User-Agent и разобранные из него браузер, ОС и тип устройства.
+func setUserAgent(session *models.Session, userAgent string) {
+	const maxUserAgentLength = 512
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session.UserAgent = userAgent
+
+	ua := useragent.New(userAgent)
+	name, version := ua.Browser()
+	session.Browser = name
+	if version != "" {
+		session.Browser += " " + version
+	}
+	session.OS = ua.OS()
+
+	switch {
+	case ua.Bot():
+		session.Device = models.SessionDeviceBot
+	case ua.Mobile():
+		session.Device = models.SessionDeviceMobile
+	default:
+		session.Device = models.SessionDeviceDesktop
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"logur.dev/logur"
+)
+
+type sessionTestRepo struct {
+	repositories.SessionRepository
+	mu       sync.Mutex
+	sessions map[string]models.Session
+}
+
+func (r *sessionTestRepo) CreateWithRefreshToken(ctx context.Context, session models.Session, token models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session.ID] = session
+	return nil
+}
+
+func (r *sessionTestRepo) GetByID(ctx context.Context, id string) (models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	if !ok {
+		return models.Session{}, models.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (r *sessionTestRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *sessionTestRepo) DeleteByUser(ctx context.Context, userID uint) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []string
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			ids = append(ids, id)
+			delete(r.sessions, id)
+		}
+	}
+	return ids, nil
+}
+
+type sessionTestTokens struct {
+	repositories.RefreshTokenRepository
+}
+
+func (r *sessionTestTokens) DeleteFamily(ctx context.Context, familyID string) error {
+	return nil
+}
+
+func (r *sessionTestTokens) DeleteByUser(ctx context.Context, userID uint) error {
+	return nil
+}
+
+// newSessionTestReplicas возвращает два экземпляра сервиса с общими базой и
+// store, как у двух реплик API.
+func newSessionTestReplicas() (*SessionService, *SessionService, *sessionTestRepo) {
+	repo := &sessionTestRepo{sessions: make(map[string]models.Session)}
+	store := cache.NewMemoryStore(time.Minute)
+	first := NewSessionService(repo, &sessionTestTokens{}, store, logur.NoopLogger{})
+	second := NewSessionService(repo, &sessionTestTokens{}, store, logur.NoopLogger{})
+	return first, second, repo
+}
+
+func TestIsActiveChecksOwnerOnCacheHit(t *testing.T) {
+	ctx := context.Background()
+	service, _, _ := newSessionTestReplicas()
+
+	if err := service.Start(ctx, models.RefreshToken{UserID: 1, FamilyID: "family"}, "10.0.0.1", ""); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if active, err := service.IsActive(ctx, "family", 1); err != nil || !active {
+		t.Errorf("IsActive for the owner = (%v, %v), want (true, nil)", active, err)
+	}
+	if active, err := service.IsActive(ctx, "family", 2); err != nil || active {
+		t.Errorf("IsActive for another user = (%v, %v), want (false, nil)", active, err)
+	}
+}
+
+func TestEndIsVisibleToOtherReplicas(t *testing.T) {
+	ctx := context.Background()
+	first, second, _ := newSessionTestReplicas()
+
+	if err := first.Start(ctx, models.RefreshToken{UserID: 1, FamilyID: "family"}, "10.0.0.1", ""); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if active, _ := second.IsActive(ctx, "family", 1); !active {
+		t.Fatal("session is not active on the second replica")
+	}
+
+	if err := first.End(ctx, "family"); err != nil {
+		t.Fatalf("End: %v", err)
+	}
+	if active, _ := second.IsActive(ctx, "family", 1); active {
+		t.Error("ended session is still active on the second replica")
+	}
+}
+
+func TestIsActiveDoesNotOverwriteRevocation(t *testing.T) {
+	ctx := context.Background()
+	service, _, repo := newSessionTestReplicas()
+
+	// Состояние, прочитанное из базы до отзыва, не должно затереть отзыв в кеше.
+	repo.sessions["family"] = models.Session{ID: "family", UserID: 1}
+	if err := service.End(ctx, "family"); err != nil {
+		t.Fatalf("End: %v", err)
+	}
+	repo.sessions["family"] = models.Session{ID: "family", UserID: 1}
+	service.addCached(ctx, "family", activeState(1))
+
+	if active, _ := service.IsActive(ctx, "family", 1); active {
+		t.Error("stale database state overwrote the cached revocation")
+	}
+}
@@ -1,40 +1,48 @@
 package services
 
 import (
+	"context"
+
 	"emperror.dev/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/merdernoty/anime-service/internal/domain/dtos"
+	"github.com/merdernoty/anime-service/internal/domain/models"
 	"github.com/merdernoty/anime-service/internal/domain/repositories"
 	"logur.dev/logur"
 )
 
+var ErrCannotChangeOwnRole = errors.New("users cannot change their own role")
+
 type UserServiceImlp struct {
-	repo repositories.UserRepository
-	logger         logur.LoggerFacade
+	repo              repositories.UserRepository
+	emailVerification *EmailVerificationService
+	logger            logur.LoggerFacade
 }
 
-
-func NewUserService(repo repositories.UserRepository, logger logur.LoggerFacade) *UserServiceImlp {
+func NewUserService(repo repositories.UserRepository, emailVerification *EmailVerificationService, logger logur.LoggerFacade) *UserServiceImlp {
 	return &UserServiceImlp{
-		repo:   repo,
-		logger: logger,
+		repo:              repo,
+		emailVerification: emailVerification,
+		logger:            logger,
 	}
 }
 
 func (s *UserServiceImlp) GetUserProfile(ctx *gin.Context, id uint) (dtos.UserResponseDTO, error) {
-	user, err:= s.repo.GetByID(ctx, id)
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return dtos.UserResponseDTO{}, ErrUserNotFound
 	}
 	user.Password = ""
 	return dtos.UserResponseDTO{
-		ID:        user.ID,
-		Email:     user.Email,
-		NickName:  user.Nickname,
-		FirstName: user.Firstname,
-		LastName:  user.Lastname,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		Email:           user.Email,
+		NickName:        user.Nickname,
+		FirstName:       user.Firstname,
+		LastName:        user.Lastname,
+		Role:            string(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
 	}, nil
 }
 
@@ -44,23 +52,29 @@ func (s *UserServiceImlp) UpdateUserProfile(ctx *gin.Context, id uint, dto dtos.
 		return dtos.UserResponseDTO{}, ErrUserNotFound
 	}
 
-	if dto.NickName != "" {
-		if dto.NickName != user.Nickname {
-			existingUser, err := s.repo.GetByNickName(ctx, dto.NickName)
+	if nickname := models.NormalizeNickname(dto.NickName); nickname != "" {
+		if err := models.ValidateNickname(nickname); err != nil {
+			return dtos.UserResponseDTO{}, err
+		}
+		if nickname != user.Nickname {
+			existingUser, err := s.repo.GetByNickName(ctx, nickname)
 			if err == nil && existingUser.ID != user.ID {
 				return dtos.UserResponseDTO{}, errors.New("nickname already exists")
 			}
-			user.Nickname = dto.NickName
+			user.Nickname = nickname
 		}
 	}
 
-	if dto.Email != "" {
-		if dto.Email != user.Email {
-			existingUser, err := s.repo.GetByEmail(ctx, dto.Email)
+	emailChanged := false
+	if email := models.NormalizeEmail(dto.Email); email != "" {
+		if email != models.NormalizeEmail(user.Email) {
+			existingUser, err := s.repo.GetByEmail(ctx, email)
 			if err == nil && existingUser.ID != user.ID {
 				return dtos.UserResponseDTO{}, errors.New("пользователь с таким email уже существует")
 			}
-			user.Email = dto.Email
+			user.Email = email
+			user.EmailVerifiedAt = nil
+			emailChanged = true
 		}
 	}
 
@@ -72,11 +86,91 @@ func (s *UserServiceImlp) UpdateUserProfile(ctx *gin.Context, id uint, dto dtos.
 		user.Lastname = dto.LastName
 	}
 
-
-    updatedUser, err := s.repo.Update(ctx, user)
+	updatedUser, err := s.repo.Update(ctx, user)
 	if err != nil {
 		return dtos.UserResponseDTO{}, err
-	} 
+	}
+
+	if emailChanged {
+		// Письмо отправляется не чаще интервала повторной отправки, чтобы сменой
+		// email нельзя было засыпать письмами чужой адрес. Остальные ошибки уже
+		// залогированы; письмо можно запросить повторно.
+		if err := s.emailVerification.SendVerification(ctx, updatedUser); errors.Is(err, ErrVerificationThrottled) {
+			s.logger.Info("verification email throttled after email change", map[string]interface{}{
+				"user_id": updatedUser.ID,
+			})
+		}
+	}
 
 	return dtos.ToUserResponse(updatedUser), nil
 }
+
+// UpdateUserRole меняет роль пользователя. Администратор не может изменить
+// собственную роль, чтобы не остаться без администраторов.
+func (s *UserServiceImlp) UpdateUserRole(ctx context.Context, actorID uint, userID uint, role models.Role) (dtos.UserResponseDTO, error) {
+	if !role.IsValid() {
+		return dtos.UserResponseDTO{}, models.ErrInvalidRole
+	}
+	if actorID == userID {
+		return dtos.UserResponseDTO{}, ErrCannotChangeOwnRole
+	}
+
+	if err := s.repo.UpdateRole(ctx, userID, role); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return dtos.UserResponseDTO{}, ErrUserNotFound
+		}
+		return dtos.UserResponseDTO{}, errors.Wrap(err, "failed to update user role")
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return dtos.UserResponseDTO{}, ErrUserNotFound
+	}
+
+	s.logger.Info("user role changed", map[string]interface{}{
+		"actor_id": actorID,
+		"user_id":  userID,
+		"role":     role,
+	})
+
+	return dtos.ToUserResponse(user), nil
+}
+
+// BootstrapAdmin назначает первого администратора при старте сервиса: владельца
+// email, только если адрес подтвержден и других администраторов еще нет. Иначе
+// назначение пропускается с предупреждением — адрес может занять любой
+// пользователь, а разжалованный администратор не должен вернуть себе роль
+// перезапуском.
+func (s *UserServiceImlp) BootstrapAdmin(ctx context.Context, email string) error {
+	skip := func(reason string) error {
+		s.logger.Warn("bootstrap admin skipped", map[string]interface{}{
+			"email":  email,
+			"reason": reason,
+		})
+		return nil
+	}
+
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		return skip("user not found")
+	}
+	if user.Role == models.RoleAdmin {
+		return nil
+	}
+	if !user.IsEmailVerified() {
+		return skip("email is not verified")
+	}
+
+	if err := s.repo.PromoteFirstAdmin(ctx, user.ID); err != nil {
+		if errors.Is(err, models.ErrAdminExists) {
+			return skip("an admin already exists")
+		}
+		return errors.Wrap(err, "failed to promote admin")
+	}
+
+	s.logger.Info("bootstrap admin promoted", map[string]interface{}{
+		"user_id": user.ID,
+		"email":   email,
+	})
+	return nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/domain/dtos"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"logur.dev/logur"
+)
+
+type bootstrapTestUsers struct {
+	repositories.UserRepository
+	users []models.User
+}
+
+func (r *bootstrapTestUsers) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	for _, user := range r.users {
+		if models.NormalizeEmail(user.Email) == models.NormalizeEmail(email) {
+			return user, nil
+		}
+	}
+	return models.User{}, models.ErrUserNotFound
+}
+
+func (r *bootstrapTestUsers) PromoteFirstAdmin(ctx context.Context, id uint) error {
+	for _, user := range r.users {
+		if user.Role == models.RoleAdmin {
+			return models.ErrAdminExists
+		}
+	}
+	for i := range r.users {
+		if r.users[i].ID == id {
+			r.users[i].Role = models.RoleAdmin
+			return nil
+		}
+	}
+	return models.ErrUserNotFound
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	verifiedAt := time.Now()
+	newUser := func(id uint, email string, role models.Role, verified bool) models.User {
+		user := models.User{Email: email, Role: role}
+		user.ID = id
+		if verified {
+			user.EmailVerifiedAt = &verifiedAt
+		}
+		return user
+	}
+
+	tests := []struct {
+		name  string
+		users []models.User
+		admin bool
+	}{
+		{
+			name:  "verified email and no admin",
+			users: []models.User{newUser(1, "admin@example.com", models.RoleUser, true)},
+			admin: true,
+		},
+		{
+			name:  "unverified email",
+			users: []models.User{newUser(1, "admin@example.com", models.RoleUser, false)},
+		},
+		{
+			name: "admin already exists",
+			users: []models.User{
+				newUser(1, "admin@example.com", models.RoleUser, true),
+				newUser(2, "other@example.com", models.RoleAdmin, true),
+			},
+		},
+		{
+			name:  "no such user",
+			users: []models.User{newUser(1, "user@example.com", models.RoleUser, true)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &bootstrapTestUsers{users: tt.users}
+			service := NewUserService(repo, nil, logur.NoopLogger{})
+
+			if err := service.BootstrapAdmin(context.Background(), "Admin@Example.com"); err != nil {
+				t.Fatalf("BootstrapAdmin: %v", err)
+			}
+			if promoted := repo.users[0].Role == models.RoleAdmin; promoted != tt.admin {
+				t.Errorf("user promoted = %v, want %v", promoted, tt.admin)
+			}
+		})
+	}
+}
+
+type profileTestUsers struct {
+	repositories.UserRepository
+	user models.User
+}
+
+func (r *profileTestUsers) GetByID(ctx context.Context, id uint) (models.User, error) {
+	return r.user, nil
+}
+
+func (r *profileTestUsers) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	return models.User{}, models.ErrUserNotFound
+}
+
+func (r *profileTestUsers) Update(ctx context.Context, user models.User) (models.User, error) {
+	r.user = user
+	return user, nil
+}
+
+func TestUpdateUserProfileThrottlesVerificationEmails(t *testing.T) {
+	user := models.User{Nickname: "user", Email: "user@example.com"}
+	user.ID = 1
+	repo := &profileTestUsers{user: user}
+	verification, mailer := newVerificationTestService(t, repo)
+	service := NewUserService(repo, verification, logur.NoopLogger{})
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	for _, email := range []string{"first@example.com", "second@example.com", "third@example.com"} {
+		if _, err := service.UpdateUserProfile(ctx, 1, dtos.UpdateUserDTO{Email: email}); err != nil {
+			t.Fatalf("UpdateUserProfile(%s): %v", email, err)
+		}
+	}
+
+	select {
+	case message := <-mailer.sent:
+		if message.To != "first@example.com" {
+			t.Errorf("verification email sent to %q, want first@example.com", message.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("verification email was not sent")
+	}
+	select {
+	case message := <-mailer.sent:
+		t.Errorf("second verification email within the cooldown sent to %q", message.To)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestUpdateUserProfileRejectsNicknameWithAt(t *testing.T) {
+	user := models.User{Nickname: "user_name", Email: "user@example.com"}
+	user.ID = 1
+	repo := &profileTestUsers{user: user}
+	service := NewUserService(repo, nil, logur.NoopLogger{})
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if _, err := service.UpdateUserProfile(ctx, 1, dtos.UpdateUserDTO{NickName: "other@example.com"}); err != models.ErrInvalidNickname {
+		t.Fatalf("UpdateUserProfile with @ in nickname: err = %v, want %v", err, models.ErrInvalidNickname)
+	}
+	if repo.user.Nickname != "user_name" {
+		t.Errorf("nickname changed to %q", repo.user.Nickname)
+	}
+}
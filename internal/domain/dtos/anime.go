@@ -1,5 +1,13 @@
 package dtos
-import "github.com/merdernoty/anime-service/internal/domain/models"
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+)
 
 type AnimeResponse struct {
 	MALId         int64         `json:"mal_id" example:"5114"`
@@ -19,6 +27,81 @@ type AnimeResponse struct {
 	Genres        []GenreObject `json:"genres,omitempty"`
 }
 
+type SearchAnimeRequest struct {
+	Query          string   `form:"query" example:"fullmetal"`
+	Type           string   `form:"type" binding:"omitempty,oneof=tv movie ova special ona music cm pv tv_special" example:"tv"`
+	Status         string   `form:"status" binding:"omitempty,oneof=airing complete upcoming" example:"complete"`
+	Rating         string   `form:"rating" binding:"omitempty,oneof=g pg pg13 r17 r rx" example:"pg13"`
+	Genres         string   `form:"genres" example:"1,2"`
+	ExcludedGenres string   `form:"genres_exclude" example:"12"`
+	MinScore       *float64 `form:"min_score" binding:"omitempty,min=0,max=10" example:"7.5"`
+	MaxScore       *float64 `form:"max_score" binding:"omitempty,min=0,max=10" example:"10"`
+	StartDate      string   `form:"start_date" binding:"omitempty,datetime=2006-01-02" example:"2009-04-05"`
+	EndDate        string   `form:"end_date" binding:"omitempty,datetime=2006-01-02" example:"2010-07-04"`
+	OrderBy        string   `form:"order_by" binding:"omitempty,oneof=mal_id title start_date end_date episodes score scored_by rank popularity members favorites" example:"score"`
+	Sort           string   `form:"sort" binding:"omitempty,oneof=asc desc" example:"desc"`
+	SFW            bool     `form:"sfw" example:"true"`
+	Page           int      `form:"page" example:"1"`
+	Limit          int      `form:"limit" example:"10"`
+}
+
+// ToAnimeSearch преобразует параметры запроса в фильтр поиска, проверяя списки жанров и диапазоны.
+func (r SearchAnimeRequest) ToAnimeSearch() (models.AnimeSeatch, error) {
+	genres, err := parseIDList(r.Genres)
+	if err != nil {
+		return models.AnimeSeatch{}, errors.Wrap(err, "invalid genres")
+	}
+
+	excludedGenres, err := parseIDList(r.ExcludedGenres)
+	if err != nil {
+		return models.AnimeSeatch{}, errors.Wrap(err, "invalid genres_exclude")
+	}
+
+	if r.MinScore != nil && r.MaxScore != nil && *r.MinScore > *r.MaxScore {
+		return models.AnimeSeatch{}, errors.New("min_score must not be greater than max_score")
+	}
+
+	if r.StartDate != "" && r.EndDate != "" && r.StartDate > r.EndDate {
+		return models.AnimeSeatch{}, errors.New("start_date must not be after end_date")
+	}
+
+	return models.AnimeSeatch{
+		Query:          r.Query,
+		Type:           r.Type,
+		Status:         r.Status,
+		Rating:         r.Rating,
+		Genres:         genres,
+		ExcludedGenres: excludedGenres,
+		MinScore:       r.MinScore,
+		MaxScore:       r.MaxScore,
+		StartDate:      r.StartDate,
+		EndDate:        r.EndDate,
+		OrderBy:        r.OrderBy,
+		Sort:           r.Sort,
+		SFW:            r.SFW,
+		Page:           r.Page,
+		Limit:          r.Limit,
+	}, nil
+}
+
+func parseIDList(value string) ([]int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	ids := make([]int64, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || id <= 0 {
+			return nil, errors.Errorf("%q is not a valid ID", part)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 type GenreObject struct {
 	ID   int64  `json:"id" example:"1"`
 	Name string `json:"name" example:"Action"`
@@ -32,19 +115,33 @@ type AnimeListResponse struct {
 }
 
 type UserAnimeResponse struct {
-	ID             uint              `json:"id" example:"1"`
-	UserID         uint              `json:"user_id" example:"42"`
-	AnimeMALID     int64             `json:"anime_mal_id" example:"5114"`
-	Status         models.WatchStatus `json:"status" example:"watching"`
-	Rating         float32           `json:"rating" example:"9.5"`
-	Notes          string            `json:"notes,omitempty" example:"My favorite anime!"`
-	EpisodesWatched int              `json:"episodes_watched" example:"24"`
-	AnimeTitle     string            `json:"anime_title" example:"Fullmetal Alchemist: Brotherhood"`
-	AnimeImage     string            `json:"anime_image" example:"https://cdn.myanimelist.net/images/anime/1223/96541.jpg"`
-	AnimeType      string            `json:"anime_type" example:"TV"`
-	AnimeEpisodes  int               `json:"anime_episodes" example:"64"`
-	AnimeStatus    string            `json:"anime_status" example:"Finished Airing"`
-	AnimeScore     float64           `json:"anime_score" example:"9.16"`
+	ID              uint               `json:"id" example:"1"`
+	UserID          uint               `json:"user_id" example:"42"`
+	AnimeMALID      int64              `json:"anime_mal_id" example:"5114"`
+	Status          models.WatchStatus `json:"status" example:"watching"`
+	Rating          float32            `json:"rating" example:"9.5"`
+	Notes           string             `json:"notes,omitempty" example:"My favorite anime!"`
+	EpisodesWatched int                `json:"episodes_watched" example:"24"`
+	RewatchCount    int                `json:"rewatch_count" example:"1"`
+	RewatchEpisodes int                `json:"rewatch_episodes" example:"12"`
+	StartedAt       *time.Time         `json:"started_at,omitempty" example:"2025-01-10T20:00:00Z"`
+	FinishedAt      *time.Time         `json:"finished_at,omitempty" example:"2025-03-02T22:30:00Z"`
+	AnimeTitle      string             `json:"anime_title" example:"Fullmetal Alchemist: Brotherhood"`
+	AnimeImage      string             `json:"anime_image" example:"https://cdn.myanimelist.net/images/anime/1223/96541.jpg"`
+	AnimeType       string             `json:"anime_type" example:"TV"`
+	AnimeEpisodes   int                `json:"anime_episodes" example:"64"`
+	AnimeStatus     string             `json:"anime_status" example:"Finished Airing"`
+	AnimeScore      float64            `json:"anime_score" example:"9.16"`
+	Tags            []string           `json:"tags" example:"favorite,classic"`
+	Version         int64              `json:"version" example:"3"`
+}
+
+// UserAnimeConflictResponse — ответ 412: версия записи не совпала с If-Match,
+// в current — актуальное состояние записи.
+type UserAnimeConflictResponse struct {
+	Error   string            `json:"error" example:"precondition failed"`
+	Details string            `json:"details" example:"user anime version mismatch"`
+	Current UserAnimeResponse `json:"current"`
 }
 
 type UserAnimeListResponse struct {
@@ -59,23 +156,158 @@ type StatsResponse struct {
 	TotalPlanToWatch int     `json:"total_plan_to_watch" example:"30"`
 	TotalWatching    int     `json:"total_watching" example:"5"`
 	TotalWaiting     int     `json:"total_waiting" example:"10"`
+	TotalDropped     int     `json:"total_dropped" example:"3"`
+	TotalOnHold      int     `json:"total_on_hold" example:"2"`
+	TotalRewatching  int     `json:"total_rewatching" example:"1"`
+	TotalRewatches   int     `json:"total_rewatches" example:"4"`
 	TotalEpisodes    int     `json:"total_episodes" example:"347"`
 	AverageRating    float64 `json:"average_rating" example:"8.75"`
 }
 
 type AddAnimeRequest struct {
 	AnimeMALID int64              `json:"anime_mal_id" binding:"required" example:"5114"`
-	Status     models.WatchStatus `json:"status" binding:"required" example:"watching"`
+	Status     models.WatchStatus `json:"status" binding:"required,oneof=watched plan_to_watch watching waiting dropped on_hold" example:"watching"`
 }
 
+// UpdateStatusRequest — новый статус записи. Здесь и в остальных запросах на
+// изменение записи поле version можно передать вместо заголовка If-Match.
 type UpdateStatusRequest struct {
-	Status models.WatchStatus `json:"status" binding:"required" example:"watched"`
+	Status  models.WatchStatus `json:"status" binding:"required,oneof=watched plan_to_watch watching waiting dropped on_hold rewatching" example:"watched"`
+	Version int64              `json:"version" binding:"omitempty,min=1" example:"3"`
 }
 
 type UpdateEpisodesRequest struct {
-	EpisodesWatched int `json:"episodes_watched" binding:"required" example:"24"`
+	EpisodesWatched *int  `json:"episodes_watched" binding:"required,min=0" example:"24"`
+	Version         int64 `json:"version" binding:"omitempty,min=1" example:"3"`
+}
+
+// UpdateEpisodesResponse — прогресс и итоговый статус после обновления эпизодов.
+type UpdateEpisodesResponse struct {
+	Message         string             `json:"message" example:"Количество просмотренных эпизодов успешно обновлено"`
+	AnimeMALID      int64              `json:"anime_mal_id" example:"5114"`
+	EpisodesWatched int                `json:"episodes_watched" example:"64"`
+	Status          models.WatchStatus `json:"status" example:"watched"`
+	RewatchEpisodes int                `json:"rewatch_episodes" example:"0"`
+	Version         int64              `json:"version" example:"4"`
+}
+
+// MarkEpisodesRequest отмечает эпизоды from..to; без to отмечается один эпизод.
+type MarkEpisodesRequest struct {
+	From      int        `json:"from" binding:"required,min=1" example:"1"`
+	To        int        `json:"to" binding:"omitempty,gtefield=From" example:"12"`
+	WatchedAt *time.Time `json:"watched_at" example:"2025-03-02T22:30:00Z"`
+	Rating    *float32   `json:"rating" binding:"omitempty,min=0,max=10" example:"8"`
+	Version   int64      `json:"version" binding:"omitempty,min=1" example:"3"`
+}
+
+// UnmarkEpisodesRequest снимает отметки с эпизодов from..to; без to — с одного эпизода.
+type UnmarkEpisodesRequest struct {
+	From    int   `form:"from" binding:"required,min=1" example:"1"`
+	To      int   `form:"to" binding:"omitempty,gtefield=From" example:"12"`
+	Version int64 `form:"version" binding:"omitempty,min=1" example:"3"`
+}
+
+type EpisodeWatchResponse struct {
+	ID            uint      `json:"id" example:"1"`
+	Episode       int       `json:"episode" example:"5"`
+	RewatchNumber int       `json:"rewatch_number" example:"0"`
+	Unwatched     bool      `json:"unwatched" example:"false"`
+	Rating        *float32  `json:"rating,omitempty" example:"8"`
+	WatchedAt     time.Time `json:"watched_at" example:"2025-03-02T22:30:00Z"`
+}
+
+type EpisodeWatchHistoryResponse struct {
+	Items []EpisodeWatchResponse `json:"items"`
+}
+
+// UpdateTrackingRequest полностью заменяет даты просмотра и счетчики пересмотров;
+// отсутствующая дата очищается.
+type UpdateTrackingRequest struct {
+	StartedAt       *time.Time `json:"started_at" example:"2025-01-10T20:00:00Z"`
+	FinishedAt      *time.Time `json:"finished_at" example:"2025-03-02T22:30:00Z"`
+	RewatchCount    int        `json:"rewatch_count" binding:"min=0" example:"1"`
+	RewatchEpisodes int        `json:"rewatch_episodes" binding:"min=0" example:"12"`
+	Version         int64      `json:"version" binding:"omitempty,min=1" example:"3"`
+}
+
+func (r UpdateTrackingRequest) ToTracking() models.UserAnimeTracking {
+	return models.UserAnimeTracking{
+		StartedAt:       r.StartedAt,
+		FinishedAt:      r.FinishedAt,
+		RewatchCount:    r.RewatchCount,
+		RewatchEpisodes: r.RewatchEpisodes,
+	}
+}
+
+// UserAnimeTrackingResponse — даты просмотра и счетчики пересмотров записи.
+type UserAnimeTrackingResponse struct {
+	AnimeMALID      int64              `json:"anime_mal_id" example:"5114"`
+	Status          models.WatchStatus `json:"status" example:"watched"`
+	RewatchCount    int                `json:"rewatch_count" example:"1"`
+	RewatchEpisodes int                `json:"rewatch_episodes" example:"12"`
+	StartedAt       *time.Time         `json:"started_at,omitempty" example:"2025-01-10T20:00:00Z"`
+	FinishedAt      *time.Time         `json:"finished_at,omitempty" example:"2025-03-02T22:30:00Z"`
+	Version         int64              `json:"version" example:"4"`
+}
+
+// PatchUserAnimeRequest — JSON Merge Patch (RFC 7396) записи списка: отсутствующее
+// поле не меняется, null сбрасывает значение, tags заменяются целиком.
+type PatchUserAnimeRequest struct {
+	Status          models.Optional[models.WatchStatus] `json:"status" swaggertype:"string" enums:"watched,plan_to_watch,watching,waiting,dropped,on_hold,rewatching" example:"watching"`
+	EpisodesWatched models.Optional[int]                `json:"episodes_watched" swaggertype:"integer" example:"12"`
+	Rating          models.Optional[float32]            `json:"rating" swaggertype:"number" example:"8.5"`
+	Notes           models.Optional[string]             `json:"notes" swaggertype:"string" example:"Пересмотреть с друзьями"`
+	StartedAt       models.Optional[*time.Time]         `json:"started_at" swaggertype:"string" format:"date-time" example:"2025-01-10T20:00:00Z"`
+	FinishedAt      models.Optional[*time.Time]         `json:"finished_at" swaggertype:"string" format:"date-time" example:"2025-03-02T22:30:00Z"`
+	Tags            models.Optional[[]string]           `json:"tags" swaggertype:"array,string" example:"favorite,classic"`
+	Version         int64                               `json:"version" binding:"omitempty,min=1" example:"3"`
+}
+
+func (r PatchUserAnimeRequest) ToPatch() models.UserAnimePatch {
+	return models.UserAnimePatch{
+		Status:          r.Status,
+		EpisodesWatched: r.EpisodesWatched,
+		Rating:          r.Rating,
+		Notes:           r.Notes,
+		StartedAt:       r.StartedAt,
+		FinishedAt:      r.FinishedAt,
+		Tags:            r.Tags,
+	}
 }
 
 type UpdateRatingRequest struct {
-	Rating float32 `json:"rating" binding:"required,min=0,max=10" example:"9.5"`
+	Rating  float32 `json:"rating" binding:"required,min=0,max=10" example:"9.5"`
+	Version int64   `json:"version" binding:"omitempty,min=1" example:"3"`
+}
+
+// BulkUserAnimeOperation — операция пакетного запроса. status нужен для add и
+// set_status, rating — для set_rating, episodes_watched — для set_episodes.
+type BulkUserAnimeOperation struct {
+	Action          models.BulkAction  `json:"action" binding:"required,oneof=add remove set_status set_rating set_episodes" example:"add"`
+	AnimeMALID      int64              `json:"anime_mal_id" binding:"required,min=1" example:"5114"`
+	Status          models.WatchStatus `json:"status" example:"plan_to_watch"`
+	Rating          *float32           `json:"rating" binding:"omitempty,min=0,max=10" example:"9.5"`
+	EpisodesWatched *int               `json:"episodes_watched" binding:"omitempty,min=0" example:"12"`
+	Version         int64              `json:"version" binding:"omitempty,min=1" example:"3"`
+}
+
+// BulkUserAnimeRequest — до models.MaxBulkOperations операций над списком, выполняемых в одной транзакции.
+type BulkUserAnimeRequest struct {
+	Mode       models.BulkMode          `json:"mode" binding:"omitempty,oneof=all_or_nothing best_effort" example:"all_or_nothing"`
+	Operations []BulkUserAnimeOperation `json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+func (r BulkUserAnimeRequest) ToOperations() []models.BulkOperation {
+	ops := make([]models.BulkOperation, 0, len(r.Operations))
+	for _, op := range r.Operations {
+		ops = append(ops, models.BulkOperation{
+			Action:          op.Action,
+			AnimeMALID:      op.AnimeMALID,
+			Status:          op.Status,
+			Rating:          op.Rating,
+			EpisodesWatched: op.EpisodesWatched,
+			Version:         op.Version,
+		})
+	}
+	return ops
 }
@@ -10,7 +10,7 @@ type CreateUserDTO struct {
 	Password  string `json:"password" validate:"required,min=6" example:"StrongPass123!" swaggertype:"string"`
 }
 
-type UpdateUserDTO struct { 
+type UpdateUserDTO struct {
 	NickName  string `json:"nickname" validate:"required,min=6" example:"johndoe123" swaggertype:"string"`
 	FirstName string `json:"firstname" validate:"required" example:"John" swaggertype:"string"`
 	LastName  string `json:"lastname" validate:"required" example:"Doe" swaggertype:"string"`
@@ -19,34 +19,100 @@ type UpdateUserDTO struct {
 }
 
 type UserResponseDTO struct {
-	ID        uint      `json:"id" example:"1" swaggertype:"integer"`
-	NickName  string    `json:"nickname" example:"johndoe123" swaggertype:"string"`
-	Email     string    `json:"email" example:"john.doe@example.com" swaggertype:"string"`
-	FirstName string    `json:"firstname,omitempty" example:"John" swaggertype:"string"`
-	LastName  string    `json:"lastname,omitempty" example:"Doe" swaggertype:"string"`
-	AvatarURL string    `json:"avatar_url,omitempty" example:"https://example.com/avatar.jpg" swaggertype:"string"`
-	CreatedAt time.Time `json:"created_at" example:"2024-04-28T10:30:00Z" swaggertype:"string"`
-	UpdatedAt time.Time `json:"updated_at" example:"2024-04-28T10:30:00Z" swaggertype:"string"`
+	ID              uint       `json:"id" example:"1" swaggertype:"integer"`
+	NickName        string     `json:"nickname" example:"johndoe123" swaggertype:"string"`
+	Email           string     `json:"email" example:"john.doe@example.com" swaggertype:"string"`
+	FirstName       string     `json:"firstname,omitempty" example:"John" swaggertype:"string"`
+	LastName        string     `json:"lastname,omitempty" example:"Doe" swaggertype:"string"`
+	AvatarURL       string     `json:"avatar_url,omitempty" example:"https://example.com/avatar.jpg" swaggertype:"string"`
+	Role            string     `json:"role" example:"user" swaggertype:"string"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" example:"2024-04-28T10:30:00Z" swaggertype:"string"`
+	LockedUntil     *time.Time `json:"locked_until,omitempty" example:"2024-04-28T10:45:00Z" swaggertype:"string"`
+	CreatedAt       time.Time  `json:"created_at" example:"2024-04-28T10:30:00Z" swaggertype:"string"`
+	UpdatedAt       time.Time  `json:"updated_at" example:"2024-04-28T10:30:00Z" swaggertype:"string"`
 }
 
+// LoginDTO принимает email или никнейм в поле login. Поле email оставлено для
+// старых клиентов и используется, только если login не передан.
 type LoginDTO struct {
-	Email    string `json:"email" validate:"required_without=NickName,omitempty,email" example:"john.doe@example.com" swaggertype:"string"`
+	Login    string `json:"login" validate:"required_without=Email" example:"johndoe123" swaggertype:"string"`
+	Email    string `json:"email,omitempty" validate:"required_without=Login" example:"john.doe@example.com" swaggertype:"string"`
 	Password string `json:"password" validate:"required" example:"StrongPass123!" swaggertype:"string"`
 }
 
-type TokenResponseDTO struct {
-	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..." swaggertype:"string"`
-	ExpiresIn    int64  `json:"expires_in" example:"3600" swaggertype:"integer"`
-	TokenType    string `json:"token_type" example:"Bearer" swaggertype:"string"`
+// Identifier возвращает email или никнейм, по которому выполняется вход.
+func (d LoginDTO) Identifier() string {
+	if d.Login != "" {
+		return d.Login
+	}
+	return d.Email
 }
 
+type TokenResponseDTO struct {
+	AccessToken string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..." swaggertype:"string"`
+	ExpiresIn   int64  `json:"expires_in" example:"3600" swaggertype:"integer"`
+	TokenType   string `json:"token_type" example:"Bearer" swaggertype:"string"`
+}
 
 type RefreshTokenDTO struct {
 	RefreshToken string `json:"refresh_token" validate:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..." swaggertype:"string"`
 }
 
+type ForgotPasswordDTO struct {
+	Email string `json:"email" binding:"required,email" example:"john.doe@example.com" swaggertype:"string"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token" binding:"required" example:"kq3Yp1x..." swaggertype:"string"`
+	Password string `json:"password" binding:"required,min=6" example:"NewStrongPass123!" swaggertype:"string"`
+}
+
+type VerifyEmailDTO struct {
+	Token string `json:"token" binding:"required" example:"eyJ1aWQiOjEs..." swaggertype:"string"`
+}
+
+// MFAChallengeDTO возвращается вместо токенов при входе пользователя с 2FA
+type MFAChallengeDTO struct {
+	MFARequired bool   `json:"mfa_required" example:"true" swaggertype:"boolean"`
+	MFAToken    string `json:"mfa_token" example:"Jx8v2kq..." swaggertype:"string"`
+	ExpiresIn   int64  `json:"expires_in" example:"300" swaggertype:"integer"`
+}
+
+type MFALoginDTO struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"Jx8v2kq..." swaggertype:"string"`
+	Code     string `json:"code" binding:"required" example:"123456" swaggertype:"string"`
+}
+
+type MFACodeDTO struct {
+	Code string `json:"code" binding:"required" example:"123456" swaggertype:"string"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP" swaggertype:"string"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/anime-service:john.doe@example.com?issuer=anime-service&secret=JBSWY3DPEHPK3PXP" swaggertype:"string"`
+	QRCodePNG  string `json:"qr_code_png" example:"iVBORw0KGgoAAAANSUhEUgAA..." swaggertype:"string"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"ABCDE-FGHIJ"`
+}
 
 type ErrorResponse struct {
 	Code    int    `json:"code" example:"400" swaggertype:"integer"`
 	Message string `json:"message" example:"Invalid input" swaggertype:"string"`
-}
\ No newline at end of file
+}
+type SessionResponse struct {
+	ID         string    `json:"id" example:"3f2b8c1d9e7a4b6c8d0e1f2a3b4c5d6e" swaggertype:"string"`
+	IP         string    `json:"ip" example:"203.0.113.10" swaggertype:"string"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (X11; Linux x86_64) ..." swaggertype:"string"`
+	Browser    string    `json:"browser" example:"Firefox 125.0" swaggertype:"string"`
+	OS         string    `json:"os" example:"Linux x86_64" swaggertype:"string"`
+	Device     string    `json:"device" example:"desktop" swaggertype:"string"`
+	Current    bool      `json:"current" example:"true" swaggertype:"boolean"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-04-28T10:30:00Z" swaggertype:"string"`
+	LastUsedAt time.Time `json:"last_used_at" example:"2024-04-28T10:30:00Z" swaggertype:"string"`
+}
+
+type SessionListResponse struct {
+	Items []SessionResponse `json:"items"`
+}
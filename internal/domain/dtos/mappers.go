@@ -4,12 +4,53 @@ import "github.com/merdernoty/anime-service/internal/domain/models"
 
 func ToUserResponse(user models.User) UserResponseDTO {
 	return UserResponseDTO{
-		ID: 	  user.ID,
-		NickName: user.Nickname,
-		Email:    user.Email,	
-		FirstName: user.Firstname,
-		LastName:  user.Lastname,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		NickName:        user.Nickname,
+		Email:           user.Email,
+		FirstName:       user.Firstname,
+		LastName:        user.Lastname,
+		Role:            string(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		LockedUntil:     user.LockedUntil,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+func ToSessionResponse(session models.Session, currentSessionID string) SessionResponse {
+	return SessionResponse{
+		ID:         session.ID,
+		IP:         session.IP,
+		UserAgent:  session.UserAgent,
+		Browser:    session.Browser,
+		OS:         session.OS,
+		Device:     session.Device,
+		Current:    session.ID == currentSessionID,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+	}
+}
+
+func ToUserAnimeResponse(item *models.UserAnimeWithDetails) UserAnimeResponse {
+	return UserAnimeResponse{
+		ID:              item.ID,
+		UserID:          item.UserID,
+		AnimeMALID:      item.AnimeMALID,
+		Status:          item.Status,
+		Rating:          item.Rating,
+		Notes:           item.Notes,
+		EpisodesWatched: item.EpisodesWatched,
+		RewatchCount:    item.RewatchCount,
+		RewatchEpisodes: item.RewatchEpisodes,
+		StartedAt:       item.StartedAt,
+		FinishedAt:      item.FinishedAt,
+		AnimeTitle:      item.AnimeTitle,
+		AnimeImage:      item.AnimeImage,
+		AnimeType:       item.AnimeType,
+		AnimeEpisodes:   item.AnimeEpisodes,
+		AnimeStatus:     item.AnimeStatus,
+		AnimeScore:      item.AnimeScore,
+		Tags:            item.Tags,
+		Version:         item.Version,
 	}
 }
@@ -8,4 +8,9 @@ type UserUpdateDTO struct {
 	Avatar    *string `json:"avatar,omitempty" example:"https://example.com/avatar.jpg" validate:"omitempty,url"`
 	LastName  *string `json:"lastname,omitempty" example:"Doe" validate:"omitempty"`
 	FirstName *string `json:"firstname,omitempty" example:"John" validate:"omitempty"`
-}
\ No newline at end of file
+}
+
+// UpdateUserRoleDTO модель для смены роли пользователя администратором
+type UpdateUserRoleDTO struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin" example:"moderator" enums:"user,moderator,admin"`
+}
@@ -1,37 +1,80 @@
 package models
 
+import (
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// ErrAnimeNotFound — аниме с таким MAL ID не существует (Jikan API ответил 404).
+var ErrAnimeNotFound = errors.New("anime not found")
+
 type Anime struct {
-	MALId         int64   `json:"mal_id"`
-	Title         string  `json:"title"`
-	TitleEnglish  string  `json:"title_english"`
-	TitleJapanese string  `json:"title_japanese"`
-	Synopsis      string  `json:"synopsis"`
-	ImageURL      string  `json:"image_url"`
-	Type          string  `json:"type"`
-	Source        string  `json:"source"`
-	Episodes      int     `json:"episodes"`
-	Status        string  `json:"status"`
-	Airing        bool    `json:"airing"`
-	Score         float64 `json:"score"`
-	Rank          int     `json:"rank"`
-	Popularity    int     `json:"popularity"`
-	Genres        []Genre `json:"genres"`
+	MALId         int64     `json:"mal_id" gorm:"column:mal_id;primaryKey;autoIncrement:false"`
+	Title         string    `json:"title"`
+	TitleEnglish  string    `json:"title_english"`
+	TitleJapanese string    `json:"title_japanese"`
+	Synopsis      string    `json:"synopsis"`
+	ImageURL      string    `json:"image_url"`
+	Type          string    `json:"type"`
+	Source        string    `json:"source"`
+	Episodes      int       `json:"episodes"`
+	Status        string    `json:"status"`
+	Airing        bool      `json:"airing"`
+	Score         float64   `json:"score"`
+	Rank          int       `json:"rank"`
+	Popularity    int       `json:"popularity"`
+	Genres        []Genre   `json:"genres" gorm:"-"`
+	FetchedAt     time.Time `json:"-" gorm:"not null;index"`
+}
+
+// AnimeStatusFinished — статус завершенного тайтла в ответах Jikan.
+const AnimeStatusFinished = "Finished Airing"
+
+// IsFinished сообщает, что показ тайтла завершен.
+func (a *Anime) IsFinished() bool {
+	return !a.Airing && a.Status == AnimeStatusFinished
+}
+
+// IsCaughtUp сообщает, что после episodesWatched эпизодов смотреть больше
+// нечего. Пока онгоинг идет, каталог часто не знает числа эпизодов; тогда
+// просмотр всего отмеченного и есть все вышедшее.
+func (a *Anime) IsCaughtUp(episodesWatched int) bool {
+	if a.Episodes > 0 {
+		return episodesWatched == a.Episodes
+	}
+	return a.Airing && episodesWatched > 0
+}
+
+// AnimeGenre — строка таблицы anime_genres локального каталога аниме.
+type AnimeGenre struct {
+	AnimeMALID int64  `gorm:"column:anime_mal_id;primaryKey;autoIncrement:false"`
+	GenreID    int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name       string `gorm:"not null"`
 }
 
 type Genre struct {
-	ID  int64  `json:"id"`
+	ID   int64  `json:"id"`
 	Name string `json:"name"`
 }
 
+// AnimeSeatch — набор фильтров расширенного поиска аниме (соответствует параметрам Jikan /anime).
 type AnimeSeatch struct {
-	Query     string `json:"query" form:"query"`
-	Type      string `json:"type" form:"type"`
-	Status    string `json:"status" form:"status"`
-	Genre     string `json:"genre" form:"genre"`
-	Page      int    `json:"page" form:"page"`
-	Limit     int    `json:"limit" form:"limit"`
-	Sort      string `json:"sort" form:"sort"`
-	SortOrder string `json:"sort_order" form:"sort_order"`
+	Query          string   `json:"query" form:"query"`
+	Type           string   `json:"type" form:"type"`
+	Status         string   `json:"status" form:"status"`
+	Rating         string   `json:"rating" form:"rating"`
+	Genres         []int64  `json:"genres"`
+	ExcludedGenres []int64  `json:"genres_exclude"`
+	MinScore       *float64 `json:"min_score" form:"min_score"`
+	MaxScore       *float64 `json:"max_score" form:"max_score"`
+	StartDate      string   `json:"start_date" form:"start_date"`
+	EndDate        string   `json:"end_date" form:"end_date"`
+	OrderBy        string   `json:"order_by" form:"order_by"`
+	Sort           string   `json:"sort" form:"sort"`
+	SFW            bool     `json:"sfw" form:"sfw"`
+	Page           int      `json:"page" form:"page"`
+	Limit          int      `json:"limit" form:"limit"`
 }
 
 type AnimeList struct {
@@ -39,4 +82,4 @@ type AnimeList struct {
 	TotalCount int      `json:"total_count"`
 	Page       int      `json:"page"`
 	Limit      int      `json:"limit"`
-}
\ No newline at end of file
+}
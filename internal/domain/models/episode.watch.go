@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// MaxEpisodeRange ограничивает диапазон, отмечаемый одним запросом, для тайтлов без известного числа эпизодов.
+const MaxEpisodeRange = 2000
+
+// EpisodeWatch — событие журнала просмотров: отметка или снятие отметки с эпизода.
+// Журнал только дополняется; текущее состояние эпизода определяется последним событием
+// в рамках просмотра с номером RewatchNumber (0 — первый просмотр).
+type EpisodeWatch struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"not null;index:idx_episode_watches_user_anime"`
+	AnimeMALID    int64     `json:"anime_mal_id" gorm:"column:anime_mal_id;not null;index:idx_episode_watches_user_anime"`
+	Episode       int       `json:"episode" gorm:"not null"`
+	RewatchNumber int       `json:"rewatch_number" gorm:"not null;default:0"`
+	Unwatched     bool      `json:"unwatched" gorm:"not null;default:false"`
+	Rating        *float32  `json:"rating,omitempty"`
+	WatchedAt     time.Time `json:"watched_at" gorm:"not null;index"`
+}
+
+// ValidateEpisodeRange проверяет диапазон эпизодов [from, to] относительно числа эпизодов тайтла.
+func ValidateEpisodeRange(from, to int, anime *Anime) error {
+	if from < 1 || to < from {
+		return errors.WithDetails(ErrInvalidEpisodeCount, "from", from, "to", to)
+	}
+	if anime.Episodes > 0 && to > anime.Episodes {
+		return errors.WithDetails(ErrInvalidEpisodeCount, "to", to, "episodes", anime.Episodes)
+	}
+	if anime.Episodes == 0 && to > MaxEpisodeRange {
+		return errors.WithDetails(ErrInvalidEpisodeCount, "to", to, "max", MaxEpisodeRange)
+	}
+	return nil
+}
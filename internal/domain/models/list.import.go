@@ -0,0 +1,151 @@
+package models
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+)
+
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// ImportPolicy определяет, что делать с записью импорта, если аниме уже есть в списке.
+type ImportPolicy string
+
+const (
+	ImportPolicySkip       ImportPolicy = "skip"
+	ImportPolicyOverwrite  ImportPolicy = "overwrite"
+	ImportPolicyKeepNewest ImportPolicy = "newest"
+)
+
+func (p ImportPolicy) IsValid() bool {
+	switch p {
+	case ImportPolicySkip, ImportPolicyOverwrite, ImportPolicyKeepNewest:
+		return true
+	}
+	return false
+}
+
+// ImportAction — итог обработки одной записи импорта.
+type ImportAction string
+
+const (
+	ImportActionCreate    ImportAction = "create"
+	ImportActionUpdate    ImportAction = "update"
+	ImportActionSkip      ImportAction = "skip"
+	ImportActionUnchanged ImportAction = "unchanged"
+	ImportActionFailed    ImportAction = "failed"
+)
+
+type ImportOptions struct {
+	DryRun bool         `json:"dry_run"`
+	Policy ImportPolicy `json:"policy"`
+}
+
+// FieldChange — различие одного поля записи между списком и файлом импорта.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+type ImportEntryResult struct {
+	AnimeMALID int64         `json:"anime_mal_id"`
+	Title      string        `json:"title,omitempty"`
+	Action     ImportAction  `json:"action"`
+	Changes    []FieldChange `json:"changes,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+}
+
+type ImportReport struct {
+	Total     int                 `json:"total"`
+	Created   int                 `json:"created"`
+	Updated   int                 `json:"updated"`
+	Skipped   int                 `json:"skipped"`
+	Unchanged int                 `json:"unchanged"`
+	Failed    int                 `json:"failed"`
+	Entries   []ImportEntryResult `json:"entries"`
+}
+
+// Add учитывает результат обработки записи в счетчиках отчета.
+func (r *ImportReport) Add(result ImportEntryResult) {
+	r.Total++
+	switch result.Action {
+	case ImportActionCreate:
+		r.Created++
+	case ImportActionUpdate:
+		r.Updated++
+	case ImportActionSkip:
+		r.Skipped++
+	case ImportActionUnchanged:
+		r.Unchanged++
+	case ImportActionFailed:
+		r.Failed++
+	}
+	r.Entries = append(r.Entries, result)
+}
+
+type ImportJobStatus string
+
+const (
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	ImportJobFailed    ImportJobStatus = "failed"
+)
+
+// ImportJob — задача импорта списка. Небольшие файлы обрабатываются сразу и
+// возвращаются завершенной задачей, большие — в фоне.
+type ImportJob struct {
+	ID         string          `json:"id"`
+	UserID     uint            `json:"user_id"`
+	Status     ImportJobStatus `json:"status"`
+	Options    ImportOptions   `json:"options"`
+	Report     *ImportReport   `json:"report,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// ImportDiff сравнивает запись списка с записью из файла импорта.
+func ImportDiff(existing, imported *UserAnime) []FieldChange {
+	var changes []FieldChange
+	add := func(field string, old, new interface{}) {
+		changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+	}
+
+	if existing.Status != imported.Status {
+		add("status", existing.Status, imported.Status)
+	}
+	if existing.Rating != imported.Rating {
+		add("rating", existing.Rating, imported.Rating)
+	}
+	if existing.Notes != imported.Notes {
+		add("notes", existing.Notes, imported.Notes)
+	}
+	if existing.EpisodesWatched != imported.EpisodesWatched {
+		add("episodes_watched", existing.EpisodesWatched, imported.EpisodesWatched)
+	}
+	if existing.RewatchCount != imported.RewatchCount {
+		add("rewatch_count", existing.RewatchCount, imported.RewatchCount)
+	}
+	if existing.RewatchEpisodes != imported.RewatchEpisodes {
+		add("rewatch_episodes", existing.RewatchEpisodes, imported.RewatchEpisodes)
+	}
+	if !sameDay(existing.StartedAt, imported.StartedAt) {
+		add("started_at", existing.StartedAt, imported.StartedAt)
+	}
+	if !sameDay(existing.FinishedAt, imported.FinishedAt) {
+		add("finished_at", existing.FinishedAt, imported.FinishedAt)
+	}
+	return changes
+}
+
+// sameDay сравнивает даты с точностью до дня: MAL хранит даты без времени.
+func sameDay(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
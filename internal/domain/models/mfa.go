@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+)
+
+var ErrRecoveryCodeInvalid = errors.New("recovery code is invalid or already used")
+
+// RecoveryCodeCount — сколько одноразовых кодов восстановления выдается при включении 2FA.
+const RecoveryCodeCount = 10
+
+// RecoveryCode — одноразовый код восстановления для входа без TOTP. Хранится только хеш кода.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// MFAChallenge — незавершенный вход пользователя с 2FA, ожидающий второй фактор.
+type MFAChallenge struct {
+	UserID uint `json:"user_id"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+)
+
+var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid, expired or already used")
+
+// PasswordResetToken — одноразовый токен сброса пароля. Хранится только хеш токена.
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// IsUsable сообщает, что токен еще не использован и не истек.
+func (t *PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected")
+)
+
+// RefreshTokenReuseGrace — сколько после ротации токен еще принимается повторно.
+// Две вкладки, одновременно обновившие токен, получают одного и того же
+// преемника, а не отзыв семейства.
+const RefreshTokenReuseGrace = 10 * time.Second
+
+// RefreshToken — серверная запись refresh-токена. Хранится только хеш токена.
+// Все токены, полученные ротацией от одного входа, составляют семейство FamilyID;
+// повторное предъявление уже использованного токена отзывает все семейство.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// InReuseGrace сообщает, что токен использован не раньше RefreshTokenReuseGrace до now.
+func (t *RefreshToken) InReuseGrace(now time.Time) bool {
+	return t.UsedAt != nil && now.Sub(*t.UsedAt) <= RefreshTokenReuseGrace
+}
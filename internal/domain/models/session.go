@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+const (
+	SessionDeviceDesktop = "desktop"
+	SessionDeviceMobile  = "mobile"
+	SessionDeviceBot     = "bot"
+)
+
+// Session — вход пользователя с конкретного устройства. ID сессии совпадает
+// с FamilyID ее refresh-токенов и передается в access-токене.
+type Session struct {
+	ID         string `gorm:"primaryKey;size:64"`
+	UserID     uint   `gorm:"not null;index"`
+	IP         string `gorm:"size:64"`
+	UserAgent  string
+	Browser    string
+	OS         string
+	Device     string
+	CreatedAt  time.Time
+	LastUsedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import "emperror.dev/errors"
+
+// MaxBulkOperations — сколько операций можно передать в одном пакетном запросе.
+const MaxBulkOperations = 100
+
+var ErrInvalidBulkOperation = errors.New("invalid bulk operation")
+
+// BulkAction — действие над записью списка в пакетном запросе.
+type BulkAction string
+
+const (
+	BulkActionAdd         BulkAction = "add"
+	BulkActionRemove      BulkAction = "remove"
+	BulkActionSetStatus   BulkAction = "set_status"
+	BulkActionSetRating   BulkAction = "set_rating"
+	BulkActionSetEpisodes BulkAction = "set_episodes"
+)
+
+// NeedsAnime сообщает, нужны ли действию данные аниме из каталога.
+func (a BulkAction) NeedsAnime() bool {
+	return a == BulkActionAdd || a == BulkActionSetEpisodes
+}
+
+// BulkMode определяет, что делать с остальными операциями пакета, если одна из них не выполнилась.
+type BulkMode string
+
+const (
+	BulkModeAllOrNothing BulkMode = "all_or_nothing"
+	BulkModeBestEffort   BulkMode = "best_effort"
+)
+
+func (m BulkMode) IsValid() bool {
+	switch m {
+	case BulkModeAllOrNothing, BulkModeBestEffort:
+		return true
+	}
+	return false
+}
+
+// BulkOutcome — итог одной операции пакета.
+type BulkOutcome string
+
+const (
+	BulkOutcomeApplied    BulkOutcome = "applied"
+	BulkOutcomeFailed     BulkOutcome = "failed"
+	BulkOutcomeRolledBack BulkOutcome = "rolled_back" // Операция выполнилась, но транзакция откачена из-за другой
+	BulkOutcomeSkipped    BulkOutcome = "skipped"     // Операция не выполнялась, потому что пакет уже не может быть применен
+)
+
+// BulkOperation — одна операция пакетного изменения списка. Status, Rating и
+// EpisodesWatched заполняются в зависимости от Action; Version, если не 0,
+// должна совпадать с версией записи на момент выполнения операции.
+type BulkOperation struct {
+	Action          BulkAction
+	AnimeMALID      int64
+	Status          WatchStatus
+	Rating          *float32
+	EpisodesWatched *int
+	Version         int64
+}
+
+// Validate проверяет операцию без обращения к хранилищу.
+func (op BulkOperation) Validate() error {
+	if op.AnimeMALID <= 0 {
+		return errors.WithDetails(ErrInvalidBulkOperation, "anime_mal_id", op.AnimeMALID)
+	}
+	if op.Version < 0 {
+		return errors.WithDetails(ErrInvalidBulkOperation, "version", op.Version)
+	}
+
+	switch op.Action {
+	case BulkActionAdd, BulkActionSetStatus:
+		if !op.Status.IsValid() {
+			return errors.WithDetails(ErrInvalidWatchStatus, "status", op.Status)
+		}
+	case BulkActionRemove:
+	case BulkActionSetRating:
+		if op.Rating == nil {
+			return errors.WithDetails(ErrInvalidBulkOperation, "action", op.Action, "missing", "rating")
+		}
+		if *op.Rating < 0 || *op.Rating > 10 {
+			return errors.WithDetails(ErrInvalidRating, "rating", *op.Rating)
+		}
+	case BulkActionSetEpisodes:
+		if op.EpisodesWatched == nil {
+			return errors.WithDetails(ErrInvalidBulkOperation, "action", op.Action, "missing", "episodes_watched")
+		}
+		if *op.EpisodesWatched < 0 {
+			return errors.WithDetails(ErrInvalidEpisodeCount, "episodes_watched", *op.EpisodesWatched)
+		}
+	default:
+		return errors.WithDetails(ErrInvalidBulkOperation, "action", op.Action)
+	}
+
+	return nil
+}
+
+type BulkOperationResult struct {
+	Index      int         `json:"index"`
+	Action     BulkAction  `json:"action"`
+	AnimeMALID int64       `json:"anime_mal_id"`
+	Outcome    BulkOutcome `json:"outcome"`
+	Error      string      `json:"error,omitempty"`
+	Version    int64       `json:"version,omitempty"`
+}
+
+type BulkResult struct {
+	Mode      BulkMode              `json:"mode"`
+	Committed bool                  `json:"committed"`
+	Applied   int                   `json:"applied"`
+	Failed    int                   `json:"failed"`
+	Results   []BulkOperationResult `json:"results"`
+}
+
+// Add учитывает итог операции в счетчиках.
+func (r *BulkResult) Add(result BulkOperationResult) {
+	switch result.Outcome {
+	case BulkOutcomeApplied:
+		r.Applied++
+	case BulkOutcomeFailed:
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
@@ -1,36 +1,256 @@
 package models
 
 import (
+	"sort"
 	"time"
+
+	"emperror.dev/errors"
 )
 
 type WatchStatus string
 
 const (
 	StatusWatched     WatchStatus = "watched"
-	StatusPlanToWatch WatchStatus = "plan_to_watch" 
-	StatusWatching    WatchStatus = "watching" 
-	StatusWaiting     WatchStatus = "waiting"  
+	StatusPlanToWatch WatchStatus = "plan_to_watch"
+	StatusWatching    WatchStatus = "watching"
+	StatusWaiting     WatchStatus = "waiting"
+	StatusDropped     WatchStatus = "dropped"
+	StatusOnHold      WatchStatus = "on_hold"
+	StatusRewatching  WatchStatus = "rewatching"
+)
+
+var (
+	ErrInvalidWatchStatus       = errors.New("invalid watch status")
+	ErrInvalidStatusTransition  = errors.New("invalid watch status transition")
+	ErrInvalidEpisodeCount      = errors.New("invalid episodes watched count")
+	ErrInvalidTrackingDates     = errors.New("invalid started/finished dates")
+	ErrUserAnimeNotFound        = errors.New("user anime not found")
+	ErrUserAnimeVersionMismatch = errors.New("user anime version mismatch")
 )
 
+// statusTransitions — допустимые переходы между статусами записи в списке пользователя.
+var statusTransitions = map[WatchStatus][]WatchStatus{
+	StatusPlanToWatch: {StatusWatching, StatusWaiting, StatusWatched, StatusOnHold, StatusDropped},
+	StatusWatching:    {StatusPlanToWatch, StatusWaiting, StatusWatched, StatusOnHold, StatusDropped},
+	StatusWaiting:     {StatusWatching, StatusWatched, StatusOnHold, StatusDropped},
+	StatusOnHold:      {StatusPlanToWatch, StatusWatching, StatusWaiting, StatusWatched, StatusDropped},
+	StatusDropped:     {StatusPlanToWatch, StatusWatching, StatusOnHold},
+	StatusWatched:     {StatusWatching, StatusDropped, StatusRewatching},
+	StatusRewatching:  {StatusWatched, StatusOnHold, StatusDropped},
+}
+
+func (s WatchStatus) IsValid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo сообщает, можно ли перевести запись из статуса s в статус to.
+func (s WatchStatus) CanTransitionTo(to WatchStatus) bool {
+	if s == to {
+		return true
+	}
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusTransitionPairs возвращает разрешенные переходы между разными статусами
+// парами {из, в} в стабильном порядке.
+func StatusTransitionPairs() [][2]WatchStatus {
+	var pairs [][2]WatchStatus
+	for from, targets := range statusTransitions {
+		for _, to := range targets {
+			pairs = append(pairs, [2]WatchStatus{from, to})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+// IsValidInitialStatus сообщает, можно ли добавить аниме в список сразу с этим статусом.
+func (s WatchStatus) IsValidInitialStatus() bool {
+	return s.IsValid() && s != StatusRewatching
+}
+
 type UserAnime struct {
-	ID		uint       `json:"id" db:"id" gorm:"primaryKey"`
-	UserID	uint       `json:"user_id" db:"user_id" gorm:"not null"`
-	AnimeMALID  int64       `json:"anime_mal_id" db:"anime_mal_id"`
-	Status	WatchStatus `json:"status" db:"status" gorm:"not null"`
-	Rating	float32    `json:"rating" db:"rating"`
-	Notes       string      `json:"notes" db:"notes"`
-	EpisodesWatched int     `json:"episodes_watched" db:"episodes_watched"` 
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+	ID              uint        `json:"id" db:"id" gorm:"primaryKey"`
+	UserID          uint        `json:"user_id" db:"user_id" gorm:"not null"`
+	AnimeMALID      int64       `json:"anime_mal_id" db:"anime_mal_id"`
+	Status          WatchStatus `json:"status" db:"status" gorm:"not null"`
+	Rating          float32     `json:"rating" db:"rating"`
+	Notes           string      `json:"notes" db:"notes"`
+	EpisodesWatched int         `json:"episodes_watched" db:"episodes_watched"`
+	RewatchCount    int         `json:"rewatch_count" db:"rewatch_count" gorm:"not null;default:0"`
+	RewatchEpisodes int         `json:"rewatch_episodes" db:"rewatch_episodes" gorm:"not null;default:0"`
+	StartedAt       *time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt      *time.Time  `json:"finished_at" db:"finished_at"`
+	CreatedAt       time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at" db:"updated_at"`
+	Tags            []string    `json:"tags" db:"tags" gorm:"type:jsonb;not null;default:'[]'"`
+	Version         int64       `json:"version" db:"version" gorm:"not null;default:1"`
+}
+
+// AnyVersion — ожидаемая версия для If-Match: *: запись должна существовать, но
+// ее версия не проверяется.
+const AnyVersion int64 = -1
+
+// CheckVersion сравнивает версию записи с ожидаемой клиентом; expected == 0 или
+// AnyVersion означает, что клиент версию не проверяет.
+func (ua *UserAnime) CheckVersion(expected int64) error {
+	if expected > 0 && ua.Version != expected {
+		return errors.WithDetails(ErrUserAnimeVersionMismatch, "expected", expected, "actual", ua.Version)
+	}
+	return nil
+}
+
+// ChangeStatus переводит запись в новый статус с проверкой таблицы переходов.
+func (ua *UserAnime) ChangeStatus(to WatchStatus) error {
+	if !to.IsValid() {
+		return errors.WithDetails(ErrInvalidWatchStatus, "status", to)
+	}
+	if !ua.Status.CanTransitionTo(to) {
+		return errors.WithDetails(ErrInvalidStatusTransition, "from", ua.Status, "to", to)
+	}
+
+	ua.setStatus(to, time.Now())
+	return nil
+}
+
+// InitStatus задает статус новой записи и проставляет дату начала просмотра.
+func (ua *UserAnime) InitStatus(status WatchStatus) error {
+	if !status.IsValidInitialStatus() {
+		return errors.WithDetails(ErrInvalidWatchStatus, "status", status)
+	}
+
+	ua.Status = status
+	if status == StatusWatching {
+		now := time.Now()
+		ua.StartedAt = &now
+	}
+	return nil
+}
+
+// setStatus меняет статус и ведет даты просмотра: первый переход в watching
+// фиксирует начало, переход в watched — окончание. Начало повторного
+// просмотра увеличивает счетчик пересмотров и обнуляет его прогресс.
+func (ua *UserAnime) setStatus(to WatchStatus, now time.Time) {
+	if to == ua.Status {
+		return
+	}
+
+	switch to {
+	case StatusWatching:
+		if ua.StartedAt == nil {
+			ua.StartedAt = &now
+		}
+	case StatusWatched:
+		ua.FinishedAt = &now
+	case StatusRewatching:
+		ua.RewatchCount++
+		ua.RewatchEpisodes = 0
+	}
+	ua.Status = to
+}
+
+// UserAnimeTracking — вручную задаваемые даты просмотра и счетчики пересмотров.
+type UserAnimeTracking struct {
+	StartedAt       *time.Time
+	FinishedAt      *time.Time
+	RewatchCount    int
+	RewatchEpisodes int
+}
+
+// SetTracking вручную переопределяет даты просмотра и счетчики пересмотров.
+func (ua *UserAnime) SetTracking(tracking UserAnimeTracking, anime *Anime) error {
+	if err := validateTrackingDates(tracking.StartedAt, tracking.FinishedAt, time.Now()); err != nil {
+		return err
+	}
+	if tracking.RewatchCount < 0 || tracking.RewatchEpisodes < 0 || (anime.Episodes > 0 && tracking.RewatchEpisodes > anime.Episodes) {
+		return errors.WithDetails(ErrInvalidEpisodeCount, "rewatch_count", tracking.RewatchCount, "rewatch_episodes", tracking.RewatchEpisodes)
+	}
+
+	ua.StartedAt = tracking.StartedAt
+	ua.FinishedAt = tracking.FinishedAt
+	ua.RewatchCount = tracking.RewatchCount
+	ua.RewatchEpisodes = tracking.RewatchEpisodes
+	return nil
+}
+
+// validateTrackingDates проверяет, что даты просмотра не в будущем и окончание не раньше начала.
+func validateTrackingDates(startedAt, finishedAt *time.Time, now time.Time) error {
+	if (startedAt != nil && startedAt.After(now)) || (finishedAt != nil && finishedAt.After(now)) {
+		return errors.WithDetails(ErrInvalidTrackingDates, "reason", "date in the future")
+	}
+	if startedAt != nil && finishedAt != nil && finishedAt.Before(*startedAt) {
+		return errors.WithDetails(ErrInvalidTrackingDates, "reason", "finished before started")
+	}
+	return nil
+}
+
+// ApplyEpisodeProgress записывает количество просмотренных эпизодов и
+// автоматически сдвигает статус по прогрессу: первый эпизод начинает просмотр,
+// последний эпизод завершенного тайтла закрывает его, а догнавший онгоинг
+// переводится в ожидание. У онгоинга с неизвестным числом эпизодов последним
+// считается последний отмеченный. Во время пересмотра прогресс пишется в
+// RewatchEpisodes. Переходы, не разрешенные таблицей, пропускаются.
+func (ua *UserAnime) ApplyEpisodeProgress(episodesWatched int, anime *Anime) error {
+	if err := ua.SetEpisodeProgress(episodesWatched, anime); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if episodesWatched > 0 && ua.Status == StatusPlanToWatch {
+		ua.setStatus(StatusWatching, now)
+	}
+
+	if !anime.IsCaughtUp(episodesWatched) || ua.Status == StatusDropped {
+		return nil
+	}
+
+	target := StatusWaiting
+	if anime.IsFinished() {
+		target = StatusWatched
+	}
+	if ua.Status.CanTransitionTo(target) {
+		ua.setStatus(target, now)
+	}
+	return nil
+}
+
+// SetEpisodeProgress записывает количество просмотренных эпизодов (во время
+// пересмотра — в RewatchEpisodes), не меняя статус.
+func (ua *UserAnime) SetEpisodeProgress(episodesWatched int, anime *Anime) error {
+	if episodesWatched < 0 {
+		return errors.WithDetails(ErrInvalidEpisodeCount, "episodes_watched", episodesWatched)
+	}
+	if anime.Episodes > 0 && episodesWatched > anime.Episodes {
+		return errors.WithDetails(ErrInvalidEpisodeCount, "episodes_watched", episodesWatched, "episodes", anime.Episodes)
+	}
+
+	if ua.Status == StatusRewatching {
+		ua.RewatchEpisodes = episodesWatched
+	} else {
+		ua.EpisodesWatched = episodesWatched
+	}
+	return nil
 }
 
 type UserAnimeFilter struct {
-	UserID int64       `json:"user_id" form:"user_id"`
-	Status WatchStatus `json:"status" form:"status"`
-	Query  string      `json:"query" form:"query"`
-	Page   int         `json:"page" form:"page"` 
-	Limit  int         `json:"limit" form:"limit"`
+	UserID       int64       `json:"user_id" form:"user_id"`
+	Status       WatchStatus `json:"status" form:"status"`
+	Query        string      `json:"query" form:"query"`
+	StartedYear  int         `json:"started_year" form:"started_year"`
+	FinishedYear int         `json:"finished_year" form:"finished_year"`
+	Page         int         `json:"page" form:"page"`
+	Limit        int         `json:"limit" form:"limit"`
 }
 
 type UserAnimeList struct {
@@ -41,20 +261,37 @@ type UserAnimeList struct {
 }
 
 type UserAnimeWithDetails struct {
-	UserAnime    `json:",inline"`
-	AnimeTitle   string    `json:"anime_title"`
-	AnimeImage   string    `json:"anime_image"`
-	AnimeType    string    `json:"anime_type"`
-	AnimeEpisodes int      `json:"anime_episodes"`
-	AnimeStatus  string    `json:"anime_status"`
-	AnimeScore   float64   `json:"anime_score"`
+	UserAnime     `json:",inline"`
+	AnimeTitle    string  `json:"anime_title"`
+	AnimeImage    string  `json:"anime_image"`
+	AnimeType     string  `json:"anime_type"`
+	AnimeEpisodes int     `json:"anime_episodes"`
+	AnimeStatus   string  `json:"anime_status"`
+	AnimeScore    float64 `json:"anime_score"`
+}
+
+// NewUserAnimeWithDetails дополняет запись списка данными аниме из каталога.
+func NewUserAnimeWithDetails(userAnime *UserAnime, anime *Anime) *UserAnimeWithDetails {
+	return &UserAnimeWithDetails{
+		UserAnime:     *userAnime,
+		AnimeTitle:    anime.Title,
+		AnimeImage:    anime.ImageURL,
+		AnimeType:     anime.Type,
+		AnimeEpisodes: anime.Episodes,
+		AnimeStatus:   anime.Status,
+		AnimeScore:    anime.Score,
+	}
 }
 
 type AnimeStats struct {
-	TotalWatched     int `json:"total_watched"`
-	TotalPlanToWatch int `json:"total_plan_to_watch"`
-	TotalWatching    int `json:"total_watching"`
-	TotalWaiting     int `json:"total_waiting"`
-	TotalEpisodes    int `json:"total_episodes"`
+	TotalWatched     int     `json:"total_watched"`
+	TotalPlanToWatch int     `json:"total_plan_to_watch"`
+	TotalWatching    int     `json:"total_watching"`
+	TotalWaiting     int     `json:"total_waiting"`
+	TotalDropped     int     `json:"total_dropped"`
+	TotalOnHold      int     `json:"total_on_hold"`
+	TotalRewatching  int     `json:"total_rewatching"`
+	TotalRewatches   int     `json:"total_rewatches"`
+	TotalEpisodes    int     `json:"total_episodes"`
 	AverageRating    float64 `json:"average_rating"`
-}
\ No newline at end of file
+}
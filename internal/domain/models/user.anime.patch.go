@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"emperror.dev/errors"
+)
+
+const (
+	MaxUserAnimeNotesLength = 2000
+	MaxUserAnimeTags        = 20
+	MaxUserAnimeTagLength   = 32
+)
+
+var (
+	ErrInvalidRating = errors.New("rating must be between 0 and 10")
+	ErrInvalidNotes  = errors.New("notes are too long")
+	ErrInvalidTags   = errors.New("invalid tags")
+)
+
+// Optional — поле документа JSON Merge Patch (RFC 7396). Set сообщает, что поле
+// было в документе; null дает Set и нулевое Value, то есть сброс значения.
+type Optional[T any] struct {
+	Set   bool
+	Value T
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		var zero T
+		o.Value = zero
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// UserAnimePatch — изменения записи списка; поля без Set не меняются.
+type UserAnimePatch struct {
+	Status          Optional[WatchStatus]
+	EpisodesWatched Optional[int]
+	Rating          Optional[float32]
+	Notes           Optional[string]
+	StartedAt       Optional[*time.Time]
+	FinishedAt      Optional[*time.Time]
+	Tags            Optional[[]string]
+}
+
+// ApplyPatch применяет к записи все поля patch, кроме прогресса эпизодов: он
+// ведется по журналу просмотров и задается через SetEpisodeProgress или
+// ApplyEpisodeProgress. Статус меняется первым, чтобы явно переданные даты
+// имели приоритет над проставленными при смене статуса.
+func (ua *UserAnime) ApplyPatch(patch UserAnimePatch) error {
+	if patch.Status.Set {
+		if err := ua.ChangeStatus(patch.Status.Value); err != nil {
+			return err
+		}
+	}
+	if patch.StartedAt.Set {
+		ua.StartedAt = patch.StartedAt.Value
+	}
+	if patch.FinishedAt.Set {
+		ua.FinishedAt = patch.FinishedAt.Value
+	}
+	if patch.Rating.Set {
+		ua.Rating = patch.Rating.Value
+	}
+	if patch.Notes.Set {
+		ua.Notes = strings.TrimSpace(patch.Notes.Value)
+	}
+	if patch.Tags.Set {
+		tags, err := NormalizeTags(patch.Tags.Value)
+		if err != nil {
+			return err
+		}
+		ua.Tags = tags
+	}
+	return nil
+}
+
+// Validate проверяет запись целиком: после изменения нескольких полей сразу
+// каждое может быть допустимым по отдельности, но не вместе.
+func (ua *UserAnime) Validate(anime *Anime) error {
+	if !ua.Status.IsValid() {
+		return errors.WithDetails(ErrInvalidWatchStatus, "status", ua.Status)
+	}
+	if ua.Rating < 0 || ua.Rating > 10 {
+		return errors.WithDetails(ErrInvalidRating, "rating", ua.Rating)
+	}
+	if utf8.RuneCountInString(ua.Notes) > MaxUserAnimeNotesLength {
+		return errors.WithDetails(ErrInvalidNotes, "max", MaxUserAnimeNotesLength)
+	}
+	if len(ua.Tags) > MaxUserAnimeTags {
+		return errors.WithDetails(ErrInvalidTags, "max", MaxUserAnimeTags)
+	}
+	if err := validateTrackingDates(ua.StartedAt, ua.FinishedAt, time.Now()); err != nil {
+		return err
+	}
+	if ua.EpisodesWatched < 0 || ua.RewatchEpisodes < 0 || ua.RewatchCount < 0 {
+		return errors.WithDetails(ErrInvalidEpisodeCount, "episodes_watched", ua.EpisodesWatched, "rewatch_episodes", ua.RewatchEpisodes)
+	}
+	if anime.Episodes > 0 && (ua.EpisodesWatched > anime.Episodes || ua.RewatchEpisodes > anime.Episodes) {
+		return errors.WithDetails(ErrInvalidEpisodeCount, "episodes_watched", ua.EpisodesWatched, "episodes", anime.Episodes)
+	}
+	return nil
+}
+
+// NormalizeTags приводит теги к нижнему регистру, убирает пустые и повторы,
+// сохраняя порядок.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxUserAnimeTagLength {
+			return nil, errors.WithDetails(ErrInvalidTags, "tag", tag, "max_length", MaxUserAnimeTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxUserAnimeTags {
+		return nil, errors.WithDetails(ErrInvalidTags, "count", len(normalized), "max", MaxUserAnimeTags)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// patchDocument повторяет JSON-поля PatchUserAnimeRequest.
+type patchDocument struct {
+	Status     Optional[WatchStatus] `json:"status"`
+	Rating     Optional[float32]     `json:"rating"`
+	Notes      Optional[string]      `json:"notes"`
+	StartedAt  Optional[*time.Time]  `json:"started_at"`
+	FinishedAt Optional[*time.Time]  `json:"finished_at"`
+	Tags       Optional[[]string]    `json:"tags"`
+}
+
+func (d patchDocument) patch() UserAnimePatch {
+	return UserAnimePatch{
+		Status:     d.Status,
+		Rating:     d.Rating,
+		Notes:      d.Notes,
+		StartedAt:  d.StartedAt,
+		FinishedAt: d.FinishedAt,
+		Tags:       d.Tags,
+	}
+}
+
+func TestOptionalDistinguishesNullFromAbsent(t *testing.T) {
+	var doc patchDocument
+	if err := json.Unmarshal([]byte(`{"notes": null, "rating": 7.5, "started_at": null}`), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if !doc.Notes.Set || doc.Notes.Value != "" {
+		t.Errorf("null notes = %+v, want set to the zero value", doc.Notes)
+	}
+	if !doc.StartedAt.Set || doc.StartedAt.Value != nil {
+		t.Errorf("null started_at = %+v, want set to nil", doc.StartedAt)
+	}
+	if !doc.Rating.Set || doc.Rating.Value != 7.5 {
+		t.Errorf("rating = %+v, want set to 7.5", doc.Rating)
+	}
+	if doc.Status.Set || doc.FinishedAt.Set || doc.Tags.Set {
+		t.Errorf("absent fields are set: status %v, finished_at %v, tags %v", doc.Status.Set, doc.FinishedAt.Set, doc.Tags.Set)
+	}
+
+	if err := json.Unmarshal([]byte(`{"rating": "high"}`), &doc); err == nil {
+		t.Error("rating of the wrong type was accepted")
+	}
+}
+
+func TestApplyPatchMergesDocument(t *testing.T) {
+	started := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	newEntry := func() *UserAnime {
+		startedAt, finishedAt := started, finished
+		return &UserAnime{Status: StatusWatched, Rating: 8, Notes: "great", StartedAt: &startedAt, FinishedAt: &finishedAt, Tags: []string{"classic"}}
+	}
+
+	tests := []struct {
+		name  string
+		doc   string
+		check func(t *testing.T, ua *UserAnime)
+	}{
+		{"empty document changes nothing", `{}`, func(t *testing.T, ua *UserAnime) {
+			want := newEntry()
+			if ua.Status != want.Status || ua.Rating != want.Rating || ua.Notes != want.Notes || len(ua.Tags) != 1 ||
+				ua.StartedAt == nil || !ua.StartedAt.Equal(started) || ua.FinishedAt == nil || !ua.FinishedAt.Equal(finished) {
+				t.Errorf("entry = %+v, want it unchanged", ua)
+			}
+		}},
+		{"null clears values", `{"notes": null, "rating": null, "finished_at": null, "tags": null}`, func(t *testing.T, ua *UserAnime) {
+			if ua.Notes != "" || ua.Rating != 0 || ua.FinishedAt != nil || len(ua.Tags) != 0 {
+				t.Errorf("entry = (notes %q, rating %v, finished_at %v, tags %v), want all cleared", ua.Notes, ua.Rating, ua.FinishedAt, ua.Tags)
+			}
+			if ua.StartedAt == nil || !ua.StartedAt.Equal(started) || ua.Status != StatusWatched {
+				t.Errorf("absent fields changed: started_at %v, status %s", ua.StartedAt, ua.Status)
+			}
+		}},
+		{"values replace fields", `{"notes": "  rewatch  ", "tags": ["Favorite", "favorite", "Mecha"]}`, func(t *testing.T, ua *UserAnime) {
+			if ua.Notes != "rewatch" {
+				t.Errorf("notes = %q, want trimmed %q", ua.Notes, "rewatch")
+			}
+			if len(ua.Tags) != 2 || ua.Tags[0] != "favorite" || ua.Tags[1] != "mecha" {
+				t.Errorf("tags = %v, want [favorite mecha]", ua.Tags)
+			}
+		}},
+		{"explicit dates win over status dates", `{"status": "watching", "started_at": "2023-12-01T00:00:00Z"}`, func(t *testing.T, ua *UserAnime) {
+			want := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+			if ua.Status != StatusWatching || ua.StartedAt == nil || !ua.StartedAt.Equal(want) {
+				t.Errorf("entry = (status %s, started_at %v), want (watching, %v)", ua.Status, ua.StartedAt, want)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc patchDocument
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			ua := newEntry()
+			if err := ua.ApplyPatch(doc.patch()); err != nil {
+				t.Fatalf("ApplyPatch: %v", err)
+			}
+			tt.check(t, ua)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	statuses := []WatchStatus{StatusPlanToWatch, StatusWatching, StatusWaiting, StatusOnHold, StatusDropped, StatusWatched, StatusRewatching}
+	allowed := map[WatchStatus][]WatchStatus{
+		StatusPlanToWatch: {StatusWatching, StatusWaiting, StatusWatched, StatusOnHold, StatusDropped},
+		StatusWatching:    {StatusPlanToWatch, StatusWaiting, StatusWatched, StatusOnHold, StatusDropped},
+		StatusWaiting:     {StatusWatching, StatusWatched, StatusOnHold, StatusDropped},
+		StatusOnHold:      {StatusPlanToWatch, StatusWatching, StatusWaiting, StatusWatched, StatusDropped},
+		StatusDropped:     {StatusPlanToWatch, StatusWatching, StatusOnHold},
+		StatusWatched:     {StatusWatching, StatusDropped, StatusRewatching},
+		StatusRewatching:  {StatusWatched, StatusOnHold, StatusDropped},
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := from == to
+			for _, target := range allowed[from] {
+				want = want || target == to
+			}
+
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("%s -> %s allowed = %v, want %v", from, to, got, want)
+			}
+
+			ua := &UserAnime{Status: from}
+			err := ua.ChangeStatus(to)
+			if want && err != nil {
+				t.Errorf("ChangeStatus(%s -> %s) = %v", from, to, err)
+			}
+			if !want && !errors.Is(err, ErrInvalidStatusTransition) {
+				t.Errorf("ChangeStatus(%s -> %s) = %v, want ErrInvalidStatusTransition", from, to, err)
+			}
+		}
+	}
+
+	if err := (&UserAnime{Status: StatusWatching}).ChangeStatus("finished"); !errors.Is(err, ErrInvalidWatchStatus) {
+		t.Errorf("ChangeStatus to an unknown status = %v, want ErrInvalidWatchStatus", err)
+	}
+	if StatusRewatching.IsValidInitialStatus() {
+		t.Error("rewatching is accepted as an initial status")
+	}
+}
+
+func TestChangeStatusTracksDates(t *testing.T) {
+	ua := &UserAnime{Status: StatusPlanToWatch}
+	if err := ua.ChangeStatus(StatusWatching); err != nil {
+		t.Fatalf("plan_to_watch -> watching: %v", err)
+	}
+	started := ua.StartedAt
+	if started == nil {
+		t.Fatal("started_at is not set when watching starts")
+	}
+	if err := ua.ChangeStatus(StatusWatched); err != nil {
+		t.Fatalf("watching -> watched: %v", err)
+	}
+	if ua.FinishedAt == nil {
+		t.Fatal("finished_at is not set when the show is watched")
+	}
+	if err := ua.ChangeStatus(StatusWatching); err != nil {
+		t.Fatalf("watched -> watching: %v", err)
+	}
+	if ua.StartedAt != started {
+		t.Error("reopening a watched entry moved started_at")
+	}
+	if err := ua.ChangeStatus(StatusWatched); err != nil {
+		t.Fatalf("watching -> watched: %v", err)
+	}
+	if err := ua.ChangeStatus(StatusRewatching); err != nil {
+		t.Fatalf("watched -> rewatching: %v", err)
+	}
+	if ua.RewatchCount != 1 || ua.RewatchEpisodes != 0 {
+		t.Errorf("rewatch = (count %d, episodes %d), want (1, 0)", ua.RewatchCount, ua.RewatchEpisodes)
+	}
+}
+
+func TestSetTracking(t *testing.T) {
+	now := time.Now()
+	weekAgo := now.Add(-7 * 24 * time.Hour)
+	yesterday := now.Add(-24 * time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+	anime := &Anime{Episodes: 12}
+
+	tests := []struct {
+		name     string
+		tracking UserAnimeTracking
+		anime    *Anime
+		wantErr  error
+	}{
+		{"dates and rewatches", UserAnimeTracking{StartedAt: &weekAgo, FinishedAt: &yesterday, RewatchCount: 2, RewatchEpisodes: 12}, anime, nil},
+		{"clears dates", UserAnimeTracking{}, anime, nil},
+		{"only finish date", UserAnimeTracking{FinishedAt: &yesterday}, anime, nil},
+		{"same start and finish", UserAnimeTracking{StartedAt: &yesterday, FinishedAt: &yesterday}, anime, nil},
+		{"unknown episode count", UserAnimeTracking{RewatchEpisodes: 100}, &Anime{}, nil},
+		{"finished before started", UserAnimeTracking{StartedAt: &yesterday, FinishedAt: &weekAgo}, anime, ErrInvalidTrackingDates},
+		{"started in the future", UserAnimeTracking{StartedAt: &tomorrow}, anime, ErrInvalidTrackingDates},
+		{"finished in the future", UserAnimeTracking{FinishedAt: &tomorrow}, anime, ErrInvalidTrackingDates},
+		{"negative rewatch count", UserAnimeTracking{RewatchCount: -1}, anime, ErrInvalidEpisodeCount},
+		{"negative rewatch episodes", UserAnimeTracking{RewatchEpisodes: -1}, anime, ErrInvalidEpisodeCount},
+		{"rewatch past the last episode", UserAnimeTracking{RewatchEpisodes: 13}, anime, ErrInvalidEpisodeCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := now.Add(-30 * 24 * time.Hour)
+			ua := &UserAnime{Status: StatusWatched, StartedAt: &started, FinishedAt: &now, RewatchCount: 1, RewatchEpisodes: 5}
+			before := *ua
+
+			err := ua.SetTracking(tt.tracking, tt.anime)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			want := UserAnimeTracking{StartedAt: before.StartedAt, FinishedAt: before.FinishedAt, RewatchCount: before.RewatchCount, RewatchEpisodes: before.RewatchEpisodes}
+			if tt.wantErr == nil {
+				want = tt.tracking
+			}
+			got := UserAnimeTracking{StartedAt: ua.StartedAt, FinishedAt: ua.FinishedAt, RewatchCount: ua.RewatchCount, RewatchEpisodes: ua.RewatchEpisodes}
+			if got != want {
+				t.Errorf("tracking = %+v, want %+v", got, want)
+			}
+			if ua.Status != StatusWatched {
+				t.Errorf("status = %s, want it unchanged", ua.Status)
+			}
+		})
+	}
+}
+
+func TestApplyEpisodeProgress(t *testing.T) {
+	finished := &Anime{Episodes: 12, Status: AnimeStatusFinished}
+	airing := &Anime{Episodes: 24, Airing: true, Status: "Currently Airing"}
+	airingUnknown := &Anime{Airing: true, Status: "Currently Airing"}
+	unknown := &Anime{Status: AnimeStatusFinished}
+
+	tests := []struct {
+		name         string
+		status       WatchStatus
+		anime        *Anime
+		episodes     int
+		wantStatus   WatchStatus
+		wantWatched  int
+		wantRewatch  int
+		wantErr      error
+		wantFinished bool
+	}{
+		{"first episode starts watching", StatusPlanToWatch, finished, 1, StatusWatching, 1, 0, nil, false},
+		{"zero episodes keeps plan", StatusPlanToWatch, finished, 0, StatusPlanToWatch, 0, 0, nil, false},
+		{"middle episode keeps watching", StatusWatching, finished, 5, StatusWatching, 5, 0, nil, false},
+		{"last episode of finished show", StatusWatching, finished, 12, StatusWatched, 12, 0, nil, true},
+		{"all episodes from plan", StatusPlanToWatch, finished, 12, StatusWatched, 12, 0, nil, true},
+		{"caught up with airing show", StatusWatching, airing, 24, StatusWaiting, 24, 0, nil, false},
+		{"behind airing show", StatusWatching, airing, 10, StatusWatching, 10, 0, nil, false},
+		{"airing show with unknown count", StatusWatching, airingUnknown, 7, StatusWaiting, 7, 0, nil, false},
+		{"airing show with unknown count from plan", StatusPlanToWatch, airingUnknown, 1, StatusWaiting, 1, 0, nil, false},
+		{"finished show with unknown count", StatusWatching, unknown, 30, StatusWatching, 30, 0, nil, false},
+		{"dropped stays dropped", StatusDropped, finished, 12, StatusDropped, 12, 0, nil, false},
+		{"on hold finishes", StatusOnHold, finished, 12, StatusWatched, 12, 0, nil, true},
+		{"rewatch progress", StatusRewatching, finished, 4, StatusRewatching, 3, 4, nil, false},
+		{"rewatch finishes", StatusRewatching, finished, 12, StatusWatched, 3, 12, nil, true},
+		{"negative count", StatusWatching, finished, -1, StatusWatching, 3, 0, ErrInvalidEpisodeCount, false},
+		{"more than the show has", StatusWatching, finished, 13, StatusWatching, 3, 0, ErrInvalidEpisodeCount, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ua := &UserAnime{Status: tt.status, EpisodesWatched: 3}
+
+			err := ua.ApplyEpisodeProgress(tt.episodes, tt.anime)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if ua.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", ua.Status, tt.wantStatus)
+			}
+			if ua.EpisodesWatched != tt.wantWatched || ua.RewatchEpisodes != tt.wantRewatch {
+				t.Errorf("progress = (%d, rewatch %d), want (%d, rewatch %d)", ua.EpisodesWatched, ua.RewatchEpisodes, tt.wantWatched, tt.wantRewatch)
+			}
+			if (ua.FinishedAt != nil) != tt.wantFinished {
+				t.Errorf("finished_at set = %v, want %v", ua.FinishedAt != nil, tt.wantFinished)
+			}
+		})
+	}
+}
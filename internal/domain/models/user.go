@@ -1,25 +1,97 @@
 package models
 
 import (
+	"strings"
+	"time"
+
 	"emperror.dev/errors"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
-	ErrPasswordEmpty = errors.New("password is empty")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrPasswordEmpty   = errors.New("password is empty")
 	ErrPasswordHashing = errors.New("failed to hash password")
+	ErrInvalidRole     = errors.New("invalid user role")
+	ErrAdminExists     = errors.New("an admin already exists")
+	ErrInvalidNickname = errors.New("nickname must not contain @")
+)
+
+// Role — роль пользователя. Роли упорядочены: модератор может все, что может
+// пользователь, администратор — все, что может модератор.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
 )
 
+var roleRanks = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes сообщает, что роль дает права роли required.
+func (r Role) Includes(required Role) bool {
+	return r.IsValid() && roleRanks[r] >= roleRanks[required]
+}
+
 type User struct {
-	gorm.Model           
-	Nickname  string `gorm:"unique;not null" json:"nickname"`
-	Firstname string `json:"firstname,omitempty"`
-	Lastname  string `json:"lastname,omitempty"`
-	AvatarURL string `json:"avatar_url,omitempty"` 
-	Email     string `gorm:"unique;not null" json:"email"`
-	Password  string `gorm:"not null" json:"-"`
+	gorm.Model
+	Nickname        string     `gorm:"unique;not null;uniqueIndex:idx_users_nickname_lower,expression:lower(nickname)" json:"nickname"`
+	Firstname       string     `json:"firstname,omitempty"`
+	Lastname        string     `json:"lastname,omitempty"`
+	AvatarURL       string     `json:"avatar_url,omitempty"`
+	Email           string     `gorm:"unique;not null;uniqueIndex:idx_users_email_lower,expression:lower(email)" json:"email"`
+	Password        string     `gorm:"not null" json:"-"`
+	Role            Role       `gorm:"type:varchar(16);not null;default:user" json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// TOTPSecret хранит секрет и во время подключения 2FA, до подтверждения кодом;
+	// включенной 2FA считается только при TOTPEnabled. Секрет зашифрован
+	// (auth.SecretBox) и расшифровывается только при проверке кода.
+	TOTPSecret  string `gorm:"column:totp_secret" json:"-"`
+	TOTPEnabled bool   `gorm:"column:totp_enabled;not null;default:false" json:"totp_enabled"`
+	// LockedUntil выставляется после серии неудачных попыток входа; до этого
+	// момента вход по паролю запрещен.
+	LockedUntil *time.Time `gorm:"column:locked_until" json:"locked_until,omitempty"`
+}
+
+// IsLocked сообщает, что вход в аккаунт временно заблокирован.
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// NormalizeEmail приводит email к виду, в котором он хранится: без пробелов по краям
+// и в нижнем регистре.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizeNickname приводит никнейм к виду, в котором он хранится.
+func NormalizeNickname(nickname string) string {
+	return strings.ToLower(strings.TrimSpace(nickname))
+}
+
+// ValidateNickname проверяет никнейм перед сохранением. Логин с "@" ищется как
+// email, поэтому никнейм с "@" нельзя было бы использовать для входа.
+func ValidateNickname(nickname string) error {
+	if strings.Contains(nickname, "@") {
+		return ErrInvalidNickname
+	}
+	return nil
+}
+
+// IsEmailVerified сообщает, что пользователь подтвердил текущий email.
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 func (u *User) HashPassword() error {
@@ -42,4 +114,3 @@ func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
-
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+)
+
+type MFARepository interface {
+	// SetPendingTOTPSecret сохраняет секрет, который еще нужно подтвердить кодом.
+	SetPendingTOTPSecret(ctx context.Context, userID uint, secret string) error
+	// EnableTOTP включает 2FA и заменяет коды восстановления пользователя новыми.
+	EnableTOTP(ctx context.Context, userID uint, codes []models.RecoveryCode) error
+	// DisableTOTP выключает 2FA, удаляя секрет и коды восстановления.
+	DisableTOTP(ctx context.Context, userID uint) error
+	// UseRecoveryCode помечает код использованным или возвращает ErrRecoveryCodeInvalid.
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) error
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+)
+
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token models.PasswordResetToken) error
+	// Consume помечает токен использованным и возвращает его. Если токен не
+	// найден, истек или уже использован, возвращает ErrPasswordResetTokenInvalid.
+	Consume(ctx context.Context, tokenHash string) (models.PasswordResetToken, error)
+	DeleteByUser(ctx context.Context, userID uint) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
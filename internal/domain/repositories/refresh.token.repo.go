@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token models.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	// Rotate помечает токен tokenHash использованным и сохраняет next в том же семействе.
+	// Если токен уже был использован не дольше RefreshTokenReuseGrace назад и next
+	// уже сохранен как его преемник, ротация считается повторной и проходит без
+	// изменений. Иначе для использованного токена возвращает ErrRefreshTokenReused
+	// и найденную запись.
+	Rotate(ctx context.Context, tokenHash string, next models.RefreshToken) (models.RefreshToken, error)
+	DeleteFamily(ctx context.Context, familyID string) error
+	DeleteByUser(ctx context.Context, userID uint) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session models.Session) error
+	// CreateWithRefreshToken в одной транзакции сохраняет сессию и первый
+	// refresh-токен ее семейства.
+	CreateWithRefreshToken(ctx context.Context, session models.Session, token models.RefreshToken) error
+	GetByID(ctx context.Context, id string) (models.Session, error)
+	ListByUser(ctx context.Context, userID uint) ([]models.Session, error)
+	Touch(ctx context.Context, session models.Session) error
+	Delete(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userID uint) ([]string, error)
+	DeleteInactive(ctx context.Context, before time.Time) (int64, error)
+}
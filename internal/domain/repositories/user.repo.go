@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/merdernoty/anime-service/internal/domain/models"
 )
@@ -14,5 +15,16 @@ type UserRepository interface {
 	Delete(ctx context.Context, id uint) error
 	GetAll(ctx context.Context) ([]models.User, error)
 	GetByNickName(ctx context.Context, nickName string) (models.User, error)
+	UpdateRole(ctx context.Context, id uint, role models.Role) error
+	// UpdatePassword сохраняет новый хеш пароля, не трогая остальные поля.
+	UpdatePassword(ctx context.Context, id uint, passwordHash string) error
+	// PromoteFirstAdmin назначает пользователя администратором, только если
+	// других администраторов нет; иначе возвращает models.ErrAdminExists.
+	PromoteFirstAdmin(ctx context.Context, id uint) error
+	// MarkEmailVerified подтверждает email пользователя, только если он не
+	// изменился с момента отправки письма.
+	MarkEmailVerified(ctx context.Context, id uint, email string, verifiedAt time.Time) error
+	// SetLockedUntil блокирует вход до указанного времени; nil снимает блокировку.
+	SetLockedUntil(ctx context.Context, id uint, until *time.Time) error
 	// GetUserFriends(ctx context.Context, userID uint) ([]models.User, error)
-}
\ No newline at end of file
+}
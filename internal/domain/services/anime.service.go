@@ -2,13 +2,14 @@ package services
 
 import (
 	"context"
+	"time"
 
 	"github.com/merdernoty/anime-service/internal/domain/models"
 )
 
 type AnimeService interface {
 	GetAnimeByID(ctx context.Context, malID int64) (*models.Anime, error)
-	SearchAnime(ctx context.Context, query string, page, limit int) ([]*models.Anime, int, error)
+	SearchAnime(ctx context.Context, search models.AnimeSeatch) ([]*models.Anime, int, error)
 	GetTopAnime(ctx context.Context, page, limit int) ([]*models.Anime, int, error)
 	GetSeasonalAnime(ctx context.Context, year, season string, page, limit int) ([]*models.Anime, int, error)
 	GetAnimeRecommendations(ctx context.Context, malID int64, page, limit int) ([]*models.Anime, error)
@@ -17,7 +18,11 @@ type AnimeService interface {
 	AddAnimeToUserList(ctx context.Context, userID uint, animeMALID int64, status models.WatchStatus) error
 	RemoveAnimeFromUserList(ctx context.Context, userID uint, animeMALID int64) error
 	UpdateAnimeStatus(ctx context.Context, userID uint, animeMALID int64, status models.WatchStatus) error
-	UpdateUserAnimeEpisodes(ctx context.Context, userID uint, animeMALID int64, episodesWatched int) error
+	UpdateUserAnimeEpisodes(ctx context.Context, userID uint, animeMALID int64, episodesWatched int) (*models.UserAnime, error)
+	MarkEpisodesWatched(ctx context.Context, userID uint, animeMALID int64, from, to int, watchedAt time.Time, rating *float32) (*models.UserAnime, error)
+	UnmarkEpisodesWatched(ctx context.Context, userID uint, animeMALID int64, from, to int) (*models.UserAnime, error)
+	GetEpisodeWatchHistory(ctx context.Context, userID uint, animeMALID int64, limit int) ([]*models.EpisodeWatch, error)
+	UpdateUserAnimeTracking(ctx context.Context, userID uint, animeMALID int64, tracking models.UserAnimeTracking) (*models.UserAnime, error)
 	UpdateUserAnimeRating(ctx context.Context, userID uint, animeMALID int64, rating float32) error
 	GetUserAnimeStats(ctx context.Context, userID uint) (*models.AnimeStats, error)
-}
\ No newline at end of file
+}
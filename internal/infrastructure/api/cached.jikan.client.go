@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"logur.dev/logur"
+)
+
+const cacheKeyPrefix = "jikan"
+
+// CacheTTL задает время жизни кэша для каждого эндпоинта Jikan.
+type CacheTTL struct {
+	Anime           time.Duration
+	Search          time.Duration
+	Top             time.Duration
+	Seasonal        time.Duration
+	Recommendations time.Duration
+}
+
+type cachedAnimePage struct {
+	Items      []*models.Anime `json:"items"`
+	TotalPages int             `json:"total_pages"`
+}
+
+// CachedJikanClient — декоратор над JikanClient, кэширующий ответы Jikan API.
+type CachedJikanClient struct {
+	client repositories.JikanClient
+	cache  cache.Cache
+	ttl    CacheTTL
+	logger logur.LoggerFacade
+}
+
+func NewCachedJikanClient(client repositories.JikanClient, cache cache.Cache, ttl CacheTTL, logger logur.LoggerFacade) *CachedJikanClient {
+	return &CachedJikanClient{
+		client: client,
+		cache:  cache,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+func (c *CachedJikanClient) GetAnimeByID(ctx context.Context, malID int64) (*models.Anime, error) {
+	key := fmt.Sprintf("%s:anime:%d", cacheKeyPrefix, malID)
+
+	var anime models.Anime
+	if c.load(ctx, key, &anime) {
+		return &anime, nil
+	}
+
+	result, err := c.client.GetAnimeByID(ctx, malID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(ctx, key, result, c.ttl.Anime)
+	return result, nil
+}
+
+func (c *CachedJikanClient) SearchAnime(ctx context.Context, search models.AnimeSeatch) ([]*models.Anime, int, error) {
+	key := fmt.Sprintf("%s:search:%s", cacheKeyPrefix, searchQuery(search).Encode())
+
+	var cached cachedAnimePage
+	if c.load(ctx, key, &cached) {
+		return cached.Items, cached.TotalPages, nil
+	}
+
+	animes, totalPages, err := c.client.SearchAnime(ctx, search)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.store(ctx, key, cachedAnimePage{Items: animes, TotalPages: totalPages}, c.ttl.Search)
+	return animes, totalPages, nil
+}
+
+func (c *CachedJikanClient) GetTopAnime(ctx context.Context, page, limit int) ([]*models.Anime, int, error) {
+	key := fmt.Sprintf("%s:top:%d:%d", cacheKeyPrefix, page, limit)
+
+	var cached cachedAnimePage
+	if c.load(ctx, key, &cached) {
+		return cached.Items, cached.TotalPages, nil
+	}
+
+	animes, totalPages, err := c.client.GetTopAnime(ctx, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.store(ctx, key, cachedAnimePage{Items: animes, TotalPages: totalPages}, c.ttl.Top)
+	return animes, totalPages, nil
+}
+
+func (c *CachedJikanClient) GetSeasonalAnime(ctx context.Context, year, season string, page, limit int) ([]*models.Anime, int, error) {
+	key := fmt.Sprintf("%s:seasonal:%s:%s:%d:%d", cacheKeyPrefix, year, season, page, limit)
+
+	var cached cachedAnimePage
+	if c.load(ctx, key, &cached) {
+		return cached.Items, cached.TotalPages, nil
+	}
+
+	animes, totalPages, err := c.client.GetSeasonalAnime(ctx, year, season, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.store(ctx, key, cachedAnimePage{Items: animes, TotalPages: totalPages}, c.ttl.Seasonal)
+	return animes, totalPages, nil
+}
+
+func (c *CachedJikanClient) GetAnimeRecommendations(ctx context.Context, malID int64, page, limit int) ([]*models.Anime, error) {
+	key := fmt.Sprintf("%s:recommendations:%d:%d:%d", cacheKeyPrefix, malID, page, limit)
+
+	var animes []*models.Anime
+	if c.load(ctx, key, &animes) {
+		return animes, nil
+	}
+
+	animes, err := c.client.GetAnimeRecommendations(ctx, malID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(ctx, key, animes, c.ttl.Recommendations)
+	return animes, nil
+}
+
+func (c *CachedJikanClient) load(ctx context.Context, key string, target interface{}) bool {
+	data, err := c.cache.Get(ctx, key)
+	if err != nil {
+		if err != cache.ErrCacheMiss {
+			c.logger.Warn("Failed to read from cache", map[string]interface{}{
+				"key":   key,
+				"error": err.Error(),
+			})
+		}
+		c.logger.Debug("Jikan cache miss", map[string]interface{}{
+			"key": key,
+		})
+		return false
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		c.logger.Warn("Failed to decode cached value", map[string]interface{}{
+			"key":   key,
+			"error": err.Error(),
+		})
+		return false
+	}
+
+	c.logger.Debug("Jikan cache hit", map[string]interface{}{
+		"key": key,
+	})
+	return true
+}
+
+func (c *CachedJikanClient) store(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.logger.Warn("Failed to encode value for cache", map[string]interface{}{
+			"key":   key,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := c.cache.Set(ctx, key, data, ttl); err != nil {
+		c.logger.Warn("Failed to write to cache", map[string]interface{}{
+			"key":   key,
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/cache"
+	"logur.dev/logur"
+)
+
+var errJikanUnavailable = errors.New("jikan unavailable")
+
+// countingJikanClient считает обращения к Jikan и отвечает аниме с запрошенным
+// MALId; пока fail установлен, возвращает ошибку.
+type countingJikanClient struct {
+	repositories.JikanClient
+	calls int
+	fail  bool
+}
+
+func (c *countingJikanClient) GetAnimeByID(ctx context.Context, malID int64) (*models.Anime, error) {
+	c.calls++
+	if c.fail {
+		return nil, errJikanUnavailable
+	}
+	return &models.Anime{MALId: malID, Title: "Anime", Episodes: 12}, nil
+}
+
+func (c *countingJikanClient) SearchAnime(ctx context.Context, search models.AnimeSeatch) ([]*models.Anime, int, error) {
+	c.calls++
+	return []*models.Anime{{MALId: 1, Title: search.Query}}, 3, nil
+}
+
+func newCachedTestClient(ttl time.Duration) (*CachedJikanClient, *countingJikanClient) {
+	client := &countingJikanClient{}
+	ttls := CacheTTL{Anime: ttl, Search: ttl, Top: ttl, Seasonal: ttl, Recommendations: ttl}
+	return NewCachedJikanClient(client, cache.NewMemoryCache(100, time.Minute), ttls, logur.NoopLogger{}), client
+}
+
+func TestCachedJikanClientServesRepeatsFromCache(t *testing.T) {
+	ctx := context.Background()
+	cached, client := newCachedTestClient(time.Minute)
+
+	first, err := cached.GetAnimeByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetAnimeByID: %v", err)
+	}
+	second, err := cached.GetAnimeByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetAnimeByID from cache: %v", err)
+	}
+	if client.calls != 1 {
+		t.Errorf("Jikan calls = %d, want 1", client.calls)
+	}
+	if second.MALId != first.MALId || second.Title != first.Title || second.Episodes != first.Episodes {
+		t.Errorf("cached anime = %+v, want %+v", second, first)
+	}
+
+	if _, err := cached.GetAnimeByID(ctx, 2); err != nil {
+		t.Fatalf("GetAnimeByID another anime: %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("Jikan calls after another id = %d, want 2", client.calls)
+	}
+}
+
+func TestCachedJikanClientKeysSearchByQuery(t *testing.T) {
+	ctx := context.Background()
+	cached, client := newCachedTestClient(time.Minute)
+
+	for i := 0; i < 2; i++ {
+		animes, totalPages, err := cached.SearchAnime(ctx, models.AnimeSeatch{Query: "naruto"})
+		if err != nil {
+			t.Fatalf("SearchAnime: %v", err)
+		}
+		if len(animes) != 1 || animes[0].Title != "naruto" || totalPages != 3 {
+			t.Errorf("SearchAnime = %d animes, %d pages, want 1 anime titled naruto, 3 pages", len(animes), totalPages)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("Jikan calls for a repeated search = %d, want 1", client.calls)
+	}
+
+	if _, _, err := cached.SearchAnime(ctx, models.AnimeSeatch{Query: "bleach"}); err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("Jikan calls for another query = %d, want 2", client.calls)
+	}
+}
+
+func TestCachedJikanClientDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	cached, client := newCachedTestClient(time.Minute)
+
+	client.fail = true
+	if _, err := cached.GetAnimeByID(ctx, 1); err != errJikanUnavailable {
+		t.Fatalf("GetAnimeByID: err = %v, want %v", err, errJikanUnavailable)
+	}
+
+	client.fail = false
+	if _, err := cached.GetAnimeByID(ctx, 1); err != nil {
+		t.Fatalf("GetAnimeByID after recovery: %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("Jikan calls = %d, want 2: the error must not be cached", client.calls)
+	}
+}
+
+func TestCachedJikanClientExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	cached, client := newCachedTestClient(50 * time.Millisecond)
+
+	if _, err := cached.GetAnimeByID(ctx, 1); err != nil {
+		t.Fatalf("GetAnimeByID: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := cached.GetAnimeByID(ctx, 1); err != nil {
+		t.Fatalf("GetAnimeByID after expiry: %v", err)
+	}
+	if client.calls != 2 {
+		t.Errorf("Jikan calls = %d, want 2 after the entry expired", client.calls)
+	}
+}
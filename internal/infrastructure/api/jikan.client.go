@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/merdernoty/anime-service/internal/domain/models"
@@ -19,16 +21,110 @@ const (
 )
 
 type JikanClient struct {
-	httpClient *http.Client
-	logger     logur.LoggerFacade
+	httpClient  *http.Client
+	limiter     *RateLimiter
+	retryPolicy RetryPolicy
+	logger      logur.LoggerFacade
+
+	retries     atomic.Uint64
+	rateLimited atomic.Uint64
+}
+
+// ClientStats — счетчики клиента Jikan для наблюдения за троттлингом.
+type ClientStats struct {
+	QueueDepth  int64  `json:"queue_depth"`
+	Retries     uint64 `json:"retries"`
+	RateLimited uint64 `json:"rate_limited"`
 }
 
-func NewJikanClient(logger logur.LoggerFacade) *JikanClient {
+func NewJikanClient(logger logur.LoggerFacade, limiter *RateLimiter, retryPolicy RetryPolicy) *JikanClient {
 	return &JikanClient{
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		logger: logger,
+		limiter:     limiter,
+		retryPolicy: retryPolicy,
+		logger:      logger,
+	}
+}
+
+func (c *JikanClient) Stats() ClientStats {
+	return ClientStats{
+		QueueDepth:  c.limiter.QueueDepth(),
+		Retries:     c.retries.Load(),
+		RateLimited: c.rateLimited.Load(),
+	}
+}
+
+// get выполняет GET-запрос к Jikan API с учетом общего лимита запросов,
+// повторяя его при 429/5xx и сетевых ошибках.
+func (c *JikanClient) get(ctx context.Context, apiURL string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && !c.retryPolicy.shouldRetry(resp.StatusCode) {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		// Retry-After относится ко всему клиенту: пауза общего лимитера
+		// задерживает и запросы остальных горутин, а не только этот повтор.
+		delay, hasRetryAfter := time.Duration(0), false
+		if err == nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				c.rateLimited.Add(1)
+			}
+			if after, ok := retryAfter(resp); ok {
+				delay, hasRetryAfter = c.retryPolicy.clampRetryAfter(after), true
+				c.limiter.Pause(delay)
+			}
+		}
+		if attempt >= c.retryPolicy.MaxRetries {
+			return resp, err
+		}
+		if !hasRetryAfter {
+			delay = c.retryPolicy.backoff(attempt + 1)
+		}
+		// Повтор, который начнется уже после дедлайна запроса, бесполезен.
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+
+		fields := map[string]interface{}{
+			"url":     apiURL,
+			"attempt": attempt + 1,
+			"delay":   delay.String(),
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		} else {
+			fields["status_code"] = resp.StatusCode
+			resp.Body.Close()
+		}
+
+		c.retries.Add(1)
+		c.logger.Warn("Retrying Jikan API request", fields)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
 	}
 }
 
@@ -37,16 +133,9 @@ func (c *JikanClient) GetAnimeByID(ctx context.Context, malID int64) (*models.An
 		"mal_id": malID,
 	})
 
-	time.Sleep(500 * time.Millisecond)
-
 	apiURL := fmt.Sprintf("%s/anime/%d", jikanBaseURL, malID)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.get(ctx, apiURL)
 	if err != nil {
 		c.logger.Error("Error fetching anime from Jikan API", map[string]interface{}{
 			"mal_id": malID,
@@ -56,6 +145,9 @@ func (c *JikanClient) GetAnimeByID(ctx context.Context, malID int64) (*models.An
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("anime %d: %w", malID, models.ErrAnimeNotFound)
+	}
 	if resp.StatusCode != http.StatusOK {
 		c.logger.Error("Received non-OK response from Jikan API", map[string]interface{}{
 			"mal_id":      malID,
@@ -66,12 +158,12 @@ func (c *JikanClient) GetAnimeByID(ctx context.Context, malID int64) (*models.An
 
 	var animeResponse struct {
 		Data struct {
-			MalID         int     `json:"mal_id"`
-			Title         string  `json:"title"`
-			TitleEnglish  string  `json:"title_english"`
-			TitleJapanese string  `json:"title_japanese"`
-			Synopsis      string  `json:"synopsis"`
-			ImageURL      string  `json:"image_url"`
+			MalID         int    `json:"mal_id"`
+			Title         string `json:"title"`
+			TitleEnglish  string `json:"title_english"`
+			TitleJapanese string `json:"title_japanese"`
+			Synopsis      string `json:"synopsis"`
+			ImageURL      string `json:"image_url"`
 			Images        struct {
 				JPG struct {
 					ImageURL string `json:"image_url"`
@@ -128,32 +220,76 @@ func (c *JikanClient) GetAnimeByID(ctx context.Context, malID int64) (*models.An
 	return anime, nil
 }
 
-func (c *JikanClient) SearchAnime(ctx context.Context, query string, page, limit int) ([]*models.Anime, int, error) {
+// searchQuery переводит фильтры поиска в параметры запроса Jikan /anime.
+func searchQuery(search models.AnimeSeatch) url.Values {
+	q := url.Values{}
+	if search.Query != "" {
+		q.Set("q", search.Query)
+	}
+	if search.Type != "" {
+		q.Set("type", search.Type)
+	}
+	if search.Status != "" {
+		q.Set("status", search.Status)
+	}
+	if search.Rating != "" {
+		q.Set("rating", search.Rating)
+	}
+	if len(search.Genres) > 0 {
+		q.Set("genres", joinIDs(search.Genres))
+	}
+	if len(search.ExcludedGenres) > 0 {
+		q.Set("genres_exclude", joinIDs(search.ExcludedGenres))
+	}
+	if search.MinScore != nil {
+		q.Set("min_score", strconv.FormatFloat(*search.MinScore, 'f', -1, 64))
+	}
+	if search.MaxScore != nil {
+		q.Set("max_score", strconv.FormatFloat(*search.MaxScore, 'f', -1, 64))
+	}
+	if search.StartDate != "" {
+		q.Set("start_date", search.StartDate)
+	}
+	if search.EndDate != "" {
+		q.Set("end_date", search.EndDate)
+	}
+	if search.OrderBy != "" {
+		q.Set("order_by", search.OrderBy)
+	}
+	if search.Sort != "" {
+		q.Set("sort", search.Sort)
+	}
+	if search.SFW {
+		q.Set("sfw", "true")
+	}
+	q.Set("page", strconv.Itoa(search.Page))
+	q.Set("limit", strconv.Itoa(search.Limit))
+	return q
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c *JikanClient) SearchAnime(ctx context.Context, search models.AnimeSeatch) ([]*models.Anime, int, error) {
+	query := search.Query
 	c.logger.Info("Searching anime in Jikan API", map[string]interface{}{
-		"query": query,
-		"page":  page,
-		"limit": limit,
+		"query":   query,
+		"filters": searchQuery(search).Encode(),
 	})
 
-	time.Sleep(500 * time.Millisecond)
-
 	apiURL, err := url.Parse(fmt.Sprintf("%s/anime", jikanBaseURL))
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
-	q := apiURL.Query()
-	q.Add("q", query)
-	q.Add("page", strconv.Itoa(page))
-	q.Add("limit", strconv.Itoa(limit))
-	apiURL.RawQuery = q.Encode()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL.String(), nil)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create request: %w", err)
-	}
+	apiURL.RawQuery = searchQuery(search).Encode()
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.get(ctx, apiURL.String())
 	if err != nil {
 		c.logger.Error("Error searching anime from Jikan API", map[string]interface{}{
 			"query": query,
@@ -173,7 +309,7 @@ func (c *JikanClient) SearchAnime(ctx context.Context, query string, page, limit
 
 	var searchResponse struct {
 		Pagination struct {
-			LastVisiblePage int `json:"last_visible_page"`
+			LastVisiblePage int  `json:"last_visible_page"`
 			HasNextPage     bool `json:"has_next_page"`
 		} `json:"pagination"`
 		Data []struct {
@@ -203,14 +339,14 @@ func (c *JikanClient) SearchAnime(ctx context.Context, query string, page, limit
 	animes := make([]*models.Anime, 0, len(searchResponse.Data))
 	for _, result := range searchResponse.Data {
 		anime := &models.Anime{
-			MALId:     int64(result.MalID),
-			Title:     result.Title,
-			Synopsis:  result.Synopsis,
-			Type:      result.Type,
-			Episodes:  result.Episodes,
-			Score:     result.Score,
-			Airing:    result.Airing,
-			ImageURL:  result.Images.JPG.ImageURL,
+			MALId:    int64(result.MalID),
+			Title:    result.Title,
+			Synopsis: result.Synopsis,
+			Type:     result.Type,
+			Episodes: result.Episodes,
+			Score:    result.Score,
+			Airing:   result.Airing,
+			ImageURL: result.Images.JPG.ImageURL,
 		}
 		animes = append(animes, anime)
 	}
@@ -224,8 +360,6 @@ func (c *JikanClient) GetTopAnime(ctx context.Context, page, limit int) ([]*mode
 		"limit": limit,
 	})
 
-	time.Sleep(500 * time.Millisecond)
-
 	apiURL, err := url.Parse(fmt.Sprintf("%s/top/anime", jikanBaseURL))
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to parse URL: %w", err)
@@ -236,12 +370,7 @@ func (c *JikanClient) GetTopAnime(ctx context.Context, page, limit int) ([]*mode
 	q.Add("limit", strconv.Itoa(limit))
 	apiURL.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL.String(), nil)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.get(ctx, apiURL.String())
 	if err != nil {
 		c.logger.Error("Error fetching top anime from Jikan API", map[string]interface{}{
 			"error": err.Error(),
@@ -259,7 +388,7 @@ func (c *JikanClient) GetTopAnime(ctx context.Context, page, limit int) ([]*mode
 
 	var topResponse struct {
 		Pagination struct {
-			LastVisiblePage int `json:"last_visible_page"`
+			LastVisiblePage int  `json:"last_visible_page"`
 			HasNextPage     bool `json:"has_next_page"`
 		} `json:"pagination"`
 		Data []struct {
@@ -309,8 +438,6 @@ func (c *JikanClient) GetSeasonalAnime(ctx context.Context, year, season string,
 		"limit":  limit,
 	})
 
-	time.Sleep(500 * time.Millisecond)
-
 	apiURL, err := url.Parse(fmt.Sprintf("%s/seasons/%s/%s", jikanBaseURL, year, season))
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to parse URL: %w", err)
@@ -321,12 +448,7 @@ func (c *JikanClient) GetSeasonalAnime(ctx context.Context, year, season string,
 	q.Add("limit", strconv.Itoa(limit))
 	apiURL.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL.String(), nil)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.get(ctx, apiURL.String())
 	if err != nil {
 		c.logger.Error("Error fetching seasonal anime from Jikan API", map[string]interface{}{
 			"year":   year,
@@ -348,7 +470,7 @@ func (c *JikanClient) GetSeasonalAnime(ctx context.Context, year, season string,
 
 	var seasonalResponse struct {
 		Pagination struct {
-			LastVisiblePage int `json:"last_visible_page"`
+			LastVisiblePage int  `json:"last_visible_page"`
 			HasNextPage     bool `json:"has_next_page"`
 		} `json:"pagination"`
 		Data []struct {
@@ -379,14 +501,14 @@ func (c *JikanClient) GetSeasonalAnime(ctx context.Context, year, season string,
 	animes := make([]*models.Anime, 0, len(seasonalResponse.Data))
 	for _, result := range seasonalResponse.Data {
 		anime := &models.Anime{
-			MALId:     int64(result.MalID),
-			Title:     result.Title,
-			Synopsis:  result.Synopsis,
-			Type:      result.Type,
-			Episodes:  result.Episodes,
-			Score:     result.Score,
-			Airing:    result.Airing,
-			ImageURL:  result.Images.JPG.ImageURL,
+			MALId:    int64(result.MalID),
+			Title:    result.Title,
+			Synopsis: result.Synopsis,
+			Type:     result.Type,
+			Episodes: result.Episodes,
+			Score:    result.Score,
+			Airing:   result.Airing,
+			ImageURL: result.Images.JPG.ImageURL,
 		}
 		animes = append(animes, anime)
 	}
@@ -399,16 +521,9 @@ func (c *JikanClient) GetAnimeRecommendations(ctx context.Context, malID int64,
 		"mal_id": malID,
 	})
 
-	time.Sleep(500 * time.Millisecond)
-
 	apiURL := fmt.Sprintf("%s/anime/%d/recommendations", jikanBaseURL, malID)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.get(ctx, apiURL)
 	if err != nil {
 		c.logger.Error("Error fetching anime recommendations from Jikan API", map[string]interface{}{
 			"mal_id": malID,
@@ -482,4 +597,4 @@ func (c *JikanClient) GetAnimeRecommendations(ctx context.Context, malID int64,
 	}
 
 	return animes, nil
-}
\ No newline at end of file
+}
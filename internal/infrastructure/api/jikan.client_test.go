@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"logur.dev/logur"
+)
+
+// scriptedTransport отвечает на запросы по очереди кодами statuses; последний
+// код повторяется. На 429 приходит Retry-After на час.
+type scriptedTransport struct {
+	statuses []int
+	requests atomic.Int32
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	i := int(s.requests.Add(1)) - 1
+	if i >= len(s.statuses) {
+		i = len(s.statuses) - 1
+	}
+	resp := &http.Response{
+		StatusCode: s.statuses[i],
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		resp.Header.Set("Retry-After", "3600")
+	}
+	return resp, nil
+}
+
+func newScriptedClient(policy RetryPolicy, statuses ...int) (*JikanClient, *scriptedTransport) {
+	transport := &scriptedTransport{statuses: statuses}
+	client := NewJikanClient(logur.NoopLogger{}, NewRateLimiter(RateLimit{Requests: 100, Per: time.Second}), policy)
+	client.httpClient = &http.Client{Transport: transport}
+	return client, transport
+}
+
+func TestGetClampsRetryAfterAndPausesLimiter(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxRetryAfter: 100 * time.Millisecond}
+	client, transport := newScriptedClient(policy, http.StatusTooManyRequests, http.StatusOK)
+
+	start := time.Now()
+	resp, err := client.get(context.Background(), "https://jikan.test/anime/1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+
+	elapsed := time.Since(start)
+	if resp.StatusCode != http.StatusOK || transport.requests.Load() != 2 {
+		t.Fatalf("get = %d after %d requests, want 200 after 2", resp.StatusCode, transport.requests.Load())
+	}
+	if elapsed < 90*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("retry after Retry-After: 3600 took %s, want the 100ms cap", elapsed)
+	}
+	if stats := client.Stats(); stats.RateLimited != 1 || stats.Retries != 1 {
+		t.Errorf("stats = %+v, want 1 rate-limited response and 1 retry", stats)
+	}
+}
+
+func TestRetryAfterPausesOtherRequests(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 0, MaxRetryAfter: 150 * time.Millisecond}
+	client, _ := newScriptedClient(policy, http.StatusTooManyRequests)
+
+	resp, err := client.get(context.Background(), "https://jikan.test/anime/1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("get without retries = %d, want 429", resp.StatusCode)
+	}
+
+	start := time.Now()
+	if err := client.limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 120*time.Millisecond {
+		t.Errorf("next request admitted after %s, want it held for the Retry-After pause", elapsed)
+	}
+}
+
+func TestGetDoesNotWaitPastDeadline(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, MaxRetryAfter: time.Minute}
+	client, transport := newScriptedClient(policy, http.StatusTooManyRequests)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := client.get(ctx, "https://jikan.test/anime/1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || transport.requests.Load() != 1 {
+		t.Errorf("get = %d after %d requests, want 429 after 1", resp.StatusCode, transport.requests.Load())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("get returned after %s, want no wait for a retry past the deadline", elapsed)
+	}
+}
+
+func TestGetBacksOffOnServerErrors(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	client, transport := newScriptedClient(policy, http.StatusBadGateway)
+
+	resp, err := client.get(context.Background(), "https://jikan.test/anime/1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || transport.requests.Load() != 3 {
+		t.Errorf("get = %d after %d requests, want 502 after 3", resp.StatusCode, transport.requests.Load())
+	}
+}
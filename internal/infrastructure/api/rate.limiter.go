@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimit описывает одно окно ограничения: не более Requests запросов за Per.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+type tokenBucket struct {
+	capacity   float64
+	tokens     float64
+	refillRate float64
+	updatedAt  time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens += elapsed * b.refillRate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.updatedAt = now
+}
+
+func (b *tokenBucket) delay() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.refillRate * float64(time.Second))
+}
+
+// RateLimiter — общий для всех горутин token bucket, соблюдающий сразу
+// несколько окон (например, 3 запроса в секунду и 60 в минуту).
+type RateLimiter struct {
+	mu          sync.Mutex
+	buckets     []*tokenBucket
+	pausedUntil time.Time
+	waiting     atomic.Int64
+}
+
+func NewRateLimiter(limits ...RateLimit) *RateLimiter {
+	now := time.Now()
+	buckets := make([]*tokenBucket, 0, len(limits))
+	for _, limit := range limits {
+		if limit.Requests <= 0 || limit.Per <= 0 {
+			continue
+		}
+		buckets = append(buckets, &tokenBucket{
+			capacity:   float64(limit.Requests),
+			tokens:     float64(limit.Requests),
+			refillRate: float64(limit.Requests) / limit.Per.Seconds(),
+			updatedAt:  now,
+		})
+	}
+	return &RateLimiter{buckets: buckets}
+}
+
+// Wait блокируется, пока во всех окнах не появится свободный токен,
+// либо пока не будет отменен контекст.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Pause задерживает все запросы на d, например по Retry-After из ответа 429.
+// Более короткая пауза не сокращает уже действующую.
+func (l *RateLimiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// QueueDepth возвращает количество запросов, ожидающих токен.
+func (l *RateLimiter) QueueDepth() int64 {
+	return l.waiting.Load()
+}
+
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	var delay time.Duration
+	for _, bucket := range l.buckets {
+		bucket.refill(now)
+		if d := bucket.delay(); d > delay {
+			delay = d
+		}
+	}
+
+	if delay > 0 {
+		return delay
+	}
+
+	for _, bucket := range l.buckets {
+		bucket.tokens--
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllowsBurstThenWaits(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Requests: 3, Per: 300 * time.Millisecond})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Wait %d: %v", i, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("burst of 3 took %s, want no waiting", elapsed)
+	}
+
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("fourth Wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("fourth request admitted after %s, want about 100ms", elapsed)
+	}
+}
+
+func TestRateLimiterHonorsEveryWindow(t *testing.T) {
+	// Окно в секунду пропустило бы 10 запросов, окно пошире — только 2.
+	limiter := NewRateLimiter(
+		RateLimit{Requests: 10, Per: 100 * time.Millisecond},
+		RateLimit{Requests: 2, Per: time.Minute},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	admitted := 0
+	for limiter.Wait(ctx) == nil {
+		admitted++
+	}
+	if admitted != 2 {
+		t.Errorf("admitted %d requests, want 2", admitted)
+	}
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Requests: 100, Per: time.Second})
+	limiter.Pause(100 * time.Millisecond)
+	// Более короткая пауза не сокращает действующую.
+	limiter.Pause(time.Millisecond)
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("request admitted %s into a 100ms pause", elapsed)
+	}
+}
+
+func TestRateLimiterWaitStopsOnCancel(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Requests: 1, Per: time.Hour})
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait on an empty bucket = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if depth := limiter.QueueDepth(); depth != 0 {
+		t.Errorf("QueueDepth after cancel = %d, want 0", depth)
+	}
+}
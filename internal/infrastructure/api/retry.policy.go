@@ -0,0 +1,69 @@
+package api
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultMaxRetryAfter — предел Retry-After, если MaxRetryAfter не задан.
+const defaultMaxRetryAfter = time.Minute
+
+type RetryPolicy struct {
+	MaxRetries    int
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+	MaxRetryAfter time.Duration // Предел паузы из Retry-After; сервер может прислать и часы
+}
+
+func (p RetryPolicy) shouldRetry(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// backoff возвращает задержку перед попыткой attempt (начиная с 1):
+// экспоненциальный рост с "full jitter", ограниченный MaxDelay.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+// clampRetryAfter ограничивает паузу из Retry-After значением MaxRetryAfter.
+func (p RetryPolicy) clampRetryAfter(delay time.Duration) time.Duration {
+	limit := p.MaxRetryAfter
+	if limit <= 0 {
+		limit = defaultMaxRetryAfter
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// retryAfter разбирает заголовок Retry-After (в секундах или в формате HTTP-даты).
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffStaysWithinBounds(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		limit := policy.BaseDelay << (attempt - 1)
+		if limit > policy.MaxDelay {
+			limit = policy.MaxDelay
+		}
+		for i := 0; i < 100; i++ {
+			if delay := policy.backoff(attempt); delay <= 0 || delay > limit {
+				t.Fatalf("backoff(%d) = %s, want (0, %s]", attempt, delay, limit)
+			}
+		}
+	}
+
+	// Сдвиг переполняет задержку на больших номерах попыток.
+	if delay := policy.backoff(100); delay <= 0 || delay > policy.MaxDelay {
+		t.Errorf("backoff(100) = %s, want (0, %s]", delay, policy.MaxDelay)
+	}
+	if delay := (RetryPolicy{}).backoff(1); delay != 0 {
+		t.Errorf("backoff without delays = %s, want 0", delay)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"absent", "", 0, false},
+		{"seconds", "5", 5 * time.Second, true},
+		{"zero", "0", 0, true},
+		{"negative", "-1", 0, false},
+		{"garbage", "soon", 0, false},
+		{"date in the past", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				resp.Header.Set("Retry-After", tt.value)
+			}
+			got, ok := retryAfter(resp)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter(%q) = (%s, %v), want (%s, %v)", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}}}
+	if got, ok := retryAfter(resp); !ok || got < 59*time.Minute || got > time.Hour {
+		t.Errorf("retryAfter(date in an hour) = (%s, %v), want about an hour", got, ok)
+	}
+}
+
+func TestClampRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit time.Duration
+		delay time.Duration
+		want  time.Duration
+	}{
+		{"below the limit", 30 * time.Second, 10 * time.Second, 10 * time.Second},
+		{"above the limit", 30 * time.Second, time.Hour, 30 * time.Second},
+		{"default limit", 0, time.Hour, defaultMaxRetryAfter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (RetryPolicy{MaxRetryAfter: tt.limit}).clampRetryAfter(tt.delay); got != tt.want {
+				t.Errorf("clampRetryAfter(%s) = %s, want %s", tt.delay, got, tt.want)
+			}
+		})
+	}
+}
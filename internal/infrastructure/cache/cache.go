@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+var (
+	ErrCacheMiss         = errors.New("cache miss")
+	ErrUnsupportedDriver = errors.New("unsupported cache driver")
+)
+
+const (
+	DriverMemory = "memory"
+	DriverRedis  = "redis"
+)
+
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Increment атомарно увеличивает счетчик и возвращает новое значение. TTL
+	// задается только при создании ключа, поэтому счетчик живет в фиксированном окне.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Add сохраняет значение, только если ключа еще нет, и сообщает, было ли оно сохранено.
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Take атомарно читает и удаляет значение: из параллельных вызовов значение
+	// получит только один, остальные — ErrCacheMiss.
+	Take(ctx context.Context, key string) ([]byte, error)
+}
+
+type Config struct {
+	Driver            string
+	Host              string
+	Port              int
+	Password          string
+	DB                int
+	MaxEntries        int
+	DefaultExpiration time.Duration
+}
+
+// New создает кэш ответов: в памяти он вытесняет давние записи при
+// превышении MaxEntries.
+func New(config Config) (Cache, error) {
+	switch config.Driver {
+	case DriverMemory, "":
+		return NewMemoryCache(config.MaxEntries, config.DefaultExpiration), nil
+	case DriverRedis:
+		return NewRedisCache(config)
+	default:
+		return nil, errors.WithDetails(ErrUnsupportedDriver, "driver", config.Driver)
+	}
+}
+
+// NewStore создает хранилище состояния (токены, счетчики попыток, задачи):
+// в отличие от кэша ответов, записи в нем не вытесняются до истечения TTL.
+// Хранилище в памяти не разделяется между репликами, поэтому подходит только
+// для одного процесса.
+func NewStore(config Config) (Cache, error) {
+	switch config.Driver {
+	case DriverMemory, "":
+		return NewMemoryStore(config.DefaultExpiration), nil
+	case DriverRedis:
+		return NewRedisCache(config)
+	default:
+		return nil, errors.WithDetails(ErrUnsupportedDriver, "driver", config.Driver)
+	}
+}
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxEntries = 10000
+
+	// purgeInterval — как часто хранилище без вытеснения удаляет истекшие записи.
+	purgeInterval = time.Minute
+)
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryCache — кэш в памяти процесса. Кэш ответов (NewMemoryCache) — LRU с
+// ограничением по количеству записей; хранилище состояния (NewMemoryStore)
+// ничего не вытесняет и только периодически удаляет истекшие записи.
+type MemoryCache struct {
+	mu                sync.Mutex
+	maxEntries        int // 0 — без ограничения
+	defaultExpiration time.Duration
+	items             map[string]*list.Element
+	order             *list.List
+	lastPurge         time.Time
+}
+
+func NewMemoryCache(maxEntries int, defaultExpiration time.Duration) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	return newMemoryCache(maxEntries, defaultExpiration)
+}
+
+// NewMemoryStore создает хранилище в памяти без вытеснения записей.
+func NewMemoryStore(defaultExpiration time.Duration) *MemoryCache {
+	return newMemoryCache(0, defaultExpiration)
+}
+
+func newMemoryCache(maxEntries int, defaultExpiration time.Duration) *MemoryCache {
+	return &MemoryCache{
+		maxEntries:        maxEntries,
+		defaultExpiration: defaultExpiration,
+		items:             make(map[string]*list.Element),
+		order:             list.New(),
+		lastPurge:         time.Now(),
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.lookup(key, time.Now())
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return entry.value, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = c.expiresAt(ttl, now)
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.insert(key, value, ttl, now)
+	return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := c.lookup(key, now); ok {
+		value, _ := strconv.ParseInt(string(entry.value), 10, 64)
+		value++
+		entry.value = []byte(strconv.FormatInt(value, 10))
+		return value, nil
+	}
+
+	c.insert(key, []byte("1"), ttl, now)
+	return 1, nil
+}
+
+func (c *MemoryCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.lookup(key, now); ok {
+		return false, nil
+	}
+
+	c.insert(key, value, ttl, now)
+	return true, nil
+}
+
+func (c *MemoryCache) Take(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.lookup(key, time.Now())
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	c.removeElement(c.items[key])
+	return entry.value, nil
+}
+
+// lookup возвращает неистекшую запись и отмечает ее как недавно использованную.
+func (c *MemoryCache) lookup(key string, now time.Time) (*memoryEntry, bool) {
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if entry.expired(now) {
+		c.removeElement(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+func (c *MemoryCache) insert(key string, value []byte, ttl time.Duration, now time.Time) {
+	element := c.order.PushFront(&memoryEntry{
+		key:       key,
+		value:     value,
+		expiresAt: c.expiresAt(ttl, now),
+	})
+	c.items[key] = element
+
+	if c.maxEntries == 0 {
+		c.purgeExpired(now)
+		return
+	}
+	for c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *MemoryCache) expiresAt(ttl time.Duration, now time.Time) time.Time {
+	if ttl <= 0 {
+		ttl = c.defaultExpiration
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// purgeExpired не чаще purgeInterval удаляет истекшие записи, к которым больше не обращаются.
+func (c *MemoryCache) purgeExpired(now time.Time) {
+	if now.Sub(c.lastPurge) < purgeInterval {
+		return
+	}
+	c.lastPurge = now
+
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*memoryEntry).expired(now) {
+			c.removeElement(element)
+		}
+		element = next
+	}
+}
+
+func (c *MemoryCache) removeElement(element *list.Element) {
+	entry := c.order.Remove(element).(*memoryEntry)
+	delete(c.items, entry.key)
+}
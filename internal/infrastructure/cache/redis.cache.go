@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// incrementScript увеличивает счетчик и при создании ставит ему срок жизни.
+// Обе команды выполняются атомарно, поэтому счетчик не остается без срока,
+// если соединение оборвется между ними.
+var incrementScript = redis.NewScript(`
+local value = redis.call("INCR", KEYS[1])
+if value == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return value
+`)
+
+type RedisCache struct {
+	client            *redis.Client
+	defaultExpiration time.Duration
+}
+
+func NewRedisCache(config Config) (*RedisCache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", config.Host, config.Port),
+		Password: config.Password,
+		DB:       config.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, errors.Wrap(err, "failed to connect to redis")
+	}
+
+	return &RedisCache{
+		client:            client,
+		defaultExpiration: config.DefaultExpiration,
+	}, nil
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return value, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.defaultExpiration
+	}
+	return errors.WithStack(c.client.Set(ctx, key, value, ttl).Err())
+}
+
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	return errors.WithStack(c.client.Del(ctx, key).Err())
+}
+
+func (c *RedisCache) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		ttl = c.defaultExpiration
+	}
+	value, err := incrementScript.Run(ctx, c.client, []string{key}, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return value, nil
+}
+
+func (c *RedisCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		ttl = c.defaultExpiration
+	}
+	added, err := c.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return added, nil
+}
+
+func (c *RedisCache) Take(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.GetDel(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return value, nil
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
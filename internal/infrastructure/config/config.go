@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"emperror.dev/errors"
 	"github.com/joho/godotenv"
 	"github.com/merdernoty/anime-service/internal/infrastructure/database"
 )
@@ -17,6 +18,11 @@ type Config struct {
 	Logger     LoggerConfig     // Настройки логирования
 	Auth       AuthConfig       // Настройки аутентификации
 	Cache      CacheConfig      // Настройки кэширования
+	Store      StoreConfig      // Настройки хранилища состояния
+	Jikan      JikanConfig      // Настройки клиента Jikan API
+	Catalog    CatalogConfig    // Настройки локального каталога аниме
+	Import     ImportConfig     // Настройки импорта списков
+	Mail       MailConfig       // Настройки отправки почты
 	Pagination PaginationConfig // Настройки пагинации
 }
 
@@ -51,8 +57,60 @@ type LoggerConfig struct {
 }
 
 type AuthConfig struct {
-	SecretKey      string        
-	TokenDuration  time.Duration 
+	SecretKey                       string
+	TokenDuration                   time.Duration
+	KeysDir                         string        // Каталог с ключами RS256/EdDSA; если пуст, токены подписываются HS256 с SecretKey
+	ActiveKeyID                     string        // kid ключа, которым подписываются новые токены
+	BootstrapAdminEmail             string        // Email пользователя, которого при старте нужно сделать первым администратором; адрес должен быть подтвержден
+	PasswordResetTTL                time.Duration // Срок жизни токена сброса пароля
+	PasswordResetURL                string        // Страница фронтенда для сброса пароля, куда ведет ссылка из письма
+	PasswordResetMaxPerEmail        int           // Запросов сброса пароля на один email за PasswordResetWindow
+	PasswordResetMaxPerIP           int           // Запросов сброса пароля с одного IP за PasswordResetWindow
+	PasswordResetWindow             time.Duration // Окно, в котором считаются запросы сброса пароля
+	RequireVerifiedEmail            bool          // Запрещать изменение списков пользователям с неподтвержденным email
+	EmailVerificationSecret         string        // Секрет, из которого выводится ключ подписи ссылок подтверждения email
+	EmailVerificationTTL            time.Duration // Срок действия ссылки подтверждения email
+	EmailVerificationURL            string        // Страница фронтенда для подтверждения email
+	EmailVerificationResendCooldown time.Duration // Минимальный интервал между письмами подтверждения
+	MFAEncryptionKey                string        // Секрет, из которого выводится ключ шифрования секретов TOTP в базе
+	RefreshTokenSecret              string        // Секрет, из которого выводится ключ для получения преемника refresh-токена при ротации
+	LoginMaxAccountFailures         int           // Неудачных попыток входа в аккаунт до блокировки
+	LoginMaxIPFailures              int           // Неудачных попыток входа с одного IP до отказа
+	LoginFailureWindow              time.Duration // Окно, в котором считаются неудачные попытки
+	LoginLockoutDuration            time.Duration // Срок блокировки аккаунта
+	LoginBaseDelay                  time.Duration // Задержка после первой неудачи, удваивается с каждой следующей
+	LoginMaxDelay                   time.Duration // Предельная задержка между попытками
+}
+
+// DefaultSecretKey — значение SECRET_KEY по умолчанию, пригодное только для разработки.
+const DefaultSecretKey = "your-secret-key"
+
+var (
+	ErrInsecureSecretKey          = errors.New("SECRET_KEY must be changed from its default value in production or JWT_KEYS_DIR must be set")
+	ErrInsecureVerificationSecret = errors.New("EMAIL_VERIFICATION_SECRET must be set to a dedicated secret in production")
+	ErrInsecureMFAEncryptionKey   = errors.New("MFA_ENCRYPTION_KEY must be set to a dedicated secret in production")
+	ErrInsecureRefreshTokenSecret = errors.New("REFRESH_TOKEN_SECRET must be set to a dedicated secret in production")
+	ErrMemoryStoreInProduction    = errors.New("STORE_DRIVER must be redis in production: the memory store is not shared between replicas")
+	ErrLogMailerInProduction      = errors.New("MAIL_DRIVER must not be log in production: emails would not be delivered")
+)
+
+func (c AuthConfig) Validate(environment string) error {
+	if environment != "production" {
+		return nil
+	}
+	if c.KeysDir == "" && (c.SecretKey == "" || c.SecretKey == DefaultSecretKey) {
+		return ErrInsecureSecretKey
+	}
+	if c.EmailVerificationSecret == "" || c.EmailVerificationSecret == DefaultSecretKey || c.EmailVerificationSecret == c.SecretKey {
+		return ErrInsecureVerificationSecret
+	}
+	if c.MFAEncryptionKey == "" || c.MFAEncryptionKey == DefaultSecretKey || c.MFAEncryptionKey == c.SecretKey {
+		return ErrInsecureMFAEncryptionKey
+	}
+	if c.RefreshTokenSecret == "" || c.RefreshTokenSecret == DefaultSecretKey || c.RefreshTokenSecret == c.SecretKey {
+		return ErrInsecureRefreshTokenSecret
+	}
+	return nil
 }
 
 type CacheConfig struct {
@@ -61,7 +119,69 @@ type CacheConfig struct {
 	Port              int           // Порт Redis
 	Password          string        // Пароль Redis
 	DB                int           // Номер базы данных Redis
+	MaxEntries        int           // Максимальное количество записей в кэше в памяти
 	DefaultExpiration time.Duration // Время жизни кэша по умолчанию
+	AnimeTTL          time.Duration // Время жизни кэша деталей аниме
+	SearchTTL         time.Duration // Время жизни кэша результатов поиска
+	TopTTL            time.Duration // Время жизни кэша топа аниме
+	SeasonalTTL       time.Duration // Время жизни кэша сезонных аниме
+	RecommendationTTL time.Duration // Время жизни кэша рекомендаций
+}
+
+// StoreConfig — хранилище состояния: токены второго шага входа, счетчики
+// попыток, отметки использованных кодов, состояние сессий, интервалы между
+// письмами. В отличие от кэша ответов, записи в нем не вытесняются до истечения
+// срока. Redis берется тот же, что у кэша.
+type StoreConfig struct {
+	Driver  string // Драйвер хранилища (redis, memory); memory не разделяется между репликами
+	RedisDB int    // Номер базы данных Redis
+}
+
+func (c StoreConfig) Validate(environment string) error {
+	if environment == "production" && c.Driver != "redis" {
+		return ErrMemoryStoreInProduction
+	}
+	return nil
+}
+
+type JikanConfig struct {
+	RequestsPerSecond int           // Лимит запросов к Jikan в секунду
+	RequestsPerMinute int           // Лимит запросов к Jikan в минуту
+	MaxRetries        int           // Максимальное количество повторов при 429/5xx
+	RetryBaseDelay    time.Duration // Базовая задержка экспоненциального backoff
+	RetryMaxDelay     time.Duration // Максимальная задержка между повторами
+	MaxRetryAfter     time.Duration // Предел паузы по заголовку Retry-After
+}
+
+type CatalogConfig struct {
+	StaleAfter       time.Duration // Через сколько запись каталога считается устаревшей
+	RefreshInterval  time.Duration // Интервал фонового обновления каталога
+	RefreshBatchSize int           // Количество записей, обновляемых за один проход
+}
+
+type ImportConfig struct {
+	MaxUploadSize int           // Максимальный размер загружаемого файла в байтах
+	SyncLimit     int           // Сколько записей импортируется в рамках запроса
+	JobTTL        time.Duration // Сколько хранится результат фоновой задачи импорта
+	JobTimeout    time.Duration // Максимальная длительность фоновой задачи импорта
+	MaxEntries    int           // Максимальное количество записей в файле импорта
+	MaxXMLSize    int           // Максимальный размер файла импорта после распаковки gzip в байтах
+}
+
+type MailConfig struct {
+	Driver   string // Драйвер отправки (log, smtp)
+	Host     string // Хост SMTP-сервера
+	Port     int    // Порт SMTP-сервера
+	Username string // Пользователь SMTP; пустой — без авторизации
+	Password string // Пароль SMTP
+	From     string // Адрес отправителя
+}
+
+func (c MailConfig) Validate(environment string) error {
+	if environment == "production" && (c.Driver == "log" || c.Driver == "") {
+		return ErrLogMailerInProduction
+	}
+	return nil
 }
 
 type PaginationConfig struct {
@@ -98,12 +218,13 @@ func Load() (*Config, error) {
 			AllowedCORSOrigins: getEnvAsSlice("HTTP_ALLOWED_CORS_ORIGINS", []string{"*"}),
 		},
 		Database: database.Config{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnvAsInt("DB_PORT", 5432),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			Name:     getEnv("DB_NAME", "postgres"),
-			Params:   dbParams,
+			Host:        getEnv("DB_HOST", "localhost"),
+			Port:        getEnvAsInt("DB_PORT", 5432),
+			User:        getEnv("DB_USER", "postgres"),
+			Password:    getEnv("DB_PASSWORD", "postgres"),
+			Name:        getEnv("DB_NAME", "postgres"),
+			Params:      dbParams,
+			AutoMigrate: getEnvAsBool("DB_AUTO_MIGRATE", true),
 		},
 		Logger: LoggerConfig{
 			Level:        getEnv("LOG_LEVEL", "info"),
@@ -117,8 +238,29 @@ func Load() (*Config, error) {
 			ReportCaller: getEnvAsBool("LOG_REPORT_CALLER", false),
 		},
 		Auth: AuthConfig{
-			SecretKey:              getEnv("SECRET_KEY", "your-secret-key"),
-			TokenDuration:  getEnvAsDuration("TOKEN_DURATION", 60*time.Minute),
+			SecretKey:                       getEnv("SECRET_KEY", DefaultSecretKey),
+			TokenDuration:                   getEnvAsDuration("TOKEN_DURATION", 60*time.Minute),
+			KeysDir:                         getEnv("JWT_KEYS_DIR", ""),
+			ActiveKeyID:                     getEnv("JWT_ACTIVE_KEY_ID", ""),
+			BootstrapAdminEmail:             getEnv("ADMIN_BOOTSTRAP_EMAIL", ""),
+			PasswordResetTTL:                getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+			PasswordResetURL:                getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+			PasswordResetMaxPerEmail:        getEnvAsInt("PASSWORD_RESET_MAX_PER_EMAIL", 3),
+			PasswordResetMaxPerIP:           getEnvAsInt("PASSWORD_RESET_MAX_PER_IP", 20),
+			PasswordResetWindow:             getEnvAsDuration("PASSWORD_RESET_WINDOW", time.Hour),
+			RequireVerifiedEmail:            getEnvAsBool("AUTH_REQUIRE_VERIFIED_EMAIL", false),
+			EmailVerificationSecret:         getEnv("EMAIL_VERIFICATION_SECRET", ""),
+			EmailVerificationTTL:            getEnvAsDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+			EmailVerificationURL:            getEnv("EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email"),
+			EmailVerificationResendCooldown: getEnvAsDuration("EMAIL_VERIFICATION_RESEND_COOLDOWN", time.Minute),
+			MFAEncryptionKey:                getEnv("MFA_ENCRYPTION_KEY", ""),
+			RefreshTokenSecret:              getEnv("REFRESH_TOKEN_SECRET", ""),
+			LoginMaxAccountFailures:         getEnvAsInt("AUTH_LOGIN_MAX_ACCOUNT_FAILURES", 5),
+			LoginMaxIPFailures:              getEnvAsInt("AUTH_LOGIN_MAX_IP_FAILURES", 50),
+			LoginFailureWindow:              getEnvAsDuration("AUTH_LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			LoginLockoutDuration:            getEnvAsDuration("AUTH_LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			LoginBaseDelay:                  getEnvAsDuration("AUTH_LOGIN_BASE_DELAY", time.Second),
+			LoginMaxDelay:                   getEnvAsDuration("AUTH_LOGIN_MAX_DELAY", 30*time.Second),
 		},
 		Cache: CacheConfig{
 			Driver:            getEnv("CACHE_DRIVER", "memory"),
@@ -126,7 +268,46 @@ func Load() (*Config, error) {
 			Port:              getEnvAsInt("CACHE_REDIS_PORT", 6379),
 			Password:          getEnv("CACHE_REDIS_PASSWORD", ""),
 			DB:                getEnvAsInt("CACHE_REDIS_DB", 0),
+			MaxEntries:        getEnvAsInt("CACHE_MAX_ENTRIES", 10000),
 			DefaultExpiration: getEnvAsDuration("CACHE_DEFAULT_EXPIRATION", 5*time.Minute),
+			AnimeTTL:          getEnvAsDuration("CACHE_ANIME_TTL", 24*time.Hour),
+			SearchTTL:         getEnvAsDuration("CACHE_SEARCH_TTL", 10*time.Minute),
+			TopTTL:            getEnvAsDuration("CACHE_TOP_TTL", 15*time.Minute),
+			SeasonalTTL:       getEnvAsDuration("CACHE_SEASONAL_TTL", 15*time.Minute),
+			RecommendationTTL: getEnvAsDuration("CACHE_RECOMMENDATION_TTL", 6*time.Hour),
+		},
+		Store: StoreConfig{
+			Driver:  getEnv("STORE_DRIVER", "memory"),
+			RedisDB: getEnvAsInt("STORE_REDIS_DB", getEnvAsInt("CACHE_REDIS_DB", 0)),
+		},
+		Jikan: JikanConfig{
+			RequestsPerSecond: getEnvAsInt("JIKAN_REQUESTS_PER_SECOND", 3),
+			RequestsPerMinute: getEnvAsInt("JIKAN_REQUESTS_PER_MINUTE", 60),
+			MaxRetries:        getEnvAsInt("JIKAN_MAX_RETRIES", 3),
+			RetryBaseDelay:    getEnvAsDuration("JIKAN_RETRY_BASE_DELAY", 500*time.Millisecond),
+			RetryMaxDelay:     getEnvAsDuration("JIKAN_RETRY_MAX_DELAY", 10*time.Second),
+			MaxRetryAfter:     getEnvAsDuration("JIKAN_MAX_RETRY_AFTER", time.Minute),
+		},
+		Catalog: CatalogConfig{
+			StaleAfter:       getEnvAsDuration("CATALOG_STALE_AFTER", 72*time.Hour),
+			RefreshInterval:  getEnvAsDuration("CATALOG_REFRESH_INTERVAL", 10*time.Minute),
+			RefreshBatchSize: getEnvAsInt("CATALOG_REFRESH_BATCH_SIZE", 20),
+		},
+		Import: ImportConfig{
+			MaxUploadSize: getEnvAsInt("IMPORT_MAX_UPLOAD_SIZE", 10<<20), // 10 MB
+			SyncLimit:     getEnvAsInt("IMPORT_SYNC_LIMIT", 200),
+			JobTTL:        getEnvAsDuration("IMPORT_JOB_TTL", 24*time.Hour),
+			JobTimeout:    getEnvAsDuration("IMPORT_JOB_TIMEOUT", 30*time.Minute),
+			MaxEntries:    getEnvAsInt("IMPORT_MAX_ENTRIES", 50000),
+			MaxXMLSize:    getEnvAsInt("IMPORT_MAX_XML_SIZE", 100<<20), // 100 MB
+		},
+		Mail: MailConfig{
+			Driver:   getEnv("MAIL_DRIVER", "log"),
+			Host:     getEnv("MAIL_SMTP_HOST", "localhost"),
+			Port:     getEnvAsInt("MAIL_SMTP_PORT", 1025),
+			Username: getEnv("MAIL_SMTP_USERNAME", ""),
+			Password: getEnv("MAIL_SMTP_PASSWORD", ""),
+			From:     getEnv("MAIL_FROM", "no-reply@anime-service.local"),
 		},
 		Pagination: PaginationConfig{
 			DefaultLimit: getEnvAsInt("PAGINATION_DEFAULT_LIMIT", 10),
@@ -134,9 +315,33 @@ func Load() (*Config, error) {
 		},
 	}
 
+	// Вне production ключи подписи ссылок, шифрования TOTP и ротации
+	// refresh-токенов выводятся из SECRET_KEY: DeriveKey с отдельной меткой для каждого назначения дает
+	// независимые ключи. В production для каждого нужен свой секрет.
+	if config.App.Environment != "production" {
+		if config.Auth.EmailVerificationSecret == "" {
+			config.Auth.EmailVerificationSecret = config.Auth.SecretKey
+		}
+		if config.Auth.MFAEncryptionKey == "" {
+			config.Auth.MFAEncryptionKey = config.Auth.SecretKey
+		}
+		if config.Auth.RefreshTokenSecret == "" {
+			config.Auth.RefreshTokenSecret = config.Auth.SecretKey
+		}
+	}
+
 	if err := config.Database.Validate(); err != nil {
 		return nil, err
 	}
+	if err := config.Auth.Validate(config.App.Environment); err != nil {
+		return nil, err
+	}
+	if err := config.Store.Validate(config.App.Environment); err != nil {
+		return nil, err
+	}
+	if err := config.Mail.Validate(config.App.Environment); err != nil {
+		return nil, err
+	}
 
 	return config, nil
 }
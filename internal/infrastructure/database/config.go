@@ -17,6 +17,9 @@ type Config struct {
 	Name     string
 
 	Params map[string]string
+
+	// AutoMigrate включает применение SQL-миграций при старте сервиса.
+	AutoMigrate bool
 }
 
 func (c Config) Validate() error {
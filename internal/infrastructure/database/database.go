@@ -92,17 +92,3 @@ func CloseDB(db *gorm.DB) error {
 	}
 	return sqlDB.Close()
 }
- 
-func AutoMigrate(db *gorm.DB, models ...interface{}) error {
-	if db == nil {
-		return errors.New("database is nil")
-	}
-
-	for _, model := range models {
-		if err := db.AutoMigrate(model); err != nil {
-			return errors.WithStack(err)
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,130 @@
+// Package dbtest подключает тесты к настоящей базе Postgres.
+//
+// Адрес базы берется из переменной окружения TEST_DATABASE_URL; если она не
+// задана, тесты, которым нужна база, пропускаются. Каждый тест работает в
+// собственной временной схеме, поэтому тесты не мешают друг другу и не
+// трогают данные в схеме public.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"logur.dev/logur"
+)
+
+// EnvURL — переменная окружения с адресом тестовой базы.
+const EnvURL = "TEST_DATABASE_URL"
+
+// Open создает временную схему и возвращает подключение, в котором она стоит
+// первой в search_path. Схема удаляется по завершении теста.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+
+	url := os.Getenv(EnvURL)
+	if url == "" {
+		t.Skipf("%s is not set", EnvURL)
+	}
+
+	admin := openURL(t, url, "")
+	schema := "test_" + randomSuffix(t)
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+
+	db := openURL(t, url, schema)
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	return db
+}
+
+// OpenMigrated — Open с примененными миграциями.
+func OpenMigrated(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db := Open(t)
+	migrator, err := database.NewMigrator(db, migrations.FS, logur.NoopLogger{})
+	if err != nil {
+		t.Fatalf("create migrator: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return db
+}
+
+// OpenGorm — OpenMigrated, обернутое в gorm, для репозиториев на gorm.
+func OpenGorm(t testing.TB) (*gorm.DB, *sql.DB) {
+	t.Helper()
+
+	db := OpenMigrated(t)
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{
+		Logger: gormlogger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open gorm: %v", err)
+	}
+	return gormDB, db
+}
+
+// CreateUser создает пользователя с уникальными никнеймом и email и возвращает его id.
+func CreateUser(t testing.TB, db *sql.DB) uint {
+	t.Helper()
+
+	name := "user_" + randomSuffix(t)
+	var id uint
+	err := db.QueryRow(`
+		INSERT INTO users (nickname, email, password, created_at, updated_at)
+		VALUES ($1, $2, 'x', now(), now())
+		RETURNING id
+	`, name, name+"@example.com").Scan(&id)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return id
+}
+
+func openURL(t testing.TB, url string, schema string) *sql.DB {
+	t.Helper()
+
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse %s: %v", EnvURL, err)
+	}
+	if schema != "" {
+		config.RuntimeParams["search_path"] = schema
+	}
+
+	db := stdlib.OpenDB(*config)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Fatalf("connect to test database: %v", err)
+	}
+	return db
+}
+
+func randomSuffix(t testing.TB) string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatalf("random schema name: %v", err)
+	}
+	return hex.EncodeToString(buf)
+}
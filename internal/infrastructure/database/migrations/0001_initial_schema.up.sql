@@ -0,0 +1,183 @@
+-- Схема, которую до перехода на миграции создавал gorm AutoMigrate. Все
+-- объекты создаются с IF NOT EXISTS, а колонки, появившиеся позже исходной
+-- схемы, добавляются с ADD COLUMN IF NOT EXISTS, чтобы миграция применялась
+-- и к уже существующим базам.
+--
+-- Down-скрипта у миграции нет: она принимает таблицы, созданные до миграций,
+-- и откат удалил бы эти данные. Ниже версии 1 мигратор не откатывает.
+
+CREATE TABLE IF NOT EXISTS users (
+    id                bigserial PRIMARY KEY,
+    created_at        timestamptz,
+    updated_at        timestamptz,
+    deleted_at        timestamptz,
+    nickname          text NOT NULL,
+    firstname         text,
+    lastname          text,
+    avatar_url        text,
+    email             text NOT NULL,
+    password          text NOT NULL,
+    role              varchar(16) NOT NULL DEFAULT 'user',
+    email_verified_at timestamptz,
+    totp_secret       text,
+    totp_enabled      boolean NOT NULL DEFAULT false,
+    locked_until      timestamptz,
+    CONSTRAINT uni_users_nickname UNIQUE (nickname),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+-- В базах, созданных до ролей, подтверждения email, 2FA и блокировок, таблица
+-- users уже есть, но без этих колонок.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role              varchar(16) NOT NULL DEFAULT 'user',
+    ADD COLUMN IF NOT EXISTS email_verified_at timestamptz,
+    ADD COLUMN IF NOT EXISTS totp_secret       text,
+    ADD COLUMN IF NOT EXISTS totp_enabled      boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS locked_until      timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+-- Никнеймы и email хранятся в нижнем регистре, а раньше сохранялись как есть.
+-- Аккаунты, совпадающие без учета регистра, миграция не трогает: какой из них
+-- оставить, решает человек. Если такие есть, миграция падает со списком
+-- конфликтов, и применить ее можно только после ручного разбора.
+DO $$
+DECLARE
+    conflicts text;
+BEGIN
+    SELECT string_agg(conflict, '; ' ORDER BY conflict) INTO conflicts
+    FROM (
+        SELECT 'email ' || lower(btrim(email)) || ' (ids ' || string_agg(id::text, ', ' ORDER BY id) || ')' AS conflict
+        FROM users
+        GROUP BY lower(btrim(email))
+        HAVING count(*) > 1
+        UNION ALL
+        SELECT 'nickname ' || lower(btrim(nickname)) || ' (ids ' || string_agg(id::text, ', ' ORDER BY id) || ')'
+        FROM users
+        GROUP BY lower(btrim(nickname))
+        HAVING count(*) > 1
+    ) duplicates;
+
+    IF conflicts IS NOT NULL THEN
+        RAISE EXCEPTION 'users differ only by case or whitespace: %', conflicts
+            USING HINT = 'merge or rename these accounts, then run the migrations again';
+    END IF;
+END
+$$;
+
+UPDATE users
+SET nickname = lower(btrim(nickname)),
+    email    = lower(btrim(email))
+WHERE nickname <> lower(btrim(nickname))
+   OR email <> lower(btrim(email));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_nickname_lower ON users (lower(nickname));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email));
+
+CREATE TABLE IF NOT EXISTS animes (
+    mal_id         bigint PRIMARY KEY,
+    title          text,
+    title_english  text,
+    title_japanese text,
+    synopsis       text,
+    image_url      text,
+    type           text,
+    source         text,
+    episodes       bigint,
+    status         text,
+    airing         boolean,
+    score          decimal,
+    rank           bigint,
+    popularity     bigint,
+    fetched_at     timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_animes_fetched_at ON animes (fetched_at);
+
+CREATE TABLE IF NOT EXISTS anime_genres (
+    anime_mal_id bigint NOT NULL,
+    genre_id     bigint NOT NULL,
+    name         text NOT NULL,
+    PRIMARY KEY (anime_mal_id, genre_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_animes (
+    id               bigserial PRIMARY KEY,
+    user_id          bigint NOT NULL,
+    anime_mal_id     bigint,
+    status           text NOT NULL,
+    rating           decimal,
+    notes            text,
+    episodes_watched bigint,
+    rewatch_count    bigint NOT NULL DEFAULT 0,
+    rewatch_episodes bigint NOT NULL DEFAULT 0,
+    started_at       timestamptz,
+    finished_at      timestamptz,
+    created_at       timestamptz,
+    updated_at       timestamptz
+);
+-- Колонки прогресса пересмотров и дат просмотра, которых нет в базах,
+-- созданных до их появления.
+ALTER TABLE user_animes
+    ADD COLUMN IF NOT EXISTS rewatch_count    bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rewatch_episodes bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS started_at       timestamptz,
+    ADD COLUMN IF NOT EXISTS finished_at      timestamptz;
+
+CREATE TABLE IF NOT EXISTS episode_watches (
+    id             bigserial PRIMARY KEY,
+    user_id        bigint NOT NULL,
+    anime_mal_id   bigint NOT NULL,
+    episode        bigint NOT NULL,
+    rewatch_number bigint NOT NULL DEFAULT 0,
+    unwatched      boolean NOT NULL DEFAULT false,
+    rating         decimal,
+    watched_at     timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_episode_watches_user_anime ON episode_watches (user_id, anime_mal_id);
+CREATE INDEX IF NOT EXISTS idx_episode_watches_watched_at ON episode_watches (watched_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL,
+    family_id  text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id           varchar(64) PRIMARY KEY,
+    user_id      bigint NOT NULL,
+    ip           varchar(64),
+    user_agent   text,
+    browser      text,
+    os           text,
+    device       text,
+    created_at   timestamptz,
+    last_used_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires_at ON password_reset_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL,
+    code_hash  text NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE user_animes DROP CONSTRAINT IF EXISTS fk_user_animes_user;
+ALTER TABLE user_animes DROP CONSTRAINT IF EXISTS uq_user_animes_user_anime;
@@ -0,0 +1,17 @@
+-- Раньше записи списка создавались без ограничений, поэтому перед их
+-- добавлением удаляем записи удаленных пользователей и дубликаты
+-- (остается самая свежая запись).
+DELETE FROM user_animes ua
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = ua.user_id);
+
+DELETE FROM user_animes ua
+USING user_animes newer
+WHERE ua.user_id = newer.user_id
+  AND ua.anime_mal_id = newer.anime_mal_id
+  AND ua.id < newer.id;
+
+ALTER TABLE user_animes
+    ADD CONSTRAINT uq_user_animes_user_anime UNIQUE (user_id, anime_mal_id);
+
+ALTER TABLE user_animes
+    ADD CONSTRAINT fk_user_animes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
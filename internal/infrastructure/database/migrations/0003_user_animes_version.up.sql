@@ -0,0 +1,4 @@
+-- Версия записи списка для оптимистичных блокировок: увеличивается при каждом
+-- изменении и отдается клиенту в ETag.
+ALTER TABLE user_animes
+    ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1 @@
+ALTER TABLE user_animes DROP COLUMN IF EXISTS tags;
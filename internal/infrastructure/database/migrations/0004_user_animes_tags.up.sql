@@ -0,0 +1,2 @@
+ALTER TABLE user_animes
+    ADD COLUMN IF NOT EXISTS tags jsonb NOT NULL DEFAULT '[]'::jsonb;
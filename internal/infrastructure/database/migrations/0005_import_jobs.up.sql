@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id          text PRIMARY KEY,
+    user_id     bigint NOT NULL,
+    status      text NOT NULL,
+    options     jsonb NOT NULL,
+    report      jsonb,
+    error       text NOT NULL DEFAULT '',
+    created_at  timestamptz NOT NULL,
+    finished_at timestamptz,
+    expires_at  timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_expires_at ON import_jobs (expires_at);
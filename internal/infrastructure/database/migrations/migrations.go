@@ -0,0 +1,12 @@
+// Package migrations содержит SQL-миграции схемы базы данных.
+//
+// Каждая миграция — пара файлов <версия>_<имя>.up.sql и <версия>_<имя>.down.sql.
+// Миграции применяются по возрастанию версии; новые файлы создаются командой
+// "migrate create <имя>". У 0001_initial_schema нет down-файла, поэтому ниже
+// версии 1 схема не откатывается.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,357 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"logur.dev/logur"
+)
+
+// migrationLockID — ключ advisory lock, под которым выполняются миграции,
+// чтобы при одновременном старте нескольких реплик схему менял только один процесс.
+const migrationLockID int64 = 7_346_201_905
+
+var (
+	ErrInvalidMigrationName = errors.New("invalid migration name")
+	ErrMissingDownMigration = errors.New("down migration is missing")
+	ErrUnknownMigration     = errors.New("applied migration is missing from the source")
+)
+
+var (
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// Migration — одна версия схемы из пары файлов up/down.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus — миграция и время ее применения; AppliedAt == nil, если
+// миграция еще не применена.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator применяет и откатывает SQL-миграции, учитывая примененные версии
+// в таблице schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     logur.LoggerFacade
+}
+
+func NewMigrator(db *sql.DB, source fs.FS, logger logur.LoggerFacade) (*Migrator, error) {
+	migrations, err := loadMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// Up применяет все непримененные миграции и возвращает их. Если в базе
+// применены версии, которых нет среди известных миграций, база новее этого
+// бинарника, и Up ничего не применяет.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		var unknown []int64
+		for version := range done {
+			if _, ok := m.find(version); !ok {
+				unknown = append(unknown, version)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+			return errors.WithDetails(ErrUnknownMigration, "versions", unknown)
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает последние steps примененных миграций и возвращает их.
+// Миграции без down-скрипта не откатываются; если такая попадает в
+// последние steps, Down ничего не откатывает.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		var pending []Migration
+		for i := 0; i < steps && i < len(versions); i++ {
+			migration, ok := m.find(versions[i])
+			if !ok {
+				return errors.WithDetails(ErrUnknownMigration, "version", versions[i])
+			}
+			if migration.Down == "" {
+				return errors.WithDetails(ErrMissingDownMigration, "version", migration.Version)
+			}
+			pending = append(pending, migration)
+		}
+
+		for _, migration := range pending {
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status возвращает все известные миграции вместе с отметкой о применении.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	done, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CreateMigration создает в каталоге dir пустую пару файлов up/down со
+// следующим номером версии и возвращает пути к ним.
+func CreateMigration(dir string, name string) (string, string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+	if !migrationNamePattern.MatchString(name) {
+		return "", "", errors.WithDetails(ErrInvalidMigrationName, "name", name)
+	}
+
+	migrations, err := loadMigrations(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := fmt.Sprintf("%04d_%s", version, name)
+	upPath := filepath.Join(dir, base+".up.sql")
+	downPath := filepath.Join(dir, base+".down.sql")
+	if err := os.WriteFile(upPath, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	if err := os.WriteFile(downPath, []byte("-- revert "+name+"\n"), 0o644); err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	return upPath, downPath, nil
+}
+
+// withLock выполняет fn на одном соединении под advisory lock и создает
+// таблицу schema_migrations, если ее еще нет.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return errors.Wrap(err, "failed to acquire migration lock")
+	}
+	defer func() {
+		// Контекст мог быть отменен, но блокировку нужно снять в любом случае.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			m.logger.Warn("failed to release migration lock", map[string]interface{}{"error": err.Error()})
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    bigint PRIMARY KEY,
+			name       text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)
+	`); err != nil {
+		return errors.Wrap(err, "failed to create schema_migrations table")
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, errors.Wrap(err, "failed to check schema_migrations table")
+	}
+	applied := make(map[int64]time.Time)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read applied migrations")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, errors.Wrap(err, "failed to scan applied migration")
+		}
+		applied[version] = appliedAt
+	}
+	return applied, errors.WithStack(rows.Err())
+}
+
+// apply выполняет up- или down-скрипт миграции и обновляет schema_migrations
+// в одной транзакции.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	direction, script := "up", migration.Up
+	if !up {
+		direction, script = "down", migration.Down
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return errors.WrapWithDetails(err, "migration failed", "version", migration.Version, "name", migration.Name, "direction", direction)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to record migration")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	m.logger.Info("migration applied", map[string]interface{}{
+		"version":   migration.Version,
+		"name":      migration.Name,
+		"direction": direction,
+	})
+	return nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// loadMigrations читает пары файлов up/down и сортирует их по версии.
+func loadMigrations(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, errors.WithDetails(ErrInvalidMigrationName, "file", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.WithDetails(ErrInvalidMigrationName, "file", entry.Name())
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, errors.WithDetails(ErrInvalidMigrationName, "file", entry.Name(), "reason", "duplicate version")
+		}
+
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, errors.WithDetails(ErrInvalidMigrationName, "version", migration.Version, "reason", "up migration is missing")
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
@@ -0,0 +1,249 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/migrations"
+	"logur.dev/logur"
+)
+
+// baselineSchema — таблицы, которые gorm AutoMigrate создавал до появления
+// ролей, подтверждения email, 2FA, блокировок и дат просмотра.
+const baselineSchema = `
+	CREATE TABLE users (
+		id         bigserial PRIMARY KEY,
+		created_at timestamptz,
+		updated_at timestamptz,
+		deleted_at timestamptz,
+		nickname   text NOT NULL,
+		firstname  text,
+		lastname   text,
+		avatar_url text,
+		email      text NOT NULL,
+		password   text NOT NULL,
+		CONSTRAINT uni_users_nickname UNIQUE (nickname),
+		CONSTRAINT uni_users_email UNIQUE (email)
+	);
+	CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+	CREATE TABLE user_animes (
+		id               bigserial PRIMARY KEY,
+		user_id          bigint NOT NULL,
+		anime_mal_id     bigint,
+		status           text NOT NULL,
+		rating           decimal,
+		notes            text,
+		episodes_watched bigint,
+		created_at       timestamptz,
+		updated_at       timestamptz
+	);
+`
+
+func newMigrator(t *testing.T, db *sql.DB) *database.Migrator {
+	t.Helper()
+
+	migrator, err := database.NewMigrator(db, migrations.FS, logur.NoopLogger{})
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	return migrator
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	migrator := newMigrator(t, db)
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) == 0 {
+		t.Fatal("Up applied no migrations on an empty schema")
+	}
+
+	again, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("second Up applied %d migrations, want 0", len(again))
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("migration %d_%s is not applied", status.Version, status.Name)
+		}
+	}
+
+	if _, err := migrator.Down(ctx, len(applied)); !errors.Is(err, database.ErrMissingDownMigration) {
+		t.Fatalf("Down below the initial migration = %v, want ErrMissingDownMigration", err)
+	}
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status after refused Down: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("refused Down reverted migration %d_%s", status.Version, status.Name)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, len(applied)-1)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if len(reverted) != len(applied)-1 {
+		t.Fatalf("Down reverted %d migrations, want %d", len(reverted), len(applied)-1)
+	}
+	var users bool
+	if err := db.QueryRow(`SELECT to_regclass('users') IS NOT NULL`).Scan(&users); err != nil {
+		t.Fatalf("check users table: %v", err)
+	}
+	if !users {
+		t.Error("users table was dropped by reverting to the initial migration")
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+}
+
+func TestMigratorUpRejectsUnknownAppliedVersions(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+	migrator := newMigrator(t, db)
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (9999, 'from_a_newer_binary')`); err != nil {
+		t.Fatalf("record unknown migration: %v", err)
+	}
+
+	if _, err := migrator.Up(ctx); !errors.Is(err, database.ErrUnknownMigration) {
+		t.Fatalf("Up with an unknown applied version = %v, want ErrUnknownMigration", err)
+	}
+}
+
+func TestMigratorAdoptsBaselineSchema(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatalf("create baseline schema: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO users (id, nickname, email, password) VALUES
+			(1, 'Alice', 'Alice@Example.com', 'x'),
+			(2, ' Bob ', 'bob@example.com', 'x');
+		SELECT setval('users_id_seq', 2);
+		INSERT INTO user_animes (user_id, anime_mal_id, status, episodes_watched)
+		VALUES (1, 5114, 'watching', 3);
+	`); err != nil {
+		t.Fatalf("seed baseline rows: %v", err)
+	}
+
+	if _, err := newMigrator(t, db).Up(ctx); err != nil {
+		t.Fatalf("Up on baseline schema: %v", err)
+	}
+
+	want := map[int64]struct {
+		nickname string
+		email    string
+	}{
+		1: {"alice", "alice@example.com"},
+		2: {"bob", "bob@example.com"},
+	}
+	rows, err := db.Query(`SELECT id, nickname, email, role, totp_enabled, email_verified_at IS NULL, locked_until IS NULL FROM users`)
+	if err != nil {
+		t.Fatalf("query users: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id                    int64
+			nickname, email, role string
+			totpEnabled           bool
+			unverified, notLocked bool
+		)
+		if err := rows.Scan(&id, &nickname, &email, &role, &totpEnabled, &unverified, &notLocked); err != nil {
+			t.Fatalf("scan user: %v", err)
+		}
+		w := want[id]
+		if nickname != w.nickname || email != w.email {
+			t.Errorf("user %d = (%q, %q), want (%q, %q)", id, nickname, email, w.nickname, w.email)
+		}
+		if role != "user" || totpEnabled || !unverified || !notLocked {
+			t.Errorf("user %d got unexpected defaults: role=%q totp_enabled=%v", id, role, totpEnabled)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("iterate users: %v", err)
+	}
+
+	var rewatchCount, rewatchEpisodes int
+	var version int64
+	if err := db.QueryRow(`SELECT rewatch_count, rewatch_episodes, version FROM user_animes WHERE user_id = 1`).Scan(&rewatchCount, &rewatchEpisodes, &version); err != nil {
+		t.Fatalf("query adopted user_animes: %v", err)
+	}
+	if rewatchCount != 0 || rewatchEpisodes != 0 || version != 1 {
+		t.Errorf("adopted entry = (rewatch_count=%d, rewatch_episodes=%d, version=%d), want (0, 0, 1)", rewatchCount, rewatchEpisodes, version)
+	}
+
+	if _, err := db.Exec(`INSERT INTO users (nickname, email, password) VALUES ('BOB', 'new@example.com', 'x')`); err == nil {
+		t.Error("inserted a nickname that differs only by case")
+	}
+}
+
+func TestMigratorRefusesCaseConflicts(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.Open(t)
+
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatalf("create baseline schema: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO users (id, nickname, email, password) VALUES
+			(1, 'Alice', 'Alice@Example.com', 'x'),
+			(2, 'ALICE', 'other@example.com', 'x'),
+			(3, 'Bob', 'alice@example.com', 'x');
+	`); err != nil {
+		t.Fatalf("seed baseline rows: %v", err)
+	}
+
+	_, err := newMigrator(t, db).Up(ctx)
+	if err == nil {
+		t.Fatal("Up succeeded on a schema with case-insensitive duplicates")
+	}
+	for _, conflict := range []string{"email alice@example.com (ids 1, 3)", "nickname alice (ids 1, 2)"} {
+		if !strings.Contains(err.Error(), conflict) {
+			t.Errorf("Up error %q does not list %q", err, conflict)
+		}
+	}
+
+	var changed int
+	if err := db.QueryRow(`SELECT count(*) FROM users WHERE nickname <> 'Alice' AND nickname <> 'ALICE' AND nickname <> 'Bob'`).Scan(&changed); err != nil {
+		t.Fatalf("count changed users: %v", err)
+	}
+	if changed != 0 {
+		t.Errorf("%d users were changed by a failed migration", changed)
+	}
+	var applied int
+	if err := db.QueryRow(`SELECT count(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatalf("count applied migrations: %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("%d migrations recorded after a failed Up", applied)
+	}
+}
@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"context"
+
+	"logur.dev/logur"
+)
+
+// LogMailer не отправляет письма, а пишет в лог получателя и тему. Текст
+// письма не логируется: в нем бывают ссылки с одноразовыми токенами. Только
+// для разработки; в production этот драйвер запрещен конфигурацией.
+type LogMailer struct {
+	logger logur.LoggerFacade
+}
+
+func NewLogMailer(logger logur.LoggerFacade) *LogMailer {
+	return &LogMailer{
+		logger: logger,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	m.logger.Info("Mail message", map[string]interface{}{
+		"to":      message.To,
+		"subject": message.Subject,
+	})
+	return nil
+}
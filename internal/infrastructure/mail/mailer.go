@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"logur.dev/logur"
+)
+
+var ErrUnsupportedDriver = errors.New("unsupported mail driver")
+
+const (
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+)
+
+// Message — текстовое письмо одному получателю.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+type Config struct {
+	Driver   string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func New(config Config, logger logur.LoggerFacade) (Mailer, error) {
+	switch config.Driver {
+	case DriverLog, "":
+		return NewLogMailer(logger), nil
+	case DriverSMTP:
+		return NewSMTPMailer(config), nil
+	default:
+		return nil, errors.WithDetails(ErrUnsupportedDriver, "driver", config.Driver)
+	}
+}
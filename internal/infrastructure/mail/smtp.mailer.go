@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// SMTPMailer отправляет письма через SMTP-сервер. Если сервер поддерживает
+// STARTTLS, соединение шифруется; авторизация выполняется, только если задан
+// Username (локальные серверы вроде MailHog работают без нее).
+type SMTPMailer struct {
+	config Config
+}
+
+func NewSMTPMailer(config Config) *SMTPMailer {
+	return &SMTPMailer{
+		config: config,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to SMTP server")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "failed to start SMTP session")
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return errors.Wrap(err, "failed to start TLS")
+		}
+	}
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return errors.Wrap(err, "SMTP authentication failed")
+		}
+	}
+
+	if err := client.Mail(m.config.From); err != nil {
+		return errors.Wrap(err, "SMTP MAIL command failed")
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return errors.Wrap(err, "SMTP RCPT command failed")
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "SMTP DATA command failed")
+	}
+	data, err := m.build(message)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return errors.Wrap(err, "failed to write message")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "failed to send message")
+	}
+
+	return client.Quit()
+}
+
+func (m *SMTPMailer) build(message Message) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(message.Body)); err != nil {
+		return nil, errors.Wrap(err, "failed to encode message body")
+	}
+	if err := qp.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to encode message body")
+	}
+	return buf.Bytes(), nil
+}
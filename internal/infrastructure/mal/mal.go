@@ -0,0 +1,229 @@
+// Package mal описывает формат XML-экспорта списков MyAnimeList и
+// сопоставление его статусов и оценок с моделями сервиса.
+package mal
+
+import (
+	"encoding/xml"
+	"math"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+)
+
+// Статусы записей в формате MyAnimeList.
+const (
+	StatusWatching    = "Watching"
+	StatusCompleted   = "Completed"
+	StatusOnHold      = "On-Hold"
+	StatusDropped     = "Dropped"
+	StatusPlanToWatch = "Plan to Watch"
+)
+
+var (
+	ErrUnknownStatus  = errors.New("unknown MyAnimeList status")
+	ErrInvalidScore   = errors.New("invalid MyAnimeList score")
+	ErrInvalidAnimeID = errors.New("invalid MyAnimeList anime id")
+)
+
+// ExportTypeAnime — значение user_export_type для списка аниме.
+const ExportTypeAnime = 1
+
+const (
+	dateLayout = "2006-01-02"
+	emptyDate  = "0000-00-00"
+)
+
+// CDATA — текстовое поле, которое MAL оборачивает в CDATA.
+type CDATA struct {
+	Value string `xml:",cdata"`
+}
+
+// Info — блок myinfo с владельцем и итогами списка.
+type Info struct {
+	XMLName          xml.Name `xml:"myinfo"`
+	UserID           uint     `xml:"user_id"`
+	UserName         string   `xml:"user_name"`
+	ExportType       int      `xml:"user_export_type"`
+	TotalAnime       int      `xml:"user_total_anime"`
+	TotalWatching    int      `xml:"user_total_watching"`
+	TotalCompleted   int      `xml:"user_total_completed"`
+	TotalOnHold      int      `xml:"user_total_onhold"`
+	TotalDropped     int      `xml:"user_total_dropped"`
+	TotalPlanToWatch int      `xml:"user_total_plantowatch"`
+}
+
+// Entry — запись anime экспорта MAL.
+type Entry struct {
+	XMLName           xml.Name `xml:"anime"`
+	SeriesAnimeDBID   int64    `xml:"series_animedb_id"`
+	SeriesTitle       CDATA    `xml:"series_title"`
+	SeriesType        string   `xml:"series_type"`
+	SeriesEpisodes    int      `xml:"series_episodes"`
+	MyID              int      `xml:"my_id"`
+	MyWatchedEpisodes int      `xml:"my_watched_episodes"`
+	MyStartDate       string   `xml:"my_start_date"`
+	MyFinishDate      string   `xml:"my_finish_date"`
+	MyScore           int      `xml:"my_score"`
+	MyStatus          string   `xml:"my_status"`
+	MyComments        CDATA    `xml:"my_comments"`
+	MyTimesWatched    int      `xml:"my_times_watched"`
+	MyRewatching      int      `xml:"my_rewatching"`
+	MyRewatchingEp    int      `xml:"my_rewatching_ep"`
+	UpdateOnImport    int      `xml:"update_on_import"`
+}
+
+// StatusFromWatchStatus переводит статус сервиса в статус MAL. У MAL нет отдельных
+// статусов для ожидания новых серий и пересмотра: первые выгружаются как Watching,
+// пересмотр — как Completed с флагом my_rewatching.
+func StatusFromWatchStatus(status models.WatchStatus) string {
+	switch status {
+	case models.StatusWatching, models.StatusWaiting:
+		return StatusWatching
+	case models.StatusWatched, models.StatusRewatching:
+		return StatusCompleted
+	case models.StatusOnHold:
+		return StatusOnHold
+	case models.StatusDropped:
+		return StatusDropped
+	default:
+		return StatusPlanToWatch
+	}
+}
+
+// ScoreFromRating округляет рейтинг 0–10 до целой оценки MAL.
+func ScoreFromRating(rating float32) int {
+	return int(math.Round(float64(rating)))
+}
+
+// NewInfo собирает блок myinfo из статистики пользователя.
+func NewInfo(userID uint, userName string, stats *models.AnimeStats) Info {
+	info := Info{
+		UserID:           userID,
+		UserName:         userName,
+		ExportType:       ExportTypeAnime,
+		TotalWatching:    stats.TotalWatching + stats.TotalWaiting,
+		TotalCompleted:   stats.TotalWatched + stats.TotalRewatching,
+		TotalOnHold:      stats.TotalOnHold,
+		TotalDropped:     stats.TotalDropped,
+		TotalPlanToWatch: stats.TotalPlanToWatch,
+	}
+	info.TotalAnime = info.TotalWatching + info.TotalCompleted + info.TotalOnHold + info.TotalDropped + info.TotalPlanToWatch
+	return info
+}
+
+// NewEntry переводит запись списка пользователя в запись экспорта MAL.
+func NewEntry(item *models.UserAnimeWithDetails) Entry {
+	entry := Entry{
+		SeriesAnimeDBID:   item.AnimeMALID,
+		SeriesTitle:       CDATA{Value: item.AnimeTitle},
+		SeriesType:        item.AnimeType,
+		SeriesEpisodes:    item.AnimeEpisodes,
+		MyWatchedEpisodes: item.EpisodesWatched,
+		MyStartDate:       formatDate(item.StartedAt),
+		MyFinishDate:      formatDate(item.FinishedAt),
+		MyScore:           ScoreFromRating(item.Rating),
+		MyStatus:          StatusFromWatchStatus(item.Status),
+		MyComments:        CDATA{Value: item.Notes},
+		MyTimesWatched:    item.RewatchCount,
+		UpdateOnImport:    1,
+	}
+	if item.Status == models.StatusRewatching {
+		entry.MyRewatching = 1
+		entry.MyRewatchingEp = item.RewatchEpisodes
+	}
+	return entry
+}
+
+// WatchStatusFromMAL переводит статус MAL в статус сервиса. Принимаются как
+// текстовые статусы, так и их числовые коды из старых экспортов.
+func WatchStatusFromMAL(status string, rewatching bool) (models.WatchStatus, error) {
+	var watchStatus models.WatchStatus
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "watching", "1":
+		watchStatus = models.StatusWatching
+	case "completed", "2":
+		watchStatus = models.StatusWatched
+	case "on-hold", "on hold", "3":
+		watchStatus = models.StatusOnHold
+	case "dropped", "4":
+		watchStatus = models.StatusDropped
+	case "plan to watch", "6":
+		watchStatus = models.StatusPlanToWatch
+	default:
+		return "", errors.WithDetails(ErrUnknownStatus, "status", status)
+	}
+
+	if rewatching && watchStatus == models.StatusWatched {
+		return models.StatusRewatching, nil
+	}
+	return watchStatus, nil
+}
+
+// ToUserAnime переводит запись экспорта MAL в запись списка пользователя.
+func (e *Entry) ToUserAnime(userID uint) (*models.UserAnime, error) {
+	if e.SeriesAnimeDBID <= 0 {
+		return nil, errors.WithDetails(ErrInvalidAnimeID, "series_animedb_id", e.SeriesAnimeDBID)
+	}
+
+	status, err := WatchStatusFromMAL(e.MyStatus, e.MyRewatching == 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.MyWatchedEpisodes < 0 || (e.SeriesEpisodes > 0 && e.MyWatchedEpisodes > e.SeriesEpisodes) {
+		return nil, errors.WithDetails(models.ErrInvalidEpisodeCount, "my_watched_episodes", e.MyWatchedEpisodes, "series_episodes", e.SeriesEpisodes)
+	}
+	if e.MyTimesWatched < 0 || e.MyRewatchingEp < 0 {
+		return nil, errors.WithDetails(models.ErrInvalidEpisodeCount, "my_times_watched", e.MyTimesWatched, "my_rewatching_ep", e.MyRewatchingEp)
+	}
+	if e.MyScore < 0 || e.MyScore > 10 {
+		return nil, errors.WithDetails(ErrInvalidScore, "my_score", e.MyScore)
+	}
+
+	userAnime := &models.UserAnime{
+		UserID:          userID,
+		AnimeMALID:      e.SeriesAnimeDBID,
+		Status:          status,
+		Rating:          float32(e.MyScore),
+		Notes:           e.MyComments.Value,
+		EpisodesWatched: e.MyWatchedEpisodes,
+		RewatchCount:    e.MyTimesWatched,
+		StartedAt:       parseDate(e.MyStartDate),
+		FinishedAt:      parseDate(e.MyFinishDate),
+	}
+	if status == models.StatusRewatching {
+		userAnime.RewatchEpisodes = e.MyRewatchingEp
+	}
+	return userAnime, nil
+}
+
+// LastActivity возвращает самую позднюю из дат начала и окончания просмотра записи.
+func (e *Entry) LastActivity() *time.Time {
+	started, finished := parseDate(e.MyStartDate), parseDate(e.MyFinishDate)
+	if finished == nil || (started != nil && started.After(*finished)) {
+		return started
+	}
+	return finished
+}
+
+// parseDate разбирает дату MAL; нулевые и частично заполненные даты считаются отсутствующими.
+func parseDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" || value == emptyDate {
+		return nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return emptyDate
+	}
+	return t.Format(dateLayout)
+}
@@ -0,0 +1,147 @@
+package mal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+)
+
+func date(value string) *time.Time {
+	t, _ := time.Parse(dateLayout, value)
+	return &t
+}
+
+func roundTripItems() []*models.UserAnimeWithDetails {
+	return []*models.UserAnimeWithDetails{
+		{
+			UserAnime:     models.UserAnime{AnimeMALID: 5114, Status: models.StatusWatched, Rating: 9.6, Notes: `<b>"best"</b> & ]]> end`, EpisodesWatched: 64, StartedAt: date("2024-01-02"), FinishedAt: date("2024-03-04"), RewatchCount: 1},
+			AnimeTitle:    "Fullmetal Alchemist: Brotherhood",
+			AnimeType:     "TV",
+			AnimeEpisodes: 64,
+		},
+		{
+			UserAnime:     models.UserAnime{AnimeMALID: 1535, Status: models.StatusRewatching, Rating: 8, EpisodesWatched: 37, RewatchCount: 2, RewatchEpisodes: 10, StartedAt: date("2023-05-06")},
+			AnimeTitle:    "Death Note",
+			AnimeType:     "TV",
+			AnimeEpisodes: 37,
+		},
+		{
+			UserAnime:     models.UserAnime{AnimeMALID: 21, Status: models.StatusOnHold, EpisodesWatched: 100},
+			AnimeTitle:    "One Piece",
+			AnimeType:     "TV",
+			AnimeEpisodes: 0,
+		},
+		{
+			UserAnime:     models.UserAnime{AnimeMALID: 20, Status: models.StatusPlanToWatch},
+			AnimeTitle:    "Naruto",
+			AnimeType:     "TV",
+			AnimeEpisodes: 220,
+		},
+	}
+}
+
+func writeExport(t *testing.T, w io.Writer, items []*models.UserAnimeWithDetails) {
+	t.Helper()
+	writer := NewWriter(w)
+	if err := writer.WriteHeader(NewInfo(7, "user", &models.AnimeStats{TotalWatched: 1, TotalRewatching: 1, TotalOnHold: 1, TotalPlanToWatch: 1})); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	for _, item := range items {
+		if err := writer.WriteEntry(NewEntry(item)); err != nil {
+			t.Fatalf("WriteEntry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func readExport(t *testing.T, r io.Reader) []*models.UserAnime {
+	t.Helper()
+	reader, err := NewReader(r, 1<<20)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer reader.Close()
+
+	var imported []*models.UserAnime
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			return imported
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		userAnime, err := entry.ToUserAnime(7)
+		if err != nil {
+			t.Fatalf("ToUserAnime(%d): %v", entry.SeriesAnimeDBID, err)
+		}
+		imported = append(imported, userAnime)
+	}
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	items := roundTripItems()
+
+	var plain bytes.Buffer
+	writeExport(t, &plain, items)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	writeExport(t, gz, items)
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip Close: %v", err)
+	}
+
+	for name, source := range map[string]io.Reader{"xml": &plain, "gzip": &compressed} {
+		t.Run(name, func(t *testing.T) {
+			imported := readExport(t, source)
+			if len(imported) != len(items) {
+				t.Fatalf("read %d entries, want %d", len(imported), len(items))
+			}
+
+			for i, got := range imported {
+				want := items[i].UserAnime
+				if got.UserID != 7 || got.AnimeMALID != want.AnimeMALID || got.Status != want.Status {
+					t.Errorf("entry %d = (user %d, anime %d, %s), want (user 7, anime %d, %s)", i, got.UserID, got.AnimeMALID, got.Status, want.AnimeMALID, want.Status)
+				}
+				if got.Rating != float32(ScoreFromRating(want.Rating)) || got.Notes != want.Notes {
+					t.Errorf("entry %d = (rating %v, notes %q), want (rating %d, notes %q)", i, got.Rating, got.Notes, ScoreFromRating(want.Rating), want.Notes)
+				}
+				if got.EpisodesWatched != want.EpisodesWatched || got.RewatchCount != want.RewatchCount || got.RewatchEpisodes != want.RewatchEpisodes {
+					t.Errorf("entry %d progress = (%d, rewatch %d/%d), want (%d, rewatch %d/%d)", i, got.EpisodesWatched, got.RewatchCount, got.RewatchEpisodes, want.EpisodesWatched, want.RewatchCount, want.RewatchEpisodes)
+				}
+				if !sameDate(got.StartedAt, want.StartedAt) || !sameDate(got.FinishedAt, want.FinishedAt) {
+					t.Errorf("entry %d dates = (%v, %v), want (%v, %v)", i, got.StartedAt, got.FinishedAt, want.StartedAt, want.FinishedAt)
+				}
+			}
+		})
+	}
+}
+
+// TestExportMapsStatusesWithoutMALEquivalent проверяет статусы, которых нет в
+// MAL: при обратном импорте они становятся ближайшими статусами MAL.
+func TestExportMapsStatusesWithoutMALEquivalent(t *testing.T) {
+	items := []*models.UserAnimeWithDetails{
+		{UserAnime: models.UserAnime{AnimeMALID: 1, Status: models.StatusWaiting, EpisodesWatched: 5}, AnimeEpisodes: 12},
+	}
+
+	var buf bytes.Buffer
+	writeExport(t, &buf, items)
+	imported := readExport(t, &buf)
+	if len(imported) != 1 || imported[0].Status != models.StatusWatching {
+		t.Fatalf("waiting entry imported as %+v, want watching", imported)
+	}
+}
@@ -0,0 +1,104 @@
+package mal
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+
+	"emperror.dev/errors"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ErrFileTooLarge — распакованный файл больше разрешенного размера.
+var ErrFileTooLarge = errors.New("MyAnimeList export is too large")
+
+// Reader читает записи экспорта MAL потоком. Сжатый gzip файл распознается
+// по сигнатуре и распаковывается на лету.
+type Reader struct {
+	dec    *xml.Decoder
+	closer io.Closer
+}
+
+// NewReader читает экспорт из r. maxSize ограничивает размер XML после
+// распаковки: сжатый файл небольшого размера может распаковаться в гигабайты.
+// При maxSize <= 0 размер не ограничивается.
+func NewReader(r io.Reader, maxSize int64) (*Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "error reading import file")
+	}
+
+	reader := &Reader{}
+	var source io.Reader = buffered
+	if len(magic) == len(gzipMagic) && magic[0] == gzipMagic[0] && magic[1] == gzipMagic[1] {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, errors.Wrap(err, "error opening gzip stream")
+		}
+		reader.closer = gz
+		source = gz
+	}
+
+	if maxSize > 0 {
+		source = &sizeLimitedReader{r: source, remaining: maxSize}
+	}
+	reader.dec = xml.NewDecoder(source)
+	return reader, nil
+}
+
+// sizeLimitedReader, в отличие от io.LimitReader, не обрезает поток молча, а
+// возвращает ErrFileTooLarge, как только прочитано больше remaining байт.
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrFileTooLarge
+	}
+	// Читаем на байт больше лимита, чтобы отличить файл ровно лимитного размера от большего.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, err
+}
+
+// Next возвращает следующую запись anime или io.EOF, когда записей больше нет.
+func (r *Reader) Next() (*Entry, error) {
+	for {
+		token, err := r.dec.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing import file")
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "anime" {
+			continue
+		}
+
+		entry := &Entry{}
+		if err := r.dec.DecodeElement(entry, &start); err != nil {
+			return nil, errors.Wrap(err, "error parsing anime entry")
+		}
+		return entry, nil
+	}
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
@@ -0,0 +1,52 @@
+package mal
+
+import (
+	"encoding/xml"
+	"io"
+
+	"emperror.dev/errors"
+)
+
+var rootElement = xml.StartElement{Name: xml.Name{Local: "myanimelist"}}
+
+// Writer пишет экспорт MAL потоком: заголовок, затем записи по одной,
+// не собирая весь список в памяти.
+type Writer struct {
+	w   io.Writer
+	enc *xml.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	return &Writer{w: w, enc: enc}
+}
+
+// WriteHeader открывает корневой элемент и пишет блок myinfo.
+func (w *Writer) WriteHeader(info Info) error {
+	if _, err := io.WriteString(w.w, xml.Header); err != nil {
+		return errors.Wrap(err, "error writing xml header")
+	}
+	if err := w.enc.EncodeToken(rootElement); err != nil {
+		return errors.Wrap(err, "error writing root element")
+	}
+	if err := w.enc.Encode(info); err != nil {
+		return errors.Wrap(err, "error writing myinfo")
+	}
+	return nil
+}
+
+func (w *Writer) WriteEntry(entry Entry) error {
+	if err := w.enc.Encode(entry); err != nil {
+		return errors.Wrap(err, "error writing anime entry")
+	}
+	return nil
+}
+
+// Close закрывает корневой элемент и сбрасывает буфер кодировщика.
+func (w *Writer) Close() error {
+	if err := w.enc.EncodeToken(rootElement.End()); err != nil {
+		return errors.Wrap(err, "error closing root element")
+	}
+	return errors.Wrap(w.enc.Flush(), "error flushing xml encoder")
+}
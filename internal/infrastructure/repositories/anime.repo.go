@@ -0,0 +1,280 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"logur.dev/logur"
+)
+
+var ErrCatalogAnimeNotFound = errors.New("anime not found in catalog")
+
+// AnimeRepository хранит локальный каталог аниме (таблицы animes и anime_genres),
+// заполняемый при первом запросе к Jikan API.
+type AnimeRepository struct {
+	db     *sql.DB
+	logger logur.LoggerFacade
+}
+
+func NewAnimeRepository(db *sql.DB, logger logur.LoggerFacade) *AnimeRepository {
+	return &AnimeRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *AnimeRepository) GetByMALID(ctx context.Context, malID int64) (*models.Anime, error) {
+	query := `
+		SELECT mal_id, title, title_english, title_japanese, synopsis, image_url, type, source,
+			episodes, status, airing, score, rank, popularity, fetched_at
+		FROM animes
+		WHERE mal_id = $1
+	`
+
+	anime := &models.Anime{}
+	err := r.db.QueryRowContext(ctx, query, malID).Scan(
+		&anime.MALId,
+		&anime.Title,
+		&anime.TitleEnglish,
+		&anime.TitleJapanese,
+		&anime.Synopsis,
+		&anime.ImageURL,
+		&anime.Type,
+		&anime.Source,
+		&anime.Episodes,
+		&anime.Status,
+		&anime.Airing,
+		&anime.Score,
+		&anime.Rank,
+		&anime.Popularity,
+		&anime.FetchedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCatalogAnimeNotFound
+	}
+
+	if err != nil {
+		r.logger.Error("Error getting anime from catalog", map[string]interface{}{
+			"mal_id": malID,
+			"error":  err.Error(),
+		})
+		return nil, errors.Wrap(err, "error getting anime from catalog")
+	}
+
+	genres, err := r.getGenres(ctx, malID)
+	if err != nil {
+		return nil, err
+	}
+	anime.Genres = genres
+
+	return anime, nil
+}
+
+// GetByMALIDs возвращает аниме из каталога по списку идентификаторов одним
+// запросом, без жанров. Отсутствующих в каталоге аниме в результате нет.
+func (r *AnimeRepository) GetByMALIDs(ctx context.Context, malIDs []int64) (map[int64]*models.Anime, error) {
+	animes := make(map[int64]*models.Anime, len(malIDs))
+	if len(malIDs) == 0 {
+		return animes, nil
+	}
+
+	query := `
+		SELECT mal_id, title, title_english, title_japanese, synopsis, image_url, type, source,
+			episodes, status, airing, score, rank, popularity, fetched_at
+		FROM animes
+		WHERE mal_id = ANY($1)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, malIDs)
+	if err != nil {
+		r.logger.Error("Error getting animes from catalog", map[string]interface{}{
+			"count": len(malIDs),
+			"error": err.Error(),
+		})
+		return nil, errors.Wrap(err, "error getting animes from catalog")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		anime := &models.Anime{}
+		if err := rows.Scan(
+			&anime.MALId,
+			&anime.Title,
+			&anime.TitleEnglish,
+			&anime.TitleJapanese,
+			&anime.Synopsis,
+			&anime.ImageURL,
+			&anime.Type,
+			&anime.Source,
+			&anime.Episodes,
+			&anime.Status,
+			&anime.Airing,
+			&anime.Score,
+			&anime.Rank,
+			&anime.Popularity,
+			&anime.FetchedAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "error scanning catalog anime")
+		}
+		animes[anime.MALId] = anime
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error iterating catalog animes")
+	}
+
+	return animes, nil
+}
+
+func (r *AnimeRepository) getGenres(ctx context.Context, malID int64) ([]models.Genre, error) {
+	query := `
+		SELECT genre_id, name
+		FROM anime_genres
+		WHERE anime_mal_id = $1
+		ORDER BY genre_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, malID)
+	if err != nil {
+		r.logger.Error("Error getting anime genres", map[string]interface{}{
+			"mal_id": malID,
+			"error":  err.Error(),
+		})
+		return nil, errors.Wrap(err, "error getting anime genres")
+	}
+	defer rows.Close()
+
+	genres := make([]models.Genre, 0)
+	for rows.Next() {
+		var genre models.Genre
+		if err := rows.Scan(&genre.ID, &genre.Name); err != nil {
+			return nil, errors.Wrap(err, "error scanning anime genre row")
+		}
+		genres = append(genres, genre)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error iterating anime genre rows")
+	}
+
+	return genres, nil
+}
+
+func (r *AnimeRepository) Upsert(ctx context.Context, anime *models.Anime) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error starting transaction")
+	}
+	defer tx.Rollback()
+
+	anime.FetchedAt = time.Now()
+
+	query := `
+		INSERT INTO animes (
+			mal_id, title, title_english, title_japanese, synopsis, image_url, type, source,
+			episodes, status, airing, score, rank, popularity, fetched_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+		)
+		ON CONFLICT (mal_id) DO UPDATE SET
+			title = EXCLUDED.title,
+			title_english = EXCLUDED.title_english,
+			title_japanese = EXCLUDED.title_japanese,
+			synopsis = EXCLUDED.synopsis,
+			image_url = EXCLUDED.image_url,
+			type = EXCLUDED.type,
+			source = EXCLUDED.source,
+			episodes = EXCLUDED.episodes,
+			status = EXCLUDED.status,
+			airing = EXCLUDED.airing,
+			score = EXCLUDED.score,
+			rank = EXCLUDED.rank,
+			popularity = EXCLUDED.popularity,
+			fetched_at = EXCLUDED.fetched_at
+	`
+
+	_, err = tx.ExecContext(ctx, query,
+		anime.MALId,
+		anime.Title,
+		anime.TitleEnglish,
+		anime.TitleJapanese,
+		anime.Synopsis,
+		anime.ImageURL,
+		anime.Type,
+		anime.Source,
+		anime.Episodes,
+		anime.Status,
+		anime.Airing,
+		anime.Score,
+		anime.Rank,
+		anime.Popularity,
+		anime.FetchedAt,
+	)
+	if err != nil {
+		r.logger.Error("Error upserting anime into catalog", map[string]interface{}{
+			"mal_id": anime.MALId,
+			"error":  err.Error(),
+		})
+		return errors.Wrap(err, "error upserting anime into catalog")
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM anime_genres WHERE anime_mal_id = $1`, anime.MALId); err != nil {
+		return errors.Wrap(err, "error deleting anime genres")
+	}
+
+	for _, genre := range anime.Genres {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO anime_genres (anime_mal_id, genre_id, name) VALUES ($1, $2, $3)`,
+			anime.MALId, genre.ID, genre.Name,
+		)
+		if err != nil {
+			r.logger.Error("Error inserting anime genre", map[string]interface{}{
+				"mal_id":   anime.MALId,
+				"genre_id": genre.ID,
+				"error":    err.Error(),
+			})
+			return errors.Wrap(err, "error inserting anime genre")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "error committing transaction")
+}
+
+// ListStaleMALIDs возвращает MAL ID аниме, данные которых не обновлялись с момента olderThan.
+func (r *AnimeRepository) ListStaleMALIDs(ctx context.Context, olderThan time.Time, limit int) ([]int64, error) {
+	query := `
+		SELECT mal_id
+		FROM animes
+		WHERE fetched_at < $1
+		ORDER BY fetched_at
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, olderThan, limit)
+	if err != nil {
+		r.logger.Error("Error listing stale catalog animes", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, errors.Wrap(err, "error listing stale catalog animes")
+	}
+	defer rows.Close()
+
+	var malIDs []int64
+	for rows.Next() {
+		var malID int64
+		if err := rows.Scan(&malID); err != nil {
+			return nil, errors.Wrap(err, "error scanning stale anime row")
+		}
+		malIDs = append(malIDs, malID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error iterating stale anime rows")
+	}
+
+	return malIDs, nil
+}
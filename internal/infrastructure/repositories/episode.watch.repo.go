@@ -0,0 +1,461 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"logur.dev/logur"
+)
+
+// EpisodeWatchRepository ведет журнал просмотров эпизодов (таблица episode_watches)
+// и пересчитывает по нему прогресс записи в user_animes в той же транзакции.
+type EpisodeWatchRepository struct {
+	db         *sql.DB
+	userAnimes *UserAnimeRepository
+	logger     logur.LoggerFacade
+}
+
+func NewEpisodeWatchRepository(db *sql.DB, userAnimes *UserAnimeRepository, logger logur.LoggerFacade) *EpisodeWatchRepository {
+	return &EpisodeWatchRepository{
+		db:         db,
+		userAnimes: userAnimes,
+		logger:     logger,
+	}
+}
+
+// MarkWatched отмечает эпизоды просмотренными. Повторная отметка тоже попадает в журнал.
+func (r *EpisodeWatchRepository) MarkWatched(ctx context.Context, userID uint, animeMALID int64, episodes []int, watchedAt time.Time, rating *float32, anime *models.Anime, expectedVersion int64) (*models.UserAnime, error) {
+	return r.record(ctx, userID, animeMALID, anime, expectedVersion, func(userAnime *models.UserAnime, watched map[int]bool) []models.EpisodeWatch {
+		events := make([]models.EpisodeWatch, 0, len(episodes))
+		for _, episode := range episodes {
+			events = append(events, newEpisodeWatch(userAnime, episode, false, watchedAt, rating))
+		}
+		return events
+	})
+}
+
+// MarkUnwatched снимает отметки с эпизодов; эпизоды без отметки пропускаются.
+func (r *EpisodeWatchRepository) MarkUnwatched(ctx context.Context, userID uint, animeMALID int64, episodes []int, anime *models.Anime, expectedVersion int64) (*models.UserAnime, error) {
+	now := time.Now()
+	return r.record(ctx, userID, animeMALID, anime, expectedVersion, func(userAnime *models.UserAnime, watched map[int]bool) []models.EpisodeWatch {
+		events := make([]models.EpisodeWatch, 0, len(episodes))
+		for _, episode := range episodes {
+			if watched[episode] {
+				events = append(events, newEpisodeWatch(userAnime, episode, true, now, nil))
+			}
+		}
+		return events
+	})
+}
+
+// SetProgress приводит журнал к состоянию "просмотрены эпизоды с 1 по episodesWatched":
+// недостающие эпизоды отмечаются, лишние — снимаются.
+func (r *EpisodeWatchRepository) SetProgress(ctx context.Context, userID uint, animeMALID int64, episodesWatched int, anime *models.Anime, expectedVersion int64) (*models.UserAnime, error) {
+	return r.record(ctx, userID, animeMALID, anime, expectedVersion, progressPlan(episodesWatched, time.Now()))
+}
+
+// progressPlan строит события, после которых просмотрены ровно эпизоды с 1 по episodesWatched.
+func progressPlan(episodesWatched int, now time.Time) func(userAnime *models.UserAnime, watched map[int]bool) []models.EpisodeWatch {
+	return func(userAnime *models.UserAnime, watched map[int]bool) []models.EpisodeWatch {
+		var events []models.EpisodeWatch
+		for episode := 1; episode <= episodesWatched; episode++ {
+			if !watched[episode] {
+				events = append(events, newEpisodeWatch(userAnime, episode, false, now, nil))
+			}
+		}
+		var extra []int
+		for episode := range watched {
+			if episode > episodesWatched {
+				extra = append(extra, episode)
+			}
+		}
+		sort.Ints(extra)
+		for _, episode := range extra {
+			events = append(events, newEpisodeWatch(userAnime, episode, true, now, nil))
+		}
+		return events
+	}
+}
+
+// ListByUserAnime возвращает журнал по записи, начиная с последних событий.
+func (r *EpisodeWatchRepository) ListByUserAnime(ctx context.Context, userID uint, animeMALID int64, limit int) ([]*models.EpisodeWatch, error) {
+	query := `
+		SELECT id, user_id, anime_mal_id, episode, rewatch_number, unwatched, rating, watched_at
+		FROM episode_watches
+		WHERE user_id = $1 AND anime_mal_id = $2
+		ORDER BY id DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, animeMALID, limit)
+	if err != nil {
+		r.logger.Error("Error listing episode watches", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, errors.Wrap(err, "error listing episode watches")
+	}
+	defer rows.Close()
+
+	var watches []*models.EpisodeWatch
+	for rows.Next() {
+		watch := &models.EpisodeWatch{}
+		if err := rows.Scan(
+			&watch.ID,
+			&watch.UserID,
+			&watch.AnimeMALID,
+			&watch.Episode,
+			&watch.RewatchNumber,
+			&watch.Unwatched,
+			&watch.Rating,
+			&watch.WatchedAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "error scanning episode watch row")
+		}
+		watches = append(watches, watch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error iterating episode watch rows")
+	}
+
+	return watches, nil
+}
+
+// record блокирует запись пользователя, дописывает события, построенные plan по текущему
+// набору просмотренных эпизодов, и сохраняет пересчитанный прогресс. При
+// expectedVersion > 0 версия записи должна совпадать с ожидаемой.
+func (r *EpisodeWatchRepository) record(
+	ctx context.Context,
+	userID uint,
+	animeMALID int64,
+	anime *models.Anime,
+	expectedVersion int64,
+	plan func(userAnime *models.UserAnime, watched map[int]bool) []models.EpisodeWatch,
+) (*models.UserAnime, error) {
+	return r.userAnimes.modify(ctx, userID, animeMALID, expectedVersion, r.progressChange(ctx, anime, plan))
+}
+
+// progressChange дописывает в журнал события plan и пересчитывает по нему прогресс и статус записи.
+func (r *EpisodeWatchRepository) progressChange(
+	ctx context.Context,
+	anime *models.Anime,
+	plan func(userAnime *models.UserAnime, watched map[int]bool) []models.EpisodeWatch,
+) func(q querier, userAnime *models.UserAnime) error {
+	return func(q querier, userAnime *models.UserAnime) error {
+		watched, err := r.syncWatched(ctx, q, userAnime, plan)
+		if err != nil {
+			return err
+		}
+		return userAnime.ApplyEpisodeProgress(watched, anime)
+	}
+}
+
+// Patch применяет к записи изменения patch в одной транзакции. Новое количество
+// просмотренных эпизодов переносится в журнал так же, как в SetProgress; статус
+// по прогрессу сдвигается, только если patch не задает его явно. Итоговая запись
+// проверяется целиком.
+func (r *EpisodeWatchRepository) Patch(ctx context.Context, userID uint, animeMALID int64, patch models.UserAnimePatch, anime *models.Anime, expectedVersion int64) (*models.UserAnime, error) {
+	return r.userAnimes.modify(ctx, userID, animeMALID, expectedVersion, func(q querier, userAnime *models.UserAnime) error {
+		if err := userAnime.ApplyPatch(patch); err != nil {
+			return err
+		}
+
+		if patch.EpisodesWatched.Set {
+			watched, err := r.syncWatched(ctx, q, userAnime, progressPlan(patch.EpisodesWatched.Value, time.Now()))
+			if err != nil {
+				return err
+			}
+			if patch.Status.Set {
+				err = userAnime.SetEpisodeProgress(watched, anime)
+			} else {
+				err = userAnime.ApplyEpisodeProgress(watched, anime)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return userAnime.Validate(anime)
+	})
+}
+
+// Import записывает запись из файла импорта в одной транзакции: создает ее, если
+// expectedVersion == 0, или меняет существующую запись этой версии. Статус
+// меняется по таблице переходов, даты, оценка, заметки и счетчик пересмотров
+// берутся из imported, а прогресс текущего просмотра переносится в журнал так же,
+// как в SetProgress. Теги записи не меняются.
+func (r *EpisodeWatchRepository) Import(ctx context.Context, imported *models.UserAnime, anime *models.Anime, expectedVersion int64) (*models.UserAnime, error) {
+	var userAnime *models.UserAnime
+	err := r.userAnimes.inTx(ctx, func(tx *sql.Tx) error {
+		if expectedVersion == 0 {
+			// Пересмотр не может быть начальным статусом: запись создается
+			// просмотренной и переводится в пересмотр ниже.
+			initial := imported.Status
+			if initial == models.StatusRewatching {
+				initial = models.StatusWatched
+			}
+			if _, err := r.userAnimes.upsertStatus(ctx, tx, imported.UserID, imported.AnimeMALID, initial, 0); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		userAnime, err = r.userAnimes.modifyIn(ctx, tx, imported.UserID, imported.AnimeMALID, expectedVersion, func(q querier, userAnime *models.UserAnime) error {
+			return r.importChange(ctx, q, userAnime, imported, anime)
+		})
+		return err
+	})
+	return userAnime, err
+}
+
+func (r *EpisodeWatchRepository) importChange(ctx context.Context, q querier, userAnime *models.UserAnime, imported *models.UserAnime, anime *models.Anime) error {
+	err := userAnime.ApplyPatch(models.UserAnimePatch{
+		Status:     models.Optional[models.WatchStatus]{Set: true, Value: imported.Status},
+		Rating:     models.Optional[float32]{Set: true, Value: imported.Rating},
+		Notes:      models.Optional[string]{Set: true, Value: imported.Notes},
+		StartedAt:  models.Optional[*time.Time]{Set: true, Value: imported.StartedAt},
+		FinishedAt: models.Optional[*time.Time]{Set: true, Value: imported.FinishedAt},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Счетчик пересмотров задает номер просмотра в журнале, поэтому он меняется
+	// до переноса прогресса.
+	userAnime.RewatchCount = imported.RewatchCount
+	progress := imported.EpisodesWatched
+	if imported.Status == models.StatusRewatching {
+		// В журнал пишется только текущий пересмотр; прогресс первого просмотра
+		// остается счетчиком, как у записей, заведенных до журнала.
+		userAnime.EpisodesWatched = imported.EpisodesWatched
+		progress = imported.RewatchEpisodes
+	}
+
+	watched, err := r.syncWatched(ctx, q, userAnime, progressPlan(progress, time.Now()))
+	if err != nil {
+		return err
+	}
+	if err := userAnime.SetEpisodeProgress(watched, anime); err != nil {
+		return err
+	}
+	return userAnime.Validate(anime)
+}
+
+// BulkOutcome — итог одной операции ApplyBulk: измененная запись (nil для
+// удаления) или ошибка операции.
+type BulkOutcome struct {
+	UserAnime *models.UserAnime
+	Err       error
+}
+
+// ApplyBulk выполняет операции ops по порядку в одной транзакции. В режиме atomic
+// первая ошибка откатывает всю транзакцию, и следующие операции не выполняются.
+// Иначе каждая операция выполняется под точкой сохранения: неудачная откатывается
+// до нее, а выполненные фиксируются. Для add и set_episodes в animes должно быть
+// аниме операции. Ошибка возвращается, только если не удалось выполнить саму
+// транзакцию; committed сообщает, зафиксированы ли изменения.
+func (r *EpisodeWatchRepository) ApplyBulk(ctx context.Context, userID uint, ops []models.BulkOperation, animes map[int64]*models.Anime, atomic bool) (outcomes []BulkOutcome, committed bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "error starting transaction")
+	}
+	defer tx.Rollback()
+
+	outcomes = make([]BulkOutcome, 0, len(ops))
+	for _, op := range ops {
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT bulk_operation`); err != nil {
+				return nil, false, errors.Wrap(err, "error creating savepoint")
+			}
+		}
+
+		userAnime, opErr := r.applyBulkOperation(ctx, tx, userID, op, animes[op.AnimeMALID])
+		outcomes = append(outcomes, BulkOutcome{UserAnime: userAnime, Err: opErr})
+
+		switch {
+		case opErr != nil && atomic:
+			return outcomes, false, nil
+		case opErr != nil:
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT bulk_operation`); err != nil {
+				return nil, false, errors.Wrap(err, "error rolling back to savepoint")
+			}
+		case !atomic:
+			if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT bulk_operation`); err != nil {
+				return nil, false, errors.Wrap(err, "error releasing savepoint")
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Error committing bulk user anime changes", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil, false, errors.Wrap(err, "error committing transaction")
+	}
+
+	return outcomes, true, nil
+}
+
+func (r *EpisodeWatchRepository) applyBulkOperation(ctx context.Context, q querier, userID uint, op models.BulkOperation, anime *models.Anime) (*models.UserAnime, error) {
+	switch op.Action {
+	case models.BulkActionAdd:
+		return r.userAnimes.upsertStatus(ctx, q, userID, op.AnimeMALID, op.Status, op.Version)
+	case models.BulkActionRemove:
+		return nil, r.userAnimes.deleteByUserAndAnimeMALID(ctx, q, userID, op.AnimeMALID, op.Version)
+	case models.BulkActionSetStatus:
+		return r.userAnimes.modifyIn(ctx, q, userID, op.AnimeMALID, op.Version, func(q querier, userAnime *models.UserAnime) error {
+			return userAnime.ChangeStatus(op.Status)
+		})
+	case models.BulkActionSetRating:
+		return r.userAnimes.modifyIn(ctx, q, userID, op.AnimeMALID, op.Version, func(q querier, userAnime *models.UserAnime) error {
+			userAnime.Rating = *op.Rating
+			return nil
+		})
+	case models.BulkActionSetEpisodes:
+		return r.userAnimes.modifyIn(ctx, q, userID, op.AnimeMALID, op.Version, r.progressChange(ctx, anime, progressPlan(*op.EpisodesWatched, time.Now())))
+	default:
+		return nil, errors.WithDetails(models.ErrInvalidBulkOperation, "action", op.Action)
+	}
+}
+
+// syncWatched дописывает в журнал события, построенные plan по текущему набору
+// просмотренных эпизодов, и возвращает количество просмотренных эпизодов после них.
+func (r *EpisodeWatchRepository) syncWatched(
+	ctx context.Context,
+	q querier,
+	userAnime *models.UserAnime,
+	plan func(userAnime *models.UserAnime, watched map[int]bool) []models.EpisodeWatch,
+) (int, error) {
+	watched, err := r.watchedEpisodes(ctx, q, userAnime)
+	if err != nil {
+		return 0, err
+	}
+
+	// Записи, прогресс которых велся до появления журнала, переносятся в него
+	// отметками с датой последнего изменения записи.
+	var backfill []models.EpisodeWatch
+	if len(watched) == 0 {
+		progress := userAnime.EpisodesWatched
+		if userAnime.Status == models.StatusRewatching {
+			progress = userAnime.RewatchEpisodes
+		}
+		for episode := 1; episode <= progress; episode++ {
+			backfill = append(backfill, newEpisodeWatch(userAnime, episode, false, userAnime.UpdatedAt, nil))
+			watched[episode] = true
+		}
+	}
+
+	events := append(backfill, plan(userAnime, watched)...)
+	if err := r.insert(ctx, q, events); err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if event.Unwatched {
+			delete(watched, event.Episode)
+		} else {
+			watched[event.Episode] = true
+		}
+	}
+
+	return len(watched), nil
+}
+
+// watchedEpisodes возвращает эпизоды, последнее событие которых в текущем просмотре — отметка.
+func (r *EpisodeWatchRepository) watchedEpisodes(ctx context.Context, q querier, userAnime *models.UserAnime) (map[int]bool, error) {
+	query := `
+		SELECT episode FROM (
+			SELECT DISTINCT ON (episode) episode, unwatched
+			FROM episode_watches
+			WHERE user_id = $1 AND anime_mal_id = $2 AND rewatch_number = $3
+			ORDER BY episode, id DESC
+		) latest
+		WHERE NOT unwatched
+	`
+
+	rows, err := q.QueryContext(ctx, query, userAnime.UserID, userAnime.AnimeMALID, userAnime.RewatchCount)
+	if err != nil {
+		r.logger.Error("Error getting watched episodes", map[string]interface{}{
+			"user_id":      userAnime.UserID,
+			"anime_mal_id": userAnime.AnimeMALID,
+			"error":        err.Error(),
+		})
+		return nil, errors.Wrap(err, "error getting watched episodes")
+	}
+	defer rows.Close()
+
+	watched := make(map[int]bool)
+	for rows.Next() {
+		var episode int
+		if err := rows.Scan(&episode); err != nil {
+			return nil, errors.Wrap(err, "error scanning watched episode")
+		}
+		watched[episode] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error iterating watched episodes")
+	}
+
+	return watched, nil
+}
+
+func (r *EpisodeWatchRepository) insert(ctx context.Context, q querier, events []models.EpisodeWatch) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	const columns = 7
+	placeholders := make([]string, 0, len(events))
+	args := make([]interface{}, 0, len(events)*columns)
+	for i, event := range events {
+		base := i * columns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7))
+		args = append(args,
+			event.UserID,
+			event.AnimeMALID,
+			event.Episode,
+			event.RewatchNumber,
+			event.Unwatched,
+			event.Rating,
+			event.WatchedAt,
+		)
+	}
+
+	query := `
+		INSERT INTO episode_watches (
+			user_id, anime_mal_id, episode, rewatch_number, unwatched, rating, watched_at
+		) VALUES ` + strings.Join(placeholders, ", ")
+
+	if _, err := q.ExecContext(ctx, query, args...); err != nil {
+		r.logger.Error("Error inserting episode watches", map[string]interface{}{
+			"count": len(events),
+			"error": err.Error(),
+		})
+		return errors.Wrap(err, "error inserting episode watches")
+	}
+
+	return nil
+}
+
+func newEpisodeWatch(userAnime *models.UserAnime, episode int, unwatched bool, watchedAt time.Time, rating *float32) models.EpisodeWatch {
+	return models.EpisodeWatch{
+		UserID:        userAnime.UserID,
+		AnimeMALID:    userAnime.AnimeMALID,
+		Episode:       episode,
+		RewatchNumber: userAnime.RewatchCount,
+		Unwatched:     unwatched,
+		Rating:        rating,
+		WatchedAt:     watchedAt,
+	}
+}
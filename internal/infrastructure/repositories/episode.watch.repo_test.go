@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+	"logur.dev/logur"
+)
+
+func TestProgressPlan(t *testing.T) {
+	now := time.Now()
+	userAnime := &models.UserAnime{UserID: 1, AnimeMALID: 5114, RewatchCount: 2}
+
+	type event struct {
+		episode   int
+		unwatched bool
+	}
+	tests := []struct {
+		name     string
+		progress int
+		watched  []int
+		want     []event
+	}{
+		{"from scratch", 3, nil, []event{{1, false}, {2, false}, {3, false}}},
+		{"fills gaps", 4, []int{1, 3}, []event{{2, false}, {4, false}}},
+		{"already there", 2, []int{1, 2}, nil},
+		{"unmarks extra episodes in order", 1, []int{1, 5, 2, 9}, []event{{2, true}, {5, true}, {9, true}}},
+		{"reset to zero", 0, []int{1, 2}, []event{{1, true}, {2, true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watched := make(map[int]bool)
+			for _, episode := range tt.watched {
+				watched[episode] = true
+			}
+
+			events := progressPlan(tt.progress, now)(userAnime, watched)
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d: %+v", len(events), len(tt.want), events)
+			}
+			for i, got := range events {
+				if got.Episode != tt.want[i].episode || got.Unwatched != tt.want[i].unwatched {
+					t.Errorf("event %d = (episode %d, unwatched %v), want (episode %d, unwatched %v)", i, got.Episode, got.Unwatched, tt.want[i].episode, tt.want[i].unwatched)
+				}
+				if got.RewatchNumber != 2 || !got.WatchedAt.Equal(now) {
+					t.Errorf("event %d = (rewatch %d, at %v), want (rewatch 2, at %v)", i, got.RewatchNumber, got.WatchedAt, now)
+				}
+			}
+		})
+	}
+}
+
+// TestSyncWatchedBackfillsLegacyProgress проверяет, что прогресс записи, которая
+// велась до появления журнала, переносится в журнал при первом изменении.
+func TestSyncWatchedBackfillsLegacyProgress(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	userAnimes := NewUserAnimeRepository(db, logur.NoopLogger{})
+	episodes := NewEpisodeWatchRepository(db, userAnimes, logur.NoopLogger{})
+	userID := dbtest.CreateUser(t, db)
+	anime := &models.Anime{MALId: 5114, Episodes: 12, Status: models.AnimeStatusFinished}
+
+	if _, err := userAnimes.ChangeStatus(ctx, userID, anime.MALId, models.StatusWatching, 0); err != nil {
+		t.Fatalf("create entry: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE user_animes SET episodes_watched = 5 WHERE user_id = $1 AND anime_mal_id = $2`, userID, anime.MALId); err != nil {
+		t.Fatalf("set legacy progress: %v", err)
+	}
+
+	userAnime, err := episodes.MarkWatched(ctx, userID, anime.MALId, []int{6}, time.Now(), nil, anime, 0)
+	if err != nil {
+		t.Fatalf("MarkWatched: %v", err)
+	}
+	if userAnime.EpisodesWatched != 6 {
+		t.Errorf("episodes watched = %d, want 6", userAnime.EpisodesWatched)
+	}
+	log, err := episodes.ListByUserAnime(ctx, userID, anime.MALId, 100)
+	if err != nil {
+		t.Fatalf("ListByUserAnime: %v", err)
+	}
+	marked := make(map[int]bool)
+	for _, watch := range log {
+		if watch.Unwatched {
+			t.Errorf("unexpected unwatch of episode %d", watch.Episode)
+		}
+		marked[watch.Episode] = true
+	}
+	if len(log) != 6 || len(marked) != 6 {
+		t.Fatalf("log has %d events for %d episodes, want 6 for episodes 1-6", len(log), len(marked))
+	}
+
+	// Журнал уже не пуст, поэтому повторного переноса нет.
+	userAnime, err = episodes.SetProgress(ctx, userID, anime.MALId, 3, anime, 0)
+	if err != nil {
+		t.Fatalf("SetProgress: %v", err)
+	}
+	if userAnime.EpisodesWatched != 3 {
+		t.Errorf("episodes watched = %d, want 3", userAnime.EpisodesWatched)
+	}
+	log, err = episodes.ListByUserAnime(ctx, userID, anime.MALId, 100)
+	if err != nil {
+		t.Fatalf("ListByUserAnime: %v", err)
+	}
+	if len(log) != 9 {
+		t.Errorf("log has %d events, want 9 after unmarking episodes 4-6", len(log))
+	}
+}
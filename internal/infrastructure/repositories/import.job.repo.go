@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"logur.dev/logur"
+)
+
+// ImportJobRepository хранит задачи импорта списков (таблица import_jobs), чтобы
+// их состояние было видно всем экземплярам API и переживало перезапуск.
+type ImportJobRepository struct {
+	db     *sql.DB
+	logger logur.LoggerFacade
+}
+
+func NewImportJobRepository(db *sql.DB, logger logur.LoggerFacade) *ImportJobRepository {
+	return &ImportJobRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Save создает или обновляет задачу; после expiresAt задача считается удаленной.
+func (r *ImportJobRepository) Save(ctx context.Context, job *models.ImportJob, expiresAt time.Time) error {
+	options, err := json.Marshal(job.Options)
+	if err != nil {
+		return errors.Wrap(err, "error encoding import job options")
+	}
+	var report []byte
+	if job.Report != nil {
+		if report, err = json.Marshal(job.Report); err != nil {
+			return errors.Wrap(err, "error encoding import job report")
+		}
+	}
+
+	query := `
+		INSERT INTO import_jobs (id, user_id, status, options, report, error, created_at, finished_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			status = EXCLUDED.status,
+			report = EXCLUDED.report,
+			error = EXCLUDED.error,
+			finished_at = EXCLUDED.finished_at,
+			expires_at = EXCLUDED.expires_at
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		job.ID,
+		job.UserID,
+		job.Status,
+		string(options),
+		nullableJSON(report),
+		job.Error,
+		job.CreatedAt,
+		job.FinishedAt,
+		expiresAt,
+	)
+	if err != nil {
+		r.logger.Error("Error saving import job", map[string]interface{}{
+			"job_id": job.ID,
+			"error":  err.Error(),
+		})
+		return errors.Wrap(err, "error saving import job")
+	}
+
+	return nil
+}
+
+// GetByID возвращает неистекшую задачу.
+func (r *ImportJobRepository) GetByID(ctx context.Context, id string) (*models.ImportJob, error) {
+	query := `
+		SELECT id, user_id, status, options, report, error, created_at, finished_at
+		FROM import_jobs
+		WHERE id = $1 AND expires_at > now()
+	`
+
+	job := &models.ImportJob{}
+	var options []byte
+	var report []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Status,
+		&options,
+		&report,
+		&job.Error,
+		&job.CreatedAt,
+		&job.FinishedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrImportJobNotFound
+	}
+	if err != nil {
+		r.logger.Error("Error getting import job", map[string]interface{}{
+			"job_id": id,
+			"error":  err.Error(),
+		})
+		return nil, errors.Wrap(err, "error getting import job")
+	}
+
+	if err := json.Unmarshal(options, &job.Options); err != nil {
+		return nil, errors.Wrap(err, "error decoding import job options")
+	}
+	if report != nil {
+		job.Report = &models.ImportReport{}
+		if err := json.Unmarshal(report, job.Report); err != nil {
+			return nil, errors.Wrap(err, "error decoding import job report")
+		}
+	}
+
+	return job, nil
+}
+
+// DeleteExpired удаляет задачи, срок хранения которых истек.
+func (r *ImportJobRepository) DeleteExpired(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM import_jobs WHERE expires_at <= now()`); err != nil {
+		return errors.Wrap(err, "error deleting expired import jobs")
+	}
+	return nil
+}
+
+// nullableJSON превращает пустое значение в NULL для колонки jsonb.
+func nullableJSON(data []byte) interface{} {
+	if data == nil {
+		return nil
+	}
+	return string(data)
+}
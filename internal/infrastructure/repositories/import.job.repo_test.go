@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+	"logur.dev/logur"
+)
+
+func TestImportJobSaveAndGet(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	repo := NewImportJobRepository(db, logur.NoopLogger{})
+
+	job := &models.ImportJob{
+		ID:        "job",
+		UserID:    1,
+		Status:    models.ImportJobRunning,
+		Options:   models.ImportOptions{Policy: models.ImportPolicySkip},
+		CreatedAt: time.Now(),
+	}
+	if err := repo.Save(ctx, job, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Save running job: %v", err)
+	}
+
+	finished := time.Now()
+	job.Status = models.ImportJobCompleted
+	job.FinishedAt = &finished
+	job.Report = &models.ImportReport{Created: 2}
+	if err := repo.Save(ctx, job, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Save completed job: %v", err)
+	}
+
+	loaded, err := repo.GetByID(ctx, "job")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if loaded.Status != models.ImportJobCompleted || loaded.Options.Policy != models.ImportPolicySkip || loaded.Report == nil || loaded.Report.Created != 2 || loaded.FinishedAt == nil {
+		t.Errorf("GetByID = %+v, want the completed job", loaded)
+	}
+}
+
+func TestImportJobExpires(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	repo := NewImportJobRepository(db, logur.NoopLogger{})
+
+	job := &models.ImportJob{ID: "job", UserID: 1, Status: models.ImportJobPending, CreatedAt: time.Now()}
+	if err := repo.Save(ctx, job, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, "job"); !errors.Is(err, models.ErrImportJobNotFound) {
+		t.Errorf("GetByID for an expired job: err = %v, want %v", err, models.ErrImportJobNotFound)
+	}
+	if err := repo.DeleteExpired(ctx); err != nil {
+		t.Fatalf("DeleteExpired: %v", err)
+	}
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM import_jobs`).Scan(&count); err != nil {
+		t.Fatalf("count jobs: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d jobs left after DeleteExpired, want 0", count)
+	}
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"gorm.io/gorm"
+)
+
+func NewMFARepository(db *gorm.DB) repositories.MFARepository {
+	return &MFARepositoryImpl{
+		db: db,
+	}
+}
+
+type MFARepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r *MFARepositoryImpl) SetPendingTOTPSecret(ctx context.Context, userID uint, secret string) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_enabled = ?", userID, false).
+		Update("totp_secret", secret)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *MFARepositoryImpl) EnableTOTP(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("id = ?", userID).Update("totp_enabled", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrUserNotFound
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		for i := range codes {
+			codes[i].UserID = userID
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *MFARepositoryImpl) DisableTOTP(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":  "",
+			"totp_enabled": false,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+func (r *MFARepositoryImpl) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	result := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrRecoveryCodeInvalid
+	}
+	return nil
+}
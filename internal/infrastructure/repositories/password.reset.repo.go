@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func NewPasswordResetTokenRepository(db *gorm.DB) repositories.PasswordResetTokenRepository {
+	return &PasswordResetTokenRepositoryImpl{
+		db: db,
+	}
+}
+
+type PasswordResetTokenRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r *PasswordResetTokenRepositoryImpl) Create(ctx context.Context, token models.PasswordResetToken) error {
+	return r.db.WithContext(ctx).Create(&token).Error
+}
+
+func (r *PasswordResetTokenRepositoryImpl) Consume(ctx context.Context, tokenHash string) (models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).
+			First(&token)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return models.ErrPasswordResetTokenInvalid
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		now := time.Now()
+		if !token.IsUsable(now) {
+			return models.ErrPasswordResetTokenInvalid
+		}
+
+		return tx.Model(&token).Update("used_at", now).Error
+	})
+	return token, err
+}
+
+func (r *PasswordResetTokenRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PasswordResetToken{}).Error
+}
+
+func (r *PasswordResetTokenRepositoryImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.PasswordResetToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+)
+
+// querier — общее подмножество *sql.DB и *sql.Tx, позволяющее выполнять одни и те же
+// запросы как отдельно, так и внутри транзакции.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
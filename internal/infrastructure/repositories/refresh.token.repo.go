@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func NewRefreshTokenRepository(db *gorm.DB) repositories.RefreshTokenRepository {
+	return &RefreshTokenRepositoryImpl{
+		db: db,
+	}
+}
+
+type RefreshTokenRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r *RefreshTokenRepositoryImpl) Create(ctx context.Context, token models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(&token).Error
+}
+
+func (r *RefreshTokenRepositoryImpl) GetByHash(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	result := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return models.RefreshToken{}, models.ErrRefreshTokenNotFound
+	}
+	if result.Error != nil {
+		return models.RefreshToken{}, result.Error
+	}
+	return token, nil
+}
+
+func (r *RefreshTokenRepositoryImpl) Rotate(ctx context.Context, tokenHash string, next models.RefreshToken) (models.RefreshToken, error) {
+	var current models.RefreshToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).
+			First(&current)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return models.ErrRefreshTokenNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		now := time.Now()
+		if current.UsedAt != nil {
+			if current.InReuseGrace(now) {
+				var successors int64
+				err := tx.Model(&models.RefreshToken{}).
+					Where("token_hash = ? AND family_id = ?", next.TokenHash, current.FamilyID).
+					Count(&successors).Error
+				if err != nil {
+					return err
+				}
+				if successors > 0 {
+					return nil
+				}
+			}
+			return models.ErrRefreshTokenReused
+		}
+		if current.IsExpired(now) {
+			return models.ErrRefreshTokenExpired
+		}
+
+		if err := tx.Model(&current).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		next.UserID = current.UserID
+		next.FamilyID = current.FamilyID
+		return tx.Create(&next).Error
+	})
+	return current, err
+}
+
+func (r *RefreshTokenRepositoryImpl) DeleteFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Where("family_id = ?", familyID).Delete(&models.RefreshToken{}).Error
+}
+
+func (r *RefreshTokenRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.RefreshToken{}).Error
+}
+
+func (r *RefreshTokenRepositoryImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+)
+
+func TestRefreshTokenRotate(t *testing.T) {
+	ctx := context.Background()
+	gormDB, db := dbtest.OpenGorm(t)
+	refreshTokens := NewRefreshTokenRepository(gormDB)
+	userID := dbtest.CreateUser(t, db)
+
+	expiresAt := time.Now().Add(time.Hour)
+	first := models.RefreshToken{UserID: userID, FamilyID: "family", TokenHash: "first", ExpiresAt: expiresAt}
+	if err := refreshTokens.Create(ctx, first); err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+
+	second := models.RefreshToken{TokenHash: "second", ExpiresAt: expiresAt}
+	current, err := refreshTokens.Rotate(ctx, "first", second)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if current.UserID != userID || current.FamilyID != "family" {
+		t.Errorf("rotated token = user %d family %q, want user %d family %q", current.UserID, current.FamilyID, userID, "family")
+	}
+	stored, err := refreshTokens.GetByHash(ctx, "second")
+	if err != nil {
+		t.Fatalf("GetByHash successor: %v", err)
+	}
+	if stored.UserID != userID || stored.FamilyID != "family" {
+		t.Errorf("successor = user %d family %q, want user %d family %q", stored.UserID, stored.FamilyID, userID, "family")
+	}
+
+	// Вторая вкладка предъявила тот же токен сразу после ротации.
+	if _, err := refreshTokens.Rotate(ctx, "first", second); err != nil {
+		t.Errorf("Rotate within the grace period: err = %v, want nil", err)
+	}
+
+	// Повтор с другим преемником — это уже не параллельный запрос того же клиента.
+	if _, err := refreshTokens.Rotate(ctx, "first", models.RefreshToken{TokenHash: "forged", ExpiresAt: expiresAt}); err != models.ErrRefreshTokenReused {
+		t.Errorf("Rotate with a different successor: err = %v, want %v", err, models.ErrRefreshTokenReused)
+	}
+
+	usedAt := time.Now().Add(-2 * models.RefreshTokenReuseGrace)
+	if err := gormDB.Model(&models.RefreshToken{}).Where("token_hash = ?", "first").Update("used_at", usedAt).Error; err != nil {
+		t.Fatalf("age used_at: %v", err)
+	}
+	current, err = refreshTokens.Rotate(ctx, "first", second)
+	if err != models.ErrRefreshTokenReused {
+		t.Errorf("Rotate after the grace period: err = %v, want %v", err, models.ErrRefreshTokenReused)
+	}
+	if current.FamilyID != "family" {
+		t.Errorf("reused token family = %q, want %q", current.FamilyID, "family")
+	}
+
+	if _, err := refreshTokens.Rotate(ctx, "missing", models.RefreshToken{TokenHash: "third", ExpiresAt: expiresAt}); err != models.ErrRefreshTokenNotFound {
+		t.Errorf("Rotate unknown token: err = %v, want %v", err, models.ErrRefreshTokenNotFound)
+	}
+
+	expired := models.RefreshToken{UserID: userID, FamilyID: "family", TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := refreshTokens.Create(ctx, expired); err != nil {
+		t.Fatalf("create expired refresh token: %v", err)
+	}
+	if _, err := refreshTokens.Rotate(ctx, "expired", models.RefreshToken{TokenHash: "fourth", ExpiresAt: expiresAt}); err != models.ErrRefreshTokenExpired {
+		t.Errorf("Rotate expired token: err = %v, want %v", err, models.ErrRefreshTokenExpired)
+	}
+}
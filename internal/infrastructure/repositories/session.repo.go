@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func NewSessionRepository(db *gorm.DB) repositories.SessionRepository {
+	return &SessionRepositoryImpl{
+		db: db,
+	}
+}
+
+type SessionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r *SessionRepositoryImpl) Create(ctx context.Context, session models.Session) error {
+	return r.db.WithContext(ctx).Create(&session).Error
+}
+
+func (r *SessionRepositoryImpl) CreateWithRefreshToken(ctx context.Context, session models.Session, token models.RefreshToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return tx.Create(&token).Error
+	})
+}
+
+func (r *SessionRepositoryImpl) GetByID(ctx context.Context, id string) (models.Session, error) {
+	var session models.Session
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&session)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return models.Session{}, models.ErrSessionNotFound
+	}
+	if result.Error != nil {
+		return models.Session{}, result.Error
+	}
+	return session, nil
+}
+
+func (r *SessionRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("last_used_at DESC").Find(&sessions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+// Touch обновляет время последнего использования сессии и данные устройства.
+func (r *SessionRepositoryImpl) Touch(ctx context.Context, session models.Session) error {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+		"ip":           session.IP,
+		"user_agent":   session.UserAgent,
+		"browser":      session.Browser,
+		"os":           session.OS,
+		"device":       session.Device,
+		"last_used_at": session.LastUsedAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepositoryImpl) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteByUser удаляет все сессии пользователя и возвращает их ID.
+func (r *SessionRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) ([]string, error) {
+	var deleted []models.Session
+	result := r.db.WithContext(ctx).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("user_id = ?", userID).
+		Delete(&deleted)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	ids := make([]string, 0, len(deleted))
+	for _, session := range deleted {
+		ids = append(ids, session.ID)
+	}
+	return ids, nil
+}
+
+func (r *SessionRepositoryImpl) DeleteInactive(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("last_used_at < ?", before).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
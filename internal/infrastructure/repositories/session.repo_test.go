@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+)
+
+func TestCreateWithRefreshTokenIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	gormDB, db := dbtest.OpenGorm(t)
+	sessions := NewSessionRepository(gormDB)
+	refreshTokens := NewRefreshTokenRepository(gormDB)
+	userID := dbtest.CreateUser(t, db)
+
+	now := time.Now()
+	existing := models.RefreshToken{UserID: userID, FamilyID: "family-1", TokenHash: "hash", ExpiresAt: now.Add(time.Hour)}
+	if err := refreshTokens.Create(ctx, existing); err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+
+	session := models.Session{ID: "family-2", UserID: userID, CreatedAt: now, LastUsedAt: now}
+	duplicate := models.RefreshToken{UserID: userID, FamilyID: "family-2", TokenHash: "hash", ExpiresAt: now.Add(time.Hour)}
+	if err := sessions.CreateWithRefreshToken(ctx, session, duplicate); err == nil {
+		t.Fatal("CreateWithRefreshToken stored a refresh token with a duplicate hash")
+	}
+	if _, err := sessions.GetByID(ctx, session.ID); err != models.ErrSessionNotFound {
+		t.Errorf("session after a failed refresh token insert: err = %v, want %v", err, models.ErrSessionNotFound)
+	}
+
+	token := models.RefreshToken{UserID: userID, FamilyID: "family-2", TokenHash: "other-hash", ExpiresAt: now.Add(time.Hour)}
+	if err := sessions.CreateWithRefreshToken(ctx, session, token); err != nil {
+		t.Fatalf("CreateWithRefreshToken: %v", err)
+	}
+	if _, err := sessions.GetByID(ctx, session.ID); err != nil {
+		t.Errorf("GetByID: %v", err)
+	}
+	if _, err := refreshTokens.GetByHash(ctx, token.TokenHash); err != nil {
+		t.Errorf("GetByHash: %v", err)
+	}
+}
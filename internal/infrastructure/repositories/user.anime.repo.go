@@ -3,13 +3,13 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"emperror.dev/errors"
 	"github.com/merdernoty/anime-service/internal/domain/models"
-	"github.com/merdernoty/anime-service/internal/infrastructure/api"
 	"logur.dev/logur"
 )
 
@@ -25,17 +25,39 @@ func NewUserAnimeRepository(db *sql.DB, logger logur.LoggerFacade) *UserAnimeRep
 	}
 }
 
-func (r *UserAnimeRepository) GetByID(ctx context.Context, id uint) (*models.UserAnime, error) {
-	query := `
-		SELECT id, user_id, anime_mal_id, status, rating, notes, episodes_watched, created_at, updated_at
-		FROM user_animes
-		WHERE id = $1
-	`
+var userAnimeColumns = []string{
+	"id", "user_id", "anime_mal_id", "status", "rating", "notes", "episodes_watched", "rewatch_count",
+	"rewatch_episodes", "started_at", "finished_at", "created_at", "updated_at", "tags", "version",
+}
 
-	row := r.db.QueryRowContext(ctx, query, id)
-	userAnime := &models.UserAnime{}
+// selectUserAnimeColumns возвращает список колонок user_animes для SELECT с заданным префиксом таблицы.
+func selectUserAnimeColumns(prefix string) string {
+	columns := make([]string, 0, len(userAnimeColumns))
+	for _, column := range userAnimeColumns {
+		columns = append(columns, prefix+column)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// animeDetailsColumns — поля каталога для записей списка; у отсутствующих в каталоге аниме они пустые.
+const animeDetailsColumns = `COALESCE(a.title, ''), COALESCE(a.image_url, ''), COALESCE(a.type, ''),
+			COALESCE(a.episodes, 0), COALESCE(a.status, ''), COALESCE(a.score, 0)`
+
+// userAnimeWithDetailsScanDest дополняет userAnimeScanDest полями animeDetailsColumns.
+func userAnimeWithDetailsScanDest(item *models.UserAnimeWithDetails) []interface{} {
+	return append(userAnimeScanDest(&item.UserAnime),
+		&item.AnimeTitle,
+		&item.AnimeImage,
+		&item.AnimeType,
+		&item.AnimeEpisodes,
+		&item.AnimeStatus,
+		&item.AnimeScore,
+	)
+}
 
-	err := row.Scan(
+// userAnimeScanDest возвращает указатели на поля в порядке userAnimeColumns.
+func userAnimeScanDest(userAnime *models.UserAnime) []interface{} {
+	return []interface{}{
 		&userAnime.ID,
 		&userAnime.UserID,
 		&userAnime.AnimeMALID,
@@ -43,12 +65,61 @@ func (r *UserAnimeRepository) GetByID(ctx context.Context, id uint) (*models.Use
 		&userAnime.Rating,
 		&userAnime.Notes,
 		&userAnime.EpisodesWatched,
+		&userAnime.RewatchCount,
+		&userAnime.RewatchEpisodes,
+		&userAnime.StartedAt,
+		&userAnime.FinishedAt,
 		&userAnime.CreatedAt,
 		&userAnime.UpdatedAt,
-	)
+		tagsColumn{tags: &userAnime.Tags},
+		&userAnime.Version,
+	}
+}
+
+// tagsColumn читает теги записи из колонки jsonb.
+type tagsColumn struct {
+	tags *[]string
+}
+
+func (c tagsColumn) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*c.tags = []string{}
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return errors.Errorf("unsupported tags value %T", src)
+	}
+	return errors.WithStack(json.Unmarshal(data, c.tags))
+}
+
+// tagsValue кодирует теги для записи в колонку jsonb.
+func tagsValue(tags []string) string {
+	if tags == nil {
+		tags = []string{}
+	}
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
+
+func (r *UserAnimeRepository) GetByID(ctx context.Context, id uint) (*models.UserAnime, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM user_animes
+		WHERE id = $1
+	`, selectUserAnimeColumns(""))
+
+	row := r.db.QueryRowContext(ctx, query, id)
+	userAnime := &models.UserAnime{}
+
+	err := row.Scan(userAnimeScanDest(userAnime)...)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user anime not found")
+		return nil, models.ErrUserAnimeNotFound
 	}
 
 	if err != nil {
@@ -63,29 +134,27 @@ func (r *UserAnimeRepository) GetByID(ctx context.Context, id uint) (*models.Use
 }
 
 func (r *UserAnimeRepository) GetByUserAndAnimeMALID(ctx context.Context, userID uint, animeMALID int64) (*models.UserAnime, error) {
-	query := `
-		SELECT id, user_id, anime_mal_id, status, rating, notes, episodes_watched, created_at, updated_at
+	return r.getByUserAndAnimeMALID(ctx, r.db, userID, animeMALID, false)
+}
+
+// getByUserAndAnimeMALID читает запись через q; forUpdate блокирует строку до конца транзакции.
+func (r *UserAnimeRepository) getByUserAndAnimeMALID(ctx context.Context, q querier, userID uint, animeMALID int64, forUpdate bool) (*models.UserAnime, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM user_animes
 		WHERE user_id = $1 AND anime_mal_id = $2
-	`
+	`, selectUserAnimeColumns(""))
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
 
-	row := r.db.QueryRowContext(ctx, query, userID, animeMALID)
+	row := q.QueryRowContext(ctx, query, userID, animeMALID)
 	userAnime := &models.UserAnime{}
 
-	err := row.Scan(
-		&userAnime.ID,
-		&userAnime.UserID,
-		&userAnime.AnimeMALID,
-		&userAnime.Status,
-		&userAnime.Rating,
-		&userAnime.Notes,
-		&userAnime.EpisodesWatched,
-		&userAnime.CreatedAt,
-		&userAnime.UpdatedAt,
-	)
+	err := row.Scan(userAnimeScanDest(userAnime)...)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user anime not found")
+		return nil, models.ErrUserAnimeNotFound
 	}
 
 	if err != nil {
@@ -100,22 +169,40 @@ func (r *UserAnimeRepository) GetByUserAndAnimeMALID(ctx context.Context, userID
 	return userAnime, nil
 }
 
-func (r *UserAnimeRepository) List(ctx context.Context, filter models.UserAnimeFilter) ([]*models.UserAnime, int, error) {
+// buildFilterConditions собирает WHERE-условия фильтра для таблицы user_animes
+// с заданным префиксом (например, "ua.") и возвращает следующий номер параметра.
+func buildFilterConditions(filter models.UserAnimeFilter, prefix string) (string, []interface{}, int) {
 	var conditions []string
 	var args []interface{}
 	argCounter := 1
 
-	conditions = append(conditions, fmt.Sprintf("user_id = $%d", argCounter))
+	conditions = append(conditions, fmt.Sprintf("%suser_id = $%d", prefix, argCounter))
 	args = append(args, filter.UserID)
 	argCounter++
 
 	if filter.Status != "" {
-		conditions = append(conditions, fmt.Sprintf("status = $%d", argCounter))
+		conditions = append(conditions, fmt.Sprintf("%sstatus = $%d", prefix, argCounter))
 		args = append(args, filter.Status)
 		argCounter++
 	}
 
-	whereClause := strings.Join(conditions, " AND ")
+	if filter.StartedYear > 0 {
+		conditions = append(conditions, fmt.Sprintf("EXTRACT(YEAR FROM %sstarted_at) = $%d", prefix, argCounter))
+		args = append(args, filter.StartedYear)
+		argCounter++
+	}
+
+	if filter.FinishedYear > 0 {
+		conditions = append(conditions, fmt.Sprintf("EXTRACT(YEAR FROM %sfinished_at) = $%d", prefix, argCounter))
+		args = append(args, filter.FinishedYear)
+		argCounter++
+	}
+
+	return strings.Join(conditions, " AND "), args, argCounter
+}
+
+func (r *UserAnimeRepository) List(ctx context.Context, filter models.UserAnimeFilter) ([]*models.UserAnime, int, error) {
+	whereClause, args, argCounter := buildFilterConditions(filter, "")
 
 	countQuery := fmt.Sprintf(`
 		SELECT COUNT(*) FROM user_animes WHERE %s
@@ -141,12 +228,12 @@ func (r *UserAnimeRepository) List(ctx context.Context, filter models.UserAnimeF
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, user_id, anime_mal_id, status, rating, notes, episodes_watched, created_at, updated_at
+		SELECT %s
 		FROM user_animes
 		WHERE %s
 		ORDER BY updated_at DESC
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argCounter, argCounter+1)
+	`, selectUserAnimeColumns(""), whereClause, argCounter, argCounter+1)
 
 	args = append(args, limit, offset)
 
@@ -162,17 +249,7 @@ func (r *UserAnimeRepository) List(ctx context.Context, filter models.UserAnimeF
 	var userAnimes []*models.UserAnime
 	for rows.Next() {
 		userAnime := &models.UserAnime{}
-		err := rows.Scan(
-			&userAnime.ID,
-			&userAnime.UserID,
-			&userAnime.AnimeMALID,
-			&userAnime.Status,
-			&userAnime.Rating,
-			&userAnime.Notes,
-			&userAnime.EpisodesWatched,
-			&userAnime.CreatedAt,
-			&userAnime.UpdatedAt,
-		)
+		err := rows.Scan(userAnimeScanDest(userAnime)...)
 		if err != nil {
 			r.logger.Error("Error scanning user anime row", map[string]interface{}{
 				"error": err.Error(),
@@ -192,10 +269,11 @@ func (r *UserAnimeRepository) List(ctx context.Context, filter models.UserAnimeF
 func (r *UserAnimeRepository) Create(ctx context.Context, userAnime *models.UserAnime) error {
 	query := `
 		INSERT INTO user_animes (
-			user_id, anime_mal_id, status, rating, notes, episodes_watched, created_at, updated_at
+			user_id, anime_mal_id, status, rating, notes, episodes_watched, rewatch_count,
+			rewatch_episodes, started_at, finished_at, created_at, updated_at, tags
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8
-		) RETURNING id
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+		) RETURNING id, version
 	`
 
 	now := time.Now()
@@ -209,9 +287,14 @@ func (r *UserAnimeRepository) Create(ctx context.Context, userAnime *models.User
 		userAnime.Rating,
 		userAnime.Notes,
 		userAnime.EpisodesWatched,
+		userAnime.RewatchCount,
+		userAnime.RewatchEpisodes,
+		userAnime.StartedAt,
+		userAnime.FinishedAt,
 		userAnime.CreatedAt,
 		userAnime.UpdatedAt,
-	).Scan(&userAnime.ID)
+		tagsValue(userAnime.Tags),
+	).Scan(&userAnime.ID, &userAnime.Version)
 
 	if err != nil {
 		r.logger.Error("Error creating user anime", map[string]interface{}{
@@ -225,27 +308,52 @@ func (r *UserAnimeRepository) Create(ctx context.Context, userAnime *models.User
 	return nil
 }
 
+// Update сохраняет запись, если ее версия в базе совпадает с userAnime.Version,
+// и увеличивает версию. Если запись успели изменить, возвращается
+// models.ErrUserAnimeVersionMismatch.
 func (r *UserAnimeRepository) Update(ctx context.Context, userAnime *models.UserAnime) error {
+	return r.update(ctx, r.db, userAnime)
+}
+
+func (r *UserAnimeRepository) update(ctx context.Context, q querier, userAnime *models.UserAnime) error {
 	query := `
 		UPDATE user_animes SET
 			status = $1, 
 			rating = $2, 
 			notes = $3, 
 			episodes_watched = $4,
-			updated_at = $5
-		WHERE id = $6
+			rewatch_count = $5,
+			rewatch_episodes = $6,
+			started_at = $7,
+			finished_at = $8,
+			updated_at = $9,
+			tags = $10,
+			version = version + 1
+		WHERE id = $11 AND version = $12
+		RETURNING version
 	`
 
-	userAnime.UpdatedAt = time.Now()
+	updatedAt := time.Now()
 
-	result, err := r.db.ExecContext(ctx, query,
+	var version int64
+	err := q.QueryRowContext(ctx, query,
 		userAnime.Status,
 		userAnime.Rating,
 		userAnime.Notes,
 		userAnime.EpisodesWatched,
-		userAnime.UpdatedAt,
+		userAnime.RewatchCount,
+		userAnime.RewatchEpisodes,
+		userAnime.StartedAt,
+		userAnime.FinishedAt,
+		updatedAt,
+		tagsValue(userAnime.Tags),
 		userAnime.ID,
-	)
+		userAnime.Version,
+	).Scan(&version)
+
+	if err == sql.ErrNoRows {
+		return r.missingOrStale(ctx, q, `id = $1`, userAnime.ID)
+	}
 
 	if err != nil {
 		r.logger.Error("Error updating user anime", map[string]interface{}{
@@ -255,16 +363,32 @@ func (r *UserAnimeRepository) Update(ctx context.Context, userAnime *models.User
 		return errors.Wrap(err, "error updating user anime")
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err, "error getting rows affected")
-	}
+	userAnime.UpdatedAt = updatedAt
+	userAnime.Version = version
+	return nil
+}
 
-	if rowsAffected == 0 {
-		return errors.New("user anime not found")
+// entryNotFound — ошибка для записи, которой нет: для If-Match: * (AnyVersion)
+// это несработавшее условие, а не отсутствие ресурса.
+func entryNotFound(expectedVersion int64) error {
+	if expectedVersion == models.AnyVersion {
+		return models.ErrUserAnimeVersionMismatch
 	}
+	return models.ErrUserAnimeNotFound
+}
 
-	return nil
+// missingOrStale объясняет, почему запрос с проверкой версии не затронул ни одной
+// строки: записи по условию where нет или ее версия уже другая.
+func (r *UserAnimeRepository) missingOrStale(ctx context.Context, q querier, where string, args ...interface{}) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM user_animes WHERE ` + where + `)`
+	if err := q.QueryRowContext(ctx, query, args...).Scan(&exists); err != nil {
+		return errors.Wrap(err, "error checking user anime existence")
+	}
+	if !exists {
+		return models.ErrUserAnimeNotFound
+	}
+	return models.ErrUserAnimeVersionMismatch
 }
 
 func (r *UserAnimeRepository) Delete(ctx context.Context, id uint) error {
@@ -285,16 +409,22 @@ func (r *UserAnimeRepository) Delete(ctx context.Context, id uint) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("user anime not found")
+		return models.ErrUserAnimeNotFound
 	}
 
 	return nil
 }
 
-func (r *UserAnimeRepository) DeleteByUserAndAnimeMALID(ctx context.Context, userID uint, animeMALID int64) error {
-	query := `DELETE FROM user_animes WHERE user_id = $1 AND anime_mal_id = $2`
+// DeleteByUserAndAnimeMALID удаляет запись из списка; при expectedVersion > 0
+// запись удаляется, только если ее версия совпадает.
+func (r *UserAnimeRepository) DeleteByUserAndAnimeMALID(ctx context.Context, userID uint, animeMALID int64, expectedVersion int64) error {
+	return r.deleteByUserAndAnimeMALID(ctx, r.db, userID, animeMALID, expectedVersion)
+}
+
+func (r *UserAnimeRepository) deleteByUserAndAnimeMALID(ctx context.Context, q querier, userID uint, animeMALID int64, expectedVersion int64) error {
+	query := `DELETE FROM user_animes WHERE user_id = $1 AND anime_mal_id = $2 AND ($3::bigint <= 0 OR version = $3)`
 
-	result, err := r.db.ExecContext(ctx, query, userID, animeMALID)
+	result, err := q.ExecContext(ctx, query, userID, animeMALID, expectedVersion)
 	if err != nil {
 		r.logger.Error("Error deleting user anime by user ID and anime MAL ID", map[string]interface{}{
 			"user_id":      userID,
@@ -310,59 +440,34 @@ func (r *UserAnimeRepository) DeleteByUserAndAnimeMALID(ctx context.Context, use
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("user anime not found")
+		if expectedVersion <= 0 {
+			return entryNotFound(expectedVersion)
+		}
+		return r.missingOrStale(ctx, q, `user_id = $1 AND anime_mal_id = $2`, userID, animeMALID)
 	}
 
 	return nil
 }
 
-func (r *UserAnimeRepository) ChangeStatus(ctx context.Context, userID uint, animeMALID int64, status models.WatchStatus) error {
-	userAnime, err := r.GetByUserAndAnimeMALID(ctx, userID, animeMALID)
-	if err != nil && !strings.Contains(err.Error(), "not found") {
-		return err
-	}
-
-	now := time.Now()
-
-	if userAnime != nil {
-		userAnime.Status = status
-		userAnime.UpdatedAt = now
-		return r.Update(ctx, userAnime)
-	}
-
-	newUserAnime := &models.UserAnime{
-		UserID:     userID,
-		AnimeMALID: animeMALID,
-		Status:     status,
-		CreatedAt:  now,
-		UpdatedAt:  now,
-	}
-
-	return r.Create(ctx, newUserAnime)
+// ChangeStatus переводит запись в статус status. Без проверки версии
+// (expectedVersion == 0) запись создается, если аниме еще нет в списке; с
+// AnyVersion запись должна существовать.
+func (r *UserAnimeRepository) ChangeStatus(ctx context.Context, userID uint, animeMALID int64, status models.WatchStatus, expectedVersion int64) (*models.UserAnime, error) {
+	return r.upsertStatus(ctx, r.db, userID, animeMALID, status, expectedVersion)
 }
 
-func (r *UserAnimeRepository) UpdateEpisodesWatched(ctx context.Context, userID uint, animeMALID int64, episodesWatched int) error {
-	userAnime, err := r.GetByUserAndAnimeMALID(ctx, userID, animeMALID)
-	if err != nil {
-		return err
-	}
-
-	userAnime.EpisodesWatched = episodesWatched
-	userAnime.UpdatedAt = time.Now()
-
-	return r.Update(ctx, userAnime)
+// UpdateTracking сохраняет вручную заданные даты просмотра и счетчики пересмотров.
+func (r *UserAnimeRepository) UpdateTracking(ctx context.Context, userID uint, animeMALID int64, tracking models.UserAnimeTracking, anime *models.Anime, expectedVersion int64) (*models.UserAnime, error) {
+	return r.modify(ctx, userID, animeMALID, expectedVersion, func(q querier, userAnime *models.UserAnime) error {
+		return userAnime.SetTracking(tracking, anime)
+	})
 }
 
-func (r *UserAnimeRepository) UpdateRating(ctx context.Context, userID uint, animeMALID int64, rating float32) error {
-	userAnime, err := r.GetByUserAndAnimeMALID(ctx, userID, animeMALID)
-	if err != nil {
-		return err
-	}
-
-	userAnime.Rating = rating
-	userAnime.UpdatedAt = time.Now()
-
-	return r.Update(ctx, userAnime)
+func (r *UserAnimeRepository) UpdateRating(ctx context.Context, userID uint, animeMALID int64, rating float32, expectedVersion int64) (*models.UserAnime, error) {
+	return r.modify(ctx, userID, animeMALID, expectedVersion, func(q querier, userAnime *models.UserAnime) error {
+		userAnime.Rating = rating
+		return nil
+	})
 }
 
 func (r *UserAnimeRepository) GetUserStats(ctx context.Context, userID uint) (*models.AnimeStats, error) {
@@ -372,6 +477,10 @@ func (r *UserAnimeRepository) GetUserStats(ctx context.Context, userID uint) (*m
 			COUNT(CASE WHEN status = 'plan_to_watch' THEN 1 END) as total_plan_to_watch,
 			COUNT(CASE WHEN status = 'watching' THEN 1 END) as total_watching,
 			COUNT(CASE WHEN status = 'waiting' THEN 1 END) as total_waiting,
+			COUNT(CASE WHEN status = 'dropped' THEN 1 END) as total_dropped,
+			COUNT(CASE WHEN status = 'on_hold' THEN 1 END) as total_on_hold,
+			COUNT(CASE WHEN status = 'rewatching' THEN 1 END) as total_rewatching,
+			COALESCE(SUM(rewatch_count), 0) as total_rewatches,
 			SUM(episodes_watched) as total_episodes,
 			AVG(CASE WHEN rating > 0 THEN rating ELSE NULL END) as average_rating
 		FROM user_animes
@@ -379,7 +488,7 @@ func (r *UserAnimeRepository) GetUserStats(ctx context.Context, userID uint) (*m
 	`
 
 	stats := &models.AnimeStats{}
-	
+
 	var avgRating sql.NullFloat64
 	var totalEpisodes sql.NullInt64
 
@@ -388,6 +497,10 @@ func (r *UserAnimeRepository) GetUserStats(ctx context.Context, userID uint) (*m
 		&stats.TotalPlanToWatch,
 		&stats.TotalWatching,
 		&stats.TotalWaiting,
+		&stats.TotalDropped,
+		&stats.TotalOnHold,
+		&stats.TotalRewatching,
+		&stats.TotalRewatches,
 		&totalEpisodes,
 		&avgRating,
 	)
@@ -415,67 +528,342 @@ func (r *UserAnimeRepository) GetUserStats(ctx context.Context, userID uint) (*m
 	return stats, nil
 }
 
-func (r *UserAnimeRepository) GetUserAnimeWithDetails(ctx context.Context, filter models.UserAnimeFilter, jikanClient *api.JikanClient) (*models.UserAnimeList, error) {
-	userAnimes, total, err := r.List(ctx, filter)
+// GetUserAnimeWithDetails возвращает страницу списка пользователя, дополненную данными
+// из локального каталога аниме. Для записей, которых еще нет в каталоге, поля аниме остаются пустыми.
+func (r *UserAnimeRepository) GetUserAnimeWithDetails(ctx context.Context, filter models.UserAnimeFilter) (*models.UserAnimeList, error) {
+	whereClause, args, argCounter := buildFilterConditions(filter, "ua.")
+
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*) FROM user_animes ua WHERE %s
+	`, whereClause)
+
+	var total int
+	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
-		return nil, err
+		r.logger.Error("Error counting user animes", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, errors.Wrap(err, "error counting user animes")
+	}
+
+	limit := 10
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	offset := 0
+	if filter.Page > 1 {
+		offset = (filter.Page - 1) * limit
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM user_animes ua
+		LEFT JOIN animes a ON a.mal_id = ua.anime_mal_id
+		WHERE %s
+		ORDER BY ua.updated_at DESC
+		LIMIT $%d OFFSET $%d
+	`, selectUserAnimeColumns("ua."), animeDetailsColumns, whereClause, argCounter, argCounter+1)
+
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Error listing user animes with details", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, errors.Wrap(err, "error listing user animes with details")
 	}
+	defer rows.Close()
 
 	response := &models.UserAnimeList{
 		TotalCount: total,
 		Page:       filter.Page,
 		Limit:      filter.Limit,
-		Items:      make([]*models.UserAnimeWithDetails, 0, len(userAnimes)),
+		Items:      make([]*models.UserAnimeWithDetails, 0, limit),
 	}
 
-	for _, userAnime := range userAnimes {
-		animeDetails, err := jikanClient.GetAnimeByID(ctx, userAnime.AnimeMALID)
-		if err != nil {
-			r.logger.Warn("Failed to get anime details from Jikan API", map[string]interface{}{
-				"anime_mal_id": userAnime.AnimeMALID,
-				"error":        err.Error(),
+	for rows.Next() {
+		item := &models.UserAnimeWithDetails{}
+		if err := rows.Scan(userAnimeWithDetailsScanDest(item)...); err != nil {
+			r.logger.Error("Error scanning user anime row", map[string]interface{}{
+				"error": err.Error(),
 			})
-			
-			detailedAnime := &models.UserAnimeWithDetails{
-				UserAnime:   *userAnime,
-				AnimeTitle:  "Unknown",
-				AnimeImage:  "",
-				AnimeType:   "",
-				AnimeStatus: "",
-			}
-			
-			response.Items = append(response.Items, detailedAnime)
-			continue
-		}
-
-		detailedAnime := &models.UserAnimeWithDetails{
-			UserAnime:      *userAnime,
-			AnimeTitle:     animeDetails.Title,
-			AnimeImage:     animeDetails.ImageURL,
-			AnimeType:      animeDetails.Type,
-			AnimeEpisodes:  animeDetails.Episodes,
-			AnimeStatus:    animeDetails.Status,
-			AnimeScore:     animeDetails.Score,
+			return nil, errors.Wrap(err, "error scanning user anime row")
 		}
+		response.Items = append(response.Items, item)
+	}
 
-		response.Items = append(response.Items, detailedAnime)
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error iterating user anime rows")
 	}
 
 	return response, nil
 }
 
+// GetUserAnimeWithDetailsByAnimeMALID возвращает одну запись списка пользователя
+// с данными из локального каталога аниме.
+func (r *UserAnimeRepository) GetUserAnimeWithDetailsByAnimeMALID(ctx context.Context, userID uint, animeMALID int64) (*models.UserAnimeWithDetails, error) {
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM user_animes ua
+		LEFT JOIN animes a ON a.mal_id = ua.anime_mal_id
+		WHERE ua.user_id = $1 AND ua.anime_mal_id = $2
+	`, selectUserAnimeColumns("ua."), animeDetailsColumns)
+
+	item := &models.UserAnimeWithDetails{}
+	err := r.db.QueryRowContext(ctx, query, userID, animeMALID).Scan(userAnimeWithDetailsScanDest(item)...)
+
+	if err == sql.ErrNoRows {
+		return nil, models.ErrUserAnimeNotFound
+	}
+
+	if err != nil {
+		r.logger.Error("Error getting user anime with details", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, errors.Wrap(err, "error getting user anime with details")
+	}
+
+	return item, nil
+}
+
+// ForEachUserAnimeWithDetails обходит весь список пользователя построчно, не загружая его
+// в память целиком, и вызывает fn для каждой записи в порядке MAL ID.
+func (r *UserAnimeRepository) ForEachUserAnimeWithDetails(ctx context.Context, userID uint, fn func(item *models.UserAnimeWithDetails) error) error {
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM user_animes ua
+		LEFT JOIN animes a ON a.mal_id = ua.anime_mal_id
+		WHERE ua.user_id = $1
+		ORDER BY ua.anime_mal_id
+	`, selectUserAnimeColumns("ua."), animeDetailsColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		r.logger.Error("Error iterating user animes with details", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return errors.Wrap(err, "error iterating user animes with details")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &models.UserAnimeWithDetails{}
+		if err := rows.Scan(userAnimeWithDetailsScanDest(item)...); err != nil {
+			return errors.Wrap(err, "error scanning user anime row")
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "error iterating user anime rows")
+}
+
+// CreateOrUpdateUserAnime добавляет аниме в список со статусом userAnime.Status или
+// переводит существующую запись в этот статус и записывает итоговую строку в userAnime.
 func (r *UserAnimeRepository) CreateOrUpdateUserAnime(ctx context.Context, userAnime *models.UserAnime) error {
-	existing, err := r.GetByUserAndAnimeMALID(ctx, userAnime.UserID, userAnime.AnimeMALID)
-	if err != nil && !strings.Contains(err.Error(), "not found") {
+	result, err := r.upsertStatus(ctx, r.db, userAnime.UserID, userAnime.AnimeMALID, userAnime.Status, 0)
+	if err != nil {
+		return err
+	}
+	*userAnime = *result
+	return nil
+}
+
+// upsertStatus создает запись со статусом status или меняет статус существующей
+// одним запросом: INSERT ... ON CONFLICT DO UPDATE ... WHERE или, если запись
+// создавать нельзя, UPDATE ... WHERE. Проверка версии и перехода статуса входит в
+// WHERE, поэтому параллельный запрос не может изменить строку между проверкой и
+// записью. Запись не создается при expectedVersion != 0 и для статусов,
+// недопустимых для новой записи (например, rewatching); при expectedVersion > 0
+// она меняется только при совпадении версии.
+func (r *UserAnimeRepository) upsertStatus(ctx context.Context, q querier, userID uint, animeMALID int64, status models.WatchStatus, expectedVersion int64) (*models.UserAnime, error) {
+	if !status.IsValid() {
+		return nil, errors.WithDetails(models.ErrInvalidWatchStatus, "status", status)
+	}
+
+	now := time.Now()
+	candidate := &models.UserAnime{
+		UserID:     userID,
+		AnimeMALID: animeMALID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	initErr := candidate.InitStatus(status)
+
+	var row *sql.Row
+	if initErr == nil && expectedVersion == 0 {
+		row = q.QueryRowContext(ctx, fmt.Sprintf(`
+			INSERT INTO user_animes (
+				user_id, anime_mal_id, status, rating, notes, episodes_watched, rewatch_count,
+				rewatch_episodes, started_at, finished_at, created_at, updated_at, tags
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			)
+			ON CONFLICT (user_id, anime_mal_id) DO UPDATE SET %s
+			WHERE %s
+			RETURNING %s
+		`, statusChangeSet("EXCLUDED.status", "EXCLUDED.updated_at"), statusChangeAllowed("EXCLUDED.status"), selectUserAnimeColumns("")),
+			candidate.UserID,
+			candidate.AnimeMALID,
+			candidate.Status,
+			candidate.Rating,
+			candidate.Notes,
+			candidate.EpisodesWatched,
+			candidate.RewatchCount,
+			candidate.RewatchEpisodes,
+			candidate.StartedAt,
+			candidate.FinishedAt,
+			candidate.CreatedAt,
+			candidate.UpdatedAt,
+			tagsValue(candidate.Tags),
+		)
+	} else {
+		row = q.QueryRowContext(ctx, fmt.Sprintf(`
+			UPDATE user_animes SET %s
+			WHERE user_id = $1 AND anime_mal_id = $2 AND ($4::bigint <= 0 OR version = $4) AND (%s)
+			RETURNING %s
+		`, statusChangeSet("$3::text", "$5::timestamptz"), statusChangeAllowed("$3::text"), selectUserAnimeColumns("")),
+			userID, animeMALID, status, expectedVersion, now,
+		)
+	}
+
+	userAnime := &models.UserAnime{}
+	err := row.Scan(userAnimeScanDest(userAnime)...)
+	if err == sql.ErrNoRows {
+		return nil, r.statusChangeRejected(ctx, q, userID, animeMALID, status, expectedVersion, initErr)
+	}
+	if err != nil {
+		r.logger.Error("Error upserting user anime status", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		return nil, errors.Wrap(err, "error upserting user anime status")
+	}
+
+	return userAnime, nil
+}
+
+// statusChangeRejected объясняет, почему upsertStatus не изменил ни одной строки:
+// записи нет, ее версия другая или переход статуса запрещен.
+func (r *UserAnimeRepository) statusChangeRejected(ctx context.Context, q querier, userID uint, animeMALID int64, status models.WatchStatus, expectedVersion int64, initErr error) error {
+	existing, err := r.getByUserAndAnimeMALID(ctx, q, userID, animeMALID, false)
+	if errors.Is(err, models.ErrUserAnimeNotFound) {
+		if expectedVersion == 0 {
+			return initErr
+		}
+		return entryNotFound(expectedVersion)
+	}
+	if err != nil {
+		return err
+	}
+	if err := existing.CheckVersion(expectedVersion); err != nil {
+		return err
+	}
+	if err := existing.ChangeStatus(status); err != nil {
 		return err
 	}
+	// Запись успели изменить между запросами.
+	return models.ErrUserAnimeVersionMismatch
+}
+
+// statusChangeSet — SET для перевода строки user_animes в статус status. Повторяет
+// UserAnime.setStatus: первый переход в watching фиксирует начало просмотра,
+// переход в watched — окончание, начало пересмотра увеличивает счетчик
+// пересмотров и обнуляет его прогресс.
+func statusChangeSet(status, now string) string {
+	changed := fmt.Sprintf("user_animes.status <> %s", status)
+	return fmt.Sprintf(`
+		status = %[1]s,
+		started_at = CASE WHEN %[1]s = '%[3]s' AND %[2]s THEN COALESCE(user_animes.started_at, %[4]s) ELSE user_animes.started_at END,
+		finished_at = CASE WHEN %[1]s = '%[5]s' AND %[2]s THEN %[4]s ELSE user_animes.finished_at END,
+		rewatch_count = CASE WHEN %[1]s = '%[6]s' AND %[2]s THEN user_animes.rewatch_count + 1 ELSE user_animes.rewatch_count END,
+		rewatch_episodes = CASE WHEN %[1]s = '%[6]s' AND %[2]s THEN 0 ELSE user_animes.rewatch_episodes END,
+		updated_at = %[4]s,
+		version = user_animes.version + 1`,
+		status, changed, models.StatusWatching, now, models.StatusWatched, models.StatusRewatching)
+}
+
+// statusTransitionValues — таблица переходов models.StatusTransitionPairs в виде VALUES.
+var statusTransitionValues = func() string {
+	pairs := models.StatusTransitionPairs()
+	values := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		values = append(values, fmt.Sprintf("('%s', '%s')", pair[0], pair[1]))
+	}
+	return "VALUES " + strings.Join(values, ", ")
+}()
+
+// statusChangeAllowed — условие, что строку можно перевести в статус status.
+func statusChangeAllowed(status string) string {
+	return fmt.Sprintf("user_animes.status = %[1]s OR (user_animes.status, %[1]s) IN (%[2]s)", status, statusTransitionValues)
+}
+
+// modify блокирует запись пользователя, проверяет ее версию, вызывает change и
+// сохраняет результат в одной транзакции.
+func (r *UserAnimeRepository) modify(
+	ctx context.Context,
+	userID uint,
+	animeMALID int64,
+	expectedVersion int64,
+	change func(q querier, userAnime *models.UserAnime) error,
+) (*models.UserAnime, error) {
+	var userAnime *models.UserAnime
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		userAnime, err = r.modifyIn(ctx, tx, userID, animeMALID, expectedVersion, change)
+		return err
+	})
+	return userAnime, err
+}
+
+// modifyIn — modify в уже открытой транзакции q.
+func (r *UserAnimeRepository) modifyIn(
+	ctx context.Context,
+	q querier,
+	userID uint,
+	animeMALID int64,
+	expectedVersion int64,
+	change func(q querier, userAnime *models.UserAnime) error,
+) (*models.UserAnime, error) {
+	userAnime, err := r.getByUserAndAnimeMALID(ctx, q, userID, animeMALID, true)
+	if errors.Is(err, models.ErrUserAnimeNotFound) {
+		return nil, entryNotFound(expectedVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := userAnime.CheckVersion(expectedVersion); err != nil {
+		return nil, err
+	}
 
-	if existing != nil {
-		userAnime.ID = existing.ID
-		userAnime.CreatedAt = existing.CreatedAt
-		userAnime.UpdatedAt = time.Now()
-		return r.Update(ctx, userAnime)
+	if err := change(q, userAnime); err != nil {
+		return nil, err
+	}
+	if err := r.update(ctx, q, userAnime); err != nil {
+		return nil, err
 	}
 
-	return r.Create(ctx, userAnime)
-}
\ No newline at end of file
+	return userAnime, nil
+}
+
+// inTx выполняет fn в транзакции и фиксирует ее, если fn не вернула ошибку.
+func (r *UserAnimeRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error starting transaction")
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "error committing transaction")
+}
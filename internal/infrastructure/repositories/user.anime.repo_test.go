@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+	"logur.dev/logur"
+)
+
+func TestUpsertStatusCreatesAndTransitionsInOneStatement(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	repo := NewUserAnimeRepository(db, logur.NoopLogger{})
+	userID := dbtest.CreateUser(t, db)
+
+	created, err := repo.upsertStatus(ctx, db, userID, 5114, models.StatusPlanToWatch, 0)
+	if err != nil {
+		t.Fatalf("create entry: %v", err)
+	}
+	if created.Version != 1 || created.Status != models.StatusPlanToWatch || created.StartedAt != nil {
+		t.Fatalf("created entry = (version=%d, status=%s, started_at=%v), want (1, %s, nil)", created.Version, created.Status, created.StartedAt, models.StatusPlanToWatch)
+	}
+
+	watching, err := repo.upsertStatus(ctx, db, userID, 5114, models.StatusWatching, 0)
+	if err != nil {
+		t.Fatalf("upsert existing entry: %v", err)
+	}
+	if watching.ID != created.ID || watching.Version != 2 || watching.Status != models.StatusWatching || watching.StartedAt == nil {
+		t.Errorf("upserted entry = (id=%d, version=%d, status=%s, started_at=%v), want (%d, 2, %s, set)", watching.ID, watching.Version, watching.Status, watching.StartedAt, created.ID, models.StatusWatching)
+	}
+
+	_, err = repo.upsertStatus(ctx, db, userID, 5114, models.StatusRewatching, 0)
+	if !errors.Is(err, models.ErrInvalidStatusTransition) {
+		t.Errorf("forbidden transition: err = %v, want %v", err, models.ErrInvalidStatusTransition)
+	}
+
+	if _, err := repo.upsertStatus(ctx, db, userID, 5114, models.StatusWatched, 0); err != nil {
+		t.Fatalf("transition to watched: %v", err)
+	}
+	rewatching, err := repo.upsertStatus(ctx, db, userID, 5114, models.StatusRewatching, 0)
+	if err != nil {
+		t.Fatalf("transition to rewatching: %v", err)
+	}
+	if rewatching.RewatchCount != 1 || rewatching.FinishedAt == nil || rewatching.Version != 4 {
+		t.Errorf("rewatching entry = (rewatch_count=%d, finished_at=%v, version=%d), want (1, set, 4)", rewatching.RewatchCount, rewatching.FinishedAt, rewatching.Version)
+	}
+
+	_, err = repo.upsertStatus(ctx, db, userID, 1, models.StatusRewatching, 0)
+	if !errors.Is(err, models.ErrInvalidWatchStatus) {
+		t.Errorf("new entry with rewatching: err = %v, want %v", err, models.ErrInvalidWatchStatus)
+	}
+}
+
+// TestChangeStatusConcurrentlyKeepsEveryWrite проверяет, что параллельные смены
+// статуса не теряют записи: каждая успешная увеличивает версию ровно на один.
+func TestChangeStatusConcurrentlyKeepsEveryWrite(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	repo := NewUserAnimeRepository(db, logur.NoopLogger{})
+	userID := dbtest.CreateUser(t, db)
+
+	if _, err := repo.ChangeStatus(ctx, userID, 5114, models.StatusPlanToWatch, 0); err != nil {
+		t.Fatalf("create entry: %v", err)
+	}
+
+	statuses := []models.WatchStatus{models.StatusWatching, models.StatusOnHold, models.StatusDropped, models.StatusPlanToWatch}
+	const workers = 32
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(status models.WatchStatus) {
+			defer wg.Done()
+			_, err := repo.ChangeStatus(ctx, userID, 5114, status, 0)
+			switch {
+			case err == nil:
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			case !errors.Is(err, models.ErrInvalidStatusTransition):
+				t.Errorf("ChangeStatus(%s): %v", status, err)
+			}
+		}(statuses[i%len(statuses)])
+	}
+	wg.Wait()
+
+	entry, err := repo.GetByUserAndAnimeMALID(ctx, userID, 5114)
+	if err != nil {
+		t.Fatalf("GetByUserAndAnimeMALID: %v", err)
+	}
+	if entry.Version != int64(1+succeeded) {
+		t.Errorf("version = %d after %d successful changes, want %d", entry.Version, succeeded, 1+succeeded)
+	}
+}
+
+func TestChangeStatusChecksVersion(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	repo := NewUserAnimeRepository(db, logur.NoopLogger{})
+	userID := dbtest.CreateUser(t, db)
+
+	created, err := repo.ChangeStatus(ctx, userID, 5114, models.StatusPlanToWatch, 0)
+	if err != nil {
+		t.Fatalf("create entry: %v", err)
+	}
+
+	updated, err := repo.ChangeStatus(ctx, userID, 5114, models.StatusWatching, created.Version)
+	if err != nil {
+		t.Fatalf("ChangeStatus with current version: %v", err)
+	}
+	if updated.Version != created.Version+1 {
+		t.Errorf("version = %d, want %d", updated.Version, created.Version+1)
+	}
+
+	_, err = repo.ChangeStatus(ctx, userID, 5114, models.StatusOnHold, created.Version)
+	if !errors.Is(err, models.ErrUserAnimeVersionMismatch) {
+		t.Errorf("ChangeStatus with stale version: err = %v, want %v", err, models.ErrUserAnimeVersionMismatch)
+	}
+
+	_, err = repo.ChangeStatus(ctx, userID, 1, models.StatusWatching, 1)
+	if !errors.Is(err, models.ErrUserAnimeNotFound) {
+		t.Errorf("ChangeStatus with version on a missing entry: err = %v, want %v", err, models.ErrUserAnimeNotFound)
+	}
+}
+
+func TestCreateOrUpdateUserAnimeConcurrently(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	repo := NewUserAnimeRepository(db, logur.NoopLogger{})
+	userID := dbtest.CreateUser(t, db)
+
+	const workers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.CreateOrUpdateUserAnime(ctx, &models.UserAnime{UserID: userID, AnimeMALID: 5114, Status: models.StatusWatching})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("CreateOrUpdateUserAnime: %v", err)
+		}
+	}
+
+	var rows int
+	if err := db.QueryRow(`SELECT count(*) FROM user_animes WHERE user_id = $1 AND anime_mal_id = 5114`, userID).Scan(&rows); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if rows != 1 {
+		t.Errorf("%d rows for one (user_id, anime_mal_id), want 1", rows)
+	}
+}
+
+func TestUpdateRatingWithoutVersionDoesNotConflict(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	repo := NewUserAnimeRepository(db, logur.NoopLogger{})
+	userID := dbtest.CreateUser(t, db)
+
+	created, err := repo.ChangeStatus(ctx, userID, 5114, models.StatusWatching, 0)
+	if err != nil {
+		t.Fatalf("create entry: %v", err)
+	}
+
+	const workers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(rating float32) {
+			defer wg.Done()
+			_, err := repo.UpdateRating(ctx, userID, 5114, rating, 0)
+			errs <- err
+		}(float32(i%10 + 1))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("UpdateRating without version: %v", err)
+		}
+	}
+
+	entry, err := repo.GetByUserAndAnimeMALID(ctx, userID, 5114)
+	if err != nil {
+		t.Fatalf("GetByUserAndAnimeMALID: %v", err)
+	}
+	if entry.Version != created.Version+workers {
+		t.Errorf("version = %d, want %d", entry.Version, created.Version+workers)
+	}
+}
+
+func TestAnyVersionRequiresExistingEntry(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.OpenMigrated(t)
+	repo := NewUserAnimeRepository(db, logur.NoopLogger{})
+	userID := dbtest.CreateUser(t, db)
+
+	_, err := repo.ChangeStatus(ctx, userID, 5114, models.StatusWatching, models.AnyVersion)
+	if !errors.Is(err, models.ErrUserAnimeVersionMismatch) {
+		t.Fatalf("ChangeStatus with AnyVersion on a missing entry: err = %v, want %v", err, models.ErrUserAnimeVersionMismatch)
+	}
+	if _, err := repo.UpdateRating(ctx, userID, 5114, 8, models.AnyVersion); !errors.Is(err, models.ErrUserAnimeVersionMismatch) {
+		t.Errorf("UpdateRating with AnyVersion on a missing entry: err = %v, want %v", err, models.ErrUserAnimeVersionMismatch)
+	}
+	if err := repo.DeleteByUserAndAnimeMALID(ctx, userID, 5114, models.AnyVersion); !errors.Is(err, models.ErrUserAnimeVersionMismatch) {
+		t.Errorf("DeleteByUserAndAnimeMALID with AnyVersion on a missing entry: err = %v, want %v", err, models.ErrUserAnimeVersionMismatch)
+	}
+
+	if _, err := repo.ChangeStatus(ctx, userID, 5114, models.StatusPlanToWatch, 0); err != nil {
+		t.Fatalf("create entry: %v", err)
+	}
+	if _, err := repo.ChangeStatus(ctx, userID, 5114, models.StatusWatching, models.AnyVersion); err != nil {
+		t.Errorf("ChangeStatus with AnyVersion on an existing entry: %v", err)
+	}
+	if err := repo.DeleteByUserAndAnimeMALID(ctx, userID, 5114, models.AnyVersion); err != nil {
+		t.Errorf("DeleteByUserAndAnimeMALID with AnyVersion on an existing entry: %v", err)
+	}
+}
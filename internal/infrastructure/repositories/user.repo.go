@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/merdernoty/anime-service/internal/domain/models"
 	"github.com/merdernoty/anime-service/internal/domain/repositories"
@@ -28,7 +29,7 @@ func (r *UserRepositoryImpl) Create(ctx context.Context, user models.User) (mode
 
 func (r *UserRepositoryImpl) GetByEmail(ctx context.Context, email string) (models.User, error) {
 	var user models.User
-	result := r.db.WithContext(ctx).Where("email = ?", email).First(&user)
+	result := r.db.WithContext(ctx).Where("lower(email) = ?", models.NormalizeEmail(email)).First(&user)
 	if result.Error != nil {
 		return models.User{}, result.Error
 	}
@@ -67,12 +68,73 @@ func (r *UserRepositoryImpl) GetAll(ctx context.Context) ([]models.User, error)
 
 func (r *UserRepositoryImpl) GetByNickName(ctx context.Context, nickName string) (models.User, error) {
 	var user models.User
-	result := r.db.WithContext(ctx).Where("nickname = ?", nickName).First(&user)
+	result := r.db.WithContext(ctx).Where("lower(nickname) = ?", models.NormalizeNickname(nickName)).First(&user)
 	if result.Error != nil {
 		return models.User{}, result.Error
 	}
 	return user, nil
 }
+
+func (r *UserRepositoryImpl) UpdateRole(ctx context.Context, id uint, role models.Role) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepositoryImpl) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password", passwordHash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepositoryImpl) PromoteFirstAdmin(ctx context.Context, id uint) error {
+	admins := r.db.Model(&models.User{}).Select("1").Where("role = ?", models.RoleAdmin)
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND NOT EXISTS (?)", id, admins).
+		Update("role", models.RoleAdmin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrAdminExists
+	}
+	return nil
+}
+
+func (r *UserRepositoryImpl) MarkEmailVerified(ctx context.Context, id uint, email string, verifiedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND email = ?", id, email).
+		Update("email_verified_at", verifiedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepositoryImpl) SetLockedUntil(ctx context.Context, id uint, until *time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("locked_until", until)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
 // func (r *UserRepositoryImpl) GetUserFriends(ctx context.Context, userID uint) ([]models.User, error) {
 // 	var friends []models.User
 // 	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Association("Friends").Find(&friends)
@@ -80,4 +142,4 @@ func (r *UserRepositoryImpl) GetByNickName(ctx context.Context, nickName string)
 // 		return nil, result.Error
 // 	}
 // 	return friends, nil
-// }
\ No newline at end of file
+// }
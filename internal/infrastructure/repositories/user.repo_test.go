@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+)
+
+func TestPromoteFirstAdminRequiresNoAdmin(t *testing.T) {
+	ctx := context.Background()
+	gormDB, db := dbtest.OpenGorm(t)
+	users := NewUserRepository(gormDB)
+	first := dbtest.CreateUser(t, db)
+	second := dbtest.CreateUser(t, db)
+
+	if err := users.PromoteFirstAdmin(ctx, first); err != nil {
+		t.Fatalf("PromoteFirstAdmin without admins: %v", err)
+	}
+	if err := users.PromoteFirstAdmin(ctx, second); err != models.ErrAdminExists {
+		t.Errorf("PromoteFirstAdmin with an admin: err = %v, want %v", err, models.ErrAdminExists)
+	}
+
+	user, err := users.GetByID(ctx, second)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.Role == models.RoleAdmin {
+		t.Error("second user was promoted while an admin exists")
+	}
+}
+
+func TestUpdatePasswordKeepsOtherColumns(t *testing.T) {
+	ctx := context.Background()
+	gormDB, db := dbtest.OpenGorm(t)
+	users := NewUserRepository(gormDB)
+	id := dbtest.CreateUser(t, db)
+
+	// Роль меняется между чтением пользователя и сохранением пароля.
+	if err := users.UpdateRole(ctx, id, models.RoleAdmin); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	if err := users.UpdatePassword(ctx, id, "new-hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+
+	user, err := users.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.Password != "new-hash" {
+		t.Errorf("password = %q, want %q", user.Password, "new-hash")
+	}
+	if user.Role != models.RoleAdmin {
+		t.Errorf("role = %q, want %q", user.Role, models.RoleAdmin)
+	}
+
+	if err := users.UpdatePassword(ctx, id+1000, "new-hash"); err != models.ErrUserNotFound {
+		t.Errorf("UpdatePassword for a missing user: err = %v, want %v", err, models.ErrUserNotFound)
+	}
+}
@@ -3,7 +3,10 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"emperror.dev/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/merdernoty/anime-service/internal/application/services"
 	"github.com/merdernoty/anime-service/internal/domain/dtos"
@@ -23,6 +26,28 @@ func NewAnimeController(animeService services.AnimeServiceImpl, logger logur.Log
 	}
 }
 
+// resolveUserID берет ID пользователя из пути /users/:user_id, а для маршрутов /me —
+// из контекста, заполненного AuthMiddleware.
+func resolveUserID(ctx *gin.Context) (uint, error) {
+	if userIDStr := ctx.Param("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return uint(userID), nil
+	}
+
+	userIDRaw, exists := ctx.Get("userID")
+	if !exists {
+		return 0, services.ErrUnauthorized
+	}
+	userID, ok := userIDRaw.(uint)
+	if !ok {
+		return 0, services.ErrUnauthorized
+	}
+	return userID, nil
+}
+
 func handleAnimeError(ctx *gin.Context, err error) {
 	switch {
 	case err == services.ErrAnimeAlreadyExists:
@@ -55,6 +80,51 @@ func handleAnimeError(ctx *gin.Context, err error) {
 			"error":   "anime not found",
 			"details": err.Error(),
 		})
+	case err == models.ErrInvalidWatchStatus:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid watch status",
+			"details": err.Error(),
+		})
+	case err == models.ErrInvalidEpisodeCount:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid episodes watched count",
+			"details": err.Error(),
+		})
+	case err == models.ErrInvalidTrackingDates:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid tracking dates",
+			"details": err.Error(),
+		})
+	case err == models.ErrInvalidRating:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid rating",
+			"details": err.Error(),
+		})
+	case err == models.ErrInvalidNotes:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid notes",
+			"details": err.Error(),
+		})
+	case err == models.ErrInvalidTags:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid tags",
+			"details": err.Error(),
+		})
+	case err == services.ErrInvalidBulkMode, err == services.ErrTooManyBulkOperations:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid bulk request",
+			"details": err.Error(),
+		})
+	case err == models.ErrInvalidStatusTransition:
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "invalid watch status transition",
+			"details": err.Error(),
+		})
+	case err == services.ErrCatalogUnavailable:
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "anime catalog unavailable",
+			"details": err.Error(),
+		})
 	case err == services.ErrFetchAnimeFailed:
 		ctx.JSON(http.StatusNotFound, gin.H{
 			"error":   "fetch anime failed",
@@ -68,10 +138,123 @@ func handleAnimeError(ctx *gin.Context, err error) {
 	}
 }
 
+// entryETag — значение заголовка ETag для версии записи списка.
+func entryETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// errInvalidIfMatch — заголовок If-Match не разобран.
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// parseIfMatch разбирает заголовок If-Match: * или список ETag записи.
+// If-Match требует строгого сравнения (RFC 9110, 13.1.1), поэтому слабые теги
+// W/"3" ни с чем не совпадают и в versions не попадают.
+func parseIfMatch(header string) (versions []int64, wildcard bool, err error) {
+	if strings.TrimSpace(header) == "*" {
+		return nil, true, nil
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		weak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return nil, false, errInvalidIfMatch
+		}
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, false, errInvalidIfMatch
+		}
+		if !weak {
+			versions = append(versions, version)
+		}
+	}
+	return versions, false, nil
+}
 
+// entryVersion возвращает версию записи, которую клиент ожидает изменить: из
+// заголовка If-Match ("3"; * — models.AnyVersion: запись должна существовать, но
+// ее версия не проверяется) или, если заголовка нет, из
+// поля version запроса. Если в If-Match несколько тегов, ожидаемой считается
+// текущая версия записи, когда она есть среди них; если ни один строгий тег не
+// совпадает, отвечает 412 с актуальным состоянием записи. Если условие
+// обязательно, но не передано, отвечает 428.
+func (c *AnimeController) entryVersion(ctx *gin.Context, userID uint, animeMALID int64, requestVersion int64, required bool) (int64, bool) {
+	ifMatch := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if ifMatch == "" {
+		if required && requestVersion == 0 {
+			ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": "Передайте версию записи в заголовке If-Match или в поле version"})
+			return 0, false
+		}
+		return requestVersion, true
+	}
 
+	versions, wildcard, err := parseIfMatch(ifMatch)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный заголовок If-Match"})
+		return 0, false
+	}
+	if wildcard {
+		return models.AnyVersion, true
+	}
+	if len(versions) == 1 {
+		return versions[0], true
+	}
+	if len(versions) == 0 {
+		c.handleEntryError(ctx, userID, animeMALID, models.ErrUserAnimeVersionMismatch)
+		return 0, false
+	}
+
+	// Репозиторий сверяет одну версию, поэтому из списка берется текущая. Если
+	// запись изменится до записи, репозиторий все равно вернет несовпадение.
+	entry, err := c.animeService.GetUserAnimeEntry(ctx, userID, animeMALID)
+	if errors.Is(err, services.ErrAnimeNotInUserList) {
+		c.handleEntryError(ctx, userID, animeMALID, models.ErrUserAnimeVersionMismatch)
+		return 0, false
+	}
+	if err != nil {
+		handleAnimeError(ctx, err)
+		return 0, false
+	}
+	for _, version := range versions {
+		if version == entry.Version {
+			return version, true
+		}
+	}
+	c.handleEntryError(ctx, userID, animeMALID, models.ErrUserAnimeVersionMismatch)
+	return 0, false
+}
+
+// handleEntryError отвечает 412 с актуальным состоянием записи, если ее версия не
+// совпала с ожидаемой (или 412 без него, если записи нет), остальные ошибки
+// передает handleAnimeError.
+func (c *AnimeController) handleEntryError(ctx *gin.Context, userID uint, animeMALID int64, err error) {
+	if !errors.Is(err, models.ErrUserAnimeVersionMismatch) {
+		handleAnimeError(ctx, err)
+		return
+	}
+
+	entry, getErr := c.animeService.GetUserAnimeEntry(ctx, userID, animeMALID)
+	if errors.Is(getErr, services.ErrAnimeNotInUserList) {
+		// If-Match не выполняется и для записи, которой нет.
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": "precondition failed", "details": err.Error()})
+		return
+	}
+	if getErr != nil {
+		handleAnimeError(ctx, getErr)
+		return
+	}
+
+	ctx.Header("ETag", entryETag(entry.Version))
+	ctx.JSON(http.StatusPreconditionFailed, dtos.UserAnimeConflictResponse{
+		Error:   "precondition failed",
+		Details: err.Error(),
+		Current: dtos.ToUserAnimeResponse(entry),
+	})
+}
 
 // GetAnimeByID godoc
+//
 //	@Summary		Получить информацию об аниме по его ID
 //	@Description	Получает детальную информацию об аниме по его MAL ID
 //	@Tags			anime
@@ -96,7 +279,7 @@ func (c *AnimeController) GetAnimeByID(ctx *gin.Context) {
 			"mal_id": malID,
 			"error":  err.Error(),
 		})
-		
+
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить информацию об аниме"})
 		return
 	}
@@ -137,36 +320,59 @@ func (c *AnimeController) GetAnimeByID(ctx *gin.Context) {
 }
 
 // SearchAnime godoc
+//
 //	@Summary		Поиск аниме по запросу
-//	@Description	Выполняет поиск аниме по заданному запросу с пагинацией
+//	@Description	Выполняет поиск аниме по заданному запросу и фильтрам с пагинацией
 //	@Tags			anime
 //	@Accept			json
 //	@Produce		json
-//	@Param			query	query		string	true	"Поисковый запрос"
-//	@Param			page	query		int		false	"Номер страницы"						default(1)	minimum(1)
-//	@Param			limit	query		int		false	"Количество результатов на странице"	default(10)	minimum(1)	maximum(50)
-//	@Success		200		{object}	dtos.AnimeListResponse
-//	@Failure		500		{object}	map[string]string	"Внутренняя ошибка сервера"
+//	@Param			query			query		string	false	"Поисковый запрос"
+//	@Param			type			query		string	false	"Тип аниме"							Enums(tv, movie, ova, special, ona, music, cm, pv, tv_special)
+//	@Param			status			query		string	false	"Статус выхода"						Enums(airing, complete, upcoming)
+//	@Param			rating			query		string	false	"Возрастной рейтинг"				Enums(g, pg, pg13, r17, r, rx)
+//	@Param			genres			query		string	false	"ID жанров через запятую"
+//	@Param			genres_exclude	query		string	false	"ID исключаемых жанров через запятую"
+//	@Param			min_score		query		number	false	"Минимальная оценка"				minimum(0)	maximum(10)
+//	@Param			max_score		query		number	false	"Максимальная оценка"				minimum(0)	maximum(10)
+//	@Param			start_date		query		string	false	"Дата начала выхода (YYYY-MM-DD)"
+//	@Param			end_date		query		string	false	"Дата окончания выхода (YYYY-MM-DD)"
+//	@Param			order_by		query		string	false	"Поле сортировки"					Enums(mal_id, title, start_date, end_date, episodes, score, scored_by, rank, popularity, members, favorites)
+//	@Param			sort			query		string	false	"Направление сортировки"			Enums(asc, desc)
+//	@Param			sfw				query		bool	false	"Исключить контент для взрослых"
+//	@Param			page			query		int		false	"Номер страницы"					default(1)	minimum(1)
+//	@Param			limit			query		int		false	"Количество результатов на странице"	default(10)	minimum(1)	maximum(50)
+//	@Success		200				{object}	dtos.AnimeListResponse
+//	@Failure		400				{object}	map[string]string	"Неверные параметры поиска"
+//	@Failure		500				{object}	map[string]string	"Внутренняя ошибка сервера"
 //	@Router			/anime/search [get]
 func (c *AnimeController) SearchAnime(ctx *gin.Context) {
-	query := ctx.Query("query")
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	var request dtos.SearchAnimeRequest
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверные параметры поиска", "details": err.Error()})
+		return
+	}
 
 	// Проверяем валидность параметров
-	if page < 1 {
-		page = 1
+	if request.Page < 1 {
+		request.Page = 1
 	}
-	if limit < 1 {
-		limit = 10
-	} else if limit > 50 {
-		limit = 50
+	if request.Limit < 1 {
+		request.Limit = 10
+	} else if request.Limit > 50 {
+		request.Limit = 50
+	}
+
+	search, err := request.ToAnimeSearch()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверные параметры поиска", "details": err.Error()})
+		return
 	}
+	page, limit := search.Page, search.Limit
 
-	animes, totalPages, err := c.animeService.SearchAnime(ctx, query, page, limit)
+	animes, totalPages, err := c.animeService.SearchAnime(ctx, search)
 	if err != nil {
 		c.logger.Error("Error searching anime", map[string]interface{}{
-			"query": query,
+			"query": search.Query,
 			"error": err.Error(),
 		})
 		handleAnimeError(ctx, err)
@@ -212,6 +418,7 @@ func (c *AnimeController) SearchAnime(ctx *gin.Context) {
 }
 
 // GetTopAnime godoc
+//
 //	@Summary		Получить список популярных аниме
 //	@Description	Возвращает список популярных аниме с пагинацией
 //	@Tags			anime
@@ -284,6 +491,7 @@ func (c *AnimeController) GetTopAnime(ctx *gin.Context) {
 }
 
 // GetSeasonalAnime godoc
+//
 //	@Summary		Получить список сезонных аниме
 //	@Description	Возвращает список аниме для указанного сезона и года
 //	@Tags			anime
@@ -375,6 +583,7 @@ func (c *AnimeController) GetSeasonalAnime(ctx *gin.Context) {
 }
 
 // GetAnimeRecommendations godoc
+//
 //	@Summary		Получить рекомендации аниме
 //	@Description	Возвращает список рекомендаций аниме на основе указанного MAL ID
 //	@Tags			anime
@@ -436,32 +645,50 @@ func (c *AnimeController) GetAnimeRecommendations(ctx *gin.Context) {
 }
 
 // GetUserAnimeList godoc
+//
 //	@Summary		Получить список аниме пользователя
-//	@Description	Возвращает список аниме пользователя с возможностью фильтрации по статусу
+//	@Description	Возвращает список аниме пользователя с возможностью фильтрации по статусу и годам начала и окончания просмотра
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			user_id	path		int		true	"ID пользователя"
-//	@Param			status	query		string	false	"Статус аниме (watched, plan_to_watch, watching, waiting)"
+//	@Param			status	query		string	false	"Статус аниме"	Enums(watched, plan_to_watch, watching, waiting, dropped, on_hold, rewatching)
 //	@Param			page	query		int		false	"Номер страницы"						default(1)	minimum(1)
 //	@Param			limit	query		int		false	"Количество результатов на странице"	default(10)	minimum(1)	maximum(50)
+//	@Param			started_year	query	int	false	"Год начала просмотра"
+//	@Param			finished_year	query	int	false	"Год окончания просмотра"
 //	@Success		200		{object}	dtos.UserAnimeListResponse
-//	@Failure		400		{object}	map[string]string	"Неверный ID пользователя"
+//	@Failure		400		{object}	map[string]string	"Неверный ID пользователя или фильтр"
 //	@Failure		500		{object}	map[string]string	"Внутренняя ошибка сервера"
+//	@Failure		503		{object}	map[string]string	"Данные аниме временно недоступны; повторите запрос позже"
 //	@Router			/users/{user_id}/anime [get]
 func (c *AnimeController) GetUserAnimeList(ctx *gin.Context) {
-	userIDStr := ctx.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	userID, err := resolveUserID(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
 		return
 	}
 
 	status := models.WatchStatus(ctx.DefaultQuery("status", ""))
+	if status != "" && !status.IsValid() {
+		handleAnimeError(ctx, models.ErrInvalidWatchStatus)
+		return
+	}
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
 	query := ctx.DefaultQuery("query", "")
 
+	startedYear, err := strconv.Atoi(ctx.DefaultQuery("started_year", "0"))
+	if err != nil || startedYear < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный год начала просмотра"})
+		return
+	}
+	finishedYear, err := strconv.Atoi(ctx.DefaultQuery("finished_year", "0"))
+	if err != nil || finishedYear < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный год окончания просмотра"})
+		return
+	}
+
 	// Проверяем валидность параметров
 	if page < 1 {
 		page = 1
@@ -473,11 +700,13 @@ func (c *AnimeController) GetUserAnimeList(ctx *gin.Context) {
 	}
 
 	filter := models.UserAnimeFilter{
-		UserID: int64(userID),
-		Status: status,
-		Page:   page,
-		Limit:  limit,
-		Query:  query,
+		UserID:       int64(userID),
+		Status:       status,
+		Page:         page,
+		Limit:        limit,
+		Query:        query,
+		StartedYear:  startedYear,
+		FinishedYear: finishedYear,
 	}
 
 	userAnimeList, err := c.animeService.GetUserAnimeList(ctx, filter)
@@ -486,6 +715,10 @@ func (c *AnimeController) GetUserAnimeList(ctx *gin.Context) {
 			"user_id": userID,
 			"error":   err.Error(),
 		})
+		if err == services.ErrCatalogUnavailable {
+			handleAnimeError(ctx, err)
+			return
+		}
 		handleAuthError(ctx, err)
 		return
 	}
@@ -493,21 +726,7 @@ func (c *AnimeController) GetUserAnimeList(ctx *gin.Context) {
 	// Преобразуем результаты в ответ API
 	items := make([]dtos.UserAnimeResponse, 0, len(userAnimeList.Items))
 	for _, item := range userAnimeList.Items {
-		items = append(items, dtos.UserAnimeResponse{
-			ID:              item.ID,
-			UserID:          item.UserID,
-			AnimeMALID:      item.AnimeMALID,
-			Status:          item.Status,
-			Rating:          item.Rating,
-			Notes:           item.Notes,
-			EpisodesWatched: item.EpisodesWatched,
-			AnimeTitle:      item.AnimeTitle,
-			AnimeImage:      item.AnimeImage,
-			AnimeType:       item.AnimeType,
-			AnimeEpisodes:   item.AnimeEpisodes,
-			AnimeStatus:     item.AnimeStatus,
-			AnimeScore:      item.AnimeScore,
-		})
+		items = append(items, dtos.ToUserAnimeResponse(item))
 	}
 
 	ctx.JSON(http.StatusOK, dtos.UserAnimeListResponse{
@@ -518,7 +737,45 @@ func (c *AnimeController) GetUserAnimeList(ctx *gin.Context) {
 	})
 }
 
+// GetUserAnimeEntry godoc
+//
+//	@Summary		Получить запись из списка пользователя
+//	@Description	Возвращает запись списка пользователя по MAL ID аниме. Версия записи возвращается в заголовке ETag; ее нужно передавать в If-Match при изменении или удалении записи
+//	@Tags			users
+//	@Produce		json
+//	@Param			user_id		path		int		true	"ID пользователя"
+//	@Param			anime_id	path		int		true	"MAL ID аниме"
+//	@Success		200			{object}	dtos.UserAnimeResponse
+//	@Header			200			{string}	ETag	"Версия записи"
+//	@Failure		400			{object}	map[string]string	"Неверные входные данные"
+//	@Failure		404			{object}	map[string]string	"Запись не найдена"
+//	@Failure		500			{object}	map[string]string	"Внутренняя ошибка сервера"
+//	@Router			/users/{user_id}/anime/{anime_id} [get]
+func (c *AnimeController) GetUserAnimeEntry(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	animeMALID, err := strconv.ParseInt(ctx.Param("anime_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аниме"})
+		return
+	}
+
+	entry, err := c.animeService.GetUserAnimeEntry(ctx, userID, animeMALID)
+	if err != nil {
+		handleAnimeError(ctx, err)
+		return
+	}
+
+	ctx.Header("ETag", entryETag(entry.Version))
+	ctx.JSON(http.StatusOK, dtos.ToUserAnimeResponse(entry))
+}
+
 // AddAnimeToUserList godoc
+//
 //	@Summary		Добавить аниме в список пользователя
 //	@Description	Добавляет аниме в список пользователя с указанным статусом
 //	@Tags			users
@@ -532,16 +789,15 @@ func (c *AnimeController) GetUserAnimeList(ctx *gin.Context) {
 //	@Failure		500		{object}	map[string]string		"Внутренняя ошибка сервера"
 //	@Router			/users/{user_id}/anime [post]
 func (c *AnimeController) AddAnimeToUserList(ctx *gin.Context) {
-	userIDStr := ctx.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	userID, err := resolveUserID(ctx)
 	if err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
 		return
 	}
 
 	var request dtos.AddAnimeRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
 		return
 	}
 
@@ -552,7 +808,7 @@ func (c *AnimeController) AddAnimeToUserList(ctx *gin.Context) {
 			"anime_mal_id": request.AnimeMALID,
 			"error":        err.Error(),
 		})
-		handleAuthError(ctx, err)
+		handleAnimeError(ctx, err)
 		return
 	}
 
@@ -560,6 +816,7 @@ func (c *AnimeController) AddAnimeToUserList(ctx *gin.Context) {
 }
 
 // RemoveAnimeFromUserList godoc
+//
 //	@Summary		Удалить аниме из списка пользователя
 //	@Description	Удаляет аниме из списка пользователя
 //	@Tags			users
@@ -567,16 +824,19 @@ func (c *AnimeController) AddAnimeToUserList(ctx *gin.Context) {
 //	@Produce		json
 //	@Param			user_id		path		int					true	"ID пользователя"
 //	@Param			anime_id	path		int					true	"MAL ID аниме"
+//	@Param			If-Match	header		string				false	"ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version"
+//	@Param			version		query		int					false	"Версия записи вместо If-Match"
 //	@Success		200			{object}	map[string]string	"Успешное удаление"
 //	@Failure		400			{object}	map[string]string	"Неверные входные данные"
 //	@Failure		404			{object}	map[string]string	"Запись не найдена"
+//	@Failure		412			{object}	dtos.UserAnimeConflictResponse	"Запись изменилась, в ответе ее актуальное состояние"
+//	@Failure		428			{object}	map[string]string	"Не передана версия записи"
 //	@Failure		500			{object}	map[string]string	"Внутренняя ошибка сервера"
 //	@Router			/users/{user_id}/anime/{anime_id} [delete]
 func (c *AnimeController) RemoveAnimeFromUserList(ctx *gin.Context) {
-	userIDStr := ctx.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	userID, err := resolveUserID(ctx)
 	if err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
 		return
 	}
 
@@ -587,27 +847,137 @@ func (c *AnimeController) RemoveAnimeFromUserList(ctx *gin.Context) {
 		return
 	}
 
-	err = c.animeService.RemoveAnimeFromUserList(ctx, uint(userID), animeMALID)
+	requestVersion, err := strconv.ParseInt(ctx.DefaultQuery("version", "0"), 10, 64)
+	if err != nil || requestVersion < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверная версия записи"})
+		return
+	}
+	expectedVersion, ok := c.entryVersion(ctx, userID, animeMALID, requestVersion, true)
+	if !ok {
+		return
+	}
+
+	err = c.animeService.RemoveAnimeFromUserList(ctx, uint(userID), animeMALID, expectedVersion)
 	if err != nil {
 		c.logger.Error("Error removing anime from user list", map[string]interface{}{
 			"user_id":      userID,
 			"anime_mal_id": animeMALID,
 			"error":        err.Error(),
 		})
-		
-		if err.Error() == "user anime not found" {
-			handleAuthError(ctx, err)
-			return
-		}
-		
-		handleAuthError(ctx, err)
+		c.handleEntryError(ctx, userID, animeMALID, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Аниме успешно удалено из списка пользователя"})
 }
 
+// PatchUserAnime godoc
+//
+//	@Summary		Изменить запись в списке пользователя
+//	@Description	Применяет JSON Merge Patch к статусу, прогрессу, рейтингу, заметкам, датам и тегам записи в одной транзакции. Отсутствующее поле не меняется, null сбрасывает значение. Без явного статуса он сдвигается по прогрессу так же, как в PUT /episodes
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id		path		int							true	"ID пользователя"
+//	@Param			anime_id	path		int							true	"MAL ID аниме"
+//	@Param			If-Match	header		string						false	"ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version"
+//	@Param			patch		body		dtos.PatchUserAnimeRequest	true	"Изменяемые поля"
+//	@Success		200			{object}	dtos.UserAnimeResponse		"Запись после изменения"
+//	@Header			200			{string}	ETag						"Новая версия записи"
+//	@Failure		400			{object}	map[string]string			"Неверные входные данные"
+//	@Failure		404			{object}	map[string]string			"Запись не найдена"
+//	@Failure		412			{object}	dtos.UserAnimeConflictResponse	"Запись изменилась, в ответе ее актуальное состояние"
+//	@Failure		422			{object}	map[string]string			"Недопустимый переход статуса"
+//	@Failure		428			{object}	map[string]string			"Не передана версия записи"
+//	@Failure		500			{object}	map[string]string			"Внутренняя ошибка сервера"
+//	@Router			/users/{user_id}/anime/{anime_id} [patch]
+func (c *AnimeController) PatchUserAnime(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	animeMALID, err := strconv.ParseInt(ctx.Param("anime_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аниме"})
+		return
+	}
+
+	var request dtos.PatchUserAnimeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	expectedVersion, ok := c.entryVersion(ctx, userID, animeMALID, request.Version, true)
+	if !ok {
+		return
+	}
+
+	entry, err := c.animeService.PatchUserAnime(ctx, userID, animeMALID, request.ToPatch(), expectedVersion)
+	if err != nil {
+		c.logger.Error("Error patching user anime", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		c.handleEntryError(ctx, userID, animeMALID, err)
+		return
+	}
+
+	ctx.Header("ETag", entryETag(entry.Version))
+	ctx.JSON(http.StatusOK, dtos.ToUserAnimeResponse(entry))
+}
+
+// BulkUpdateUserAnime godoc
+//
+//	@Summary		Пакетно изменить список пользователя
+//	@Description	Выполняет до 100 операций (add, remove, set_status, set_rating, set_episodes) в одной транзакции. В режиме all_or_nothing ошибка любой операции отменяет весь пакет, в режиме best_effort фиксируются все успешные операции. Итог каждой операции — в results. Аниме, которых нет в локальном каталоге, загружаются из Jikan API не больше 10 за запрос; если их больше или Jikan API недоступен, возвращается 503, а загруженные аниме сохраняются в каталоге
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id		path		int							true	"ID пользователя"
+//	@Param			bulk		body		dtos.BulkUserAnimeRequest	true	"Операции"
+//	@Success		200			{object}	models.BulkResult			"Изменения зафиксированы"
+//	@Failure		400			{object}	map[string]string			"Неверные входные данные"
+//	@Failure		422			{object}	models.BulkResult			"Пакет all_or_nothing отменен; причина — в results"
+//	@Failure		500			{object}	map[string]string			"Внутренняя ошибка сервера"
+//	@Failure		503			{object}	map[string]string			"Данные аниме временно недоступны; повторите запрос позже"
+//	@Router			/users/{user_id}/anime/bulk [post]
+func (c *AnimeController) BulkUpdateUserAnime(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	var request dtos.BulkUserAnimeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	result, err := c.animeService.BulkUpdateUserAnime(ctx, userID, request.Mode, request.ToOperations())
+	if err != nil {
+		c.logger.Error("Error applying bulk changes to user anime list", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		handleAnimeError(ctx, err)
+		return
+	}
+
+	if result.Mode == models.BulkModeAllOrNothing && !result.Committed {
+		ctx.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
 // UpdateUserAnimeStatus godoc
+//
 //	@Summary		Обновить статус аниме в списке пользователя
 //	@Description	Обновляет статус аниме в списке пользователя
 //	@Tags			users
@@ -615,17 +985,20 @@ func (c *AnimeController) RemoveAnimeFromUserList(ctx *gin.Context) {
 //	@Produce		json
 //	@Param			user_id		path		int							true	"ID пользователя"
 //	@Param			anime_id	path		int							true	"MAL ID аниме"
+//	@Param			If-Match	header		string						false	"ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version. * — любая версия существующей записи"
 //	@Param			status		body		dtos.UpdateStatusRequest	true	"Новый статус аниме"
 //	@Success		200			{object}	map[string]string			"Успешное обновление"
+//	@Header			200			{string}	ETag						"Новая версия записи"
 //	@Failure		400			{object}	map[string]string			"Неверные входные данные"
 //	@Failure		404			{object}	map[string]string			"Запись не найдена"
+//	@Failure		412			{object}	dtos.UserAnimeConflictResponse	"Запись изменилась, в ответе ее актуальное состояние"
+//	@Failure		428			{object}	map[string]string			"Не передана версия записи"
 //	@Failure		500			{object}	map[string]string			"Внутренняя ошибка сервера"
 //	@Router			/users/{user_id}/anime/{anime_id}/status [put]
 func (c *AnimeController) UpdateUserAnimeStatus(ctx *gin.Context) {
-	userIDStr := ctx.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	userID, err := resolveUserID(ctx)
 	if err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
 		return
 	}
 
@@ -638,49 +1011,53 @@ func (c *AnimeController) UpdateUserAnimeStatus(ctx *gin.Context) {
 
 	var request dtos.UpdateStatusRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
 		return
 	}
 
-	err = c.animeService.UpdateUserAnimeStatus(ctx, uint(userID), animeMALID, request.Status)
+	expectedVersion, ok := c.entryVersion(ctx, userID, animeMALID, request.Version, true)
+	if !ok {
+		return
+	}
+
+	userAnime, err := c.animeService.UpdateUserAnimeStatus(ctx, uint(userID), animeMALID, request.Status, expectedVersion)
 	if err != nil {
 		c.logger.Error("Error updating user anime status", map[string]interface{}{
 			"user_id":      userID,
 			"anime_mal_id": animeMALID,
 			"error":        err.Error(),
 		})
-		
-		if err.Error() == "user anime not found" {
-			handleAuthError(ctx, err)
-			return
-		}
-		
-		handleAuthError(ctx, err)
+		c.handleEntryError(ctx, userID, animeMALID, err)
 		return
 	}
 
+	ctx.Header("ETag", entryETag(userAnime.Version))
 	ctx.JSON(http.StatusOK, gin.H{"message": "Статус аниме успешно обновлен"})
 }
 
 // UpdateUserAnimeEpisodes godoc
+//
 //	@Summary		Обновить количество просмотренных эпизодов
-//	@Description	Обновляет количество просмотренных эпизодов аниме в списке пользователя
+//	@Description	Обновляет количество просмотренных эпизодов аниме в списке пользователя. Статус меняется автоматически: первый эпизод переводит из plan_to_watch в watching, последний эпизод завершенного тайтла — в watched, догнавший онгоинг — в waiting
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			user_id		path		int							true	"ID пользователя"
 //	@Param			anime_id	path		int							true	"MAL ID аниме"
+//	@Param			If-Match	header		string						false	"ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version"
 //	@Param			episodes	body		dtos.UpdateEpisodesRequest	true	"Новое количество просмотренных эпизодов"
-//	@Success		200			{object}	map[string]string			"Успешное обновление"
-//	@Failure		400			{object}	map[string]string			"Неверные входные данные"
+//	@Success		200			{object}	dtos.UpdateEpisodesResponse	"Успешное обновление"
+//	@Header			200			{string}	ETag						"Новая версия записи"
+//	@Failure		400			{object}	map[string]string			"Неверные входные данные или количество эпизодов вне диапазона"
 //	@Failure		404			{object}	map[string]string			"Запись не найдена"
+//	@Failure		412			{object}	dtos.UserAnimeConflictResponse	"Запись изменилась, в ответе ее актуальное состояние"
+//	@Failure		428			{object}	map[string]string			"Не передана версия записи"
 //	@Failure		500			{object}	map[string]string			"Внутренняя ошибка сервера"
 //	@Router			/users/{user_id}/anime/{anime_id}/episodes [put]
 func (c *AnimeController) UpdateUserAnimeEpisodes(ctx *gin.Context) {
-	userIDStr := ctx.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	userID, err := resolveUserID(ctx)
 	if err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
 		return
 	}
 
@@ -693,32 +1070,302 @@ func (c *AnimeController) UpdateUserAnimeEpisodes(ctx *gin.Context) {
 
 	var request dtos.UpdateEpisodesRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = c.animeService.UpdateUserAnimeEpisodes(ctx, uint(userID), animeMALID, request.EpisodesWatched)
+	expectedVersion, ok := c.entryVersion(ctx, userID, animeMALID, request.Version, true)
+	if !ok {
+		return
+	}
+
+	userAnime, err := c.animeService.UpdateUserAnimeEpisodes(ctx, uint(userID), animeMALID, *request.EpisodesWatched, expectedVersion)
 	if err != nil {
 		c.logger.Error("Error updating user anime episodes", map[string]interface{}{
 			"user_id":          userID,
 			"anime_mal_id":     animeMALID,
-			"episodes_watched": request.EpisodesWatched,
+			"episodes_watched": *request.EpisodesWatched,
 			"error":            err.Error(),
 		})
-		
-		if err.Error() == "user anime not found" {
-			handleAuthError(ctx, err)
-			return
-		}
-		
-		handleAuthError(ctx, err)
+		c.handleEntryError(ctx, userID, animeMALID, err)
+		return
+	}
+
+	ctx.Header("ETag", entryETag(userAnime.Version))
+	ctx.JSON(http.StatusOK, dtos.UpdateEpisodesResponse{
+		Message:         "Количество просмотренных эпизодов успешно обновлено",
+		AnimeMALID:      userAnime.AnimeMALID,
+		EpisodesWatched: userAnime.EpisodesWatched,
+		Status:          userAnime.Status,
+		RewatchEpisodes: userAnime.RewatchEpisodes,
+		Version:         userAnime.Version,
+	})
+}
+
+// MarkEpisodesWatched godoc
+//
+//	@Summary		Отметить эпизоды просмотренными
+//	@Description	Добавляет в журнал просмотров отметки для эпизода или диапазона эпизодов и пересчитывает прогресс и статус записи
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id		path		int							true	"ID пользователя"
+//	@Param			anime_id	path		int							true	"MAL ID аниме"
+//	@Param			If-Match	header		string						false	"ETag записи (или список ETag; слабые W/ не совпадают); без него версия не проверяется"
+//	@Param			episodes	body		dtos.MarkEpisodesRequest	true	"Диапазон эпизодов"
+//	@Success		200			{object}	dtos.UpdateEpisodesResponse	"Прогресс после отметки"
+//	@Header			200			{string}	ETag						"Новая версия записи"
+//	@Failure		400			{object}	map[string]string			"Неверные входные данные или эпизоды вне диапазона"
+//	@Failure		404			{object}	map[string]string			"Запись не найдена"
+//	@Failure		412			{object}	dtos.UserAnimeConflictResponse	"Запись изменилась, в ответе ее актуальное состояние"
+//	@Failure		500			{object}	map[string]string			"Внутренняя ошибка сервера"
+//	@Router			/users/{user_id}/anime/{anime_id}/episodes/watched [post]
+func (c *AnimeController) MarkEpisodesWatched(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	animeMALID, err := strconv.ParseInt(ctx.Param("anime_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аниме"})
+		return
+	}
+
+	var request dtos.MarkEpisodesRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.To == 0 {
+		request.To = request.From
+	}
+	watchedAt := time.Now()
+	if request.WatchedAt != nil {
+		watchedAt = *request.WatchedAt
+	}
+
+	// Отметка эпизодов дописывает журнал и не затирает чужие изменения, поэтому
+	// версию здесь проверяем, только если клиент ее передал.
+	expectedVersion, ok := c.entryVersion(ctx, userID, animeMALID, request.Version, false)
+	if !ok {
+		return
+	}
+
+	userAnime, err := c.animeService.MarkEpisodesWatched(ctx, userID, animeMALID, request.From, request.To, watchedAt, request.Rating, expectedVersion)
+	if err != nil {
+		c.logger.Error("Error marking episodes watched", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		c.handleEntryError(ctx, userID, animeMALID, err)
+		return
+	}
+
+	ctx.Header("ETag", entryETag(userAnime.Version))
+	ctx.JSON(http.StatusOK, dtos.UpdateEpisodesResponse{
+		Message:         "Эпизоды отмечены просмотренными",
+		AnimeMALID:      userAnime.AnimeMALID,
+		EpisodesWatched: userAnime.EpisodesWatched,
+		Status:          userAnime.Status,
+		RewatchEpisodes: userAnime.RewatchEpisodes,
+		Version:         userAnime.Version,
+	})
+}
+
+// UnmarkEpisodesWatched godoc
+//
+//	@Summary		Снять отметку просмотра с эпизодов
+//	@Description	Добавляет в журнал просмотров снятие отметки для эпизода или диапазона эпизодов и пересчитывает прогресс записи
+//	@Tags			users
+//	@Produce		json
+//	@Param			user_id		path		int							true	"ID пользователя"
+//	@Param			anime_id	path		int							true	"MAL ID аниме"
+//	@Param			from		query		int							true	"Первый эпизод"	minimum(1)
+//	@Param			to			query		int							false	"Последний эпизод (по умолчанию равен from)"
+//	@Param			version		query		int							false	"Версия записи вместо If-Match"
+//	@Param			If-Match	header		string						false	"ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version"
+//	@Success		200			{object}	dtos.UpdateEpisodesResponse	"Прогресс после снятия отметки"
+//	@Header			200			{string}	ETag						"Новая версия записи"
+//	@Failure		400			{object}	map[string]string			"Неверные входные данные или эпизоды вне диапазона"
+//	@Failure		404			{object}	map[string]string			"Запись не найдена"
+//	@Failure		412			{object}	dtos.UserAnimeConflictResponse	"Запись изменилась, в ответе ее актуальное состояние"
+//	@Failure		428			{object}	map[string]string			"Не передана версия записи"
+//	@Failure		500			{object}	map[string]string			"Внутренняя ошибка сервера"
+//	@Router			/users/{user_id}/anime/{anime_id}/episodes/watched [delete]
+func (c *AnimeController) UnmarkEpisodesWatched(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	animeMALID, err := strconv.ParseInt(ctx.Param("anime_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аниме"})
+		return
+	}
+
+	var request dtos.UnmarkEpisodesRequest
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.To == 0 {
+		request.To = request.From
+	}
+
+	expectedVersion, ok := c.entryVersion(ctx, userID, animeMALID, request.Version, true)
+	if !ok {
+		return
+	}
+
+	userAnime, err := c.animeService.UnmarkEpisodesWatched(ctx, userID, animeMALID, request.From, request.To, expectedVersion)
+	if err != nil {
+		c.logger.Error("Error unmarking episodes watched", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		c.handleEntryError(ctx, userID, animeMALID, err)
+		return
+	}
+
+	ctx.Header("ETag", entryETag(userAnime.Version))
+	ctx.JSON(http.StatusOK, dtos.UpdateEpisodesResponse{
+		Message:         "Отметка просмотра снята",
+		AnimeMALID:      userAnime.AnimeMALID,
+		EpisodesWatched: userAnime.EpisodesWatched,
+		Status:          userAnime.Status,
+		RewatchEpisodes: userAnime.RewatchEpisodes,
+		Version:         userAnime.Version,
+	})
+}
+
+// GetEpisodeWatchHistory godoc
+//
+//	@Summary		Журнал просмотра эпизодов
+//	@Description	Возвращает события журнала просмотров по аниме из списка пользователя, начиная с последних
+//	@Tags			users
+//	@Produce		json
+//	@Param			user_id		path		int		true	"ID пользователя"
+//	@Param			anime_id	path		int		true	"MAL ID аниме"
+//	@Param			limit		query		int		false	"Количество событий"	default(100)	minimum(1)	maximum(500)
+//	@Success		200			{object}	dtos.EpisodeWatchHistoryResponse
+//	@Failure		400			{object}	map[string]string	"Неверные входные данные"
+//	@Failure		500			{object}	map[string]string	"Внутренняя ошибка сервера"
+//	@Router			/users/{user_id}/anime/{anime_id}/episodes/history [get]
+func (c *AnimeController) GetEpisodeWatchHistory(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	animeMALID, err := strconv.ParseInt(ctx.Param("anime_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аниме"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+	if limit < 1 {
+		limit = 100
+	} else if limit > 500 {
+		limit = 500
+	}
+
+	watches, err := c.animeService.GetEpisodeWatchHistory(ctx, userID, animeMALID, limit)
+	if err != nil {
+		handleAnimeError(ctx, err)
+		return
+	}
+
+	items := make([]dtos.EpisodeWatchResponse, 0, len(watches))
+	for _, watch := range watches {
+		items = append(items, dtos.EpisodeWatchResponse{
+			ID:            watch.ID,
+			Episode:       watch.Episode,
+			RewatchNumber: watch.RewatchNumber,
+			Unwatched:     watch.Unwatched,
+			Rating:        watch.Rating,
+			WatchedAt:     watch.WatchedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, dtos.EpisodeWatchHistoryResponse{Items: items})
+}
+
+// UpdateUserAnimeTracking godoc
+//
+//	@Summary		Задать даты просмотра и пересмотры
+//	@Description	Вручную заменяет даты начала и окончания просмотра, количество пересмотров и прогресс текущего пересмотра. Не переданная дата очищается
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id		path		int								true	"ID пользователя"
+//	@Param			anime_id	path		int								true	"MAL ID аниме"
+//	@Param			If-Match	header		string							false	"ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version"
+//	@Param			tracking	body		dtos.UpdateTrackingRequest		true	"Даты просмотра и счетчики пересмотров"
+//	@Success		200			{object}	dtos.UserAnimeTrackingResponse	"Успешное обновление"
+//	@Header			200			{string}	ETag							"Новая версия записи"
+//	@Failure		400			{object}	map[string]string				"Неверные входные данные"
+//	@Failure		404			{object}	map[string]string				"Запись не найдена"
+//	@Failure		412			{object}	dtos.UserAnimeConflictResponse	"Запись изменилась, в ответе ее актуальное состояние"
+//	@Failure		428			{object}	map[string]string				"Не передана версия записи"
+//	@Failure		500			{object}	map[string]string				"Внутренняя ошибка сервера"
+//	@Router			/users/{user_id}/anime/{anime_id}/tracking [put]
+func (c *AnimeController) UpdateUserAnimeTracking(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	animeMALID, err := strconv.ParseInt(ctx.Param("anime_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аниме"})
+		return
+	}
+
+	var request dtos.UpdateTrackingRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expectedVersion, ok := c.entryVersion(ctx, userID, animeMALID, request.Version, true)
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Количество просмотренных эпизодов успешно обновлено"})
+	userAnime, err := c.animeService.UpdateUserAnimeTracking(ctx, userID, animeMALID, request.ToTracking(), expectedVersion)
+	if err != nil {
+		c.logger.Error("Error updating user anime tracking", map[string]interface{}{
+			"user_id":      userID,
+			"anime_mal_id": animeMALID,
+			"error":        err.Error(),
+		})
+		c.handleEntryError(ctx, userID, animeMALID, err)
+		return
+	}
+
+	ctx.Header("ETag", entryETag(userAnime.Version))
+	ctx.JSON(http.StatusOK, dtos.UserAnimeTrackingResponse{
+		AnimeMALID:      userAnime.AnimeMALID,
+		Status:          userAnime.Status,
+		RewatchCount:    userAnime.RewatchCount,
+		RewatchEpisodes: userAnime.RewatchEpisodes,
+		StartedAt:       userAnime.StartedAt,
+		FinishedAt:      userAnime.FinishedAt,
+		Version:         userAnime.Version,
+	})
 }
 
 // UpdateUserAnimeRating godoc
+//
 //	@Summary		Обновить рейтинг аниме
 //	@Description	Обновляет пользовательский рейтинг аниме в списке пользователя
 //	@Tags			users
@@ -726,17 +1373,20 @@ func (c *AnimeController) UpdateUserAnimeEpisodes(ctx *gin.Context) {
 //	@Produce		json
 //	@Param			user_id		path		int							true	"ID пользователя"
 //	@Param			anime_id	path		int							true	"MAL ID аниме"
+//	@Param			If-Match	header		string						false	"ETag записи (или список ETag; слабые W/ не совпадают); обязателен, если не передан version"
 //	@Param			rating		body		dtos.UpdateRatingRequest	true	"Новый рейтинг аниме (от 0 до 10)"
 //	@Success		200			{object}	map[string]string			"Успешное обновление"
+//	@Header			200			{string}	ETag						"Новая версия записи"
 //	@Failure		400			{object}	map[string]string			"Неверные входные данные"
 //	@Failure		404			{object}	map[string]string			"Запись не найдена"
+//	@Failure		412			{object}	dtos.UserAnimeConflictResponse	"Запись изменилась, в ответе ее актуальное состояние"
+//	@Failure		428			{object}	map[string]string			"Не передана версия записи"
 //	@Failure		500			{object}	map[string]string			"Внутренняя ошибка сервера"
 //	@Router			/users/{user_id}/anime/{anime_id}/rating [put]
 func (c *AnimeController) UpdateUserAnimeRating(ctx *gin.Context) {
-	userIDStr := ctx.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	userID, err := resolveUserID(ctx)
 	if err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
 		return
 	}
 
@@ -759,7 +1409,12 @@ func (c *AnimeController) UpdateUserAnimeRating(ctx *gin.Context) {
 		return
 	}
 
-	err = c.animeService.UpdateUserAnimeRating(ctx, uint(userID), animeMALID, request.Rating)
+	expectedVersion, ok := c.entryVersion(ctx, userID, animeMALID, request.Version, true)
+	if !ok {
+		return
+	}
+
+	userAnime, err := c.animeService.UpdateUserAnimeRating(ctx, uint(userID), animeMALID, request.Rating, expectedVersion)
 	if err != nil {
 		c.logger.Error("Error updating user anime rating", map[string]interface{}{
 			"user_id":      userID,
@@ -767,20 +1422,16 @@ func (c *AnimeController) UpdateUserAnimeRating(ctx *gin.Context) {
 			"rating":       request.Rating,
 			"error":        err.Error(),
 		})
-		
-		if err.Error() == "user anime not found" {
-			handleAuthError(ctx, err)
-			return
-		}
-		
-		handleAuthError(ctx, err)
+		c.handleEntryError(ctx, userID, animeMALID, err)
 		return
 	}
 
+	ctx.Header("ETag", entryETag(userAnime.Version))
 	ctx.JSON(http.StatusOK, gin.H{"message": "Рейтинг аниме успешно обновлен"})
 }
 
 // GetUserAnimeStats godoc
+//
 //	@Summary		Получить статистику пользователя по аниме
 //	@Description	Возвращает статистику пользователя по просмотру аниме
 //	@Tags			users
@@ -792,10 +1443,9 @@ func (c *AnimeController) UpdateUserAnimeRating(ctx *gin.Context) {
 //	@Failure		500		{object}	map[string]string	"Внутренняя ошибка сервера"
 //	@Router			/users/{user_id}/anime/stats [get]
 func (c *AnimeController) GetUserAnimeStats(ctx *gin.Context) {
-	userIDStr := ctx.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	userID, err := resolveUserID(ctx)
 	if err != nil {
-		handleAuthError(ctx, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
 		return
 	}
 
@@ -814,9 +1464,13 @@ func (c *AnimeController) GetUserAnimeStats(ctx *gin.Context) {
 		TotalPlanToWatch: stats.TotalPlanToWatch,
 		TotalWatching:    stats.TotalWatching,
 		TotalWaiting:     stats.TotalWaiting,
+		TotalDropped:     stats.TotalDropped,
+		TotalOnHold:      stats.TotalOnHold,
+		TotalRewatching:  stats.TotalRewatching,
+		TotalRewatches:   stats.TotalRewatches,
 		TotalEpisodes:    stats.TotalEpisodes,
 		AverageRating:    stats.AverageRating,
 	}
 
 	ctx.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header   string
+		versions []int64
+		wildcard bool
+		invalid  bool
+	}{
+		{header: `"3"`, versions: []int64{3}},
+		{header: ` "3" , "5"`, versions: []int64{3, 5}},
+		{header: `*`, wildcard: true},
+		{header: `W/"3"`},
+		{header: `W/"3", "4"`, versions: []int64{4}},
+		{header: `"3", *`, invalid: true},
+		{header: `3`, invalid: true},
+		{header: `"abc"`, invalid: true},
+		{header: `"0"`, invalid: true},
+		{header: `"3",`, invalid: true},
+	}
+
+	for _, tt := range tests {
+		versions, wildcard, err := parseIfMatch(tt.header)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("parseIfMatch(%q) succeeded, want an error", tt.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseIfMatch(%q): %v", tt.header, err)
+			continue
+		}
+		if wildcard != tt.wildcard || !reflect.DeepEqual(versions, tt.versions) {
+			t.Errorf("parseIfMatch(%q) = (%v, %v), want (%v, %v)", tt.header, versions, wildcard, tt.versions, tt.wildcard)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/application/services"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	domainRepositories "github.com/merdernoty/anime-service/internal/domain/repositories"
+	"github.com/merdernoty/anime-service/internal/infrastructure/database/dbtest"
+	"github.com/merdernoty/anime-service/internal/infrastructure/repositories"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
+	"logur.dev/logur"
+)
+
+const testAnimeMALID = 5114
+
+// newAnimeRouter собирает маршруты /me/anime поверх тестовой базы. Аниме
+// testAnimeMALID заранее кладется в каталог, поэтому для него Jikan API не
+// нужен, и jikan может быть nil; аутентификацию заменяет middleware,
+// подставляющий userID.
+func newAnimeRouter(t *testing.T, db *sql.DB, userID uint, jikan domainRepositories.JikanClient) *gin.Engine {
+	t.Helper()
+
+	logger := logur.NoopLogger{}
+	animeRepo := repositories.NewAnimeRepository(db, logger)
+	if err := animeRepo.Upsert(context.Background(), &models.Anime{MALId: testAnimeMALID, Title: "Fullmetal Alchemist: Brotherhood", Episodes: 64}); err != nil {
+		t.Fatalf("seed catalog: %v", err)
+	}
+
+	userAnimeRepo := repositories.NewUserAnimeRepository(db, logger)
+	episodeWatchRepo := repositories.NewEpisodeWatchRepository(db, userAnimeRepo, logger)
+	catalog := services.NewAnimeCatalogService(animeRepo, jikan, 24*time.Hour, logger)
+	animeService := services.NewAnimeService(nil, userAnimeRepo, episodeWatchRepo, catalog, logger)
+	controller := controllers.NewAnimeController(*animeService, logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	myAnime := router.Group("/me/anime", func(ctx *gin.Context) {
+		ctx.Set("userID", userID)
+	})
+	myAnime.GET("", controller.GetUserAnimeList)
+	myAnime.POST("", controller.AddAnimeToUserList)
+	myAnime.POST("/bulk", controller.BulkUpdateUserAnime)
+	myAnime.PUT("/:anime_id/status", controller.UpdateUserAnimeStatus)
+	return router
+}
+
+func serveJSON(router *gin.Engine, method, path string, body interface{}, header http.Header) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	request := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	request.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestAddAndChangeStatusConcurrently одновременно добавляет аниме в список и
+// меняет его статус: в итоге должна остаться ровно одна запись с допустимым
+// статусом, а запросы — завершиться успехом, отказом в переходе или, если
+// запись еще не создана, несработавшим If-Match: *, но не ошибкой уникальности.
+func TestAddAndChangeStatusConcurrently(t *testing.T) {
+	db := dbtest.OpenMigrated(t)
+	userID := dbtest.CreateUser(t, db)
+	router := newAnimeRouter(t, db, userID, nil)
+
+	statuses := []models.WatchStatus{
+		models.StatusPlanToWatch,
+		models.StatusWatching,
+		models.StatusWaiting,
+		models.StatusOnHold,
+		models.StatusDropped,
+		models.StatusWatched,
+	}
+
+	const rounds = 8
+	var wg sync.WaitGroup
+	failures := make(chan string, rounds*len(statuses)*2)
+	for round := 0; round < rounds; round++ {
+		for _, status := range statuses {
+			wg.Add(2)
+			go func(status models.WatchStatus) {
+				defer wg.Done()
+				response := serveJSON(router, http.MethodPost, "/me/anime", map[string]interface{}{
+					"anime_mal_id": testAnimeMALID,
+					"status":       status,
+				}, nil)
+				if response.Code != http.StatusCreated && response.Code != http.StatusUnprocessableEntity {
+					failures <- fmt.Sprintf("POST status=%s: %d %s", status, response.Code, response.Body.String())
+				}
+			}(status)
+			go func(status models.WatchStatus) {
+				defer wg.Done()
+				response := serveJSON(router, http.MethodPut, fmt.Sprintf("/me/anime/%d/status", testAnimeMALID), map[string]interface{}{
+					"status": status,
+				}, http.Header{"If-Match": {"*"}})
+				if response.Code != http.StatusOK && response.Code != http.StatusUnprocessableEntity && response.Code != http.StatusPreconditionFailed {
+					failures <- fmt.Sprintf("PUT status=%s: %d %s", status, response.Code, response.Body.String())
+				}
+			}(status)
+		}
+	}
+	wg.Wait()
+	close(failures)
+
+	for failure := range failures {
+		t.Error(failure)
+	}
+
+	var count int
+	var status models.WatchStatus
+	if err := db.QueryRow(`
+		SELECT count(*), min(status) FROM user_animes WHERE user_id = $1 AND anime_mal_id = $2
+	`, userID, testAnimeMALID).Scan(&count, &status); err != nil {
+		t.Fatalf("query entries: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("%d entries for one (user_id, anime_mal_id), want 1", count)
+	}
+	if !status.IsValid() {
+		t.Errorf("entry has invalid status %q", status)
+	}
+}
+
+// testJikan отдает аниме с любым id меньше 100000; аниме с большими id не
+// существуют. Если задан err, каждый запрос завершается этой ошибкой.
+type testJikan struct {
+	domainRepositories.JikanClient
+	err      error
+	requests atomic.Int32
+}
+
+func (j *testJikan) GetAnimeByID(ctx context.Context, malID int64) (*models.Anime, error) {
+	j.requests.Add(1)
+	if j.err != nil {
+		return nil, j.err
+	}
+	if malID >= 100000 {
+		return nil, models.ErrAnimeNotFound
+	}
+	return &models.Anime{MALId: malID, Title: fmt.Sprintf("Anime %d", malID), Episodes: 12}, nil
+}
+
+func decodeBulkResult(t *testing.T, response *httptest.ResponseRecorder) models.BulkResult {
+	t.Helper()
+
+	var result models.BulkResult
+	if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode bulk result %q: %v", response.Body.String(), err)
+	}
+	return result
+}
+
+func outcomes(result models.BulkResult) []models.BulkOutcome {
+	outcomes := make([]models.BulkOutcome, 0, len(result.Results))
+	for _, r := range result.Results {
+		outcomes = append(outcomes, r.Outcome)
+	}
+	return outcomes
+}
+
+// bulkOperations — пакет, в котором вторая и четвертая операции не выполняются:
+// устаревшая версия и удаление отсутствующей записи.
+func bulkOperations(mode models.BulkMode) map[string]interface{} {
+	return map[string]interface{}{
+		"mode": mode,
+		"operations": []map[string]interface{}{
+			{"action": "add", "anime_mal_id": testAnimeMALID, "status": models.StatusWatching},
+			{"action": "set_status", "anime_mal_id": testAnimeMALID, "status": models.StatusOnHold, "version": 99},
+			{"action": "set_rating", "anime_mal_id": testAnimeMALID, "rating": 8},
+			{"action": "remove", "anime_mal_id": 1},
+		},
+	}
+}
+
+// TestBulkBestEffortRollsBackOnlyFailedOperations проверяет точки сохранения:
+// после отката неудачной операции транзакция продолжает работать, и
+// следующие операции фиксируются.
+func TestBulkBestEffortRollsBackOnlyFailedOperations(t *testing.T) {
+	db := dbtest.OpenMigrated(t)
+	userID := dbtest.CreateUser(t, db)
+	router := newAnimeRouter(t, db, userID, nil)
+
+	response := serveJSON(router, http.MethodPost, "/me/anime/bulk", bulkOperations(models.BulkModeBestEffort), nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("bulk best_effort: %d %s", response.Code, response.Body.String())
+	}
+	result := decodeBulkResult(t, response)
+
+	want := []models.BulkOutcome{models.BulkOutcomeApplied, models.BulkOutcomeFailed, models.BulkOutcomeApplied, models.BulkOutcomeFailed}
+	if fmt.Sprint(outcomes(result)) != fmt.Sprint(want) {
+		t.Errorf("outcomes = %v, want %v", outcomes(result), want)
+	}
+	if !result.Committed || result.Applied != 2 || result.Failed != 2 {
+		t.Errorf("result = (committed=%v, applied=%d, failed=%d), want (true, 2, 2)", result.Committed, result.Applied, result.Failed)
+	}
+
+	var status models.WatchStatus
+	var rating float64
+	var version int64
+	if err := db.QueryRow(`
+		SELECT status, rating, version FROM user_animes WHERE user_id = $1 AND anime_mal_id = $2
+	`, userID, testAnimeMALID).Scan(&status, &rating, &version); err != nil {
+		t.Fatalf("query entry: %v", err)
+	}
+	if status != models.StatusWatching || rating != 8 || version != 2 {
+		t.Errorf("entry = (status=%s, rating=%v, version=%d), want (%s, 8, 2)", status, rating, version, models.StatusWatching)
+	}
+}
+
+func TestBulkAllOrNothingRollsBackEverything(t *testing.T) {
+	db := dbtest.OpenMigrated(t)
+	userID := dbtest.CreateUser(t, db)
+	router := newAnimeRouter(t, db, userID, nil)
+
+	response := serveJSON(router, http.MethodPost, "/me/anime/bulk", bulkOperations(models.BulkModeAllOrNothing), nil)
+	if response.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("bulk all_or_nothing: %d %s", response.Code, response.Body.String())
+	}
+	result := decodeBulkResult(t, response)
+
+	want := []models.BulkOutcome{models.BulkOutcomeRolledBack, models.BulkOutcomeFailed, models.BulkOutcomeSkipped, models.BulkOutcomeSkipped}
+	if fmt.Sprint(outcomes(result)) != fmt.Sprint(want) {
+		t.Errorf("outcomes = %v, want %v", outcomes(result), want)
+	}
+	if result.Committed {
+		t.Error("all_or_nothing batch with a failed operation was committed")
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM user_animes WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		t.Fatalf("count entries: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d entries left after a rolled back batch, want 0", count)
+	}
+}
+
+func addOperations(malIDs ...int64) map[string]interface{} {
+	operations := make([]map[string]interface{}, 0, len(malIDs))
+	for _, malID := range malIDs {
+		operations = append(operations, map[string]interface{}{"action": "add", "anime_mal_id": malID, "status": models.StatusPlanToWatch})
+	}
+	return map[string]interface{}{"mode": models.BulkModeBestEffort, "operations": operations}
+}
+
+func TestBulkSeparatesMissingAnimeFromUnavailableCatalog(t *testing.T) {
+	db := dbtest.OpenMigrated(t)
+	userID := dbtest.CreateUser(t, db)
+
+	router := newAnimeRouter(t, db, userID, &testJikan{})
+	response := serveJSON(router, http.MethodPost, "/me/anime/bulk", addOperations(1, 100001), nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("bulk with a missing anime: %d %s", response.Code, response.Body.String())
+	}
+	result := decodeBulkResult(t, response)
+	want := []models.BulkOutcome{models.BulkOutcomeApplied, models.BulkOutcomeFailed}
+	if fmt.Sprint(outcomes(result)) != fmt.Sprint(want) {
+		t.Errorf("outcomes = %v, want %v", outcomes(result), want)
+	}
+
+	router = newAnimeRouter(t, db, userID, &testJikan{err: errors.New("received non-OK response: 429")})
+	response = serveJSON(router, http.MethodPost, "/me/anime/bulk", addOperations(2), nil)
+	if response.Code != http.StatusServiceUnavailable {
+		t.Errorf("bulk with Jikan API unavailable: %d %s, want %d", response.Code, response.Body.String(), http.StatusServiceUnavailable)
+	}
+}
+
+func TestBulkLimitsCatalogMissesPerRequest(t *testing.T) {
+	db := dbtest.OpenMigrated(t)
+	userID := dbtest.CreateUser(t, db)
+	jikan := &testJikan{}
+	router := newAnimeRouter(t, db, userID, jikan)
+
+	malIDs := make([]int64, 0, 15)
+	for malID := int64(1); malID <= 15; malID++ {
+		malIDs = append(malIDs, malID)
+	}
+
+	response := serveJSON(router, http.MethodPost, "/me/anime/bulk", addOperations(malIDs...), nil)
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("bulk with 15 catalog misses: %d %s, want %d", response.Code, response.Body.String(), http.StatusServiceUnavailable)
+	}
+	fetched := jikan.requests.Load()
+	if fetched == 0 || fetched >= 15 {
+		t.Fatalf("%d animes fetched from Jikan API in one request, want a bounded number", fetched)
+	}
+
+	// Загруженные аниме остались в каталоге, поэтому повтор догружает остальные.
+	response = serveJSON(router, http.MethodPost, "/me/anime/bulk", addOperations(malIDs...), nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("retried bulk: %d %s", response.Code, response.Body.String())
+	}
+	if result := decodeBulkResult(t, response); result.Applied != len(malIDs) {
+		t.Errorf("retried bulk applied %d operations, want %d", result.Applied, len(malIDs))
+	}
+}
+
+func TestUserAnimeListLoadsCatalogMissesInBatches(t *testing.T) {
+	db := dbtest.OpenMigrated(t)
+	userID := dbtest.CreateUser(t, db)
+	jikan := &testJikan{}
+	router := newAnimeRouter(t, db, userID, jikan)
+
+	// Записи добавлены в обход каталога, как после импорта или очистки каталога.
+	for malID := int64(1); malID <= 15; malID++ {
+		if _, err := db.Exec(`INSERT INTO user_animes (user_id, anime_mal_id, status, created_at, updated_at) VALUES ($1, $2, 'plan_to_watch', now(), now())`, userID, malID); err != nil {
+			t.Fatalf("seed entry %d: %v", malID, err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO user_animes (user_id, anime_mal_id, status, created_at, updated_at) VALUES ($1, 100001, 'plan_to_watch', now(), now())`, userID); err != nil {
+		t.Fatalf("seed entry for a missing anime: %v", err)
+	}
+
+	unavailable := newAnimeRouter(t, db, userID, &testJikan{err: errors.New("received non-OK response: 503")})
+	if response := serveJSON(unavailable, http.MethodGet, "/me/anime?limit=50", nil, nil); response.Code != http.StatusServiceUnavailable {
+		t.Errorf("list with Jikan API unavailable: %d %s, want %d", response.Code, response.Body.String(), http.StatusServiceUnavailable)
+	}
+
+	response := serveJSON(router, http.MethodGet, "/me/anime?limit=50", nil, nil)
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("list with 16 catalog misses: %d %s, want %d", response.Code, response.Body.String(), http.StatusServiceUnavailable)
+	}
+	if fetched := jikan.requests.Load(); fetched == 0 || fetched >= 16 {
+		t.Fatalf("%d animes fetched from Jikan API in one request, want a bounded number", fetched)
+	}
+
+	response = serveJSON(router, http.MethodGet, "/me/anime?limit=50", nil, nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("retried list: %d %s", response.Code, response.Body.String())
+	}
+	var list struct {
+		Items []struct {
+			AnimeMALID int64  `json:"anime_mal_id"`
+			AnimeTitle string `json:"anime_title"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list.Items) != 16 {
+		t.Fatalf("list has %d items, want 16", len(list.Items))
+	}
+	for _, item := range list.Items {
+		want := fmt.Sprintf("Anime %d", item.AnimeMALID)
+		if item.AnimeMALID == 100001 {
+			want = "Unknown"
+		}
+		if item.AnimeTitle != want {
+			t.Errorf("anime %d title = %q, want %q", item.AnimeMALID, item.AnimeTitle, want)
+		}
+	}
+}
+
+// TestIfMatchComparesStrongETags проверяет условные запросы к записи: * требует,
+// чтобы запись существовала, слабые теги не совпадают, из списка тегов подходит
+// любой строгий с текущей версией.
+func TestIfMatchComparesStrongETags(t *testing.T) {
+	db := dbtest.OpenMigrated(t)
+	userID := dbtest.CreateUser(t, db)
+	router := newAnimeRouter(t, db, userID, nil)
+	path := fmt.Sprintf("/me/anime/%d/status", testAnimeMALID)
+
+	putStatus := func(status models.WatchStatus, ifMatch string) *httptest.ResponseRecorder {
+		return serveJSON(router, http.MethodPut, path, map[string]interface{}{"status": status}, http.Header{"If-Match": {ifMatch}})
+	}
+
+	response := putStatus(models.StatusWatching, "*")
+	if response.Code != http.StatusPreconditionFailed {
+		t.Errorf("If-Match: * on a missing entry: %d, want %d", response.Code, http.StatusPreconditionFailed)
+	}
+
+	response = serveJSON(router, http.MethodPost, "/me/anime", map[string]interface{}{
+		"anime_mal_id": testAnimeMALID,
+		"status":       models.StatusPlanToWatch,
+	}, nil)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("create entry: %d %s", response.Code, response.Body.String())
+	}
+
+	response = putStatus(models.StatusWatching, "*")
+	if response.Code != http.StatusOK {
+		t.Fatalf("If-Match: * on an existing entry: %d %s", response.Code, response.Body.String())
+	}
+	current := response.Header().Get("ETag")
+
+	response = putStatus(models.StatusOnHold, "W/"+current)
+	if response.Code != http.StatusPreconditionFailed || response.Header().Get("ETag") != current {
+		t.Errorf("weak ETag: %d ETag=%q, want %d ETag=%q", response.Code, response.Header().Get("ETag"), http.StatusPreconditionFailed, current)
+	}
+
+	response = putStatus(models.StatusOnHold, `"999", "1000"`)
+	if response.Code != http.StatusPreconditionFailed || response.Header().Get("ETag") != current {
+		t.Errorf("stale ETag list: %d ETag=%q, want %d ETag=%q", response.Code, response.Header().Get("ETag"), http.StatusPreconditionFailed, current)
+	}
+
+	response = putStatus(models.StatusOnHold, `"999", `+current)
+	if response.Code != http.StatusOK {
+		t.Fatalf("ETag list with the current version: %d %s", response.Code, response.Body.String())
+	}
+	if next := response.Header().Get("ETag"); next == current {
+		t.Errorf("ETag did not change after update: %q", next)
+	}
+
+	response = putStatus(models.StatusWatching, `"abc"`)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("malformed If-Match: %d, want %d", response.Code, http.StatusBadRequest)
+	}
+}
@@ -2,20 +2,28 @@ package controllers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
+	"emperror.dev/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/merdernoty/anime-service/internal/application/services"
 	"github.com/merdernoty/anime-service/internal/domain/dtos"
+	"github.com/merdernoty/anime-service/internal/domain/models"
 )
 
 type AuthController struct {
-	authService *services.AuthServiceImpl
+	authService              *services.AuthServiceImpl
+	passwordResetService     *services.PasswordResetService
+	emailVerificationService *services.EmailVerificationService
 }
 
-func NewAuthController(authService *services.AuthServiceImpl) *AuthController {
+func NewAuthController(authService *services.AuthServiceImpl, passwordResetService *services.PasswordResetService, emailVerificationService *services.EmailVerificationService) *AuthController {
 	return &AuthController{
-		authService: authService,
+		authService:              authService,
+		passwordResetService:     passwordResetService,
+		emailVerificationService: emailVerificationService,
 	}
 }
 
@@ -31,6 +39,60 @@ func handleAuthError(ctx *gin.Context, err error) {
 			"error":   "invalid credentials",
 			"details": err.Error(),
 		})
+	case err == services.ErrNoCookieFound:
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "refresh token is missing",
+			"details": err.Error(),
+		})
+	case err == services.ErrRefreshTokenReused:
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "refresh token reuse detected",
+			"details": err.Error(),
+		})
+	case err == services.ErrInvalidResetToken:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid reset token",
+			"details": err.Error(),
+		})
+	case err == services.ErrInvalidVerificationToken:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid verification token",
+			"details": err.Error(),
+		})
+	case err == services.ErrEmailAlreadyVerified:
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "email already verified",
+			"details": err.Error(),
+		})
+	case err == models.ErrInvalidNickname:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid nickname",
+			"details": err.Error(),
+		})
+	case err == services.ErrInvalidMFACode:
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid two-factor code",
+			"details": err.Error(),
+		})
+	case err == services.ErrInvalidMFAChallenge:
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid two-factor challenge",
+			"details": err.Error(),
+		})
+	case err == services.ErrVerificationThrottled, err == services.ErrPasswordResetThrottled:
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"error":   "too many requests",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrTooManyLoginAttempts):
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		}
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"error":   "too many login attempts",
+			"details": err.Error(),
+		})
 	default:
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal server error",
@@ -39,8 +101,8 @@ func handleAuthError(ctx *gin.Context, err error) {
 	}
 }
 
-
 // Register godoc
+//
 //	@Summary		Регистрация нового пользователя
 //	@Description	Создает нового пользователя в системе
 //	@Tags			Auth
@@ -69,17 +131,19 @@ func (c *AuthController) Register(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, tokenResponse)
 }
 
-
 // Login godoc
+//
 //	@Summary		Аутентификация пользователя
-//	@Description	Выполняет вход в систему и возвращает токены
+//	@Description	Выполняет вход по email или никнейму (без учета регистра) и возвращает токены. Если у пользователя включена 2FA, вместо токенов возвращается mfa_token для /auth/login/mfa
 //	@Tags			Auth
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		dtos.LoginDTO			true	"Учетные данные для входа"
 //	@Success		200		{object}	dtos.TokenResponseDTO	"Успешная аутентификация"
+//	@Success		202		{object}	dtos.MFAChallengeDTO	"Требуется код 2FA"
 //	@Failure		400		{object}	dtos.ErrorResponse		"Ошибка валидации"
 //	@Failure		401		{object}	dtos.ErrorResponse		"Неверные учетные данные"
+//	@Failure		429		{object}	dtos.ErrorResponse		"Слишком много неудачных попыток, см. заголовок Retry-After"
 //	@Failure		500		{object}	dtos.ErrorResponse		"Внутренняя ошибка сервера"
 //	@Router			/auth/login [post]
 func (c *AuthController) Login(ctx *gin.Context) {
@@ -88,17 +152,53 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
 		return
 	}
+	if dto.Identifier() == "" || dto.Password == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": "login and password are required"})
+		return
+	}
 
-	tokenResponse, err := c.authService.Login(ctx, dto, ctx.Writer)
+	tokenResponse, challenge, err := c.authService.Login(ctx, dto, ctx.Writer)
 	if err != nil {
 		handleAuthError(ctx, err)
 		return
 	}
+	if challenge != nil {
+		ctx.JSON(http.StatusAccepted, challenge)
+		return
+	}
 	ctx.JSON(http.StatusOK, tokenResponse)
 }
 
+// LoginMFA godoc
+//
+//	@Summary		Второй шаг входа с 2FA
+//	@Description	Завершает вход по mfa_token из /auth/login и коду из приложения-аутентификатора или коду восстановления
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		dtos.MFALoginDTO		true	"Токен второго шага и код"
+//	@Success		200		{object}	dtos.TokenResponseDTO	"Успешная аутентификация"
+//	@Failure		400		{object}	dtos.ErrorResponse		"Ошибка валидации"
+//	@Failure		401		{object}	dtos.ErrorResponse		"Неверный код или токен второго шага"
+//	@Failure		500		{object}	dtos.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/auth/login/mfa [post]
+func (c *AuthController) LoginMFA(ctx *gin.Context) {
+	var dto dtos.MFALoginDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	tokenResponse, err := c.authService.CompleteMFALogin(ctx, dto)
+	if err != nil {
+		handleAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tokenResponse)
+}
 
 // RefreshToken обновляет access-токен
+//
 //	@Summary		Обновление JWT-токена
 //	@Description	Обновляет access-токен, используя refresh-токен из куки
 //	@Tags			Auth
@@ -116,8 +216,9 @@ func (c *AuthController) RefreshToken(ctx *gin.Context) {
 }
 
 // Logout выход из системы
+//
 //	@Summary		Выход из системы
-//	@Description	Удаляет refresh-токен и очищает куки
+//	@Description	Удаляет с сервера refresh-токен текущей сессии и очищает куки
 //	@Tags			Auth
 //	@Accept			json
 //	@Produce		json
@@ -128,4 +229,148 @@ func (c *AuthController) Logout(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{
 		"message": "successfully logged out",
 	})
-}
\ No newline at end of file
+}
+
+// LogoutAll выход со всех устройств
+//
+//	@Summary		Выход со всех устройств
+//	@Description	Удаляет все refresh-токены пользователя, завершая его сессии на всех устройствах
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	map[string]string	"Успешный выход"
+//	@Failure		401	{object}	dtos.ErrorResponse	"Пользователь не авторизован"
+//	@Failure		500	{object}	dtos.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/auth/logout-all [post]
+func (c *AuthController) LogoutAll(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.authService.LogoutAll(ctx, userID); err != nil {
+		handleAuthError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "successfully logged out from all sessions",
+	})
+}
+
+// ForgotPassword запрос на сброс пароля
+//
+//	@Summary		Запрос на сброс пароля
+//	@Description	Отправляет на email ссылку с одноразовым токеном сброса пароля. Ответ одинаков независимо от того, зарегистрирован ли email. Число запросов на один email и с одного IP ограничено
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		dtos.ForgotPasswordDTO	true	"Email аккаунта"
+//	@Success		202		{object}	map[string]string		"Запрос принят"
+//	@Failure		400		{object}	dtos.ErrorResponse		"Ошибка валидации"
+//	@Failure		429		{object}	dtos.ErrorResponse		"Слишком много запросов сброса пароля"
+//	@Router			/auth/password/forgot [post]
+func (c *AuthController) ForgotPassword(ctx *gin.Context) {
+	var dto dtos.ForgotPasswordDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	if err := c.passwordResetService.RequestReset(ctx, dto.Email, ctx.ClientIP()); err != nil {
+		handleAuthError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "if the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword сброс пароля
+//
+//	@Summary		Сброс пароля
+//	@Description	Задает новый пароль по токену из письма. Токен одноразовый; все сессии пользователя завершаются
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		dtos.ResetPasswordDTO	true	"Токен и новый пароль"
+//	@Success		200		{object}	map[string]string		"Пароль изменен"
+//	@Failure		400		{object}	dtos.ErrorResponse		"Ошибка валидации или недействительный токен"
+//	@Failure		500		{object}	dtos.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/auth/password/reset [post]
+func (c *AuthController) ResetPassword(ctx *gin.Context) {
+	var dto dtos.ResetPasswordDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	if err := c.passwordResetService.ResetPassword(ctx, dto.Token, dto.Password); err != nil {
+		handleAuthError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "password has been reset",
+	})
+}
+
+// VerifyEmail подтверждение email
+//
+//	@Summary		Подтверждение email
+//	@Description	Подтверждает email по подписанному токену из письма. Токен привязан к адресу и перестает действовать после его смены
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		dtos.VerifyEmailDTO	true	"Токен из письма"
+//	@Success		200		{object}	map[string]string	"Email подтвержден"
+//	@Failure		400		{object}	dtos.ErrorResponse	"Ошибка валидации или недействительный токен"
+//	@Failure		500		{object}	dtos.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/auth/verify-email [post]
+func (c *AuthController) VerifyEmail(ctx *gin.Context) {
+	var dto dtos.VerifyEmailDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	if err := c.emailVerificationService.Verify(ctx, dto.Token); err != nil {
+		handleAuthError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "email verified",
+	})
+}
+
+// ResendVerificationEmail повторная отправка письма подтверждения
+//
+//	@Summary		Повторная отправка письма подтверждения
+//	@Description	Повторно отправляет письмо со ссылкой подтверждения email. Письма отправляются не чаще одного раза за период ограничения
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		202	{object}	map[string]string	"Письмо отправлено"
+//	@Failure		401	{object}	dtos.ErrorResponse	"Пользователь не авторизован"
+//	@Failure		409	{object}	dtos.ErrorResponse	"Email уже подтвержден"
+//	@Failure		429	{object}	dtos.ErrorResponse	"Письмо уже отправлено недавно"
+//	@Failure		500	{object}	dtos.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/auth/verify-email/resend [post]
+func (c *AuthController) ResendVerificationEmail(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.emailVerificationService.Resend(ctx, userID); err != nil {
+		handleAuthError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "verification email sent",
+	})
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/infrastructure/api"
+)
+
+type HealthController struct {
+	jikanClient *api.JikanClient
+}
+
+func NewHealthController(jikanClient *api.JikanClient) *HealthController {
+	return &HealthController{
+		jikanClient: jikanClient,
+	}
+}
+
+// GetJikanStats godoc
+//
+//	@Summary		Статистика клиента Jikan API
+//	@Description	Возвращает глубину очереди ограничителя запросов и количество повторов
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	api.ClientStats
+//	@Router			/health/jikan [get]
+func (c *HealthController) GetJikanStats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.jikanClient.Stats())
+}
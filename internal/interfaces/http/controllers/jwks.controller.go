@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/pkg/auth"
+)
+
+type JWKSController struct {
+	keys auth.JWKSProvider
+}
+
+func NewJWKSController(keys auth.JWKSProvider) *JWKSController {
+	return &JWKSController{
+		keys: keys,
+	}
+}
+
+// GetJWKS godoc
+//
+//	@Summary		Открытые ключи подписи токенов
+//	@Description	Возвращает JWKS с открытыми ключами, которыми другие сервисы проверяют access-токены. При подписи HS256 набор пуст
+//	@Tags			Auth
+//	@Produce		json
+//	@Success		200	{object}	auth.JSONWebKeySet
+//	@Router			/.well-known/jwks.json [get]
+func (c *JWKSController) GetJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.keys.JWKS())
+}
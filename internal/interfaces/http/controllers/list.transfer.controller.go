@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/application/services"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"logur.dev/logur"
+)
+
+const formatMALXML = "mal-xml"
+
+type ListTransferController struct {
+	transferService *services.ListTransferService
+	maxUploadSize   int64
+	logger          logur.LoggerFacade
+}
+
+func NewListTransferController(transferService *services.ListTransferService, maxUploadSize int64, logger logur.LoggerFacade) *ListTransferController {
+	return &ListTransferController{
+		transferService: transferService,
+		maxUploadSize:   maxUploadSize,
+		logger:          logger,
+	}
+}
+
+// ExportUserAnimeList godoc
+//
+//	@Summary		Экспорт списка аниме
+//	@Description	Потоково выгружает весь список пользователя в формате XML-экспорта MyAnimeList. При Accept-Encoding: gzip ответ сжимается
+//	@Tags			users
+//	@Produce		xml
+//	@Param			format	query		string				false	"Формат экспорта"	Enums(mal-xml)	default(mal-xml)
+//	@Success		200		{file}		file				"Файл экспорта"
+//	@Failure		400		{object}	map[string]string	"Неподдерживаемый формат"
+//	@Failure		401		{object}	map[string]string	"Пользователь не авторизован"
+//	@Failure		500		{object}	map[string]string	"Внутренняя ошибка сервера"
+//	@Router			/me/anime/export [get]
+func (c *ListTransferController) ExportUserAnimeList(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if format := ctx.DefaultQuery("format", formatMALXML); format != formatMALXML {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported export format", "details": format})
+		return
+	}
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "application/xml; charset=utf-8")
+	header.Set("Content-Disposition", `attachment; filename="animelist.xml"`)
+	header.Add("Vary", "Accept-Encoding")
+
+	var w io.Writer = ctx.Writer
+	var gz *gzip.Writer
+	if acceptsGzip(ctx.Request) {
+		header.Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(ctx.Writer)
+		w = gz
+	}
+
+	err = c.transferService.ExportMAL(ctx, userID, w)
+	if err != nil && !ctx.Writer.Written() {
+		header.Del("Content-Encoding")
+		header.Del("Content-Disposition")
+		handleTransferError(ctx, err)
+		return
+	}
+	if err != nil {
+		// Поток уже начат: статус изменить нельзя, клиент получит оборванный файл.
+		c.logger.Error("Export stream aborted", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			c.logger.Error("Error closing gzip stream", map[string]interface{}{
+				"user_id": userID,
+				"error":   err.Error(),
+			})
+		}
+	}
+}
+
+// ImportUserAnimeList godoc
+//
+//	@Summary		Импорт списка аниме
+//	@Description	Импортирует список из XML-экспорта MyAnimeList (в том числе сжатого gzip). В пробном режиме ничего не сохраняет и возвращает различия. Большие файлы обрабатываются в фоне: ответ 202 содержит задачу, статус которой доступен по /me/anime/import/{job_id}
+//	@Tags			users
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			file	formData	file				true	"Файл экспорта MAL (.xml или .xml.gz)"
+//	@Param			dry_run	query		bool				false	"Пробный прогон без сохранения"	default(false)
+//	@Param			policy	query		string				false	"Что делать с уже добавленными аниме"	Enums(skip, overwrite, newest)	default(skip)
+//	@Success		200		{object}	models.ImportJob	"Импорт выполнен"
+//	@Success		202		{object}	models.ImportJob	"Импорт запущен в фоне"
+//	@Failure		400		{object}	map[string]string	"Неверный файл или параметры"
+//	@Failure		401		{object}	map[string]string	"Пользователь не авторизован"
+//	@Failure		413		{object}	map[string]string	"Файл слишком большой или содержит слишком много записей"
+//	@Failure		500		{object}	map[string]string	"Внутренняя ошибка сервера"
+//	@Router			/me/anime/import [post]
+func (c *ListTransferController) ImportUserAnimeList(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run value"})
+		return
+	}
+	options := models.ImportOptions{
+		DryRun: dryRun,
+		Policy: models.ImportPolicy(ctx.DefaultQuery("policy", string(models.ImportPolicySkip))),
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.maxUploadSize)
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import file is too large"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "import file is required", "details": err.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "cannot read import file", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	job, err := c.transferService.ImportMAL(ctx, userID, file, options)
+	if err != nil {
+		c.logger.Error("Error importing user anime list", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		handleTransferError(ctx, err)
+		return
+	}
+
+	if job.Status == models.ImportJobPending {
+		ctx.Header("Location", "/api/me/anime/import/"+job.ID)
+		ctx.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+// GetImportJob godoc
+//
+//	@Summary		Статус импорта списка
+//	@Description	Возвращает состояние фоновой задачи импорта и, после завершения, отчет по каждой записи
+//	@Tags			users
+//	@Produce		json
+//	@Param			job_id	path		string				true	"ID задачи импорта"
+//	@Success		200		{object}	models.ImportJob
+//	@Failure		401		{object}	map[string]string	"Пользователь не авторизован"
+//	@Failure		404		{object}	map[string]string	"Задача не найдена"
+//	@Failure		500		{object}	map[string]string	"Внутренняя ошибка сервера"
+//	@Router			/me/anime/import/{job_id} [get]
+func (c *ListTransferController) GetImportJob(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	job, err := c.transferService.GetImportJob(ctx, userID, ctx.Param("job_id"))
+	if err != nil {
+		handleTransferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+func handleTransferError(ctx *gin.Context, err error) {
+	switch {
+	case err == services.ErrUserNotFound:
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "user not found",
+			"details": err.Error(),
+		})
+	case err == services.ErrImportJobNotFound:
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "import job not found",
+			"details": err.Error(),
+		})
+	case err == services.ErrImportFileTooLarge:
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "import file is too large",
+			"details": err.Error(),
+		})
+	case err == services.ErrInvalidImportFile, err == services.ErrInvalidImportPolicy:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid import request",
+			"details": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal server error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"net/http"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/application/services"
+	"github.com/merdernoty/anime-service/internal/domain/dtos"
+)
+
+type MFAController struct {
+	mfaService *services.MFAService
+}
+
+func NewMFAController(mfaService *services.MFAService) *MFAController {
+	return &MFAController{
+		mfaService: mfaService,
+	}
+}
+
+// EnrollTOTP godoc
+//
+//	@Summary		Подключение 2FA
+//	@Description	Генерирует секрет TOTP и возвращает otpauth URI и QR-код (PNG в base64) для приложения-аутентификатора. 2FA включается после подтверждения кодом
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	dtos.TOTPEnrollmentResponse	"Данные для подключения"
+//	@Failure		401	{object}	dtos.ErrorResponse			"Пользователь не авторизован"
+//	@Failure		409	{object}	dtos.ErrorResponse			"2FA уже включена"
+//	@Failure		500	{object}	dtos.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/auth/mfa/totp/enroll [post]
+func (c *MFAController) EnrollTOTP(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	enrollment, err := c.mfaService.StartEnrollment(ctx, userID)
+	if err != nil {
+		handleMFAError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dtos.TOTPEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+		QRCodePNG:  enrollment.QRCode,
+	})
+}
+
+// ConfirmTOTP godoc
+//
+//	@Summary		Подтверждение подключения 2FA
+//	@Description	Включает 2FA после проверки кода из приложения и возвращает одноразовые коды восстановления. Коды показываются только один раз
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		dtos.MFACodeDTO				true	"Код из приложения"
+//	@Success		200		{object}	dtos.RecoveryCodesResponse	"2FA включена"
+//	@Failure		400		{object}	dtos.ErrorResponse			"Ошибка валидации или подключение не начато"
+//	@Failure		401		{object}	dtos.ErrorResponse			"Неверный код"
+//	@Failure		409		{object}	dtos.ErrorResponse			"2FA уже включена"
+//	@Failure		500		{object}	dtos.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/auth/mfa/totp/confirm [post]
+func (c *MFAController) ConfirmTOTP(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var dto dtos.MFACodeDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	codes, err := c.mfaService.ConfirmEnrollment(ctx, userID, dto.Code)
+	if err != nil {
+		handleMFAError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dtos.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+//
+//	@Summary		Отключение 2FA
+//	@Description	Отключает 2FA. Требуется действующий код из приложения; коды восстановления удаляются
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			request	body		dtos.MFACodeDTO		true	"Код из приложения"
+//	@Success		200		{object}	map[string]string	"2FA отключена"
+//	@Failure		400		{object}	dtos.ErrorResponse	"Ошибка валидации или 2FA не включена"
+//	@Failure		401		{object}	dtos.ErrorResponse	"Неверный код"
+//	@Failure		500		{object}	dtos.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/auth/mfa/totp/disable [post]
+func (c *MFAController) DisableTOTP(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var dto dtos.MFACodeDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	if err := c.mfaService.Disable(ctx, userID, dto.Code); err != nil {
+		handleMFAError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "two-factor authentication disabled",
+	})
+}
+
+func handleMFAError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid two-factor code",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "two-factor authentication already enabled",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrMFANotEnabled), errors.Is(err, services.ErrMFANotEnrolled):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "two-factor authentication is not set up",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrUserNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "user not found",
+			"details": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal server error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"net/http"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/application/services"
+	"github.com/merdernoty/anime-service/internal/domain/dtos"
+	"github.com/merdernoty/anime-service/pkg/auth"
+)
+
+type SessionController struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionController(sessionService *services.SessionService) *SessionController {
+	return &SessionController{
+		sessionService: sessionService,
+	}
+}
+
+// ListSessions godoc
+//
+//	@Summary		Активные сессии
+//	@Description	Возвращает активные входы пользователя с разных устройств: время входа и последнего использования, IP и разобранный User-Agent. Текущая сессия отмечена полем current
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	dtos.SessionListResponse	"Список сессий"
+//	@Failure		401	{object}	dtos.ErrorResponse			"Пользователь не авторизован"
+//	@Failure		500	{object}	dtos.ErrorResponse			"Внутренняя ошибка сервера"
+//	@Router			/me/sessions [get]
+func (c *SessionController) ListSessions(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessions, err := c.sessionService.List(ctx, userID)
+	if err != nil {
+		handleSessionError(ctx, err)
+		return
+	}
+
+	currentSessionID := currentSession(ctx)
+	response := dtos.SessionListResponse{Items: make([]dtos.SessionResponse, 0, len(sessions))}
+	for _, session := range sessions {
+		response.Items = append(response.Items, dtos.ToSessionResponse(session, currentSessionID))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// RevokeSession godoc
+//
+//	@Summary		Завершение сессии
+//	@Description	Отзывает сессию пользователя: ее refresh-токены удаляются, а выданные ей access-токены перестают приниматься сразу
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		string				true	"ID сессии"
+//	@Success		200	{object}	map[string]string	"Сессия завершена"
+//	@Failure		401	{object}	dtos.ErrorResponse	"Пользователь не авторизован"
+//	@Failure		404	{object}	dtos.ErrorResponse	"Сессия не найдена"
+//	@Failure		500	{object}	dtos.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/me/sessions/{id} [delete]
+func (c *SessionController) RevokeSession(ctx *gin.Context) {
+	userID, err := resolveUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.sessionService.Revoke(ctx, userID, ctx.Param("id")); err != nil {
+		handleSessionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "session revoked",
+	})
+}
+
+// currentSession возвращает ID сессии, которой выдан access-токен запроса.
+func currentSession(ctx *gin.Context) string {
+	value, exists := ctx.Get("payload")
+	if !exists {
+		return ""
+	}
+	payload, ok := value.(*auth.Payload)
+	if !ok {
+		return ""
+	}
+	return payload.SessionID
+}
+
+func handleSessionError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "session not found",
+			"details": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal server error",
+			"details": err.Error(),
+		})
+	}
+}
@@ -2,36 +2,46 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 
 	"emperror.dev/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/merdernoty/anime-service/internal/application/services"
 	"github.com/merdernoty/anime-service/internal/domain/dtos"
+	"github.com/merdernoty/anime-service/internal/domain/models"
 	"logur.dev/logur"
 )
 
 type UserController struct {
-	UserService *services.UserServiceImlp
-	logger logur.LoggerFacade
+	UserService   *services.UserServiceImlp
+	loginThrottle *services.LoginThrottleService
+	logger        logur.LoggerFacade
 }
 
-func NewUserController(userService *services.UserServiceImlp, logger logur.LoggerFacade) *UserController {
+func NewUserController(userService *services.UserServiceImlp, loginThrottle *services.LoginThrottleService, logger logur.LoggerFacade) *UserController {
 	return &UserController{
-		UserService: userService,
-		logger: logger,
+		UserService:   userService,
+		loginThrottle: loginThrottle,
+		logger:        logger,
 	}
 }
 
-func handleUserError(ctx *gin.Context, err error){
+func handleUserError(ctx *gin.Context, err error) {
 	switch {
-		case err == services.ErrUserNotFound:
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		case err == services.ErrUserAlreadyExists:
-			ctx.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
-		case err == services.ErrInvalidCredentials:
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
-		case err == services.ErrUnauthorized:
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	case err == services.ErrUserNotFound:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case err == services.ErrUserAlreadyExists:
+		ctx.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+	case err == services.ErrInvalidCredentials:
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	case err == services.ErrUnauthorized:
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	case err == services.ErrCannotChangeOwnRole:
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Cannot change own role"})
+	case err == models.ErrInvalidRole:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+	case err == models.ErrInvalidNickname:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid nickname", "details": err.Error()})
 	}
 }
 
@@ -49,32 +59,35 @@ func handleUserError(ctx *gin.Context, err error){
 // @Failure      500  {object}  dtos.ErrorResponse    "Внутренняя ошибка сервера"
 // @Router       /user/profile [get]
 func (c *UserController) GetUserProfile(ctx *gin.Context) (dtos.UserResponseDTO, error) {
-    userIDRaw, exists := ctx.Get("userID")
-    if !exists {
-        return dtos.UserResponseDTO{}, services.ErrUnauthorized
-    }
+	userIDRaw, exists := ctx.Get("userID")
+	if !exists {
+		return dtos.UserResponseDTO{}, services.ErrUnauthorized
+	}
 	userID, ok := userIDRaw.(uint)
 	if !ok {
 		c.logger.Error("Failed to cast userID to uint")
 		return dtos.UserResponseDTO{}, errors.New("failed to cast userID to uint")
 	}
 	profile, err := c.UserService.GetUserProfile(ctx, userID)
-    if err != nil {
-        return dtos.UserResponseDTO{}, err
-    }
-
-    userDTO := dtos.UserResponseDTO{
-        ID:        profile.ID,
-        Email:     profile.Email,
-        NickName:  profile.NickName,
-        FirstName: profile.FirstName,
-        LastName:  profile.LastName,
-        CreatedAt: profile.CreatedAt,
-        UpdatedAt: profile.UpdatedAt,
-    }
-    
-    return userDTO, nil
+	if err != nil {
+		return dtos.UserResponseDTO{}, err
+	}
+
+	userDTO := dtos.UserResponseDTO{
+		ID:              profile.ID,
+		Email:           profile.Email,
+		NickName:        profile.NickName,
+		FirstName:       profile.FirstName,
+		LastName:        profile.LastName,
+		Role:            profile.Role,
+		EmailVerifiedAt: profile.EmailVerifiedAt,
+		CreatedAt:       profile.CreatedAt,
+		UpdatedAt:       profile.UpdatedAt,
+	}
+
+	return userDTO, nil
 }
+
 // UpdateUserProfile godoc
 // @Summary		Обновить профиль пользователя
 // @Description	Обновить профиль пользователя по ID
@@ -90,9 +103,9 @@ func (c *UserController) GetUserProfile(ctx *gin.Context) (dtos.UserResponseDTO,
 // @Router			/user/profile [put]
 func (c *UserController) UpdateUserProfile(ctx *gin.Context) (dtos.UserResponseDTO, error) {
 	userIDRaw, exists := ctx.Get("userID")
-    if !exists {
-        return dtos.UserResponseDTO{}, services.ErrUnauthorized
-    }
+	if !exists {
+		return dtos.UserResponseDTO{}, services.ErrUnauthorized
+	}
 	userID, ok := userIDRaw.(uint)
 	if !ok {
 		c.logger.Error("Failed to cast userID to uint")
@@ -104,18 +117,108 @@ func (c *UserController) UpdateUserProfile(ctx *gin.Context) (dtos.UserResponseD
 		return dtos.UserResponseDTO{}, err
 	}
 	profile, err := c.UserService.UpdateUserProfile(ctx, userID, dto)
-	if err != nil {	
+	if err != nil {
 		handleUserError(ctx, err)
 		return dtos.UserResponseDTO{}, services.ErrUserNotFound
 	}
 	return dtos.UserResponseDTO{
-		ID:        profile.ID,
-		Email:     profile.Email,
-		NickName:  profile.NickName,
-		FirstName: profile.FirstName,
-		LastName:  profile.LastName,
-		CreatedAt: profile.CreatedAt,
-		UpdatedAt: profile.UpdatedAt,
+		ID:              profile.ID,
+		Email:           profile.Email,
+		NickName:        profile.NickName,
+		FirstName:       profile.FirstName,
+		LastName:        profile.LastName,
+		Role:            profile.Role,
+		EmailVerifiedAt: profile.EmailVerifiedAt,
+		CreatedAt:       profile.CreatedAt,
+		UpdatedAt:       profile.UpdatedAt,
 	}, nil
 }
 
+// UpdateUserRole godoc
+//
+//	@Summary		Сменить роль пользователя
+//	@Description	Назначает пользователю роль user, moderator или admin. Доступно только администраторам; свою роль изменить нельзя
+//	@Tags			Profile
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			user_id	path		int						true	"ID пользователя"
+//	@Param			request	body		dtos.UpdateUserRoleDTO	true	"Новая роль"
+//	@Success		200		{object}	dtos.UserResponseDTO	"Роль изменена"
+//	@Failure		400		{object}	dtos.ErrorResponse		"Ошибка валидации"
+//	@Failure		401		{object}	dtos.ErrorResponse		"Пользователь не авторизован"
+//	@Failure		403		{object}	dtos.ErrorResponse		"Недостаточно прав"
+//	@Failure		404		{object}	dtos.ErrorResponse		"Пользователь не найден"
+//	@Failure		500		{object}	dtos.ErrorResponse		"Внутренняя ошибка сервера"
+//	@Router			/users/{user_id}/role [put]
+func (c *UserController) UpdateUserRole(ctx *gin.Context) {
+	actorID, exists := ctx.Get("userID")
+	if !exists {
+		handleUserError(ctx, services.ErrUnauthorized)
+		return
+	}
+
+	userID, err := strconv.ParseUint(ctx.Param("user_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	var dto dtos.UpdateUserRoleDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation error", "details": err.Error()})
+		return
+	}
+
+	response, err := c.UserService.UpdateUserRole(ctx, actorID.(uint), uint(userID), models.Role(dto.Role))
+	if err != nil {
+		handleUserError(ctx, err)
+		if !ctx.Writer.Written() {
+			c.logger.Error("Failed to update user role", map[string]interface{}{"error": err.Error()})
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// UnlockUser godoc
+//
+//	@Summary		Снять блокировку входа
+//	@Description	Снимает временную блокировку входа, выставленную после серии неудачных попыток, и сбрасывает счетчики. Доступно только администраторам
+//	@Tags			Profile
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			user_id	path		int					true	"ID пользователя"
+//	@Success		200		{object}	map[string]string	"Блокировка снята"
+//	@Failure		400		{object}	dtos.ErrorResponse	"Неверный ID пользователя"
+//	@Failure		401		{object}	dtos.ErrorResponse	"Пользователь не авторизован"
+//	@Failure		403		{object}	dtos.ErrorResponse	"Недостаточно прав"
+//	@Failure		404		{object}	dtos.ErrorResponse	"Пользователь не найден"
+//	@Failure		500		{object}	dtos.ErrorResponse	"Внутренняя ошибка сервера"
+//	@Router			/users/{user_id}/unlock [post]
+func (c *UserController) UnlockUser(ctx *gin.Context) {
+	actorID, exists := ctx.Get("userID")
+	if !exists {
+		handleUserError(ctx, services.ErrUnauthorized)
+		return
+	}
+
+	userID, err := strconv.ParseUint(ctx.Param("user_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	if err := c.loginThrottle.Unlock(ctx, actorID.(uint), uint(userID)); err != nil {
+		handleUserError(ctx, err)
+		if !ctx.Writer.Written() {
+			c.logger.Error("Failed to unlock user", map[string]interface{}{"error": err.Error()})
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "user unlocked"})
+}
@@ -1,31 +1,41 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/merdernoty/anime-service/internal/domain/dtos"
+	"github.com/merdernoty/anime-service/internal/domain/models"
 	"github.com/merdernoty/anime-service/internal/domain/repositories"
 	"github.com/merdernoty/anime-service/pkg/auth"
 	"strconv"
 )
 
-type AuthMiddleware struct {
-	tokenMaker auth.TokenMaker
-	userRepository repositories.UserRepository
+// SessionValidator проверяет, что сессия, которой выдан access-токен, не отозвана.
+type SessionValidator interface {
+	IsActive(ctx context.Context, sessionID string, userID uint) (bool, error)
 }
 
+type AuthMiddleware struct {
+	tokenMaker           auth.TokenMaker
+	userRepository       repositories.UserRepository
+	sessions             SessionValidator
+	requireVerifiedEmail bool
+}
 
-func NewAuthMiddleware(tokenMaker auth.TokenMaker, userRepository repositories.UserRepository) *AuthMiddleware {
+func NewAuthMiddleware(tokenMaker auth.TokenMaker, userRepository repositories.UserRepository, sessions SessionValidator, requireVerifiedEmail bool) *AuthMiddleware {
 	return &AuthMiddleware{
-		tokenMaker: tokenMaker,
-		userRepository: userRepository,
+		tokenMaker:           tokenMaker,
+		userRepository:       userRepository,
+		sessions:             sessions,
+		requireVerifiedEmail: requireVerifiedEmail,
 	}
 }
 
 func (m *AuthMiddleware) Auth() gin.HandlerFunc {
-	return func (ctx *gin.Context) {
+	return func(ctx *gin.Context) {
 		token := ctx.GetHeader("Authorization")
 		if token == "" {
 			ctx.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
@@ -48,7 +58,7 @@ func (m *AuthMiddleware) Auth() gin.HandlerFunc {
 
 		accessToken := parts[1]
 
-		payload, err := m.tokenMaker.VerifyToken(accessToken)
+		payload, err := m.tokenMaker.VerifyToken(accessToken, auth.TokenTypeAccess, auth.AudienceAPI)
 		if err != nil {
 			ctx.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
 				Code:    http.StatusUnauthorized,
@@ -68,10 +78,37 @@ func (m *AuthMiddleware) Auth() gin.HandlerFunc {
 			return
 		}
 
+		if payload.SessionID == "" {
+			ctx.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: "Invalid or expired token",
+			})
+			ctx.Abort()
+			return
+		}
+
+		active, err := m.sessions.IsActive(ctx, payload.SessionID, uint(userID))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, dtos.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to verify session",
+			})
+			ctx.Abort()
+			return
+		}
+		if !active {
+			ctx.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: "Session has been revoked",
+			})
+			ctx.Abort()
+			return
+		}
+
 		basicUserInfo := map[string]interface{}{
-			"ID":        userID,
-			"Email":     payload.Email,
-			"NickName":  payload.Nickname,
+			"ID":       userID,
+			"Email":    payload.Email,
+			"NickName": payload.Nickname,
 		}
 
 		ctx.Set("user", basicUserInfo)
@@ -79,4 +116,83 @@ func (m *AuthMiddleware) Auth() gin.HandlerFunc {
 		ctx.Set("payload", payload)
 		ctx.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RequireRole пропускает только пользователей с ролью не ниже role.
+// Должен стоять после Auth.
+func (m *AuthMiddleware) RequireRole(role models.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		m.authorize(ctx, role)
+	}
+}
+
+// RequireOwnerOrRole пропускает владельца ресурса, чей ID указан в параметре
+// пути param, а остальных — только с ролью не ниже role. Должен стоять после Auth.
+func (m *AuthMiddleware) RequireOwnerOrRole(param string, role models.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ownerID, err := strconv.ParseUint(ctx.Param(param), 10, 32)
+		if err == nil && uint(ownerID) == ctx.GetUint("userID") {
+			ctx.Next()
+			return
+		}
+		m.authorize(ctx, role)
+	}
+}
+
+// RequireVerifiedEmail запрещает изменяющие запросы пользователям с
+// неподтвержденным email, если это включено в настройках. Запросы на чтение
+// пропускаются всегда. Должен стоять после Auth.
+func (m *AuthMiddleware) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		method := ctx.Request.Method
+		if !m.requireVerifiedEmail || method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			ctx.Next()
+			return
+		}
+
+		user, err := m.userRepository.GetByID(ctx, ctx.GetUint("userID"))
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: "User not found",
+			})
+			ctx.Abort()
+			return
+		}
+
+		if !user.IsEmailVerified() {
+			ctx.JSON(http.StatusForbidden, dtos.ErrorResponse{
+				Code:    http.StatusForbidden,
+				Message: "Email is not verified",
+			})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func (m *AuthMiddleware) authorize(ctx *gin.Context, role models.Role) {
+	user, err := m.userRepository.GetByID(ctx, ctx.GetUint("userID"))
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "User not found",
+		})
+		ctx.Abort()
+		return
+	}
+
+	if !user.Role.Includes(role) {
+		ctx.JSON(http.StatusForbidden, dtos.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Insufficient permissions",
+		})
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("role", user.Role)
+	ctx.Next()
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/domain/models"
+	"github.com/merdernoty/anime-service/internal/domain/repositories"
+)
+
+type roleTestUsers struct {
+	repositories.UserRepository
+	roles map[uint]models.Role
+}
+
+func (r *roleTestUsers) GetByID(ctx context.Context, id uint) (models.User, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return models.User{}, models.ErrUserNotFound
+	}
+	user := models.User{Role: role}
+	user.ID = id
+	return user, nil
+}
+
+// newRoleTestRouter собирает роутер, в котором Auth заменен установкой userID
+// из заголовка X-User-ID.
+func newRoleTestRouter(access func(m *AuthMiddleware) gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	users := &roleTestUsers{roles: map[uint]models.Role{
+		1: models.RoleUser,
+		2: models.RoleModerator,
+		3: models.RoleAdmin,
+	}}
+	m := NewAuthMiddleware(nil, users, nil, false)
+
+	router := gin.New()
+	authenticated := func(ctx *gin.Context) {
+		id, _ := strconv.ParseUint(ctx.GetHeader("X-User-ID"), 10, 32)
+		ctx.Set("userID", uint(id))
+	}
+	router.GET("/users/:user_id", authenticated, access(m), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	return router
+}
+
+func serveAs(router *gin.Engine, userID uint, path string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-User-ID", strconv.FormatUint(uint64(userID), 10))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		required models.Role
+		userID   uint
+		want     int
+	}{
+		{models.RoleAdmin, 1, http.StatusForbidden},
+		{models.RoleAdmin, 2, http.StatusForbidden},
+		{models.RoleAdmin, 3, http.StatusOK},
+		{models.RoleModerator, 1, http.StatusForbidden},
+		{models.RoleModerator, 2, http.StatusOK},
+		{models.RoleModerator, 3, http.StatusOK},
+		{models.RoleUser, 1, http.StatusOK},
+		{models.RoleAdmin, 99, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		router := newRoleTestRouter(func(m *AuthMiddleware) gin.HandlerFunc {
+			return m.RequireRole(tt.required)
+		})
+		if got := serveAs(router, tt.userID, "/users/1"); got != tt.want {
+			t.Errorf("user %d with required role %s: status = %d, want %d", tt.userID, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestRequireOwnerOrRole(t *testing.T) {
+	router := newRoleTestRouter(func(m *AuthMiddleware) gin.HandlerFunc {
+		return m.RequireOwnerOrRole("user_id", models.RoleModerator)
+	})
+
+	tests := []struct {
+		name   string
+		userID uint
+		path   string
+		want   int
+	}{
+		{"owner", 1, "/users/1", http.StatusOK},
+		{"another user", 1, "/users/2", http.StatusForbidden},
+		{"moderator", 2, "/users/1", http.StatusOK},
+		{"admin", 3, "/users/1", http.StatusOK},
+		{"user with a malformed id", 1, "/users/abc", http.StatusForbidden},
+		{"moderator with a malformed id", 2, "/users/abc", http.StatusOK},
+		{"deleted user", 99, "/users/1", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		if got := serveAs(router, tt.userID, tt.path); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
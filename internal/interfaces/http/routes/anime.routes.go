@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/domain/models"
 	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
 	"github.com/merdernoty/anime-service/internal/interfaces/http/middleware"
 )
@@ -20,26 +21,45 @@ func RegisterAnimeRoutes(router *gin.RouterGroup, animeController *controllers.A
 	authorizedUser.Use(authMiddleware.Auth())
 	{
 		myAnime := authorizedUser.Group("/anime")
+		myAnime.Use(authMiddleware.RequireVerifiedEmail())
 		{
 			myAnime.GET("", animeController.GetUserAnimeList)
 			myAnime.POST("", animeController.AddAnimeToUserList)
+			myAnime.POST("/bulk", animeController.BulkUpdateUserAnime)
+			myAnime.GET("/:anime_id", animeController.GetUserAnimeEntry)
+			myAnime.PATCH("/:anime_id", animeController.PatchUserAnime)
 			myAnime.DELETE("/:anime_id", animeController.RemoveAnimeFromUserList)
 			myAnime.PUT("/:anime_id/status", animeController.UpdateUserAnimeStatus)
 			myAnime.PUT("/:anime_id/episodes", animeController.UpdateUserAnimeEpisodes)
+			myAnime.POST("/:anime_id/episodes/watched", animeController.MarkEpisodesWatched)
+			myAnime.DELETE("/:anime_id/episodes/watched", animeController.UnmarkEpisodesWatched)
+			myAnime.GET("/:anime_id/episodes/history", animeController.GetEpisodeWatchHistory)
 			myAnime.PUT("/:anime_id/rating", animeController.UpdateUserAnimeRating)
+			myAnime.PUT("/:anime_id/tracking", animeController.UpdateUserAnimeTracking)
 			myAnime.GET("/stats", animeController.GetUserAnimeStats)
 		}
 	}
-	
+
+	// Чужие списки: владельцу доступно все, модераторам — чтение, администраторам — изменение.
+	readAccess := authMiddleware.RequireOwnerOrRole("user_id", models.RoleModerator)
+	writeAccess := authMiddleware.RequireOwnerOrRole("user_id", models.RoleAdmin)
+
 	adminRoutes := router.Group("/users")
-	adminRoutes.Use(authMiddleware.Auth())
+	adminRoutes.Use(authMiddleware.Auth(), authMiddleware.RequireVerifiedEmail())
 	{
-		adminRoutes.GET("/:user_id/anime", animeController.GetUserAnimeList)
-		adminRoutes.POST("/:user_id/anime", animeController.AddAnimeToUserList)
-		adminRoutes.DELETE("/:user_id/anime/:anime_id", animeController.RemoveAnimeFromUserList)
-		adminRoutes.PUT("/:user_id/anime/:anime_id/status", animeController.UpdateUserAnimeStatus)
-		adminRoutes.PUT("/:user_id/anime/:anime_id/episodes", animeController.UpdateUserAnimeEpisodes)
-		adminRoutes.PUT("/:user_id/anime/:anime_id/rating", animeController.UpdateUserAnimeRating)
-		adminRoutes.GET("/:user_id/anime/stats", animeController.GetUserAnimeStats)
+		adminRoutes.GET("/:user_id/anime", readAccess, animeController.GetUserAnimeList)
+		adminRoutes.POST("/:user_id/anime", writeAccess, animeController.AddAnimeToUserList)
+		adminRoutes.POST("/:user_id/anime/bulk", writeAccess, animeController.BulkUpdateUserAnime)
+		adminRoutes.GET("/:user_id/anime/:anime_id", readAccess, animeController.GetUserAnimeEntry)
+		adminRoutes.PATCH("/:user_id/anime/:anime_id", writeAccess, animeController.PatchUserAnime)
+		adminRoutes.DELETE("/:user_id/anime/:anime_id", writeAccess, animeController.RemoveAnimeFromUserList)
+		adminRoutes.PUT("/:user_id/anime/:anime_id/status", writeAccess, animeController.UpdateUserAnimeStatus)
+		adminRoutes.PUT("/:user_id/anime/:anime_id/episodes", writeAccess, animeController.UpdateUserAnimeEpisodes)
+		adminRoutes.POST("/:user_id/anime/:anime_id/episodes/watched", writeAccess, animeController.MarkEpisodesWatched)
+		adminRoutes.DELETE("/:user_id/anime/:anime_id/episodes/watched", writeAccess, animeController.UnmarkEpisodesWatched)
+		adminRoutes.GET("/:user_id/anime/:anime_id/episodes/history", readAccess, animeController.GetEpisodeWatchHistory)
+		adminRoutes.PUT("/:user_id/anime/:anime_id/rating", writeAccess, animeController.UpdateUserAnimeRating)
+		adminRoutes.PUT("/:user_id/anime/:anime_id/tracking", writeAccess, animeController.UpdateUserAnimeTracking)
+		adminRoutes.GET("/:user_id/anime/stats", readAccess, animeController.GetUserAnimeStats)
 	}
-}
\ No newline at end of file
+}
@@ -3,34 +3,55 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
-    "github.com/merdernoty/anime-service/internal/interfaces/http/middleware"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/middleware"
 )
 
 type Service struct {
-    AuthController *controllers.AuthController
-    AnimeController *controllers.AnimeController
-    UserController *controllers.UserController
+	AuthController         *controllers.AuthController
+	AnimeController        *controllers.AnimeController
+	UserController         *controllers.UserController
+	HealthController       *controllers.HealthController
+	ListTransferController *controllers.ListTransferController
+	SessionController      *controllers.SessionController
+	JWKSController         *controllers.JWKSController
+	MFAController          *controllers.MFAController
 }
 
 func SetupRoutes(
-    router *gin.Engine,
-    service *Service,
-    authMiddleware *middleware.AuthMiddleware,
+	router *gin.Engine,
+	service *Service,
+	authMiddleware *middleware.AuthMiddleware,
 ) {
-    api := router.Group("/api")
-    RegisterUserRoutes(api, service.UserController, authMiddleware)
-    RegisterAnimeRoutes(api, service.AnimeController, authMiddleware)
-    RegisterAuthRoutes(api,service.AuthController)
+	RegisterWellKnownRoutes(router, service.JWKSController)
+
+	api := router.Group("/api")
+	RegisterUserRoutes(api, service.UserController, authMiddleware)
+	RegisterAnimeRoutes(api, service.AnimeController, authMiddleware)
+	RegisterAuthRoutes(api, service.AuthController, authMiddleware)
+	RegisterHealthRoutes(api, service.HealthController)
+	RegisterListTransferRoutes(api, service.ListTransferController, authMiddleware)
+	RegisterSessionRoutes(api, service.SessionController, authMiddleware)
+	RegisterMFARoutes(api, service.MFAController, authMiddleware)
 }
 
 func NewService(
-    authController *controllers.AuthController,
-    animeController *controllers.AnimeController,
-    userController *controllers.UserController,
+	authController *controllers.AuthController,
+	animeController *controllers.AnimeController,
+	userController *controllers.UserController,
+	healthController *controllers.HealthController,
+	listTransferController *controllers.ListTransferController,
+	sessionController *controllers.SessionController,
+	jwksController *controllers.JWKSController,
+	mfaController *controllers.MFAController,
 ) *Service {
-    return &Service{
-        AuthController: authController,
-        AnimeController: animeController,
-        UserController: userController,
-    }
-}
\ No newline at end of file
+	return &Service{
+		AuthController:         authController,
+		AnimeController:        animeController,
+		UserController:         userController,
+		HealthController:       healthController,
+		ListTransferController: listTransferController,
+		SessionController:      sessionController,
+		JWKSController:         jwksController,
+		MFAController:          mfaController,
+	}
+}
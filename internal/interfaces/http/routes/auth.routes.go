@@ -3,15 +3,22 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/middleware"
 )
 
-func RegisterAuthRoutes(router *gin.RouterGroup, authController *controllers.AuthController) {
+func RegisterAuthRoutes(router *gin.RouterGroup, authController *controllers.AuthController, authMiddleware *middleware.AuthMiddleware) {
 
 	authRoutes := router.Group("/auth")
 	{
 		authRoutes.POST("/register", authController.Register)
 		authRoutes.POST("/login", authController.Login)
+		authRoutes.POST("/login/mfa", authController.LoginMFA)
 		authRoutes.POST("/refresh", authController.RefreshToken)
 		authRoutes.POST("/logout", authController.Logout)
+		authRoutes.POST("/logout-all", authMiddleware.Auth(), authController.LogoutAll)
+		authRoutes.POST("/password/forgot", authController.ForgotPassword)
+		authRoutes.POST("/password/reset", authController.ResetPassword)
+		authRoutes.POST("/verify-email", authController.VerifyEmail)
+		authRoutes.POST("/verify-email/resend", authMiddleware.Auth(), authController.ResendVerificationEmail)
 	}
-}	
\ No newline at end of file
+}
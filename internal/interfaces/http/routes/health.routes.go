@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
+)
+
+func RegisterHealthRoutes(router *gin.RouterGroup, healthController *controllers.HealthController) {
+	healthRoutes := router.Group("/health")
+	{
+		healthRoutes.GET("/jikan", healthController.GetJikanStats)
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/middleware"
+)
+
+func RegisterListTransferRoutes(router *gin.RouterGroup, transferController *controllers.ListTransferController, authMiddleware *middleware.AuthMiddleware) {
+	myAnime := router.Group("/me/anime")
+	myAnime.Use(authMiddleware.Auth(), authMiddleware.RequireVerifiedEmail())
+	{
+		myAnime.GET("/export", transferController.ExportUserAnimeList)
+		myAnime.POST("/import", transferController.ImportUserAnimeList)
+		myAnime.GET("/import/:job_id", transferController.GetImportJob)
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/middleware"
+)
+
+func RegisterMFARoutes(router *gin.RouterGroup, mfaController *controllers.MFAController, authMiddleware *middleware.AuthMiddleware) {
+	totp := router.Group("/auth/mfa/totp")
+	totp.Use(authMiddleware.Auth())
+	{
+		totp.POST("/enroll", mfaController.EnrollTOTP)
+		totp.POST("/confirm", mfaController.ConfirmTOTP)
+		totp.POST("/disable", mfaController.DisableTOTP)
+	}
+}
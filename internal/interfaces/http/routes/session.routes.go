@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/middleware"
+)
+
+func RegisterSessionRoutes(router *gin.RouterGroup, sessionController *controllers.SessionController, authMiddleware *middleware.AuthMiddleware) {
+	sessions := router.Group("/me/sessions")
+	sessions.Use(authMiddleware.Auth())
+	{
+		sessions.GET("", sessionController.ListSessions)
+		sessions.DELETE("/:id", sessionController.RevokeSession)
+	}
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/merdernoty/anime-service/internal/interfaces/http/controllers"
+)
+
+func RegisterWellKnownRoutes(router *gin.Engine, jwksController *controllers.JWKSController) {
+	wellKnown := router.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", jwksController.GetJWKS)
+	}
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AsymmetricTokenMaker подписывает токены активным ключом RS256 или EdDSA из
+// набора и указывает его в заголовке kid. Проверка принимает подпись любым
+// ключом набора, поэтому после ротации старые токены остаются валидными до
+// истечения, пока открытый ключ лежит в каталоге.
+type AsymmetricTokenMaker struct {
+	keys     *KeySet
+	duration time.Duration
+}
+
+func NewAsymmetricTokenMaker(keys *KeySet, duration time.Duration) *AsymmetricTokenMaker {
+	return &AsymmetricTokenMaker{
+		keys:     keys,
+		duration: duration,
+	}
+}
+
+func (maker *AsymmetricTokenMaker) CreateToken(userID uint, nickname string, email string, sessionID string, tokenType string, audience string) (string, *Payload, error) {
+	payload, err := NewPayload(userID, nickname, email, sessionID, tokenType, audience, maker.duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := maker.keys.Active()
+	token := jwt.NewWithClaims(key.Method, payload)
+	token.Header["kid"] = key.ID
+
+	signed, err := token.SignedString(key.Private)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to sign token")
+	}
+	return signed, payload, nil
+}
+
+func (maker *AsymmetricTokenMaker) VerifyToken(tokenString string, expectedType string, audience string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := maker.keys.Lookup(kid)
+		if !ok {
+			return nil, ErrUnknownKeyID
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("token algorithm does not match its key")
+		}
+		return key.Public, nil
+	}
+
+	return parseToken(tokenString, keyFunc, []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}, expectedType, audience)
+}
+
+func (maker *AsymmetricTokenMaker) JWKS() JSONWebKeySet {
+	return maker.keys.JWKS()
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// HMACSigner подписывает произвольные данные для ссылок из писем: токен —
+// это base64url(JSON) и HMAC-SHA256 от него через точку. Данные не шифруются,
+// поэтому класть в них секреты нельзя.
+//
+// Подписыватель создается для одного назначения (purpose): ключ выводится из
+// секрета с этим назначением в качестве метки, а само назначение подписывается
+// вместе с данными и сверяется в Verify. Токен, выпущенный для одной цели, не
+// подходит для другой, даже если секрет у них общий.
+type HMACSigner struct {
+	secret  []byte
+	purpose string
+}
+
+// signedPayload — подписываемый JSON: назначение токена и данные.
+type signedPayload struct {
+	Purpose string          `json:"purpose"`
+	Claims  json.RawMessage `json:"claims"`
+}
+
+func NewHMACSigner(secret string, purpose string) (*HMACSigner, error) {
+	key, err := DeriveKey(secret, "hmac "+purpose, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &HMACSigner{
+		secret:  key,
+		purpose: purpose,
+	}, nil
+}
+
+func (s *HMACSigner) Sign(claims interface{}) (string, error) {
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode signed claims")
+	}
+	data, err := json.Marshal(signedPayload{Purpose: s.purpose, Claims: encoded})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode signed claims")
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + s.signature(payload), nil
+}
+
+// Verify проверяет подпись и назначение токена и декодирует данные в claims.
+func (s *HMACSigner) Verify(token string, claims interface{}) error {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(payload))) {
+		return ErrInvalidToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	var signed signedPayload
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return ErrInvalidToken
+	}
+	if signed.Purpose != s.purpose {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(signed.Claims, claims); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (s *HMACSigner) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
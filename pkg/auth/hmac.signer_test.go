@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+type testClaims struct {
+	UserID uint `json:"uid"`
+}
+
+func TestHMACSignerRoundTrip(t *testing.T) {
+	signer, err := NewHMACSigner("secret", "email-verification")
+	if err != nil {
+		t.Fatalf("NewHMACSigner: %v", err)
+	}
+
+	token, err := signer.Sign(testClaims{UserID: 42})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	var claims testClaims
+	if err := signer.Verify(token, &claims); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.UserID != 42 {
+		t.Errorf("UserID = %d, want 42", claims.UserID)
+	}
+}
+
+func TestHMACSignerRejectsOtherPurpose(t *testing.T) {
+	verification, err := NewHMACSigner("secret", "email-verification")
+	if err != nil {
+		t.Fatalf("NewHMACSigner: %v", err)
+	}
+	other, err := NewHMACSigner("secret", "email-change")
+	if err != nil {
+		t.Fatalf("NewHMACSigner: %v", err)
+	}
+
+	token, err := other.Sign(testClaims{UserID: 42})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := verification.Verify(token, &testClaims{}); err != ErrInvalidToken {
+		t.Errorf("Verify token of another purpose: err = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestHMACSignerRejectsTamperedToken(t *testing.T) {
+	signer, err := NewHMACSigner("secret", "email-verification")
+	if err != nil {
+		t.Fatalf("NewHMACSigner: %v", err)
+	}
+	token, err := signer.Sign(testClaims{UserID: 42})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forged, err := signer.Sign(testClaims{UserID: 1})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	for name, tampered := range map[string]string{
+		"payload":   forgedPayload + "." + signature,
+		"signature": payload + "." + signature[:len(signature)-2] + "AA",
+		"no dot":    payload + signature,
+	} {
+		if err := signer.Verify(tampered, &testClaims{}); err != ErrInvalidToken {
+			t.Errorf("%s: err = %v, want %v", name, err, ErrInvalidToken)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package auth
 
 import (
-	"strconv"
 	"time"
 
 	"emperror.dev/errors"
@@ -10,7 +9,7 @@ import (
 
 type JWTTokenMaker struct {
 	secretKey string
-	duration time.Duration
+	duration  time.Duration
 }
 
 func NewJWTTokenMaker(secretKey string, duration time.Duration) *JWTTokenMaker {
@@ -21,54 +20,34 @@ func NewJWTTokenMaker(secretKey string, duration time.Duration) *JWTTokenMaker {
 }
 
 func (maker *JWTTokenMaker) CreateToken(
-    userID uint,
-    nickname string, 
+	userID uint,
+	nickname string,
 	email string,
-    token_type string,
-    expiresIn time.Duration,
-) (string, error) {
-    payload := &Payload{
-        UserID:    strconv.FormatUint(uint64(userID), 10),
-        Nickname:  nickname,
-        Email:     email,
-        TokenType: token_type,
-        IssuedAt:  time.Now().Unix(),
-        ExpiresAt: time.Now().Add(expiresIn).Unix(), 
-    }
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-    return token.SignedString([]byte(maker.secretKey))
-}
-func (maker *JWTTokenMaker) VerifyToken(tokenString string) (*Payload, error) {
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(maker.secretKey), nil
+	sessionID string,
+	tokenType string,
+	audience string,
+) (string, *Payload, error) {
+	payload, err := NewPayload(userID, nickname, email, sessionID, tokenType, audience, maker.duration)
+	if err != nil {
+		return "", nil, err
 	}
-
-	token, err := jwt.ParseWithClaims(
-		tokenString, 
-		&Payload{}, 
-		keyFunc,
-	)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	signed, err := token.SignedString([]byte(maker.secretKey))
 	if err != nil {
-		switch {
-		case err.Error() == "token is expired":
-			return nil, errors.New("token is expired")
-		case err.Error() == "token is malformed":
-			return nil, errors.New("token is malformed")
-		case err.Error() == "token is unverifiable":
-			return nil, errors.New("token is unverifiable")
-		default:
-			return nil, errors.Wrap(err, "failed to parse token")
-		}
+		return "", nil, errors.Wrap(err, "failed to sign token")
 	}
+	return signed, payload, nil
+}
 
-	payload, ok := token.Claims.(*Payload)
-	if !ok {
-		return nil, errors.New("failed to parse token claims")
+func (maker *JWTTokenMaker) VerifyToken(tokenString string, expectedType string, audience string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		return []byte(maker.secretKey), nil
 	}
 
-	return payload, nil
-}
\ No newline at end of file
+	return parseToken(tokenString, keyFunc, []string{jwt.SigningMethodHS256.Alg()}, expectedType, audience)
+}
+
+// JWKS возвращает пустой набор: общий секрет HS256 публиковать нельзя.
+func (maker *JWTTokenMaker) JWKS() JSONWebKeySet {
+	return JSONWebKeySet{Keys: []JSONWebKey{}}
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyTokenChecksTypeAndAudience(t *testing.T) {
+	maker := NewJWTTokenMaker("secret", time.Minute)
+	const otherAudience = "another-service"
+
+	tests := []struct {
+		name         string
+		tokenType    string
+		audience     string
+		expectedType string
+		wantAudience string
+		wantErr      error
+	}{
+		{"access for the api", TokenTypeAccess, AudienceAPI, TokenTypeAccess, AudienceAPI, nil},
+		{"refresh as access", TokenTypeRefresh, AudienceAPI, TokenTypeAccess, AudienceAPI, ErrInvalidTokenType},
+		{"access as refresh", TokenTypeAccess, AudienceAPI, TokenTypeRefresh, AudienceAPI, ErrInvalidTokenType},
+		{"untyped token", "", AudienceAPI, TokenTypeAccess, AudienceAPI, ErrInvalidTokenType},
+		{"access for another service", TokenTypeAccess, otherAudience, TokenTypeAccess, AudienceAPI, ErrInvalidToken},
+		{"api token at another service", TokenTypeAccess, AudienceAPI, TokenTypeAccess, otherAudience, ErrInvalidToken},
+		{"no audience", TokenTypeAccess, "", TokenTypeAccess, AudienceAPI, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _, err := maker.CreateToken(1, "user", "user@example.com", "session", tt.tokenType, tt.audience)
+			if err != nil {
+				t.Fatalf("CreateToken: %v", err)
+			}
+
+			payload, err := maker.VerifyToken(token, tt.expectedType, tt.wantAudience)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyToken: err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (payload.UserID != "1" || payload.SessionID != "session") {
+				t.Errorf("payload = (user %q, session %q), want (1, session)", payload.UserID, payload.SessionID)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenRejectsForeignTokens(t *testing.T) {
+	maker := NewJWTTokenMaker("secret", time.Minute)
+
+	sign := func(claims jwt.Claims, method jwt.SigningMethod, key interface{}) string {
+		t.Helper()
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+		return token
+	}
+	payload := func(mutate func(*Payload)) *Payload {
+		p, err := NewPayload(1, "user", "user@example.com", "session", TokenTypeAccess, AudienceAPI, time.Minute)
+		if err != nil {
+			t.Fatalf("NewPayload: %v", err)
+		}
+		mutate(p)
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"another secret", sign(payload(func(*Payload) {}), jwt.SigningMethodHS256, []byte("other")), ErrInvalidToken},
+		{"unsigned", sign(payload(func(*Payload) {}), jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), ErrInvalidToken},
+		{"another issuer", sign(payload(func(p *Payload) { p.Issuer = "someone-else" }), jwt.SigningMethodHS256, []byte("secret")), ErrInvalidToken},
+		{"expired", sign(payload(func(p *Payload) { p.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }), jwt.SigningMethodHS256, []byte("secret")), ErrExpiredToken},
+		{"without expiry", sign(payload(func(p *Payload) { p.ExpiresAt = nil }), jwt.SigningMethodHS256, []byte("secret")), ErrInvalidToken},
+		{"not yet valid", sign(payload(func(p *Payload) { p.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour)) }), jwt.SigningMethodHS256, []byte("secret")), ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := maker.VerifyToken(tt.token, TokenTypeAccess, AudienceAPI); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyToken: err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
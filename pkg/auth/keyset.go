@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyFileExt = ".pem"
+
+var (
+	ErrNoSigningKey     = errors.New("keyset has no private key to sign tokens with")
+	ErrUnsupportedKey   = errors.New("unsupported key type, expected RSA or Ed25519")
+	ErrUnknownKeyID     = errors.New("token is signed with an unknown key")
+	ErrAmbiguousSignKey = errors.New("keyset has several private keys, active key id must be set")
+)
+
+// SigningKey — ключ из набора. Для ключей, оставленных только для проверки
+// подписи (после ротации), Private равен nil.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet — набор ключей подписи: активный ключ подписывает новые токены,
+// остальные продолжают принимать уже выданные.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// LoadKeySet читает ключи из файлов <kid>.pem в каталоге dir. Файл может
+// содержать закрытый ключ RSA или Ed25519 (PKCS#8 или PKCS#1) либо только
+// открытый ключ (PKIX) — такие ключи используются лишь для проверки подписи.
+// Если activeKeyID пуст, активным становится единственный закрытый ключ набора.
+func LoadKeySet(dir string, activeKeyID string) (*KeySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read keys directory")
+	}
+
+	set := &KeySet{keys: make(map[string]*SigningKey)}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != keyFileExt {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read key file")
+		}
+
+		key, err := parseKey(strings.TrimSuffix(entry.Name(), keyFileExt), data)
+		if err != nil {
+			return nil, errors.WithDetails(err, "file", entry.Name())
+		}
+		set.keys[key.ID] = key
+	}
+
+	if activeKeyID == "" {
+		for _, key := range set.keys {
+			if key.Private == nil {
+				continue
+			}
+			if set.active != nil {
+				return nil, ErrAmbiguousSignKey
+			}
+			set.active = key
+		}
+	} else if key, ok := set.keys[activeKeyID]; ok && key.Private != nil {
+		set.active = key
+	}
+	if set.active == nil {
+		return nil, errors.WithDetails(ErrNoSigningKey, "active_key_id", activeKeyID)
+	}
+
+	return set, nil
+}
+
+func parseKey(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM block")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, errors.WithDetails(ErrUnsupportedKey, "pem_type", block.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse key")
+	}
+
+	key := &SigningKey{ID: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	return key, nil
+}
+
+// Active возвращает ключ, которым подписываются новые токены.
+func (s *KeySet) Active() *SigningKey {
+	return s.active
+}
+
+// Lookup возвращает ключ по его kid.
+func (s *KeySet) Lookup(id string) (*SigningKey, bool) {
+	key, ok := s.keys[id]
+	return key, ok
+}
+
+// JSONWebKey — открытый ключ в формате RFC 7517.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS возвращает открытые ключи набора, отсортированные по kid.
+func (s *KeySet) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwk := JSONWebKey{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+	return set
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+keyFileExt), data, 0o600); err != nil {
+		t.Fatalf("write key %s: %v", kid, err)
+	}
+}
+
+func writePrivateKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal private key %s: %v", kid, err)
+	}
+	writePEM(t, dir, kid, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("marshal public key %s: %v", kid, err)
+	}
+	writePEM(t, dir, kid, "PUBLIC KEY", der)
+}
+
+func newAsymmetricMaker(t *testing.T, dir, activeKeyID string) *AsymmetricTokenMaker {
+	t.Helper()
+	keys, err := LoadKeySet(dir, activeKeyID)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	return NewAsymmetricTokenMaker(keys, time.Minute)
+}
+
+func createAccessToken(t *testing.T, maker TokenMaker) string {
+	t.Helper()
+	token, _, err := maker.CreateToken(1, "user", "user@example.com", "session", TokenTypeAccess, AudienceAPI)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	return token
+}
+
+func tokenKeyID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Payload{})
+	if err != nil {
+		t.Fatalf("parse token header: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	writePrivateKey(t, dir, "2024-01", rsaKey)
+
+	before := newAsymmetricMaker(t, dir, "")
+	oldToken := createAccessToken(t, before)
+	if kid := tokenKeyID(t, oldToken); kid != "2024-01" {
+		t.Fatalf("token kid = %q, want 2024-01", kid)
+	}
+
+	// Ротация: новый ключ Ed25519 становится активным, от старого остается
+	// только открытая часть для проверки уже выданных токенов.
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %v", err)
+	}
+	writePrivateKey(t, dir, "2024-06", edPrivate)
+	writePublicKey(t, dir, "2024-01", &rsaKey.PublicKey)
+
+	if _, err := LoadKeySet(dir, ""); err != nil {
+		t.Fatalf("LoadKeySet with one private key: %v", err)
+	}
+	after := newAsymmetricMaker(t, dir, "2024-06")
+	newToken := createAccessToken(t, after)
+	if kid := tokenKeyID(t, newToken); kid != "2024-06" {
+		t.Errorf("token kid after rotation = %q, want 2024-06", kid)
+	}
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := after.VerifyToken(token, TokenTypeAccess, AudienceAPI); err != nil {
+			t.Errorf("VerifyToken %s token after rotation: %v", name, err)
+		}
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "2024-01" || jwks.Keys[1].KeyID != "2024-06" {
+		t.Fatalf("JWKS = %+v, want keys 2024-01 and 2024-06", jwks.Keys)
+	}
+	if key := jwks.Keys[0]; key.KeyType != "RSA" || key.Algorithm != "RS256" || key.Use != "sig" || key.N == "" || key.E == "" {
+		t.Errorf("RSA JWK = %+v", key)
+	}
+	edJWK := jwks.Keys[1]
+	if edJWK.KeyType != "OKP" || edJWK.Curve != "Ed25519" || edJWK.Algorithm != "EdDSA" {
+		t.Errorf("Ed25519 JWK = %+v", edJWK)
+	}
+	// Сторонний сервис проверяет токен только по опубликованному ключу.
+	published, err := base64.RawURLEncoding.DecodeString(edJWK.X)
+	if err != nil || !ed25519.PublicKey(published).Equal(edPublic) {
+		t.Fatalf("published Ed25519 key does not match the signing key")
+	}
+	parts := strings.Split(newToken, ".")
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(published, []byte(parts[0]+"."+parts[1]), signature) {
+		t.Error("token signature does not verify with the published key")
+	}
+
+	// Старый ключ удален из каталога: выданные им токены больше не принимаются.
+	if err := os.Remove(filepath.Join(dir, "2024-01"+keyFileExt)); err != nil {
+		t.Fatalf("remove old key: %v", err)
+	}
+	retired := newAsymmetricMaker(t, dir, "2024-06")
+	if _, err := retired.VerifyToken(oldToken, TokenTypeAccess, AudienceAPI); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken with a removed key: err = %v, want %v", err, ErrInvalidToken)
+	}
+	if len(retired.JWKS().Keys) != 1 {
+		t.Errorf("JWKS after removing the old key has %d keys, want 1", len(retired.JWKS().Keys))
+	}
+}
+
+func TestLoadKeySetChoosesActiveKey(t *testing.T) {
+	dir := t.TempDir()
+	_, first, _ := ed25519.GenerateKey(rand.Reader)
+	_, second, _ := ed25519.GenerateKey(rand.Reader)
+	writePrivateKey(t, dir, "first", first)
+	writePrivateKey(t, dir, "second", second)
+
+	if _, err := LoadKeySet(dir, ""); !errors.Is(err, ErrAmbiguousSignKey) {
+		t.Errorf("LoadKeySet with two private keys: err = %v, want %v", err, ErrAmbiguousSignKey)
+	}
+	if _, err := LoadKeySet(dir, "missing"); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("LoadKeySet with an unknown active key: err = %v, want %v", err, ErrNoSigningKey)
+	}
+
+	writePublicKey(t, dir, "public", first.Public())
+	if _, err := LoadKeySet(dir, "public"); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("LoadKeySet with a public active key: err = %v, want %v", err, ErrNoSigningKey)
+	}
+	keys, err := LoadKeySet(dir, "second")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	if keys.Active().ID != "second" {
+		t.Errorf("active key = %q, want second", keys.Active().ID)
+	}
+}
+
+func TestAsymmetricVerifyRejectsAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	writePrivateKey(t, dir, "ed", edKey)
+	writePrivateKey(t, dir, "rsa", rsaKey)
+	maker := newAsymmetricMaker(t, dir, "ed")
+
+	// Токен подписан RSA, но ссылается на kid ключа Ed25519.
+	payload, err := NewPayload(1, "user", "user@example.com", "session", TokenTypeAccess, AudienceAPI, time.Minute)
+	if err != nil {
+		t.Fatalf("NewPayload: %v", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, payload)
+	token.Header["kid"] = "ed"
+	signed, err := token.SignedString(rsaKey)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	if _, err := maker.VerifyToken(signed, TokenTypeAccess, AudienceAPI); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken with a mismatched algorithm: err = %v, want %v", err, ErrInvalidToken)
+	}
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"emperror.dev/errors"
+)
+
+const opaqueTokenBytes = 32
+
+// NewOpaqueToken генерирует случайный непрозрачный токен и его хеш для хранения на сервере.
+func NewOpaqueToken() (token string, tokenHash string, err error) {
+	buf := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", errors.Wrap(err, "failed to generate token")
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken возвращает хеш, под которым токен хранится в базе.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewRefreshToken генерирует непрозрачный refresh-токен и его хеш для хранения на сервере.
+func NewRefreshToken() (token string, tokenHash string, err error) {
+	return NewOpaqueToken()
+}
+
+// HashRefreshToken возвращает хеш, под которым токен хранится в базе.
+func HashRefreshToken(token string) string {
+	return HashOpaqueToken(token)
+}
+
+// RefreshTokenRotator выводит преемника refresh-токена из самого токена:
+// HMAC-SHA256 от него на ключе, выведенном из секрета сервера. Преемник
+// детерминирован, поэтому два запроса, одновременно обменявшие один и тот же
+// токен, получают одинаковый новый токен. Без секрета преемника не вычислить,
+// так что украденный использованный токен не дает доступа к следующему.
+type RefreshTokenRotator struct {
+	key []byte
+}
+
+func NewRefreshTokenRotator(secret string) (*RefreshTokenRotator, error) {
+	key, err := DeriveKey(secret, "refresh-token rotation", sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshTokenRotator{key: key}, nil
+}
+
+// Next возвращает преемника token и его хеш для хранения на сервере.
+func (r *RefreshTokenRotator) Next(token string) (next string, nextHash string) {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(token))
+	next = base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return next, HashRefreshToken(next)
+}
+
+// NewTokenFamilyID генерирует идентификатор семейства refresh-токенов.
+func NewTokenFamilyID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate token family id")
+	}
+	return hex.EncodeToString(buf), nil
+}
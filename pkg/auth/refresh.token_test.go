@@ -0,0 +1,34 @@
+package auth
+
+import "testing"
+
+func TestRefreshTokenRotatorNext(t *testing.T) {
+	rotator, err := NewRefreshTokenRotator("secret")
+	if err != nil {
+		t.Fatalf("NewRefreshTokenRotator: %v", err)
+	}
+
+	next, nextHash := rotator.Next("token")
+	again, againHash := rotator.Next("token")
+	if next != again || nextHash != againHash {
+		t.Errorf("Next is not deterministic: %q/%q, %q/%q", next, nextHash, again, againHash)
+	}
+	if nextHash != HashRefreshToken(next) {
+		t.Errorf("hash = %q, want HashRefreshToken(next)", nextHash)
+	}
+	if next == "token" {
+		t.Error("successor equals the rotated token")
+	}
+
+	if other, _ := rotator.Next("other"); other == next {
+		t.Error("different tokens share a successor")
+	}
+
+	foreign, err := NewRefreshTokenRotator("another-secret")
+	if err != nil {
+		t.Fatalf("NewRefreshTokenRotator: %v", err)
+	}
+	if successor, _ := foreign.Next("token"); successor == next {
+		t.Error("successor does not depend on the secret")
+	}
+}
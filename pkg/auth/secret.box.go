@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+const sealedSecretPrefix = "v1."
+
+var ErrInvalidSealedSecret = errors.New("sealed secret is invalid")
+
+// DeriveKey выводит из secret ключ длины size для назначения label (HKDF-SHA256).
+// Ключи с разными label независимы, даже если выведены из одного секрета.
+func DeriveKey(secret string, label string, size int) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, "anime-service "+label, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive key")
+	}
+	return key, nil
+}
+
+// SecretBox шифрует небольшие секреты для хранения в базе (AES-256-GCM).
+// Зашифрованное значение — "v1." и base64url от nonce и шифротекста.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox создает SecretBox с ключом, выведенным из secret для назначения label.
+func NewSecretBox(secret string, label string) (*SecretBox, error) {
+	key, err := DeriveKey(secret, label, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+func (b *SecretBox) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "failed to generate nonce")
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedSecretPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (b *SecretBox) Open(sealed string) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedSecretPrefix)
+	if !ok {
+		return "", ErrInvalidSealedSecret
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrInvalidSealedSecret
+	}
+
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidSealedSecret
+	}
+	return string(plaintext), nil
+}
@@ -1,37 +1,119 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
 	"time"
 
+	"emperror.dev/errors"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+
+	// Issuer — значение claim iss во всех токенах сервиса.
+	Issuer = "anime-service"
+	// AudienceAPI — получатель access-токенов HTTP API.
+	AudienceAPI = "anime-service-api"
+)
+
+var (
+	ErrInvalidToken     = errors.New("token is invalid")
+	ErrExpiredToken     = errors.New("token is expired")
+	ErrInvalidTokenType = errors.New("token has unexpected type")
+)
+
 type TokenMaker interface {
-	CreateToken(userID uint, nickname string, email string, token_type string, expiresIn time.Duration) (string, error)
-	VerifyToken(token string) (*Payload, error)
+	// CreateToken выпускает токен заданного типа для получателя audience на
+	// срок, настроенный в реализации, и возвращает его вместе с claims.
+	CreateToken(userID uint, nickname string, email string, sessionID string, tokenType string, audience string) (string, *Payload, error)
+	// VerifyToken проверяет подпись, iss, aud, exp и nbf токена, а также его тип.
+	VerifyToken(token string, expectedType string, audience string) (*Payload, error)
+}
+
+// JWKSProvider отдает открытые ключи, которыми другие сервисы проверяют токены.
+type JWKSProvider interface {
+	JWKS() JSONWebKeySet
 }
 
 type Payload struct {
 	jwt.RegisteredClaims
-	UserID string `json:"user_id"`
-	Nickname string `json:"nickname"`
-	Email string `json:"email"`
+	UserID    string `json:"user_id"`
+	Nickname  string `json:"nickname"`
+	Email     string `json:"email"`
 	TokenType string `json:"token_type"`
-	IssuedAt int64 `json:"issued_at"`
-	ExpiresAt int64 `json:"expires_at"`
+	SessionID string `json:"sid"`
 }
 
-func NewPayload(userID, nickname string, email string, TokenType string, IssuedAt int64,ExpiresAt int64) *Payload {
+func NewPayload(userID uint, nickname string, email string, sessionID string, tokenType string, audience string, duration time.Duration) (*Payload, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
+	subject := strconv.FormatUint(uint64(userID), 10)
 	return &Payload{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(24 * time.Hour)),
-			IssuedAt: jwt.NewNumericDate(now),
+			ID:        tokenID,
+			Issuer:    Issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
-			Issuer: "anime-service",
 		},
-		UserID: userID,
-		Nickname: nickname,
-		Email: email,
+		UserID:    subject,
+		Nickname:  nickname,
+		Email:     email,
+		TokenType: tokenType,
+		SessionID: sessionID,
+	}, nil
+}
+
+// ExpiresIn возвращает срок жизни токена.
+func (p *Payload) ExpiresIn() time.Duration {
+	return p.ExpiresAt.Sub(p.IssuedAt.Time)
+}
+
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate token id")
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// parseToken проверяет подпись и стандартные claims токена и сверяет его тип.
+func parseToken(tokenString string, keyFunc jwt.Keyfunc, methods []string, expectedType string, audience string) (*Payload, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&Payload{},
+		keyFunc,
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return nil, ErrExpiredToken
+		default:
+			return nil, errors.WithDetails(ErrInvalidToken, "reason", err.Error())
+		}
 	}
-}
\ No newline at end of file
+
+	payload, ok := token.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if payload.TokenType != expectedType {
+		return nil, ErrInvalidTokenType
+	}
+
+	return payload, nil
+}